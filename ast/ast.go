@@ -23,8 +23,43 @@ type Expression interface {
 	expressionNode()
 }
 
+// Comment is a comment preserved from the source
+type Comment struct {
+	Token token.Token // the COMMENT token
+	Text  string      // the comment including its // or /* */ delimiters
+}
+
+func (c *Comment) TokenLiteral() string { return c.Token.Literal }
+func (c *Comment) String() string       { return c.Text }
+
+// IsBlock reports whether the comment is a /* */ comment
+func (c *Comment) IsBlock() bool { return strings.HasPrefix(c.Text, "/*") }
+
+// IsLegal reports whether the comment is a /*! */ legal comment, such as a
+// license header, which is kept even when comments are removed
+func (c *Comment) IsLegal() bool { return strings.HasPrefix(c.Text, "/*!") }
+
+// Trivia holds the comments attached to a node. Leading comments come before
+// the node, trailing comments follow a statement on its last line.
+type Trivia struct {
+	LeadingComments  []*Comment
+	TrailingComments []*Comment
+}
+
+// Comments returns the comments attached to the node
+func (t *Trivia) Comments() *Trivia { return t }
+
+// Commented is implemented by nodes that carry comments
+type Commented interface {
+	Node
+	Comments() *Trivia
+}
+
 type Program struct {
 	Statements []Statement
+
+	// EndComments are the comments after the last statement
+	EndComments []*Comment
 }
 
 func (p *Program) TokenLiteral() string {
@@ -42,7 +77,20 @@ func (p *Program) String() string {
 	return out.String()
 }
 
+// MissingNode stands for a statement the parser could not make sense of,
+// so that a program with syntax errors still has a complete tree without
+// nil children. It spans the tokens skipped until the parser could resume.
+type MissingNode struct {
+	Token token.Token // the first token of the statement
+}
+
+func (m *MissingNode) statementNode()       {}
+func (m *MissingNode) expressionNode()      {}
+func (m *MissingNode) TokenLiteral() string { return m.Token.Literal }
+func (m *MissingNode) String() string       { return "" }
+
 type Identifier struct {
+	Trivia
 	Token token.Token // the IDENT token
 	Value string
 }
@@ -52,9 +100,17 @@ func (i *Identifier) TokenLiteral() string { return i.Token.Literal }
 func (i *Identifier) String() string       { return i.Value }
 
 type LetStatement struct {
-	Token token.Token // the LET token
-	Name  *Identifier
-	Value Expression
+	Trivia
+	Token   token.Token // the LET, CONST or VAR token
+	Name    *Identifier // the declared name, nil when Pattern is set
+	Pattern Expression  // the *ObjectPattern or *ArrayPattern of a destructuring declaration
+	Type    TypeNode    // the annotated type, nil when not given
+	Value   Expression  // the initializer, nil when not given
+	JSDoc   *JSDoc      // the documentation comment, if any
+
+	// Definite is set by let x!: T, which asserts that x is assigned before
+	// it is used
+	Definite bool
 }
 
 func (ls *LetStatement) statementNode()       {}
@@ -63,10 +119,16 @@ func (ls *LetStatement) String() string {
 	var out bytes.Buffer
 
 	out.WriteString(ls.TokenLiteral() + " ")
-	out.WriteString(ls.Name.String())
-	out.WriteString(" = ")
+	out.WriteString(ls.Target().String())
+	if ls.Definite {
+		out.WriteString("!")
+	}
+	if ls.Type != nil {
+		out.WriteString(": " + ls.Type.String())
+	}
 
 	if ls.Value != nil {
+		out.WriteString(" = ")
 		out.WriteString(ls.Value.String())
 	}
 
@@ -75,7 +137,16 @@ func (ls *LetStatement) String() string {
 	return out.String()
 }
 
+// Target returns the declared name or pattern
+func (ls *LetStatement) Target() Expression {
+	if ls.Pattern != nil {
+		return ls.Pattern
+	}
+	return ls.Name
+}
+
 type ReturnStatement struct {
+	Trivia
 	Token       token.Token // the RETURN token
 	ReturnValue Expression
 }
@@ -97,6 +168,7 @@ func (rs *ReturnStatement) String() string {
 }
 
 type ExpressionStatement struct {
+	Trivia
 	Token      token.Token // the first token of the expression
 	Expression Expression
 }
@@ -111,6 +183,7 @@ func (es *ExpressionStatement) String() string {
 }
 
 type IntegerLiteral struct {
+	Trivia
 	Token token.Token
 	Value int64
 }
@@ -120,6 +193,7 @@ func (il *IntegerLiteral) TokenLiteral() string { return il.Token.Literal }
 func (il *IntegerLiteral) String() string       { return il.Token.Literal }
 
 type StringLiteral struct {
+	Trivia
 	Token token.Token
 	Value string
 }
@@ -128,7 +202,20 @@ func (sl *StringLiteral) expressionNode()      {}
 func (sl *StringLiteral) TokenLiteral() string { return sl.Token.Literal }
 func (sl *StringLiteral) String() string       { return "\"" + sl.Value + "\"" }
 
+// RegExpLiteral is a regular expression literal, e.g. /ab+c/gi
+type RegExpLiteral struct {
+	Trivia
+	Token   token.Token // the REGEXP token
+	Pattern string
+	Flags   string
+}
+
+func (rl *RegExpLiteral) expressionNode()      {}
+func (rl *RegExpLiteral) TokenLiteral() string { return rl.Token.Literal }
+func (rl *RegExpLiteral) String() string       { return "/" + rl.Pattern + "/" + rl.Flags }
+
 type Boolean struct {
+	Trivia
 	Token token.Token
 	Value bool
 }
@@ -137,8 +224,40 @@ func (b *Boolean) expressionNode()      {}
 func (b *Boolean) TokenLiteral() string { return b.Token.Literal }
 func (b *Boolean) String() string       { return b.Token.Literal }
 
+// NullLiteral is the null keyword
+type NullLiteral struct {
+	Trivia
+	Token token.Token
+}
+
+func (nl *NullLiteral) expressionNode()      {}
+func (nl *NullLiteral) TokenLiteral() string { return nl.Token.Literal }
+func (nl *NullLiteral) String() string       { return nl.Token.Literal }
+
+// ThisExpression is the this keyword
+type ThisExpression struct {
+	Trivia
+	Token token.Token
+}
+
+func (te *ThisExpression) expressionNode()      {}
+func (te *ThisExpression) TokenLiteral() string { return te.Token.Literal }
+func (te *ThisExpression) String() string       { return te.Token.Literal }
+
+// SuperExpression is the super keyword, which is only valid in a call,
+// super(), or a property access, super.method()
+type SuperExpression struct {
+	Trivia
+	Token token.Token
+}
+
+func (se *SuperExpression) expressionNode()      {}
+func (se *SuperExpression) TokenLiteral() string { return se.Token.Literal }
+func (se *SuperExpression) String() string       { return se.Token.Literal }
+
 // A prefix expression (e.g. -5, !true)
 type PrefixExpression struct {
+	Trivia
 	Token    token.Token // The prefix token, e.g. !
 	Operator string
 	Right    Expression
@@ -151,14 +270,49 @@ func (pe *PrefixExpression) String() string {
 
 	out.WriteString("(")
 	out.WriteString(pe.Operator)
+	if pe.IsKeyword() {
+		out.WriteString(" ")
+	}
 	out.WriteString(pe.Right.String())
 	out.WriteString(")")
 
 	return out.String()
 }
 
+// IsKeyword reports whether the operator is a keyword, like typeof
+func (pe *PrefixExpression) IsKeyword() bool {
+	return pe.Operator == "typeof" || pe.Operator == "void" || pe.Operator == "delete"
+}
+
+// A postfix expression (e.g. i++)
+type PostfixExpression struct {
+	Trivia
+	Token    token.Token // The operator token, e.g. ++
+	Left     Expression
+	Operator string
+}
+
+func (pe *PostfixExpression) expressionNode()      {}
+func (pe *PostfixExpression) TokenLiteral() string { return pe.Token.Literal }
+func (pe *PostfixExpression) String() string {
+	return "(" + pe.Left.String() + pe.Operator + ")"
+}
+
+// NonNullExpression is x!, which asserts that x is neither null nor
+// undefined
+type NonNullExpression struct {
+	Trivia
+	Token      token.Token // the ! token
+	Expression Expression
+}
+
+func (ne *NonNullExpression) expressionNode()      {}
+func (ne *NonNullExpression) TokenLiteral() string { return ne.Token.Literal }
+func (ne *NonNullExpression) String() string       { return ne.Expression.String() + "!" }
+
 // An infix expression (e.g. 5 + 5)
 type InfixExpression struct {
+	Trivia
 	Token    token.Token // The operator token, e.g. +
 	Left     Expression
 	Operator string
@@ -179,12 +333,73 @@ func (ie *InfixExpression) String() string {
 	return out.String()
 }
 
+// AssignmentExpression assigns to a variable or property, with = or a
+// compound operator such as +=
+type AssignmentExpression struct {
+	Trivia
+	Token    token.Token // The operator token, e.g. =
+	Target   Expression
+	Operator string
+	Value    Expression
+}
+
+func (ae *AssignmentExpression) expressionNode()      {}
+func (ae *AssignmentExpression) TokenLiteral() string { return ae.Token.Literal }
+func (ae *AssignmentExpression) String() string {
+	return "(" + ae.Target.String() + " " + ae.Operator + " " + ae.Value.String() + ")"
+}
+
+// ConditionalExpression is the ternary operator (e.g. a ? b : c)
+type ConditionalExpression struct {
+	Trivia
+	Token       token.Token // The ? token
+	Condition   Expression
+	Consequence Expression
+	Alternative Expression
+}
+
+func (ce *ConditionalExpression) expressionNode()      {}
+func (ce *ConditionalExpression) TokenLiteral() string { return ce.Token.Literal }
+func (ce *ConditionalExpression) String() string {
+	return "(" + ce.Condition.String() + " ? " + ce.Consequence.String() + " : " + ce.Alternative.String() + ")"
+}
+
+// ParenthesizedExpression is an expression written in parentheses. It is
+// kept in the tree because parentheses matter to some rules, e.g. a ?? b || c
+// is an error while a ?? (b || c) is not.
+type ParenthesizedExpression struct {
+	Trivia
+	Token      token.Token // The ( token
+	Expression Expression
+}
+
+func (pe *ParenthesizedExpression) expressionNode()      {}
+func (pe *ParenthesizedExpression) TokenLiteral() string { return pe.Token.Literal }
+func (pe *ParenthesizedExpression) String() string       { return pe.Expression.String() }
+
+// SkipParentheses returns the expression inside any number of parentheses
+func SkipParentheses(expr Expression) Expression {
+	for {
+		pe, ok := expr.(*ParenthesizedExpression)
+		if !ok {
+			return expr
+		}
+		expr = pe.Expression
+	}
+}
+
 // FunctionLiteral is a function definition
 type FunctionLiteral struct {
-	Token      token.Token     
-	Name       *Identifier     
-	Parameters []*Identifier   
-	Body       *BlockStatement 
+	Trivia
+	Token      token.Token
+	Name       *Identifier
+	Parameters []*BindingElement
+	ThisType   TypeNode // the type of a this parameter, nil when not given
+	ReturnType TypeNode // the annotated return type, nil when not given
+	Body       *BlockStatement
+	JSDoc      *JSDoc // the documentation comment, if any
+	Async      bool   // async function, which returns a promise
+	Generator  bool   // function*, which returns a generator
 }
 
 func (fl *FunctionLiteral) expressionNode()      {}
@@ -193,26 +408,76 @@ func (fl *FunctionLiteral) String() string {
 	var out bytes.Buffer
 
 	params := []string{}
+	if fl.ThisType != nil {
+		params = append(params, "this: "+fl.ThisType.String())
+	}
 	for _, p := range fl.Parameters {
 		params = append(params, p.String())
 	}
 
+	if fl.Async {
+		out.WriteString("async ")
+	}
 	out.WriteString(fl.TokenLiteral())
+	if fl.Generator {
+		out.WriteString("*")
+	}
 	if fl.Name != nil {
 		out.WriteString(" " + fl.Name.Value)
 	}
 	out.WriteString("(")
 	out.WriteString(strings.Join(params, ", "))
-	out.WriteString(") ")
+	out.WriteString(")")
+	if fl.ReturnType != nil {
+		out.WriteString(": " + fl.ReturnType.String())
+	}
+	out.WriteString(" ")
 	out.WriteString(fl.Body.String())
 
 	return out.String()
 }
 
+// AwaitExpression waits for a promise to settle, e.g. await fetch(url)
+type AwaitExpression struct {
+	Trivia
+	Token    token.Token // the await identifier
+	Argument Expression
+}
+
+func (ae *AwaitExpression) expressionNode()      {}
+func (ae *AwaitExpression) TokenLiteral() string { return ae.Token.Literal }
+func (ae *AwaitExpression) String() string       { return "(await " + ae.Argument.String() + ")" }
+
+// YieldExpression suspends a generator, e.g. yield value, or delegates to
+// another iterable with yield* values
+type YieldExpression struct {
+	Trivia
+	Token    token.Token // the yield identifier
+	Argument Expression  // nil for a bare yield
+	Delegate bool        // yield*
+}
+
+func (ye *YieldExpression) expressionNode()      {}
+func (ye *YieldExpression) TokenLiteral() string { return ye.Token.Literal }
+func (ye *YieldExpression) String() string {
+	out := "(yield"
+	if ye.Delegate {
+		out += "*"
+	}
+	if ye.Argument != nil {
+		out += " " + ye.Argument.String()
+	}
+	return out + ")"
+}
+
 // BlockStatement represents a block of statements
 type BlockStatement struct {
+	Trivia
 	Token      token.Token // The { token
 	Statements []Statement
+
+	// EndComments are the comments between the last statement and the }
+	EndComments []*Comment
 }
 
 func (bs *BlockStatement) statementNode()       {}
@@ -229,11 +494,224 @@ func (bs *BlockStatement) String() string {
 	return out.String()
 }
 
+// SwitchStatement is switch (Discriminant) { case a: ... default: ... }
+type SwitchStatement struct {
+	Trivia
+	Token        token.Token // the SWITCH token
+	Discriminant Expression
+	Cases        []*SwitchCase
+}
+
+func (ss *SwitchStatement) statementNode()       {}
+func (ss *SwitchStatement) TokenLiteral() string { return ss.Token.Literal }
+func (ss *SwitchStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("switch (" + ss.Discriminant.String() + ") { ")
+	for _, c := range ss.Cases {
+		out.WriteString(c.String())
+	}
+	out.WriteString("}")
+
+	return out.String()
+}
+
+// SwitchCase is a case clause of a switch statement, or its default clause
+// when Test is nil. Without a break its statements fall through to the next
+// clause.
+type SwitchCase struct {
+	Token      token.Token // the CASE or DEFAULT token
+	Test       Expression
+	Consequent []Statement
+}
+
+func (sc *SwitchCase) String() string {
+	var out bytes.Buffer
+
+	if sc.Test == nil {
+		out.WriteString("default: ")
+	} else {
+		out.WriteString("case " + sc.Test.String() + ": ")
+	}
+	for _, s := range sc.Consequent {
+		out.WriteString(s.String() + " ")
+	}
+
+	return out.String()
+}
+
+// BreakStatement leaves the innermost loop or switch statement, or the
+// statement with the label
+type BreakStatement struct {
+	Trivia
+	Token token.Token // the BREAK token
+	Label *Identifier // nil without a label
+}
+
+func (bs *BreakStatement) statementNode()       {}
+func (bs *BreakStatement) TokenLiteral() string { return bs.Token.Literal }
+func (bs *BreakStatement) String() string {
+	if bs.Label != nil {
+		return "break " + bs.Label.Value + ";"
+	}
+	return "break;"
+}
+
+// LabeledStatement is Label: Body, which break and continue statements in
+// the body may name
+type LabeledStatement struct {
+	Trivia
+	Token token.Token // the IDENT token of the label
+	Label *Identifier
+	Body  Statement
+}
+
+func (ls *LabeledStatement) statementNode()       {}
+func (ls *LabeledStatement) TokenLiteral() string { return ls.Token.Literal }
+func (ls *LabeledStatement) String() string       { return ls.Label.Value + ": " + ls.Body.String() }
+
+// IfStatement is if (Condition) Consequence else Alternative, where the
+// else branch may be left out
+type IfStatement struct {
+	Trivia
+	Token       token.Token // the IF token
+	Condition   Expression
+	Consequence Statement
+	Alternative Statement // nil without an else branch
+}
+
+func (is *IfStatement) statementNode()       {}
+func (is *IfStatement) TokenLiteral() string { return is.Token.Literal }
+func (is *IfStatement) String() string {
+	out := "if (" + is.Condition.String() + ") " + is.Consequence.String()
+	if is.Alternative != nil {
+		out += " else " + is.Alternative.String()
+	}
+	return out
+}
+
+// ThrowStatement is throw Argument;
+type ThrowStatement struct {
+	Trivia
+	Token    token.Token // the THROW token
+	Argument Expression
+}
+
+func (ts *ThrowStatement) statementNode()       {}
+func (ts *ThrowStatement) TokenLiteral() string { return ts.Token.Literal }
+func (ts *ThrowStatement) String() string       { return "throw " + ts.Argument.String() + ";" }
+
+// TryStatement is try { } catch (e) { } finally { }, where either the catch
+// clause or the finally block may be left out
+type TryStatement struct {
+	Trivia
+	Token     token.Token // the TRY token
+	Block     *BlockStatement
+	Handler   *CatchClause
+	Finalizer *BlockStatement
+}
+
+func (ts *TryStatement) statementNode()       {}
+func (ts *TryStatement) TokenLiteral() string { return ts.Token.Literal }
+func (ts *TryStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("try " + ts.Block.String())
+	if ts.Handler != nil {
+		out.WriteString(" " + ts.Handler.String())
+	}
+	if ts.Finalizer != nil {
+		out.WriteString(" finally " + ts.Finalizer.String())
+	}
+
+	return out.String()
+}
+
+// CatchClause is catch (Param) { }. Param is nil for catch { }, and may be a
+// destructuring pattern.
+type CatchClause struct {
+	Token token.Token // the CATCH token
+	Param Expression
+	Body  *BlockStatement
+}
+
+func (cc *CatchClause) String() string {
+	if cc.Param == nil {
+		return "catch " + cc.Body.String()
+	}
+	return "catch (" + cc.Param.String() + ") " + cc.Body.String()
+}
+
+// ForOfStatement is for (const x of xs) Body, or for await (...) with Await
+// set. Kind is the let, const or var declaring the loop variable, or empty
+// when Target is an existing variable, property or pattern.
+type ForOfStatement struct {
+	Trivia
+	Token  token.Token // the FOR token
+	Await  bool
+	Kind   string
+	Target Expression
+	Right  Expression
+	Body   Statement
+}
+
+func (fs *ForOfStatement) statementNode()       {}
+func (fs *ForOfStatement) TokenLiteral() string { return fs.Token.Literal }
+func (fs *ForOfStatement) String() string {
+	await := ""
+	if fs.Await {
+		await = " await"
+	}
+	return "for" + await + " (" + forHead(fs.Kind, fs.Target) + " of " + fs.Right.String() + ") " + fs.Body.String()
+}
+
+// ForInStatement is for (const key in object) Body
+type ForInStatement struct {
+	Trivia
+	Token  token.Token // the FOR token
+	Kind   string
+	Target Expression
+	Right  Expression
+	Body   Statement
+}
+
+func (fs *ForInStatement) statementNode()       {}
+func (fs *ForInStatement) TokenLiteral() string { return fs.Token.Literal }
+func (fs *ForInStatement) String() string {
+	return "for (" + forHead(fs.Kind, fs.Target) + " in " + fs.Right.String() + ") " + fs.Body.String()
+}
+
+func forHead(kind string, target Expression) string {
+	if kind == "" {
+		return target.String()
+	}
+	return kind + " " + target.String()
+}
+
+// ContinueStatement starts the next iteration of the innermost loop, or of
+// the loop with the label
+type ContinueStatement struct {
+	Trivia
+	Token token.Token // the CONTINUE token
+	Label *Identifier // nil without a label
+}
+
+func (cs *ContinueStatement) statementNode()       {}
+func (cs *ContinueStatement) TokenLiteral() string { return cs.Token.Literal }
+func (cs *ContinueStatement) String() string {
+	if cs.Label != nil {
+		return "continue " + cs.Label.Value + ";"
+	}
+	return "continue;"
+}
+
 // CallExpression represents a function call (function())
 type CallExpression struct {
+	Trivia
 	Token     token.Token // The '(' token
 	Function  Expression  // The function to call
 	Arguments []Expression
+	Optional  bool // f?.(), which is undefined when f is null or undefined
 }
 
 func (ce *CallExpression) expressionNode()      {}
@@ -247,6 +725,9 @@ func (ce *CallExpression) String() string {
 	}
 
 	out.WriteString(ce.Function.String())
+	if ce.Optional {
+		out.WriteString("?.")
+	}
 	out.WriteString("(")
 	out.WriteString(strings.Join(args, ", "))
 	out.WriteString(")")
@@ -254,12 +735,51 @@ func (ce *CallExpression) String() string {
 	return out.String()
 }
 
+// NewExpression constructs an object, e.g. new Map(entries). Arguments is
+// nil when the argument list is left out, as in new Date.
+type NewExpression struct {
+	Trivia
+	Token     token.Token // the new token
+	Callee    Expression
+	Arguments []Expression
+}
+
+func (ne *NewExpression) expressionNode()      {}
+func (ne *NewExpression) TokenLiteral() string { return ne.Token.Literal }
+func (ne *NewExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("new " + ne.Callee.String())
+	if ne.Arguments != nil {
+		args := []string{}
+		for _, a := range ne.Arguments {
+			args = append(args, a.String())
+		}
+		out.WriteString("(" + strings.Join(args, ", ") + ")")
+	}
+
+	return out.String()
+}
+
+// MetaProperty is new.target, the function new was called with
+type MetaProperty struct {
+	Trivia
+	Token    token.Token // the new token
+	Property string      // target
+}
+
+func (mp *MetaProperty) expressionNode()      {}
+func (mp *MetaProperty) TokenLiteral() string { return mp.Token.Literal }
+func (mp *MetaProperty) String() string       { return mp.Token.Literal + "." + mp.Property }
+
 // MethodCallExpression represents a method call (object.method())
 type MethodCallExpression struct {
+	Trivia
 	Token     token.Token // The '(' token
 	Object    Expression  // Object on which the method is called
 	Method    *Identifier // Method name
 	Arguments []Expression
+	Optional  bool // object?.method, which is undefined when object is null or undefined
 }
 
 func (mc *MethodCallExpression) expressionNode()      {}
@@ -273,6 +793,9 @@ func (mc *MethodCallExpression) String() string {
 	}
 
 	out.WriteString(mc.Object.String())
+	if mc.Optional {
+		out.WriteString("?")
+	}
 	out.WriteString(".")
 	out.WriteString(mc.Method.String())
 	if mc.Arguments != nil {
@@ -283,3 +806,239 @@ func (mc *MethodCallExpression) String() string {
 
 	return out.String()
 }
+
+// IndexExpression is an element access (e.g. a[0])
+type IndexExpression struct {
+	Trivia
+	Token    token.Token // The '[' token
+	Left     Expression
+	Index    Expression
+	Optional bool // a?.[0], which is undefined when a is null or undefined
+}
+
+func (ie *IndexExpression) expressionNode()      {}
+func (ie *IndexExpression) TokenLiteral() string { return ie.Token.Literal }
+func (ie *IndexExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString(ie.Left.String())
+	if ie.Optional {
+		out.WriteString("?.")
+	}
+	out.WriteString("[")
+	out.WriteString(ie.Index.String())
+	out.WriteString("]")
+
+	return out.String()
+}
+
+// IsOptionalChain reports whether expr is part of an optional chain, i.e. a
+// property access, element access or call with a ?. somewhere on its left.
+// The whole chain evaluates to undefined when the operand of a ?. is null
+// or undefined.
+func IsOptionalChain(expr Expression) bool {
+	for {
+		switch e := expr.(type) {
+		case *MethodCallExpression:
+			if e.Optional {
+				return true
+			}
+			expr = e.Object
+		case *CallExpression:
+			if e.Optional {
+				return true
+			}
+			expr = e.Function
+		case *IndexExpression:
+			if e.Optional {
+				return true
+			}
+			expr = e.Left
+		default:
+			return false
+		}
+	}
+}
+
+// ArrayLiteral is an array, e.g. [1, 2, ...rest]. Holes, as in [1, , 3],
+// are nil elements.
+type ArrayLiteral struct {
+	Trivia
+	Token    token.Token // the '[' token
+	Elements []Expression
+}
+
+func (al *ArrayLiteral) expressionNode()      {}
+func (al *ArrayLiteral) TokenLiteral() string { return al.Token.Literal }
+func (al *ArrayLiteral) String() string {
+	elements := []string{}
+	for _, el := range al.Elements {
+		if el == nil {
+			elements = append(elements, "")
+			continue
+		}
+		elements = append(elements, el.String())
+	}
+
+	out := "[" + strings.Join(elements, ", ")
+	if n := len(al.Elements); n > 0 && al.Elements[n-1] == nil {
+		// A trailing hole needs its own comma
+		out += ","
+	}
+	return out + "]"
+}
+
+// ObjectLiteral is an object, e.g. { a: 1, b, ...rest }
+type ObjectLiteral struct {
+	Trivia
+	Token      token.Token // the '{' token
+	Properties []*ObjectProperty
+}
+
+func (ol *ObjectLiteral) expressionNode()      {}
+func (ol *ObjectLiteral) TokenLiteral() string { return ol.Token.Literal }
+func (ol *ObjectLiteral) String() string {
+	if len(ol.Properties) == 0 {
+		return "{}"
+	}
+
+	props := []string{}
+	for _, p := range ol.Properties {
+		props = append(props, p.String())
+	}
+	return "{ " + strings.Join(props, ", ") + " }"
+}
+
+// ObjectProperty is a property of an object literal. A shorthand property
+// { a } has the Identifier a as both Key and Value, and a spread property
+// { ...a } has a SpreadElement as its Value and no Key.
+type ObjectProperty struct {
+	Trivia
+	Token     token.Token // the first token of the property
+	Key       Expression  // *Identifier, *StringLiteral or *IntegerLiteral
+	Value     Expression
+	Shorthand bool
+
+	// Method is set for a method, e.g. async m() {}, whose Value is the
+	// *FunctionLiteral
+	Method bool
+}
+
+func (op *ObjectProperty) String() string {
+	if op.Key == nil || op.Shorthand {
+		return op.Value.String()
+	}
+	if fn, ok := op.Value.(*FunctionLiteral); ok && op.Method {
+		modifiers := ""
+		if fn.Async {
+			modifiers = "async "
+		}
+		if fn.Generator {
+			modifiers += "*"
+		}
+		s := fn.String()
+		return modifiers + op.Key.String() + s[strings.Index(s, "("):]
+	}
+	return op.Key.String() + ": " + op.Value.String()
+}
+
+// SpreadElement spreads an iterable or object, e.g. ...args
+type SpreadElement struct {
+	Trivia
+	Token    token.Token // the '...' token
+	Argument Expression
+}
+
+func (se *SpreadElement) expressionNode()      {}
+func (se *SpreadElement) TokenLiteral() string { return se.Token.Literal }
+func (se *SpreadElement) String() string       { return "..." + se.Argument.String() }
+
+// ObjectPattern destructures an object, e.g. { a, b: c = 1, ...rest }
+type ObjectPattern struct {
+	Trivia
+	Token      token.Token // the '{' token
+	Properties []*BindingElement
+}
+
+func (op *ObjectPattern) expressionNode()      {}
+func (op *ObjectPattern) TokenLiteral() string { return op.Token.Literal }
+func (op *ObjectPattern) String() string {
+	if len(op.Properties) == 0 {
+		return "{}"
+	}
+
+	props := []string{}
+	for _, p := range op.Properties {
+		props = append(props, p.String())
+	}
+	return "{ " + strings.Join(props, ", ") + " }"
+}
+
+// ArrayPattern destructures an iterable, e.g. [a, , b = 1, ...rest]. Holes
+// are nil elements.
+type ArrayPattern struct {
+	Trivia
+	Token    token.Token // the '[' token
+	Elements []*BindingElement
+}
+
+func (ap *ArrayPattern) expressionNode()      {}
+func (ap *ArrayPattern) TokenLiteral() string { return ap.Token.Literal }
+func (ap *ArrayPattern) String() string {
+	elements := []string{}
+	for _, el := range ap.Elements {
+		if el == nil {
+			elements = append(elements, "")
+			continue
+		}
+		elements = append(elements, el.String())
+	}
+
+	out := "[" + strings.Join(elements, ", ")
+	if n := len(ap.Elements); n > 0 && ap.Elements[n-1] == nil {
+		out += ","
+	}
+	return out + "]"
+}
+
+// BindingElement is a function parameter or an element of a pattern. Name
+// is an *Identifier or a nested pattern; in destructuring assignments it can
+// also be a property or element access.
+type BindingElement struct {
+	Trivia
+	Token        token.Token // the first token of the element
+	PropertyName Expression  // the key of an object pattern element, nil for shorthand
+	Name         Expression
+	Type         TypeNode   // the annotated type of a parameter, nil when not given
+	Default      Expression // the initializer, e.g. 1 in a = 1
+	Rest         bool       // ...name
+}
+
+func (be *BindingElement) String() string {
+	var out bytes.Buffer
+
+	if be.Rest {
+		out.WriteString("...")
+	}
+	if be.PropertyName != nil {
+		out.WriteString(be.PropertyName.String() + ": ")
+	}
+	out.WriteString(be.Name.String())
+	if be.Type != nil {
+		out.WriteString(": " + be.Type.String())
+	}
+	if be.Default != nil {
+		out.WriteString(" = " + be.Default.String())
+	}
+
+	return out.String()
+}
+
+// IsPattern reports whether expr is an object or array pattern
+func IsPattern(expr Expression) bool {
+	switch expr.(type) {
+	case *ObjectPattern, *ArrayPattern:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,184 @@
+package ast
+
+import (
+	"bytes"
+	"strings"
+)
+
+// JSDoc is a /** */ documentation comment parsed into its description and
+// block tags
+type JSDoc struct {
+	Comment     *Comment
+	Description string
+	Tags        []JSDocTag
+}
+
+func (d *JSDoc) TokenLiteral() string { return d.Comment.TokenLiteral() }
+func (d *JSDoc) String() string       { return d.Comment.String() }
+
+// Tag returns the first tag with the given name, without the @
+func (d *JSDoc) Tag(name string) JSDocTag {
+	for _, tag := range d.Tags {
+		if tag.TagName() == name {
+			return tag
+		}
+	}
+	return nil
+}
+
+// Deprecated returns the @deprecated tag, if any
+func (d *JSDoc) Deprecated() *JSDocDeprecatedTag {
+	for _, tag := range d.Tags {
+		if t, ok := tag.(*JSDocDeprecatedTag); ok {
+			return t
+		}
+	}
+	return nil
+}
+
+// Param returns the @param tag documenting the named parameter, if any
+func (d *JSDoc) Param(name string) *JSDocParamTag {
+	for _, tag := range d.Tags {
+		if t, ok := tag.(*JSDocParamTag); ok && t.Name == name {
+			return t
+		}
+	}
+	return nil
+}
+
+// JSDocTag is a block tag such as @param or @returns
+type JSDocTag interface {
+	TagName() string
+	String() string
+}
+
+// JSDocParamTag documents a parameter:
+// @param {type} name description, or @param {type} [name=default]
+type JSDocParamTag struct {
+	Name        string
+	Type        TypeNode // nil when no {type} is given
+	Optional    bool
+	Default     string
+	Description string
+}
+
+func (t *JSDocParamTag) TagName() string { return "param" }
+func (t *JSDocParamTag) String() string {
+	name := t.Name
+	if t.Optional {
+		name = "[" + name
+		if t.Default != "" {
+			name += "=" + t.Default
+		}
+		name += "]"
+	}
+	return formatTag("param", t.Type, name, t.Description)
+}
+
+// JSDocReturnTag documents the return value: @returns {type} description
+type JSDocReturnTag struct {
+	Type        TypeNode
+	Description string
+}
+
+func (t *JSDocReturnTag) TagName() string { return "returns" }
+func (t *JSDocReturnTag) String() string {
+	return formatTag("returns", t.Type, "", t.Description)
+}
+
+// JSDocTypeTag gives the type of a declaration: @type {type}
+type JSDocTypeTag struct {
+	Type TypeNode
+}
+
+func (t *JSDocTypeTag) TagName() string { return "type" }
+func (t *JSDocTypeTag) String() string  { return formatTag("type", t.Type, "", "") }
+
+// JSDocThisTag gives the type of this in a function: @this {type}
+type JSDocThisTag struct {
+	Type TypeNode
+}
+
+func (t *JSDocThisTag) TagName() string { return "this" }
+func (t *JSDocThisTag) String() string  { return formatTag("this", t.Type, "", "") }
+
+// JSDocTemplateTag declares type parameters: @template {constraint} T, U
+type JSDocTemplateTag struct {
+	Constraint     TypeNode
+	TypeParameters []string
+}
+
+func (t *JSDocTemplateTag) TagName() string { return "template" }
+func (t *JSDocTemplateTag) String() string {
+	return formatTag("template", t.Constraint, strings.Join(t.TypeParameters, ", "), "")
+}
+
+// JSDocDeprecatedTag marks a declaration as deprecated
+type JSDocDeprecatedTag struct {
+	Description string
+}
+
+func (t *JSDocDeprecatedTag) TagName() string { return "deprecated" }
+func (t *JSDocDeprecatedTag) String() string {
+	return formatTag("deprecated", nil, "", t.Description)
+}
+
+// JSDocTypedefTag declares a type alias: @typedef {type} Name, optionally
+// followed by @property tags describing an object type
+type JSDocTypedefTag struct {
+	Name       string
+	Type       TypeNode
+	Properties []*JSDocPropertyTag
+}
+
+func (t *JSDocTypedefTag) TagName() string { return "typedef" }
+func (t *JSDocTypedefTag) String() string {
+	var out bytes.Buffer
+
+	out.WriteString(formatTag("typedef", t.Type, t.Name, ""))
+	for _, p := range t.Properties {
+		out.WriteString("\n" + p.String())
+	}
+
+	return out.String()
+}
+
+// JSDocPropertyTag documents a property of a @typedef
+type JSDocPropertyTag struct {
+	Name        string
+	Type        TypeNode
+	Optional    bool
+	Description string
+}
+
+func (t *JSDocPropertyTag) TagName() string { return "property" }
+func (t *JSDocPropertyTag) String() string {
+	name := t.Name
+	if t.Optional {
+		name = "[" + name + "]"
+	}
+	return formatTag("property", t.Type, name, t.Description)
+}
+
+// JSDocUnknownTag is any other tag, kept with its raw text
+type JSDocUnknownTag struct {
+	Name string
+	Text string
+}
+
+func (t *JSDocUnknownTag) TagName() string { return t.Name }
+func (t *JSDocUnknownTag) String() string  { return formatTag(t.Name, nil, "", t.Text) }
+
+func formatTag(name string, typ TypeNode, subject string, description string) string {
+	parts := []string{"@" + name}
+	if typ != nil {
+		parts = append(parts, "{"+typ.String()+"}")
+	}
+	if subject != "" {
+		parts = append(parts, subject)
+	}
+	if description != "" {
+		parts = append(parts, description)
+	}
+	return strings.Join(parts, " ")
+}
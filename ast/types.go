@@ -0,0 +1,149 @@
+package ast
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/dmarro89/ts-go-compiler/token"
+)
+
+// TypeNode is a type written in the source, e.g. in a JSDoc @type tag
+type TypeNode interface {
+	Node
+	typeNode()
+}
+
+// TypeReference names a type, optionally with type arguments (Array<string>)
+type TypeReference struct {
+	Token         token.Token // the first token of the name
+	Name          string      // possibly qualified, e.g. NS.Type
+	TypeArguments []TypeNode
+}
+
+func (tr *TypeReference) typeNode()            {}
+func (tr *TypeReference) TokenLiteral() string { return tr.Token.Literal }
+func (tr *TypeReference) String() string {
+	if len(tr.TypeArguments) == 0 {
+		return tr.Name
+	}
+	return tr.Name + "<" + joinTypes(tr.TypeArguments, ", ") + ">"
+}
+
+// ArrayTypeNode is an array type, e.g. string[]
+type ArrayTypeNode struct {
+	Token       token.Token // the [ token
+	ElementType TypeNode
+}
+
+func (at *ArrayTypeNode) typeNode()            {}
+func (at *ArrayTypeNode) TokenLiteral() string { return at.Token.Literal }
+func (at *ArrayTypeNode) String() string {
+	if _, ok := at.ElementType.(*UnionTypeNode); ok {
+		return "(" + at.ElementType.String() + ")[]"
+	}
+	return at.ElementType.String() + "[]"
+}
+
+// UnionTypeNode is a union of types, e.g. string | number
+type UnionTypeNode struct {
+	Token token.Token // the first token of the union
+	Types []TypeNode
+}
+
+func (ut *UnionTypeNode) typeNode()            {}
+func (ut *UnionTypeNode) TokenLiteral() string { return ut.Token.Literal }
+func (ut *UnionTypeNode) String() string       { return joinTypes(ut.Types, " | ") }
+
+// LiteralTypeNode is a string, number or boolean literal used as a type,
+// e.g. "a"
+type LiteralTypeNode struct {
+	Token   token.Token
+	Literal Expression
+}
+
+func (lt *LiteralTypeNode) typeNode()            {}
+func (lt *LiteralTypeNode) TokenLiteral() string { return lt.Token.Literal }
+func (lt *LiteralTypeNode) String() string       { return lt.Literal.String() }
+
+// TupleTypeNode is an array type with a type per position, e.g.
+// [number, string]
+type TupleTypeNode struct {
+	Token    token.Token // the [ token
+	Elements []TypeNode
+}
+
+func (tt *TupleTypeNode) typeNode()            {}
+func (tt *TupleTypeNode) TokenLiteral() string { return tt.Token.Literal }
+func (tt *TupleTypeNode) String() string       { return "[" + joinTypes(tt.Elements, ", ") + "]" }
+
+// ObjectTypeNode is an object type with the given properties, e.g.
+// { a: number, b?: string }
+type ObjectTypeNode struct {
+	Token      token.Token // the '{' token
+	Properties []*PropertySignature
+}
+
+func (ot *ObjectTypeNode) typeNode()            {}
+func (ot *ObjectTypeNode) TokenLiteral() string { return ot.Token.Literal }
+func (ot *ObjectTypeNode) String() string {
+	if len(ot.Properties) == 0 {
+		return "{}"
+	}
+
+	parts := make([]string, len(ot.Properties))
+	for i, p := range ot.Properties {
+		parts[i] = p.String()
+	}
+	return "{ " + strings.Join(parts, " ") + " }"
+}
+
+// PropertySignature is a property of an object type, e.g. b?: string
+type PropertySignature struct {
+	Token    token.Token // the name token
+	Name     string
+	Optional bool
+	Type     TypeNode // nil when not written
+}
+
+func (ps *PropertySignature) TokenLiteral() string { return ps.Token.Literal }
+func (ps *PropertySignature) String() string {
+	name := ps.Name
+	if ps.Optional {
+		name += "?"
+	}
+	if ps.Type == nil {
+		return name + ";"
+	}
+	return name + ": " + ps.Type.String() + ";"
+}
+
+// FunctionTypeNode is a function type, e.g. function(number): string in
+// JSDoc
+type FunctionTypeNode struct {
+	Token      token.Token // the function token
+	Parameters []TypeNode
+	ReturnType TypeNode // nil when not written
+}
+
+func (ft *FunctionTypeNode) typeNode()            {}
+func (ft *FunctionTypeNode) TokenLiteral() string { return ft.Token.Literal }
+func (ft *FunctionTypeNode) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("function(")
+	out.WriteString(joinTypes(ft.Parameters, ", "))
+	out.WriteString(")")
+	if ft.ReturnType != nil {
+		out.WriteString(": " + ft.ReturnType.String())
+	}
+
+	return out.String()
+}
+
+func joinTypes(types []TypeNode, sep string) string {
+	parts := make([]string, len(types))
+	for i, t := range types {
+		parts[i] = t.String()
+	}
+	return strings.Join(parts, sep)
+}
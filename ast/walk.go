@@ -0,0 +1,148 @@
+package ast
+
+// Inspect traverses the tree rooted at node in depth-first order, calling f
+// for node and then for each statement and expression inside it, in source
+// order. When f returns false the children of the node are skipped.
+// Parameters, patterns, switch cases and catch clauses are not nodes
+// themselves, but the expressions and statements in them are visited.
+// Labels name no value and are not visited.
+func Inspect(node Node, f func(Node) bool) {
+	if node == nil || !f(node) {
+		return
+	}
+
+	switch n := node.(type) {
+	case *Program:
+		inspectStatements(n.Statements, f)
+	case *LetStatement:
+		Inspect(n.Target(), f)
+		inspectExpression(n.Value, f)
+	case *ReturnStatement:
+		inspectExpression(n.ReturnValue, f)
+	case *ExpressionStatement:
+		inspectExpression(n.Expression, f)
+	case *BlockStatement:
+		inspectStatements(n.Statements, f)
+	case *SwitchStatement:
+		Inspect(n.Discriminant, f)
+		for _, c := range n.Cases {
+			inspectExpression(c.Test, f)
+			inspectStatements(c.Consequent, f)
+		}
+	case *LabeledStatement:
+		Inspect(n.Body, f)
+	case *IfStatement:
+		Inspect(n.Condition, f)
+		Inspect(n.Consequence, f)
+		if n.Alternative != nil {
+			Inspect(n.Alternative, f)
+		}
+	case *ThrowStatement:
+		Inspect(n.Argument, f)
+	case *TryStatement:
+		Inspect(n.Block, f)
+		if n.Handler != nil {
+			inspectExpression(n.Handler.Param, f)
+			Inspect(n.Handler.Body, f)
+		}
+		if n.Finalizer != nil {
+			Inspect(n.Finalizer, f)
+		}
+	case *ForOfStatement:
+		Inspect(n.Target, f)
+		Inspect(n.Right, f)
+		Inspect(n.Body, f)
+	case *ForInStatement:
+		Inspect(n.Target, f)
+		Inspect(n.Right, f)
+		Inspect(n.Body, f)
+	case *PrefixExpression:
+		Inspect(n.Right, f)
+	case *PostfixExpression:
+		Inspect(n.Left, f)
+	case *NonNullExpression:
+		Inspect(n.Expression, f)
+	case *InfixExpression:
+		Inspect(n.Left, f)
+		Inspect(n.Right, f)
+	case *AssignmentExpression:
+		Inspect(n.Target, f)
+		Inspect(n.Value, f)
+	case *ConditionalExpression:
+		Inspect(n.Condition, f)
+		Inspect(n.Consequence, f)
+		Inspect(n.Alternative, f)
+	case *ParenthesizedExpression:
+		Inspect(n.Expression, f)
+	case *FunctionLiteral:
+		if n.Name != nil {
+			Inspect(n.Name, f)
+		}
+		inspectBindingElements(n.Parameters, f)
+		Inspect(n.Body, f)
+	case *AwaitExpression:
+		Inspect(n.Argument, f)
+	case *YieldExpression:
+		inspectExpression(n.Argument, f)
+	case *CallExpression:
+		Inspect(n.Function, f)
+		inspectExpressions(n.Arguments, f)
+	case *NewExpression:
+		Inspect(n.Callee, f)
+		inspectExpressions(n.Arguments, f)
+	case *MethodCallExpression:
+		Inspect(n.Object, f)
+		inspectExpressions(n.Arguments, f)
+	case *IndexExpression:
+		Inspect(n.Left, f)
+		Inspect(n.Index, f)
+	case *ArrayLiteral:
+		inspectExpressions(n.Elements, f)
+	case *ObjectLiteral:
+		for _, p := range n.Properties {
+			if !p.Shorthand {
+				inspectExpression(p.Key, f)
+			}
+			Inspect(p.Value, f)
+		}
+	case *SpreadElement:
+		Inspect(n.Argument, f)
+	case *ObjectPattern:
+		inspectBindingElements(n.Properties, f)
+	case *ArrayPattern:
+		inspectBindingElements(n.Elements, f)
+	}
+}
+
+// inspectExpression inspects an optional expression. A nil Expression
+// would otherwise reach Inspect as a non-nil Node holding a nil pointer.
+func inspectExpression(expr Expression, f func(Node) bool) {
+	if expr != nil {
+		Inspect(expr, f)
+	}
+}
+
+func inspectExpressions(exprs []Expression, f func(Node) bool) {
+	for _, expr := range exprs {
+		inspectExpression(expr, f)
+	}
+}
+
+func inspectStatements(statements []Statement, f func(Node) bool) {
+	for _, stmt := range statements {
+		if stmt != nil {
+			Inspect(stmt, f)
+		}
+	}
+}
+
+func inspectBindingElements(elements []*BindingElement, f func(Node) bool) {
+	for _, el := range elements {
+		if el == nil {
+			continue
+		}
+		inspectExpression(el.PropertyName, f)
+		Inspect(el.Name, f)
+		inspectExpression(el.Default, f)
+	}
+}
@@ -0,0 +1,116 @@
+// Command tsgo compiles TypeScript files to JavaScript, writing each next to
+// its source with the .js extension. A .js file is refused, since its
+// output would overwrite it.
+//
+// Usage:
+//
+//	tsgo [flags] file.ts...
+//
+// Errors and warnings are printed with the source lines they point to, in
+// color, when the output is a terminal; -pretty=false prints them one per
+// line. -format=json prints a line of JSON for each, and -format=sarif a
+// SARIF 2.1.0 log for code scanning tools.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/dmarro89/ts-go-compiler/codegen"
+	"github.com/dmarro89/ts-go-compiler/compiler"
+	"github.com/dmarro89/ts-go-compiler/diagnostics"
+)
+
+func main() {
+	pretty := flag.Bool("pretty", isTerminal(os.Stdout), "print errors in color with the source lines they point to")
+	format := flag.String("format", "text", "the format of the errors: text, json or sarif")
+	target := flag.String("target", "ESNext", "the ECMAScript version of the output, e.g. ES5 or ES2017")
+	strict := flag.Bool("strict", false, "enable the checks of strict mode")
+	strictNullChecks := flag.Bool("strictNullChecks", false, "report values that may be null or undefined where they are used")
+	noImplicitReturns := flag.Bool("noImplicitReturns", false, "report functions that do not return a value on every code path")
+	removeComments := flag.Bool("removeComments", false, "drop comments from the output")
+	maxErrors := flag.Int("maxErrors", 0, "the number of errors printed at most for each file; 0 prints them all")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: tsgo [flags] file.ts...\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	t, err := codegen.ParseTarget(*target)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	f, err := diagnostics.ParseFormat(*format)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	c := compiler.NewWithOptions(compiler.Options{
+		Target:            t,
+		Strict:            *strict,
+		StrictNullChecks:  *strictNullChecks,
+		NoImplicitReturns: *noImplicitReturns,
+		RemoveComments:    *removeComments,
+		MaxErrors:         *maxErrors,
+	})
+	printer := diagnostics.NewPrinter(os.Stdout, diagnostics.Options{Format: f, Pretty: *pretty})
+
+	failed := false
+	for _, file := range flag.Args() {
+		output := strings.TrimSuffix(file, filepath.Ext(file)) + ".js"
+		err := c.CompileFile(file, output)
+		var compileErr *compiler.Error
+		if err != nil {
+			failed = true
+			if !errors.As(err, &compileErr) {
+				fmt.Fprintln(os.Stderr, errorText(err))
+				continue
+			}
+		}
+
+		diags := c.Diagnostics()
+		if len(diags) == 0 {
+			continue
+		}
+		source, err := os.ReadFile(file)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %s\n", err)
+			continue
+		}
+		printer.Print(file, string(source), diags)
+		if compileErr != nil {
+			printer.Omit(compileErr.Omitted)
+		}
+	}
+	printer.Summary()
+
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// errorText returns the line printed for an error other than a compilation
+// error, such as a file that cannot be read. Errors with a tsc code carry
+// their own "error TSnnnn:" heading.
+func errorText(err error) string {
+	if strings.HasPrefix(err.Error(), "error TS") {
+		return err.Error()
+	}
+	return "error: " + err.Error()
+}
+
+// isTerminal reports whether f is a terminal rather than a file or a pipe
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
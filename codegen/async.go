@@ -0,0 +1,112 @@
+package codegen
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/dmarro89/ts-go-compiler/ast"
+)
+
+// generateAsyncFunction generates an async function for targets before
+// ES2017. It returns the promise the __awaiter helper makes by running a
+// generator that yields where the function awaits: a function* from ES2015
+// on, and a state machine driven by __generator before.
+func (g *Generator) generateAsyncFunction(fn *ast.FunctionLiteral) string {
+	g.pushTemps()
+
+	params, prologue := g.generateParameters(fn.Parameters)
+
+	// The generator gets the arguments of the function when it uses them
+	args := "void 0"
+	if usesArguments(fn.Body) {
+		args = "arguments"
+	}
+
+	var generator string
+	if g.downlevel(ES2015) {
+		generator = "function () " + g.generateStateMachineFunction(fn.Body)
+	} else {
+		generator = "function* () " + g.generateGeneratorBody(fn.Body)
+	}
+
+	var body bytes.Buffer
+	for _, stmt := range prologue {
+		body.WriteString(stmt + "\n")
+	}
+	body.WriteString(fmt.Sprintf("return %s(this, %s, void 0, %s);\n", g.useHelper("__awaiter"), args, generator))
+
+	return fmt.Sprintf("function%s(%s) %s", functionName(fn), params, indentBlock(g.popTemps()+body.String()))
+}
+
+// generateGeneratorBody generates the body of the generator an async
+// function or async generator becomes, where each await is a yield
+func (g *Generator) generateGeneratorBody(block *ast.BlockStatement) string {
+	g.pushTemps()
+
+	var body bytes.Buffer
+	g.writeStatements(&body, block.Statements)
+	g.writeLeadingComments(&body, block.EndComments)
+
+	return indentBlock(g.popTemps() + body.String())
+}
+
+// generateForAwait generates a for await loop for targets before ES2018,
+// where it loops over the async iterator __asyncValues makes of the value.
+// Each result is awaited, and the iterator is closed when the loop ends
+// early, as the TypeScript compiler does it.
+func (g *Generator) generateForAwait(s *ast.ForOfStatement, labels string) string {
+	done := g.newTemp()
+	errName := g.uniqueName("e")
+	g.declareTemp(errName)
+	returnFn := g.newTemp()
+	value := g.newTemp()
+	pending := g.newName() // whether the loop body has not started yet
+
+	iterator := g.copyName(s.Right)
+	result := g.newName()
+	if isSimple(s.Right) {
+		result = iterator + "_1"
+	}
+	prologue := fmt.Sprintf("%s = %s.value;\n%s = false;\n", value, result, pending) +
+		g.bindLoopVariable(s.Kind, s.Target, value, simpleValue)
+
+	loop := labels + fmt.Sprintf("for (var %s = true, %s = %s(%s), %s; %s = %s, %s = %s.done, !%s; %s = true)",
+		pending, iterator, g.useHelper("__asyncValues"), g.generateJSExpression(s.Right), result,
+		result, g.awaitCode(iterator+".next()"), done, result, done, pending) +
+		g.generateLoopBody(prologue, s.Body)
+
+	closeIterator := "try " + indentBlock(fmt.Sprintf("if (!%s && !%s && (%s = %s.return)) %s;\n",
+		pending, done, returnFn, iterator, g.awaitCode(returnFn+".call("+iterator+")"))) + "\n" +
+		fmt.Sprintf("finally { if (%s) throw %s.error; }\n", errName, errName)
+
+	return "try " + indentBlock(loop+"\n") + "\n" +
+		fmt.Sprintf("catch (%s_1) { %s = { error: %s_1 }; }\n", errName, errName, errName) +
+		"finally " + indentBlock(closeIterator)
+}
+
+// awaitCode returns the code that awaits value, already generated, in the
+// function being generated
+func (g *Generator) awaitCode(value string) string {
+	switch g.await {
+	case "yield":
+		return "yield " + value
+	case "__await":
+		return "yield " + g.useHelper("__await") + "(" + value + ")"
+	}
+	return "await " + value
+}
+
+// usesArguments reports whether a function body refers to arguments
+func usesArguments(body *ast.BlockStatement) bool {
+	found := false
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.Identifier:
+			found = found || n.Value == "arguments"
+		case *ast.FunctionLiteral:
+			return false
+		}
+		return !found
+	})
+	return found
+}
@@ -0,0 +1,252 @@
+package codegen
+
+import (
+	"strings"
+
+	"github.com/dmarro89/ts-go-compiler/ast"
+)
+
+// chainLink is one property access, element access or call of a chain such
+// as a.b?.(c)[d]
+type chainLink struct {
+	optional  bool
+	name      string           // .name, when set
+	index     ast.Expression   // [index], when set
+	arguments []ast.Expression // (arguments), for calls
+	call      bool
+}
+
+func (l chainLink) isCall() bool { return l.call }
+
+// flattenChain splits a chain into the expression it starts from and its
+// links, innermost first
+func flattenChain(expr ast.Expression) (ast.Expression, []chainLink) {
+	var links []chainLink
+	for {
+		switch e := expr.(type) {
+		case *ast.MethodCallExpression:
+			if e.Arguments != nil {
+				links = append(links, chainLink{call: true, arguments: e.Arguments})
+			}
+			links = append(links, chainLink{optional: e.Optional, name: e.Method.Value})
+			expr = e.Object
+		case *ast.CallExpression:
+			links = append(links, chainLink{optional: e.Optional, call: true, arguments: e.Arguments})
+			expr = e.Function
+		case *ast.IndexExpression:
+			links = append(links, chainLink{optional: e.Optional, index: e.Index})
+			expr = e.Left
+		default:
+			for i, j := 0, len(links)-1; i < j; i, j = i+1, j-1 {
+				links[i], links[j] = links[j], links[i]
+			}
+			return expr, links
+		}
+	}
+}
+
+// generateChain generates property accesses, element accesses and calls.
+// Before ES2020 an optional chain becomes a conditional expression, e.g.
+// a?.b is a === null || a === void 0 ? void 0 : a.b
+func (g *Generator) generateChain(expr ast.Expression) string {
+	base, links := flattenChain(expr)
+	c := &chain{g: g, base: base, links: links, downlevel: g.downlevel(ES2020)}
+	code, _ := c.generate(len(links), false)
+	return code
+}
+
+// generateOptionalDelete generates delete a?.b for targets before ES2020
+// as a === null || a === void 0 ? true : delete a.b, which deletes nothing
+// when the chain stops
+func (g *Generator) generateOptionalDelete(expr ast.Expression) string {
+	base, links := flattenChain(ast.SkipParentheses(expr))
+	c := &chain{g: g, base: base, links: links, downlevel: true, delete: true}
+	code, _ := c.generate(len(links), false)
+	return code
+}
+
+type chain struct {
+	g         *Generator
+	base      ast.Expression
+	links     []chainLink
+	downlevel bool
+	delete    bool // the whole chain is the operand of delete
+}
+
+// generate returns the code of the base followed by the first n links. When
+// wantThis is set and link n-1 is a property or element access, it also
+// returns a reference to the object accessed, for use as this in a call.
+func (c *chain) generate(n int, wantThis bool) (code string, this string) {
+	if n == 0 {
+		return c.g.operand(c.base), ""
+	}
+
+	k := -1
+	if c.downlevel {
+		for i := n - 1; i >= 0; i-- {
+			if c.links[i].optional {
+				k = i
+				break
+			}
+		}
+	}
+
+	if k < 0 {
+		object, callThis := c.generate(n-1, c.needsThis(n-1))
+		if wantThis {
+			object, this = c.capture(n-1, object)
+		}
+		return object + c.link(n-1, c.links[n-1].optional && !c.downlevel, callThis), this
+	}
+
+	// The links from k on only run when the head is not null or undefined
+	needThis := c.links[k].isCall() && k > 0 && !c.links[k-1].isCall()
+	head, callThis := c.generate(k, needThis)
+
+	ref, check := head, head
+	if k > 0 || !isSimple(c.base) {
+		ref = c.g.newTemp()
+		check = "(" + ref + " = " + head + ")"
+	}
+
+	rest := ref
+	for i := k; i < n; i++ {
+		// The object of an access is kept when the next link calls it
+		object := ""
+		if (i == n-1 && wantThis) || (i+1 < n && c.needsThis(i+1)) {
+			if i == k {
+				object = ref
+			} else {
+				rest, object = c.captureCode(rest)
+			}
+		}
+		rest += c.link(i, false, callThis)
+		callThis = object
+	}
+	if wantThis {
+		this = callThis
+	}
+
+	stopped := "void 0"
+	if c.delete && n == len(c.links) {
+		stopped, rest = "true", "delete "+rest
+	}
+	return check + " === null || " + ref + " === void 0 ? " + stopped + " : " + rest, this
+}
+
+// spreadCall reports whether link i is a call with spread arguments that
+// is generated with apply
+func (c *chain) spreadCall(i int) bool {
+	return c.links[i].call && c.g.downlevel(ES2015) && hasSpread(c.links[i].arguments)
+}
+
+// needsThis reports whether link i is generated as a call of a method
+// that takes the object of link i-1 as this
+func (c *chain) needsThis(i int) bool {
+	return i > 0 && c.spreadCall(i) && !c.links[i-1].isCall()
+}
+
+// capture returns object, assigned to a temporary unless it is simple, and
+// a reference to it
+func (c *chain) capture(n int, object string) (string, string) {
+	if n == 0 && isSimple(c.base) {
+		return object, object
+	}
+	return c.captureCode(object)
+}
+
+func (c *chain) captureCode(object string) (string, string) {
+	temp := c.g.newTemp()
+	return "(" + temp + " = " + object + ")", temp
+}
+
+// link generates link i. A call with a this reference is generated as
+// .call(this, ...), so the function still runs as a method. Before ES2015
+// a call with spread arguments is generated as .apply(this, arguments).
+func (c *chain) link(i int, optional bool, this string) string {
+	l := c.links[i]
+
+	prefix := ""
+	if optional {
+		prefix = "?."
+	}
+
+	switch {
+	case c.spreadCall(i):
+		if this == "" {
+			this = "void 0"
+			// console.log is parsed as a single name
+			if ident, ok := c.base.(*ast.Identifier); ok && i == 0 && strings.Contains(ident.Value, ".") {
+				this = ident.Value[:strings.LastIndex(ident.Value, ".")]
+			}
+		}
+		return ".apply(" + this + ", " + c.g.generateSpreadList(l.arguments, false) + ")"
+	case l.call && this != "":
+		args := []string{this}
+		for _, arg := range l.arguments {
+			args = append(args, c.g.generateJSExpression(arg))
+		}
+		return ".call(" + strings.Join(args, ", ") + ")"
+	case l.call:
+		return prefix + "(" + c.g.generateJSExpressions(l.arguments) + ")"
+	case l.index != nil:
+		return prefix + "[" + c.g.generateJSExpression(l.index) + "]"
+	case optional:
+		return "?." + l.name
+	default:
+		return "." + l.name
+	}
+}
+
+// generateNullishCoalescing generates a ?? b for targets before ES2020 as
+// a !== null && a !== void 0 ? a : b
+func (g *Generator) generateNullishCoalescing(e *ast.InfixExpression) string {
+	left := g.generateJSExpression(e.Left)
+
+	ref, check := left, left
+	if !isSimple(e.Left) {
+		ref = g.newTemp()
+		check = "(" + ref + " = " + left + ")"
+	}
+
+	return check + " !== null && " + ref + " !== void 0 ? " + ref + " : " + g.generateJSExpression(e.Right)
+}
+
+// operand generates an expression used as the operand of an operator,
+// adding the parentheses a downleveled expression needs
+func (g *Generator) operand(expr ast.Expression) string {
+	code := g.generateJSExpression(expr)
+	if g.isConditional(expr) || g.isYield(expr) {
+		return "(" + code + ")"
+	}
+	return code
+}
+
+// isYield reports whether expr is an await generated as a yield, which
+// binds looser than any operator
+func (g *Generator) isYield(expr ast.Expression) bool {
+	_, ok := expr.(*ast.AwaitExpression)
+	return ok && (g.await == "yield" || g.await == "__await")
+}
+
+// isConditional reports whether expr is generated as a conditional
+// expression although it is not written as one
+func (g *Generator) isConditional(expr ast.Expression) bool {
+	if !g.downlevel(ES2020) {
+		return false
+	}
+	switch e := expr.(type) {
+	case *ast.InfixExpression:
+		return e.Operator == "??"
+	case *ast.PrefixExpression:
+		return e.Operator == "delete" && ast.IsOptionalChain(ast.SkipParentheses(e.Right))
+	}
+	return ast.IsOptionalChain(expr)
+}
+
+// isSimple reports whether evaluating expr twice is the same as evaluating
+// it once
+func isSimple(expr ast.Expression) bool {
+	_, ok := expr.(*ast.Identifier)
+	return ok
+}
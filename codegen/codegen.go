@@ -3,12 +3,52 @@ package codegen
 import (
 	"bytes"
 	"fmt"
+	"strings"
 
 	"github.com/dmarro89/ts-go-compiler/ast"
 )
 
+// Options controls the generated JavaScript
+type Options struct {
+	// RemoveComments drops comments from the output. Legal comments starting
+	// with /*! are kept regardless.
+	RemoveComments bool
+
+	// Target is the ECMAScript version of the output
+	Target Target
+
+	// DownlevelIteration makes for...of loops use the iteration protocol
+	// before ES2015, through the __values helper, so they work on any
+	// iterable. Without it they index into the value like an array.
+	DownlevelIteration bool
+}
+
 // Generator generates code from an AST
 type Generator struct {
+	options Options
+
+	// temps holds the temporary variables of each function being generated,
+	// innermost last
+	temps []*tempScope
+
+	// helpers are the runtime helpers used by the output, such as __rest
+	helpers map[string]bool
+
+	// await is how await is written in the function being generated:
+	// await, yield in an async function turned into a generator, or
+	// __await in an async generator turned into a generator, which yields
+	// the value wrapped by the __await helper. It is empty where await
+	// cannot be written, e.g. at the top level before ES2017.
+	await string
+
+	// machine is the state machine a generator or async function becomes
+	// for ES5, while its body is generated
+	machine *stateMachine
+
+	// newTarget is what new.target is written as before ES2015: the
+	// _newTarget variable of the function being generated, or undefined in
+	// functions that cannot be called with new
+	newTarget string
 }
 
 // New creates a new code generator
@@ -16,44 +56,355 @@ func New() *Generator {
 	return &Generator{}
 }
 
+// NewWithOptions creates a new code generator with the given options
+func NewWithOptions(options Options) *Generator {
+	return &Generator{options: options}
+}
+
 // GenerateJavaScript generates JavaScript code
 func (g *Generator) GenerateJavaScript(program *ast.Program) string {
 	var out bytes.Buffer
 
-	for _, stmt := range program.Statements {
+	g.helpers = map[string]bool{}
+	g.await = ""
+	if !g.downlevel(ES2017) {
+		// Modules may await at the top level
+		g.await = "await"
+	}
+	g.pushTemps()
+	g.writeStatements(&out, program.Statements)
+	g.writeLeadingComments(&out, program.EndComments)
+	temps := g.popTemps()
+
+	return g.generateHelpers() + temps + out.String()
+}
+
+// writeStatements writes one statement per line, with its comments
+func (g *Generator) writeStatements(out *bytes.Buffer, statements []ast.Statement) {
+	for _, stmt := range statements {
+		var trivia *ast.Trivia
+		if c, ok := stmt.(ast.Commented); ok {
+			trivia = c.Comments()
+			g.writeLeadingComments(out, trivia.LeadingComments)
+		}
+
 		out.WriteString(g.generateJSStatement(stmt))
+
+		if trivia != nil {
+			for _, c := range g.keptComments(trivia.TrailingComments) {
+				out.WriteString(" " + c.Text)
+			}
+		}
 		out.WriteString("\n")
 	}
+}
 
-	return out.String()
+// writeLeadingComments writes each comment on its own line
+func (g *Generator) writeLeadingComments(out *bytes.Buffer, comments []*ast.Comment) {
+	for _, c := range g.keptComments(comments) {
+		out.WriteString(c.Text)
+		out.WriteString("\n")
+	}
+}
+
+// keptComments filters out the comments dropped by RemoveComments
+func (g *Generator) keptComments(comments []*ast.Comment) []*ast.Comment {
+	if !g.options.RemoveComments {
+		return comments
+	}
+
+	var kept []*ast.Comment
+	for _, c := range comments {
+		if c.IsLegal() {
+			kept = append(kept, c)
+		}
+	}
+	return kept
 }
 
 func (g *Generator) generateJSStatement(stmt ast.Statement) string {
 	switch s := stmt.(type) {
 	case *ast.LetStatement:
-		return fmt.Sprintf("let %s = %s;", s.Name.Value, g.generateJSExpression(s.Value))
+		return g.generateDeclaration(s)
 	case *ast.ReturnStatement:
+		if g.machine != nil {
+			return g.machine.returnInstruction(g.generateJSExpression(s.ReturnValue))
+		}
+		if g.await == "__await" {
+			// An async generator awaits the value it returns
+			value := "void 0"
+			if s.ReturnValue != nil {
+				value = g.generateJSExpression(s.ReturnValue)
+			}
+			return fmt.Sprintf("return %s;", g.awaitCode(value))
+		}
+		if s.ReturnValue == nil {
+			return "return;"
+		}
 		return fmt.Sprintf("return %s;", g.generateJSExpression(s.ReturnValue))
 	case *ast.ExpressionStatement:
+		if fn, ok := s.Expression.(*ast.FunctionLiteral); ok && fn.Name != nil {
+			// A function declaration is not terminated by a semicolon
+			return g.generateJSExpression(fn)
+		}
+		if assign, ok := s.Expression.(*ast.AssignmentExpression); ok && g.lowerPattern(assign.Target) {
+			// The value of the assignment is not used, so it is not kept
+			return g.generateDestructuringAssignment(assign, false) + ";"
+		}
 		return g.generateJSExpression(s.Expression) + ";"
+	case *ast.BlockStatement:
+		return g.generateBlock(s)
+	case *ast.IfStatement:
+		return g.generateIf(s)
+	case *ast.SwitchStatement:
+		return g.generateSwitch(s)
+	case *ast.LabeledStatement:
+		return g.generateLabeled(s)
+	case *ast.BreakStatement:
+		return s.String()
+	case *ast.ThrowStatement:
+		return "throw " + g.generateJSExpression(s.Argument) + ";"
+	case *ast.TryStatement:
+		return g.generateTry(s)
+	case *ast.ForOfStatement:
+		return g.generateForOf(s, "")
+	case *ast.ForInStatement:
+		return g.generateForIn(s)
+	case *ast.ContinueStatement:
+		return s.String()
 	default:
 		return ""
 	}
 }
 
+// generateBlock writes the statements of a block one per line, indented by
+// four spaces
+func (g *Generator) generateBlock(block *ast.BlockStatement) string {
+	var body bytes.Buffer
+	g.writeStatements(&body, block.Statements)
+	g.writeLeadingComments(&body, block.EndComments)
+
+	return indentBlock(body.String())
+}
+
+// generateFunction generates a function. Its body declares the temporary
+// variables it needs, after the statements that replace parameter syntax
+// the target lacks.
+func (g *Generator) generateFunction(fn *ast.FunctionLiteral) string {
+	defer g.enterFunction(fn)()
+	switch {
+	case fn.Async && fn.Generator && g.downlevel(ES2018):
+		return g.generateAsyncGenerator(fn)
+	case fn.Async && !fn.Generator && g.downlevel(ES2017):
+		return g.generateAsyncFunction(fn)
+	case fn.Generator && g.downlevel(ES2015):
+		return g.generateGeneratorFunction(fn)
+	}
+
+	g.pushTemps()
+
+	params, prologue := g.generateParameters(fn.Parameters)
+
+	var body bytes.Buffer
+	name := functionName(fn)
+	if g.downlevel(ES2015) && !fn.Async && !fn.Generator && usesNewTarget(fn.Body) {
+		// this is compared with the function, which needs a name for it
+		if fn.Name == nil {
+			name = " " + g.newName()
+		}
+		body.WriteString(newTargetPrologue(name[1:]) + "\n")
+	}
+	for _, stmt := range prologue {
+		body.WriteString(stmt + "\n")
+	}
+	g.writeStatements(&body, fn.Body.Statements)
+	g.writeLeadingComments(&body, fn.Body.EndComments)
+
+	keyword := "function"
+	if fn.Async {
+		keyword = "async " + keyword
+	}
+	if fn.Generator {
+		keyword += "*"
+	}
+	return fmt.Sprintf("%s%s(%s) %s", keyword, name, params, indentBlock(g.popTemps()+body.String()))
+}
+
+// enterFunction starts the body of fn, where await belongs to fn alone. It
+// returns the function that restores the state of the enclosing body.
+func (g *Generator) enterFunction(fn *ast.FunctionLiteral) func() {
+	await, machine, newTarget := g.await, g.machine, g.newTarget
+
+	g.await, g.machine, g.newTarget = "", nil, "void 0"
+	if !fn.Async && !fn.Generator {
+		g.newTarget = "_newTarget"
+	}
+	switch {
+	case fn.Async && fn.Generator && g.downlevel(ES2018):
+		g.await = "__await"
+	case fn.Async && !fn.Generator && g.downlevel(ES2017):
+		g.await = "yield"
+	case fn.Async:
+		g.await = "await"
+	}
+	return func() { g.await, g.machine, g.newTarget = await, machine, newTarget }
+}
+
+// functionName returns the name of fn preceded by a space, or nothing for
+// an anonymous function
+func functionName(fn *ast.FunctionLiteral) string {
+	if fn.Name == nil {
+		return ""
+	}
+	return " " + fn.Name.Value
+}
+
+// indentBlock wraps lines in braces, indenting them by four spaces
+func indentBlock(body string) string {
+	return "{\n" + indent(body) + "}"
+}
+
+// indent indents lines by four spaces, leaving blank lines empty
+func indent(body string) string {
+	var out bytes.Buffer
+	for _, line := range strings.SplitAfter(body, "\n") {
+		if strings.TrimSpace(line) != "" {
+			out.WriteString("    ")
+		}
+		out.WriteString(line)
+	}
+
+	return out.String()
+}
+
 func (g *Generator) generateJSExpression(expr ast.Expression) string {
 	if expr == nil {
 		return ""
 	}
+	if c, ok := expr.(ast.Commented); ok {
+		return g.inlineComments(c.Comments().LeadingComments) + g.generateExpressionCode(expr)
+	}
+	return g.generateExpressionCode(expr)
+}
 
+// inlineComments writes the comments before an expression. A line comment
+// ends its line, which is only possible because the source had a line break
+// there too.
+func (g *Generator) inlineComments(comments []*ast.Comment) string {
+	var out strings.Builder
+	for _, c := range g.keptComments(comments) {
+		out.WriteString(c.Text)
+		if c.IsBlock() {
+			out.WriteString(" ")
+		} else {
+			out.WriteString("\n")
+		}
+	}
+	return out.String()
+}
+
+func (g *Generator) generateExpressionCode(expr ast.Expression) string {
 	switch e := expr.(type) {
 	case *ast.IntegerLiteral:
 		return e.Token.Literal
 	case *ast.StringLiteral:
 		return fmt.Sprintf("\"%s\"", e.Value)
+	case *ast.RegExpLiteral:
+		return e.String()
 	case *ast.Identifier:
 		return e.Value
+	case *ast.Boolean:
+		return e.Token.Literal
+	case *ast.NullLiteral:
+		return "null"
+	case *ast.ThisExpression:
+		return "this"
+	case *ast.SuperExpression:
+		return "super"
+	case *ast.NewExpression:
+		return g.generateNew(e)
+	case *ast.MetaProperty:
+		if g.downlevel(ES2015) {
+			return g.newTarget
+		}
+		return "new.target"
+	case *ast.ParenthesizedExpression:
+		return "(" + g.generateJSExpression(e.Expression) + ")"
+	case *ast.PrefixExpression:
+		if e.Operator == "delete" && g.downlevel(ES2020) && ast.IsOptionalChain(ast.SkipParentheses(e.Right)) {
+			return g.generateOptionalDelete(e.Right)
+		}
+		right := g.operand(e.Right)
+		if e.IsKeyword() || startsWithOperator(right, e.Operator) {
+			// Keep - -x from turning into --x
+			return e.Operator + " " + right
+		}
+		return e.Operator + right
+	case *ast.PostfixExpression:
+		return g.operand(e.Left) + e.Operator
+	case *ast.NonNullExpression:
+		// x! only asserts the type of x
+		return g.generateJSExpression(e.Expression)
+	case *ast.InfixExpression:
+		if e.Operator == "??" && g.downlevel(ES2020) {
+			return g.generateNullishCoalescing(e)
+		}
+		if e.Operator == "**" && g.downlevel(ES2016) {
+			return "Math.pow(" + g.generateJSExpression(e.Left) + ", " + g.generateJSExpression(e.Right) + ")"
+		}
+		if e.Operator == "," {
+			return g.generateJSExpression(e.Left) + ", " + g.generateJSExpression(e.Right)
+		}
+		return g.operand(e.Left) + " " + e.Operator + " " + g.operand(e.Right)
+	case *ast.AssignmentExpression:
+		if g.lowerPattern(e.Target) {
+			return "(" + g.generateDestructuringAssignment(e, true) + ")"
+		}
+		if code, ok := g.lowerAssignment(e); ok {
+			return code
+		}
+		return g.generateJSExpression(e.Target) + " " + e.Operator + " " + g.generateJSExpression(e.Value)
+	case *ast.ConditionalExpression:
+		return fmt.Sprintf("%s ? %s : %s", g.operand(e.Condition),
+			g.generateJSExpression(e.Consequence), g.generateJSExpression(e.Alternative))
+	case *ast.CallExpression, *ast.MethodCallExpression, *ast.IndexExpression:
+		return g.generateChain(e)
+	case *ast.FunctionLiteral:
+		return g.generateFunction(e)
+	case *ast.AwaitExpression:
+		if g.await == "__await" {
+			return g.awaitCode(g.generateJSExpression(e.Argument))
+		}
+		if g.await == "yield" {
+			return "yield " + g.operand(e.Argument)
+		}
+		return "await " + g.operand(e.Argument)
+	case *ast.YieldExpression:
+		return g.generateYield(e)
+	case *ast.ArrayLiteral:
+		return g.generateArrayLiteral(e)
+	case *ast.ObjectLiteral:
+		return g.generateObjectLiteral(e)
+	case *ast.SpreadElement:
+		return "..." + g.generateJSExpression(e.Argument)
+	case *ast.ObjectPattern, *ast.ArrayPattern:
+		return g.generateBindingName(e)
 	default:
 		return ""
 	}
 }
+
+// generateJSExpressions generates a comma separated list, e.g. arguments
+func (g *Generator) generateJSExpressions(exprs []ast.Expression) string {
+	parts := make([]string, len(exprs))
+	for i, expr := range exprs {
+		parts[i] = g.generateJSExpression(expr)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// startsWithOperator reports whether writing operator right before code
+// would merge the two into a different token, as in - -x or + +x
+func startsWithOperator(code string, operator string) bool {
+	return (operator == "-" || operator == "+") && strings.HasPrefix(code, operator)
+}
@@ -29,6 +29,18 @@ func TestJavaScriptGeneration(t *testing.T) {
 			`return 10;`,
 			`return 10;`,
 		},
+		{
+			"return\nx;",
+			"return;\nx;",
+		},
+		{
+			`let re = /[a-z]+\/\d/gu;`,
+			`let re = /[a-z]+\/\d/gu;`,
+		},
+		{
+			`function f(): number | string { return 1; }`,
+			"function f() {\n    return 1;\n}",
+		},
 	}
 
 	for _, tt := range tests {
@@ -77,3 +89,709 @@ return z;`
 		t.Errorf("expected=%q, got=%q", expected, output)
 	}
 }
+
+func TestCommentGeneration(t *testing.T) {
+	input := `/*! Copyright (c) license */
+// the answer
+let x = 42; // trailing
+/* block */
+let y = x;
+let z = f(/* a */ x, /*! keep me */ 1);
+let o = { /*! legal */ a: 1, /* b */ b: 2 };
+let w = f(/* a */ x, /* b */ y);
+function h(/* a */ p, /*! kept */ q = 1) {}
+// end`
+
+	tests := []struct {
+		options  Options
+		expected string
+	}{
+		{
+			Options{},
+			`/*! Copyright (c) license */
+// the answer
+let x = 42; // trailing
+/* block */
+let y = x;
+let z = f(/* a */ x, /*! keep me */ 1);
+let o = { /*! legal */ a: 1, /* b */ b: 2 };
+let w = f(/* a */ x, /* b */ y);
+function h(/* a */ p, /*! kept */ q = 1) {
+}
+// end`,
+		},
+		{
+			Options{RemoveComments: true},
+			`/*! Copyright (c) license */
+let x = 42;
+let y = x;
+let z = f(x, /*! keep me */ 1);
+let o = { /*! legal */ a: 1, b: 2 };
+let w = f(x, y);
+function h(p, /*! kept */ q = 1) {
+}`,
+		},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(input)
+		p := parser.New(l)
+		program := p.ParseProgram()
+
+		generator := NewWithOptions(tt.options)
+		output := strings.TrimSpace(generator.GenerateJavaScript(program))
+
+		if output != tt.expected {
+			t.Errorf("removeComments=%t: expected=%q, got=%q", tt.options.RemoveComments, tt.expected, output)
+		}
+	}
+}
+
+func TestOperatorGeneration(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let x = (a + b) * c;`, `let x = (a + b) * c;`},
+		{`let x = a ?? b ? c : d;`, `let x = a ?? b ? c : d;`},
+		{`x **= 2;`, `x **= 2;`},
+		{`let t = typeof x;`, `let t = typeof x;`},
+		{`let y = - -x;`, `let y = - -x;`},
+		{`i++;`, `i++;`},
+		{`a = 1, b = 2;`, `a = 1, b = 2;`},
+		{`y = a >>> 2 >= 1;`, `y = a >>> 2 >= 1;`},
+		{"function add(a, b) {\n  return a + b;\n}", "function add(a, b) {\n    return a + b;\n}"},
+	}
+
+	for _, tt := range tests {
+		p := parser.New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		if len(p.Errors()) > 0 {
+			t.Fatalf("%s: parser errors: %v", tt.input, p.Errors())
+		}
+
+		output := strings.TrimSpace(New().GenerateJavaScript(program))
+		if output != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, output)
+		}
+	}
+}
+
+func TestOperatorLowering(t *testing.T) {
+	tests := []struct {
+		input    string
+		target   Target
+		expected string
+	}{
+		{`let x = a ** 2;`, ES2016, `let x = a ** 2;`},
+		{`let x = a ** b ** 2;`, ES2015, `let x = Math.pow(a, Math.pow(b, 2));`},
+		{`x **= 2;`, ES5, `x = Math.pow(x, 2);`},
+		{`f().b **= 2;`, ES5, "var _a;\n(_a = f()).b = Math.pow(_a.b, 2);"},
+		{`a[k()] **= 2;`, ES5, "var _a;\na[(_a = k())] = Math.pow(a[_a], 2);"},
+		{`a &&= b;`, ES2021, `a &&= b;`},
+		{`a &&= b;`, ES2020, `a && (a = b);`},
+		{`a.b ||= c;`, ES5, `a.b || (a.b = c);`},
+		{`a ??= b;`, ES2020, `a ?? (a = b);`},
+		{`a ??= b;`, ES2019, `a !== null && a !== void 0 ? a : (a = b);`},
+		{`f().b ??= c;`, ES5, "var _a, _b;\n(_b = (_a = f()).b) !== null && _b !== void 0 ? _b : (_a.b = c);"},
+	}
+
+	for _, tt := range tests {
+		p := parser.New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		if len(p.Errors()) > 0 {
+			t.Fatalf("%s: parser errors: %v", tt.input, p.Errors())
+		}
+
+		output := NewWithOptions(Options{Target: tt.target}).GenerateJavaScript(program)
+		if output = strings.TrimSpace(output); output != tt.expected {
+			t.Errorf("%s (%s):\nexpected=%q\ngot=     %q", tt.input, tt.target, tt.expected, output)
+		}
+	}
+}
+
+func TestOptionalChainGeneration(t *testing.T) {
+	tests := []struct {
+		input    string
+		target   Target
+		expected string
+	}{
+		{`let x = a?.b?.(c)?.[d];`, ESNext, `let x = a?.b?.(c)?.[d];`},
+		{`let x = a ?? b;`, ES2020, `let x = a ?? b;`},
+		{`let x = a?.b;`, ES2019, `let x = a === null || a === void 0 ? void 0 : a.b;`},
+		{`let x = a?.b.c();`, ES2019, `let x = a === null || a === void 0 ? void 0 : a.b.c();`},
+		{`let x = a.b?.c;`, ES5, "var _a;\nvar x = (_a = a.b) === null || _a === void 0 ? void 0 : _a.c;"},
+		{`let x = a?.b?.c;`, ES5, "var _a;\nvar x = (_a = a === null || a === void 0 ? void 0 : a.b) === null || _a === void 0 ? void 0 : _a.c;"},
+		{`let x = a?.[i];`, ES5, `var x = a === null || a === void 0 ? void 0 : a[i];`},
+		{`let x = f?.(1);`, ES5, `var x = f === null || f === void 0 ? void 0 : f(1);`},
+		{`let x = a.b?.(1);`, ES5, "var _a;\nvar x = (_a = a.b) === null || _a === void 0 ? void 0 : _a.call(a, 1);"},
+		{`let x = a.b.c?.();`, ES5, "var _a, _b;\nvar x = (_b = (_a = a.b).c) === null || _b === void 0 ? void 0 : _b.call(_a);"},
+		{`let x = a?.b + 1;`, ES5, `var x = (a === null || a === void 0 ? void 0 : a.b) + 1;`},
+		{`let x = delete a?.b;`, ES2019, `let x = a === null || a === void 0 ? true : delete a.b;`},
+		{`let x = delete a?.b?.c;`, ES5, "var _a;\nvar x = (_a = a === null || a === void 0 ? void 0 : a.b) === null || _a === void 0 ? true : delete _a.c;"},
+		{`let x = !delete a?.b;`, ES5, `var x = !(a === null || a === void 0 ? true : delete a.b);`},
+		{`let x = delete a?.b;`, ES2020, `let x = delete a?.b;`},
+		{`let x = a ?? b;`, ES2019, `let x = a !== null && a !== void 0 ? a : b;`},
+		{`let x = f() ?? b ?? c;`, ES5, "var _a, _b;\nvar x = (_b = (_a = f()) !== null && _a !== void 0 ? _a : b) !== null && _b !== void 0 ? _b : c;"},
+		{"function f(a) {\n  return a?.b;\n}", ES5, "function f(a) {\n    return a === null || a === void 0 ? void 0 : a.b;\n}"},
+		{"function f(a) {\n  return a.b ?? 0;\n}", ES5, "function f(a) {\n    var _a;\n    return (_a = a.b) !== null && _a !== void 0 ? _a : 0;\n}"},
+	}
+
+	for _, tt := range tests {
+		p := parser.New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		if len(p.Errors()) > 0 {
+			t.Fatalf("%s: parser errors: %v", tt.input, p.Errors())
+		}
+
+		output := NewWithOptions(Options{Target: tt.target}).GenerateJavaScript(program)
+		if output = strings.TrimSpace(output); output != tt.expected {
+			t.Errorf("%s (%s):\nexpected=%q\ngot=     %q", tt.input, tt.target, tt.expected, output)
+		}
+	}
+}
+
+func TestParseTarget(t *testing.T) {
+	for name, expected := range map[string]Target{"es5": ES5, "ES6": ES2015, "es2020": ES2020, "ES2021": ES2021, "esnext": ESNext} {
+		target, err := ParseTarget(name)
+		if err != nil || target != expected {
+			t.Errorf("ParseTarget(%q) = %s, %v; expected %s", name, target, err, expected)
+		}
+	}
+	if _, err := ParseTarget("es4"); err == nil {
+		t.Errorf("expected an error for an unknown target")
+	}
+}
+
+func TestDestructuringGeneration(t *testing.T) {
+	tests := []struct {
+		input    string
+		target   Target
+		expected string
+	}{
+		{`const { a, b: [c, ...d] = [] } = obj;`, ES2018, `const { a, b: [c, ...d] = [] } = obj;`},
+		{`let [x, , y] = list;`, ES2015, `let [x, , y] = list;`},
+		{`[a, b] = [b, a];`, ES2015, `[a, b] = [b, a];`},
+		{`const { a, b: [c, ...d] = [] } = obj;`, ES5, `var a = obj.a, _a = obj.b, _b = _a === void 0 ? [] : _a, c = _b[0], d = _b.slice(1);`},
+		{`let [x, , y = 1] = f();`, ES5, `var _a = f(), x = _a[0], _b = _a[2], y = _b === void 0 ? 1 : _b;`},
+		{`let { a } = f();`, ES5, `var a = f().a;`},
+		{`let { "a-b": ab, 0: zero } = o;`, ES5, `var ab = o["a-b"], zero = o[0];`},
+		{`[a, b] = [b, a];`, ES5, "var _a;\n_a = [b, a], a = _a[0], b = _a[1];"},
+		{`x = [a, o.b] = list;`, ES5, `x = (a = list[0], o.b = list[1], list);`},
+		{`let { a, ...rest } = o;`, ES2017, "var __rest = (this && this.__rest) || function (s, e) {\n" +
+			"    var t = {};\n" +
+			"    for (var p in s) if (Object.prototype.hasOwnProperty.call(s, p) && e.indexOf(p) < 0)\n" +
+			"        t[p] = s[p];\n" +
+			"    if (s != null && typeof Object.getOwnPropertySymbols === \"function\")\n" +
+			"        for (var i = 0, p = Object.getOwnPropertySymbols(s); i < p.length; i++) {\n" +
+			"            if (e.indexOf(p[i]) < 0 && Object.prototype.propertyIsEnumerable.call(s, p[i]))\n" +
+			"                t[p[i]] = s[p[i]];\n" +
+			"        }\n" +
+			"    return t;\n" +
+			"};\n" +
+			`let a = o.a, rest = __rest(o, ["a"]);`},
+		{"function f({ a, b }, c = 1) {\n  return a;\n}", ES5, "function f(_a, c) {\n    var a = _a.a, b = _a.b;\n    if (c === void 0) { c = 1; }\n    return a;\n}"},
+		{"function f([a] = []) {\n  return a;\n}", ES5, "function f(_a) {\n    var a = (_a === void 0 ? [] : _a)[0];\n    return a;\n}"},
+	}
+
+	for _, tt := range tests {
+		p := parser.New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		if len(p.Errors()) > 0 {
+			t.Fatalf("%s: parser errors: %v", tt.input, p.Errors())
+		}
+
+		output := NewWithOptions(Options{Target: tt.target}).GenerateJavaScript(program)
+		if output = strings.TrimSpace(output); output != tt.expected {
+			t.Errorf("%s (%s):\nexpected=%q\ngot=     %q", tt.input, tt.target, tt.expected, output)
+		}
+	}
+}
+
+func TestSpreadGeneration(t *testing.T) {
+	tests := []struct {
+		input    string
+		target   Target
+		expected string
+	}{
+		{`f(...args);`, ES2015, `f(...args);`},
+		{`let o = { ...a, b: 1 };`, ES2018, `let o = { ...a, b: 1 };`},
+		{"function f(a, ...rest) {\n  return rest;\n}", ES2015, "function f(a, ...rest) {\n    return rest;\n}"},
+		{`f(...args);`, ES5, `f.apply(void 0, args);`},
+		{`o.m(...args);`, ES5, `o.m.apply(o, args);`},
+		{`a.b.m(1, ...x);`, ES5, "var _a;\n(_a = a.b).m.apply(_a, __spreadArray([1], x, false));"},
+		{`a?.b.m(...x);`, ES5, "var _a;\na === null || a === void 0 ? void 0 : (_a = a.b).m.apply(_a, x);"},
+		{`console.log(...args);`, ES5, `console.log.apply(console, args);`},
+		{`let x = [...a, 1, 2, ...b];`, ES5, `var x = __spreadArray(__spreadArray(__spreadArray([], a, true), [1, 2], false), b, true);`},
+		{`let x = [0, ...a];`, ES5, `var x = __spreadArray([0], a, true);`},
+		{`let o = { ...a, b: 1 };`, ES2017, `let o = Object.assign(Object.assign({}, a), { b: 1 });`},
+		{`let o = { a: 1, ...b, ...c };`, ES5, `var o = __assign(__assign({ a: 1 }, b), c);`},
+		{"function f(a, ...rest) {\n  return rest;\n}", ES5, "function f(a) {\n" +
+			"    var rest = [];\n" +
+			"    for (var _i = 1; _i < arguments.length; _i++) {\n" +
+			"        rest[_i - 1] = arguments[_i];\n" +
+			"    }\n" +
+			"    return rest;\n" +
+			"}"},
+		{"function f(...[a, b]) {\n  return a;\n}", ES5, "function f() {\n" +
+			"    var _a = [];\n" +
+			"    for (var _i = 0; _i < arguments.length; _i++) {\n" +
+			"        _a[_i] = arguments[_i];\n" +
+			"    }\n" +
+			"    var a = _a[0], b = _a[1];\n" +
+			"    return a;\n" +
+			"}"},
+	}
+
+	for _, tt := range tests {
+		p := parser.New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		if len(p.Errors()) > 0 {
+			t.Fatalf("%s: parser errors: %v", tt.input, p.Errors())
+		}
+
+		output := NewWithOptions(Options{Target: tt.target}).GenerateJavaScript(program)
+		// The helpers are the same for every test; check they are used
+		for _, name := range []string{"__spreadArray", "__assign"} {
+			if strings.Contains(output, name+"(") && !strings.Contains(output, "var "+name+" = ") {
+				t.Errorf("%s: %s used but not defined", tt.input, name)
+			}
+		}
+		if i := strings.LastIndex(output, "\n};\n"); i >= 0 {
+			output = output[i+len("\n};\n"):]
+		}
+		if output = strings.TrimSpace(output); output != tt.expected {
+			t.Errorf("%s (%s):\nexpected=%q\ngot=     %q", tt.input, tt.target, tt.expected, output)
+		}
+	}
+}
+
+func TestSwitchTryGeneration(t *testing.T) {
+	tests := []struct {
+		input    string
+		target   Target
+		expected string
+	}{
+		{"switch (x) { case 1: case 2: f(); break; case 3: { g(); } default: h(); }", ESNext, "switch (x) {\n" +
+			"    case 1:\n" +
+			"    case 2:\n" +
+			"        f();\n" +
+			"        break;\n" +
+			"    case 3: {\n" +
+			"        g();\n" +
+			"    }\n" +
+			"    default:\n" +
+			"        h();\n" +
+			"}"},
+		{"try { f(); } catch (e) { throw e; } finally { g(); }", ESNext, "try {\n    f();\n}\ncatch (e) {\n    throw e;\n}\nfinally {\n    g();\n}"},
+		{"try { f(); } catch { }", ES2019, "try {\n    f();\n}\ncatch {\n}"},
+		{"try { f(); } catch { }", ES2018, "try {\n    f();\n}\ncatch (_a) {\n}"},
+		{"try { f(); } catch ({ message }) { g(message); }", ES5, "try {\n    f();\n}\ncatch (_a) {\n    var message = _a.message;\n    g(message);\n}"},
+		{"if (x) { f(); } else if (y) g(); else { h(); }", ESNext, "if (x) {\n    f();\n}\nelse if (y)\n    g();\nelse {\n    h();\n}"},
+		{"let x!: number; if (x!) { let y: string | null = null; }", ES5, "var x;\nif (x) {\n    var y = null;\n}"},
+	}
+
+	for _, tt := range tests {
+		p := parser.New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		if len(p.Errors()) > 0 {
+			t.Fatalf("%s: parser errors: %v", tt.input, p.Errors())
+		}
+
+		output := NewWithOptions(Options{Target: tt.target}).GenerateJavaScript(program)
+		if output = strings.TrimSpace(output); output != tt.expected {
+			t.Errorf("%s (%s):\nexpected=%q\ngot=     %q", tt.input, tt.target, tt.expected, output)
+		}
+	}
+}
+
+func TestLoopGeneration(t *testing.T) {
+	tests := []struct {
+		input              string
+		target             Target
+		downlevelIteration bool
+		expected           string
+	}{
+		{"for (const x of xs) f(x);", ES2015, false, "for (const x of xs)\n    f(x);"},
+		{"for await (const x of xs) { f(x); }", ES2018, false, "for await (const x of xs) {\n    f(x);\n}"},
+		{"for (const { a, ...r } of xs) { f(a); }", ES2017, false, "for (const _a of xs) {\n    const a = _a.a, r = __rest(_a, [\"a\"]);\n    f(a);\n}"},
+		{"for (let k in o) { f(k); }", ES5, false, "for (var k in o) {\n    f(k);\n}"},
+		{"for (const x of xs) f(x);", ES5, false, "for (var _i = 0, xs_1 = xs; _i < xs_1.length; _i++) {\n    var x = xs_1[_i];\n    f(x);\n}"},
+		{"for (const [k, v] of f()) { g(k, v); }", ES5, false, "for (var _i = 0, _a = f(); _i < _a.length; _i++) {\n" +
+			"    var _b = _a[_i], k = _b[0], v = _b[1];\n" +
+			"    g(k, v);\n" +
+			"}"},
+		{"for (x of xs) { for (y of xs) { f(x, y); } }", ES5, false, "for (var _i = 0, xs_1 = xs; _i < xs_1.length; _i++) {\n" +
+			"    x = xs_1[_i];\n" +
+			"    for (var _a = 0, xs_2 = xs; _a < xs_2.length; _a++) {\n" +
+			"        y = xs_2[_a];\n" +
+			"        f(x, y);\n" +
+			"    }\n" +
+			"}"},
+		{"for (const x of xs) { f(x); }", ES5, true, "var e_1, _a;\n" +
+			"try {\n" +
+			"    for (var xs_1 = __values(xs), xs_1_1 = xs_1.next(); !xs_1_1.done; xs_1_1 = xs_1.next()) {\n" +
+			"        var x = xs_1_1.value;\n" +
+			"        f(x);\n" +
+			"    }\n" +
+			"}\n" +
+			"catch (e_1_1) { e_1 = { error: e_1_1 }; }\n" +
+			"finally {\n" +
+			"    try {\n" +
+			"        if (xs_1_1 && !xs_1_1.done && (_a = xs_1.return)) _a.call(xs_1);\n" +
+			"    }\n" +
+			"    finally { if (e_1) throw e_1.error; }\n" +
+			"}"},
+		{"a: b: for (k in o) { break b; }", ES5, false, "a: b: for (k in o) {\n    break b;\n}"},
+		{"a: for (const x of xs) { if (x) continue a; f(x); }", ES5, true, "var e_1, _a;\n" +
+			"try {\n" +
+			"    a: for (var xs_1 = __values(xs), xs_1_1 = xs_1.next(); !xs_1_1.done; xs_1_1 = xs_1.next()) {\n" +
+			"        var x = xs_1_1.value;\n" +
+			"        if (x)\n" +
+			"            continue a;\n" +
+			"        f(x);\n" +
+			"    }\n" +
+			"}\n" +
+			"catch (e_1_1) { e_1 = { error: e_1_1 }; }\n" +
+			"finally {\n" +
+			"    try {\n" +
+			"        if (xs_1_1 && !xs_1_1.done && (_a = xs_1.return)) _a.call(xs_1);\n" +
+			"    }\n" +
+			"    finally { if (e_1) throw e_1.error; }\n" +
+			"}"},
+	}
+
+	for _, tt := range tests {
+		p := parser.New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		if len(p.Errors()) > 0 {
+			t.Fatalf("%s: parser errors: %v", tt.input, p.Errors())
+		}
+
+		output := NewWithOptions(Options{Target: tt.target, DownlevelIteration: tt.downlevelIteration}).GenerateJavaScript(program)
+		// Skip the helpers, which other tests cover
+		if i := strings.LastIndex(output, "\n};\n"); i >= 0 {
+			output = output[i+len("\n};\n"):]
+		}
+		if output = strings.TrimSpace(output); output != tt.expected {
+			t.Errorf("%s (%s):\nexpected=%q\ngot=     %q", tt.input, tt.target, tt.expected, output)
+		}
+	}
+}
+
+func TestAsyncGeneration(t *testing.T) {
+	tests := []struct {
+		input    string
+		target   Target
+		expected string
+	}{
+		{"async function f(p) { return await p; }", ES2017, "async function f(p) {\n    return await p;\n}"},
+		{"async function f(p) { const x = await p; return x + 1; }", ES2015, "function f(p) {\n" +
+			"    return __awaiter(this, void 0, void 0, function* () {\n" +
+			"        const x = yield p;\n" +
+			"        return x + 1;\n" +
+			"    });\n" +
+			"}"},
+		{"async function f() { return arguments.length + await g(); }", ES2015, "function f() {\n" +
+			"    return __awaiter(this, arguments, void 0, function* () {\n" +
+			"        return arguments.length + (yield g());\n" +
+			"    });\n" +
+			"}"},
+		{"async function f(p) { const x = await p; return x + 1; }", ES5, "function f(p) {\n" +
+			"    return __awaiter(this, void 0, void 0, function () {\n" +
+			"        var x;\n" +
+			"        return __generator(this, function (_a) {\n" +
+			"            switch (_a.label) {\n" +
+			"                case 0: return [4 /*yield*/, p];\n" +
+			"                case 1:\n" +
+			"                    x = _a.sent();\n" +
+			"                    return [2 /*return*/, x + 1];\n" +
+			"            }\n" +
+			"        });\n" +
+			"    });\n" +
+			"}"},
+		{"async function f(p) { if (p) { await p; } else { return 1; } return 2; }", ES5, "function f(p) {\n" +
+			"    return __awaiter(this, void 0, void 0, function () {\n" +
+			"        return __generator(this, function (_a) {\n" +
+			"            switch (_a.label) {\n" +
+			"                case 0:\n" +
+			"                    if (!p) return [3 /*break*/, 2];\n" +
+			"                    return [4 /*yield*/, p];\n" +
+			"                case 1:\n" +
+			"                    _a.sent();\n" +
+			"                    return [3 /*break*/, 3];\n" +
+			"                case 2: return [2 /*return*/, 1];\n" +
+			"                case 3: return [2 /*return*/, 2];\n" +
+			"            }\n" +
+			"        });\n" +
+			"    });\n" +
+			"}"},
+		{"async function f() { try { await g(); } catch (e) { h(e); } }", ES5, "function f() {\n" +
+			"    return __awaiter(this, void 0, void 0, function () {\n" +
+			"        var e;\n" +
+			"        return __generator(this, function (_a) {\n" +
+			"            switch (_a.label) {\n" +
+			"                case 0:\n" +
+			"                    _a.trys.push([0, 2, , 3]);\n" +
+			"                    return [4 /*yield*/, g()];\n" +
+			"                case 1:\n" +
+			"                    _a.sent();\n" +
+			"                    return [3 /*break*/, 3];\n" +
+			"                case 2:\n" +
+			"                    e = _a.sent();\n" +
+			"                    h(e);\n" +
+			"                    return [3 /*break*/, 3];\n" +
+			"                case 3: return [2 /*return*/];\n" +
+			"            }\n" +
+			"        });\n" +
+			"    });\n" +
+			"}"},
+		{"async function f(xs) { a: for (const x of xs) { for (const y of x) { await y; continue a; } } }", ES5, "function f(xs) {\n" +
+			"    return __awaiter(this, void 0, void 0, function () {\n" +
+			"        var _i, xs_1, x, _b, x_1, y;\n" +
+			"        return __generator(this, function (_a) {\n" +
+			"            switch (_a.label) {\n" +
+			"                case 0:\n" +
+			"                    _i = 0, xs_1 = xs;\n" +
+			"                    _a.label = 1;\n" +
+			"                case 1:\n" +
+			"                    if (!(_i < xs_1.length)) return [3 /*break*/, 6];\n" +
+			"                    x = xs_1[_i];\n" +
+			"                    _b = 0, x_1 = x;\n" +
+			"                    _a.label = 2;\n" +
+			"                case 2:\n" +
+			"                    if (!(_b < x_1.length)) return [3 /*break*/, 5];\n" +
+			"                    y = x_1[_b];\n" +
+			"                    return [4 /*yield*/, y];\n" +
+			"                case 3:\n" +
+			"                    _a.sent();\n" +
+			"                    return [3 /*break*/, 5];\n" +
+			"                case 4:\n" +
+			"                    _b++;\n" +
+			"                    return [3 /*break*/, 2];\n" +
+			"                case 5:\n" +
+			"                    _i++;\n" +
+			"                    return [3 /*break*/, 1];\n" +
+			"                case 6: return [2 /*return*/];\n" +
+			"            }\n" +
+			"        });\n" +
+			"    });\n" +
+			"}"},
+		{"async function f() { x += await g(); }", ES5, "function f() {\n" +
+			"    return __awaiter(this, void 0, void 0, function () {\n" +
+			"        var _b;\n" +
+			"        return __generator(this, function (_a) {\n" +
+			"            switch (_a.label) {\n" +
+			"                case 0:\n" +
+			"                    _b = x;\n" +
+			"                    return [4 /*yield*/, g()];\n" +
+			"                case 1:\n" +
+			"                    x = _b + (_a.sent());\n" +
+			"                    return [2 /*return*/];\n" +
+			"            }\n" +
+			"        });\n" +
+			"    });\n" +
+			"}"},
+		{"async function f(a) { a.b ??= await g(); }", ES5, "function f(a) {\n" +
+			"    return __awaiter(this, void 0, void 0, function () {\n" +
+			"        var _b, _c, _d;\n" +
+			"        return __generator(this, function (_a) {\n" +
+			"            switch (_a.label) {\n" +
+			"                case 0:\n" +
+			"                    _b = a;\n" +
+			"                    _c = _b.b;\n" +
+			"                    if (_c !== null && _c !== void 0) return [3 /*break*/, 2];\n" +
+			"                    _d = _b;\n" +
+			"                    return [4 /*yield*/, g()];\n" +
+			"                case 1:\n" +
+			"                    _c = _d.b = _a.sent();\n" +
+			"                    _a.label = 2;\n" +
+			"                case 2:\n" +
+			"                    _c;\n" +
+			"                    return [2 /*return*/];\n" +
+			"            }\n" +
+			"        });\n" +
+			"    });\n" +
+			"}"},
+		{"let o = { async m(p) { return await p; } };", ESNext, "let o = { async m(p) {\n    return await p;\n} };"},
+		{"let o = { async m(p) { return await p; } };", ES2015, "let o = { m(p) {\n" +
+			"    return __awaiter(this, void 0, void 0, function* () {\n" +
+			"        return yield p;\n" +
+			"    });\n" +
+			"} };"},
+		{"let o = { async m(p) { return await p; } };", ES5, "var o = { m: function(p) {\n" +
+			"    return __awaiter(this, void 0, void 0, function () {\n" +
+			"        return __generator(this, function (_a) {\n" +
+			"            switch (_a.label) {\n" +
+			"                case 0: return [4 /*yield*/, p];\n" +
+			"                case 1: return [2 /*return*/, _a.sent()];\n" +
+			"            }\n" +
+			"        });\n" +
+			"    });\n" +
+			"} };"},
+		{"let o = { *g() { yield 1; }, async: 1, async() {} };", ES2015, "let o = { *g() {\n    yield 1;\n}, async: 1, async() {\n} };"},
+		{"async function f(xs) { for await (const x of xs) { g(x); } }", ES2017, "async function f(xs) {\n" +
+			"    var _a, e_1, _b, _c;\n" +
+			"    try {\n" +
+			"        for (var _d = true, xs_1 = __asyncValues(xs), xs_1_1; xs_1_1 = await xs_1.next(), _a = xs_1_1.done, !_a; _d = true) {\n" +
+			"            _c = xs_1_1.value;\n" +
+			"            _d = false;\n" +
+			"            const x = _c;\n" +
+			"            g(x);\n" +
+			"        }\n" +
+			"    }\n" +
+			"    catch (e_1_1) { e_1 = { error: e_1_1 }; }\n" +
+			"    finally {\n" +
+			"        try {\n" +
+			"            if (!_d && !_a && (_b = xs_1.return)) await _b.call(xs_1);\n" +
+			"        }\n" +
+			"        finally { if (e_1) throw e_1.error; }\n" +
+			"    }\n" +
+			"}"},
+	}
+
+	for _, tt := range tests {
+		p := parser.New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		if len(p.Errors()) > 0 {
+			t.Fatalf("%s: parser errors: %v", tt.input, p.Errors())
+		}
+
+		output := NewWithOptions(Options{Target: tt.target}).GenerateJavaScript(program)
+		// Skip the helpers, which other tests cover
+		if i := strings.LastIndex(output, "\n};\n"); i >= 0 {
+			output = output[i+len("\n};\n"):]
+		}
+		if output = strings.TrimSpace(output); output != tt.expected {
+			t.Errorf("%s (%s):\nexpected=%q\ngot=     %q", tt.input, tt.target, tt.expected, output)
+		}
+	}
+}
+
+func TestGeneratorGeneration(t *testing.T) {
+	tests := []struct {
+		input    string
+		target   Target
+		expected string
+	}{
+		{"function* g(n) { const x = yield n; yield* xs; }", ES2015, "function* g(n) {\n    const x = yield n;\n    yield* xs;\n}"},
+		{"function* g(n) { const x = yield 1; yield; yield* [n, 2]; return x; }", ES5, "function g(n) {\n" +
+			"    var x;\n" +
+			"    return __generator(this, function (_a) {\n" +
+			"        switch (_a.label) {\n" +
+			"            case 0: return [4 /*yield*/, 1];\n" +
+			"            case 1:\n" +
+			"                x = _a.sent();\n" +
+			"                return [4 /*yield*/];\n" +
+			"            case 2:\n" +
+			"                _a.sent();\n" +
+			"                return [5 /*yield**/, __values([n, 2])];\n" +
+			"            case 3:\n" +
+			"                _a.sent();\n" +
+			"                return [2 /*return*/, x];\n" +
+			"        }\n" +
+			"    });\n" +
+			"}"},
+		{"function* g() { f(); }", ES5, "function g() {\n" +
+			"    return __generator(this, function (_a) {\n" +
+			"        f();\n" +
+			"        return [2 /*return*/];\n" +
+			"    });\n" +
+			"}"},
+		{"async function* f(p) { yield await p; }", ES2018, "async function* f(p) {\n    yield await p;\n}"},
+		{"async function* f(p) { const x = await p; yield x; }", ES2017, "function f(p) {\n" +
+			"    return __asyncGenerator(this, arguments, function* f_1() {\n" +
+			"        const x = yield __await(p);\n" +
+			"        yield yield __await(x);\n" +
+			"    });\n" +
+			"}"},
+		{"async function* f(xs) { yield* xs; return 1; }", ES2017, "function f(xs) {\n" +
+			"    return __asyncGenerator(this, arguments, function* f_1() {\n" +
+			"        yield __await(yield* __asyncDelegator(__asyncValues(xs)));\n" +
+			"        return yield __await(1);\n" +
+			"    });\n" +
+			"}"},
+		{"async function* f(p) { const x = await p; yield x; }", ES5, "function f(p) {\n" +
+			"    return __asyncGenerator(this, arguments, function f_1() {\n" +
+			"        var x;\n" +
+			"        return __generator(this, function (_a) {\n" +
+			"            switch (_a.label) {\n" +
+			"                case 0: return [4 /*yield*/, __await(p)];\n" +
+			"                case 1:\n" +
+			"                    x = _a.sent();\n" +
+			"                    return [4 /*yield*/, __await(x)];\n" +
+			"                case 2: return [4 /*yield*/, _a.sent()];\n" +
+			"                case 3:\n" +
+			"                    _a.sent();\n" +
+			"                    return [2 /*return*/];\n" +
+			"            }\n" +
+			"        });\n" +
+			"    });\n" +
+			"}"},
+	}
+
+	for _, tt := range tests {
+		p := parser.New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		if len(p.Errors()) > 0 {
+			t.Fatalf("%s: parser errors: %v", tt.input, p.Errors())
+		}
+
+		output := NewWithOptions(Options{Target: tt.target}).GenerateJavaScript(program)
+		// Skip the helpers, which other tests cover
+		if i := strings.LastIndex(output, "\n};\n"); i >= 0 {
+			output = output[i+len("\n};\n"):]
+		}
+		if output = strings.TrimSpace(output); output != tt.expected {
+			t.Errorf("%s (%s):\nexpected=%q\ngot=     %q", tt.input, tt.target, tt.expected, output)
+		}
+	}
+
+	// An async generator needs __await even when it never awaits
+	p := parser.New(lexer.New("async function* f() {}"))
+	output := NewWithOptions(Options{Target: ES2017}).GenerateJavaScript(p.ParseProgram())
+	if !strings.HasPrefix(output, "var __await = ") {
+		t.Errorf("expected the __await helper first, got:\n%s", output)
+	}
+}
+
+func TestNewGeneration(t *testing.T) {
+	tests := []struct {
+		input    string
+		target   Target
+		expected string
+	}{
+		{"new C(1, ...xs);", ES2015, "new C(1, ...xs);"},
+		{"new C(1, ...xs);", ES5, "new (C.bind.apply(C, __spreadArray([void 0, 1], xs, false)))();"},
+		{"new a.C(...xs);", ES5, "var _a;\nnew ((_a = a.C).bind.apply(_a, __spreadArray([void 0], xs, false)))();"},
+		{"new (f())();", ES5, "new (f())();"},
+		{"function F() { return new.target; }", ES2015, "function F() {\n    return new.target;\n}"},
+		{"function F(a = 1) { return new.target; }", ES5, "function F(a) {\n" +
+			"    var _newTarget = this && this instanceof F ? this.constructor : void 0;\n" +
+			"    if (a === void 0) { a = 1; }\n" +
+			"    return _newTarget;\n" +
+			"}"},
+		{"f(function () { return new.target; });", ES5, "f(function _a() {\n" +
+			"    var _newTarget = this && this instanceof _a ? this.constructor : void 0;\n" +
+			"    return _newTarget;\n" +
+			"});"},
+		{"function F() { this.x = 1; }", ES5, "function F() {\n    this.x = 1;\n}"},
+	}
+
+	for _, tt := range tests {
+		p := parser.New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		if len(p.Errors()) > 0 {
+			t.Fatalf("%s: parser errors: %v", tt.input, p.Errors())
+		}
+
+		output := NewWithOptions(Options{Target: tt.target}).GenerateJavaScript(program)
+		// Skip the helpers, which other tests cover
+		if i := strings.LastIndex(output, "\n};\n"); i >= 0 {
+			output = output[i+len("\n};\n"):]
+		}
+		if output = strings.TrimSpace(output); output != tt.expected {
+			t.Errorf("%s (%s):\nexpected=%q\ngot=     %q", tt.input, tt.target, tt.expected, output)
+		}
+	}
+}
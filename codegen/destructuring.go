@@ -0,0 +1,332 @@
+package codegen
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dmarro89/ts-go-compiler/ast"
+)
+
+// generateDeclaration generates let, const and var statements. Before
+// ES2015 they all become var and patterns are flattened into one variable
+// per name, e.g. var { a, b: [c] } = o becomes var a = o.a, c = o.b[0].
+func (g *Generator) generateDeclaration(s *ast.LetStatement) string {
+	if g.machine != nil {
+		return g.machine.declaration(s.Target(), s.Value)
+	}
+
+	keyword := s.Token.Literal
+	if g.downlevel(ES2015) {
+		keyword = "var"
+	}
+
+	if s.Value == nil {
+		return fmt.Sprintf("%s %s;", keyword, g.generateBindingName(s.Target()))
+	}
+	if s.Pattern == nil || !g.lowerPattern(s.Pattern) {
+		return fmt.Sprintf("%s %s = %s;", keyword, g.generateBindingName(s.Target()), g.generateJSExpression(s.Value))
+	}
+
+	f := &flattener{g: g, declare: true}
+	f.bind(s.Pattern, g.generateJSExpression(s.Value), valueKindOf(s.Value))
+	return keyword + " " + strings.Join(f.assignments, ", ") + ";"
+}
+
+// generateDestructuringAssignment flattens [a, b] = value into a comma
+// separated list of assignments. When the value of the whole expression is
+// used, the list ends with it.
+func (g *Generator) generateDestructuringAssignment(e *ast.AssignmentExpression, keepValue bool) string {
+	f := &flattener{g: g}
+
+	value := g.generateJSExpression(e.Value)
+	kind := valueKindOf(e.Value)
+	if keepValue && kind != simpleValue {
+		value, kind = f.store(value), simpleValue
+	}
+	f.bind(e.Target, value, kind)
+
+	if keepValue {
+		f.assignments = append(f.assignments, value)
+	}
+	return strings.Join(f.assignments, ", ")
+}
+
+// generateParameters generates the parameter list of a function. Before
+// ES2015 defaults and patterns are replaced by plain parameters and the
+// statements returned, which start the body of the function.
+func (g *Generator) generateParameters(params []*ast.BindingElement) (string, []string) {
+	var names, prologue []string
+
+	for _, param := range params {
+		comments := g.inlineComments(param.LeadingComments)
+		if !g.downlevel(ES2015) && !(g.downlevel(ES2018) && hasObjectRest(param.Name)) {
+			names = append(names, comments+g.generateBindingElement(param))
+			continue
+		}
+
+		if param.Rest {
+			prologue = append(prologue, g.generateRestParameter(param, len(names))...)
+			continue
+		}
+
+		if ident, ok := param.Name.(*ast.Identifier); ok {
+			names = append(names, comments+ident.Value)
+			if param.Default != nil {
+				prologue = append(prologue, fmt.Sprintf("if (%s === void 0) { %s = %s; }",
+					ident.Value, ident.Value, g.generateJSExpression(param.Default)))
+			}
+			continue
+		}
+
+		name := g.newName()
+		names = append(names, comments+name)
+
+		f := &flattener{g: g, declare: true}
+		if param.Default != nil {
+			f.bind(param.Name, fmt.Sprintf("%s === void 0 ? %s : %s", name, g.generateJSExpression(param.Default), name), conditionalValue)
+		} else {
+			f.bind(param.Name, name, simpleValue)
+		}
+		prologue = append(prologue, "var "+strings.Join(f.assignments, ", ")+";")
+	}
+
+	return strings.Join(names, ", "), prologue
+}
+
+// lowerPattern reports whether a pattern must be flattened for the target:
+// before ES2015 for any pattern, before ES2018 for patterns with an object
+// rest element
+func (g *Generator) lowerPattern(target ast.Expression) bool {
+	if !ast.IsPattern(target) {
+		return false
+	}
+	return g.downlevel(ES2015) || (g.downlevel(ES2018) && hasObjectRest(target))
+}
+
+// hasObjectRest reports whether a pattern contains { ...rest }
+func hasObjectRest(target ast.Expression) bool {
+	switch p := target.(type) {
+	case *ast.ObjectPattern:
+		for _, el := range p.Properties {
+			if el.Rest || hasObjectRest(el.Name) {
+				return true
+			}
+		}
+	case *ast.ArrayPattern:
+		for _, el := range p.Elements {
+			if el != nil && hasObjectRest(el.Name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// generateBindingName generates a name or pattern as written
+func (g *Generator) generateBindingName(target ast.Expression) string {
+	switch p := target.(type) {
+	case *ast.ObjectPattern:
+		if len(p.Properties) == 0 {
+			return "{}"
+		}
+		props := make([]string, len(p.Properties))
+		for i, el := range p.Properties {
+			props[i] = g.generateBindingElement(el)
+		}
+		return "{ " + strings.Join(props, ", ") + " }"
+	case *ast.ArrayPattern:
+		elements := make([]string, len(p.Elements))
+		for i, el := range p.Elements {
+			if el != nil {
+				elements[i] = g.generateBindingElement(el)
+			}
+		}
+		out := "[" + strings.Join(elements, ", ")
+		if n := len(p.Elements); n > 0 && p.Elements[n-1] == nil {
+			out += ","
+		}
+		return out + "]"
+	default:
+		return g.generateJSExpression(target)
+	}
+}
+
+func (g *Generator) generateBindingElement(el *ast.BindingElement) string {
+	var out strings.Builder
+
+	if el.Rest {
+		out.WriteString("...")
+	}
+	if el.PropertyName != nil {
+		out.WriteString(g.generatePropertyName(el.PropertyName) + ": ")
+	}
+	out.WriteString(g.generateBindingName(el.Name))
+	if el.Default != nil {
+		out.WriteString(" = " + g.generateJSExpression(el.Default))
+	}
+
+	return out.String()
+}
+
+func (g *Generator) generatePropertyName(key ast.Expression) string {
+	return g.generateJSExpression(key)
+}
+
+// flattener turns a pattern into one assignment per name
+type flattener struct {
+	g *Generator
+
+	// declare is set for declarations and parameters, whose temporaries are
+	// declared along with the names. Destructuring assignments declare them
+	// at the top of the function.
+	declare bool
+
+	assignments []string
+}
+
+func (f *flattener) assign(target string, value string) {
+	f.assignments = append(f.assignments, target+" = "+value)
+}
+
+func (f *flattener) temp() string {
+	if f.declare {
+		return f.g.newName()
+	}
+	return f.g.newTemp()
+}
+
+// valueKind describes the code of a value being destructured
+type valueKind int
+
+const (
+	simpleValue      valueKind = iota // can be read more than once, e.g. a name
+	complexValue                      // must be read once, e.g. a call
+	conditionalValue                  // must be read once and needs parentheses
+)
+
+func valueKindOf(expr ast.Expression) valueKind {
+	if isSimple(expr) {
+		return simpleValue
+	}
+	return complexValue
+}
+
+// bind assigns value to target. A value that is not simple is first stored
+// in a temporary when a pattern reads it more than once.
+func (f *flattener) bind(target ast.Expression, value string, kind valueKind) {
+	switch p := target.(type) {
+	case *ast.ObjectPattern:
+		value = f.prepare(value, kind, len(p.Properties))
+
+		var named []string
+		for _, el := range p.Properties {
+			if el.Rest {
+				keys := make([]string, len(named))
+				for i, name := range named {
+					keys[i] = fmt.Sprintf("%q", name)
+				}
+				f.bind(el.Name, fmt.Sprintf("%s(%s, [%s])", f.g.useHelper("__rest"), value, strings.Join(keys, ", ")), complexValue)
+				continue
+			}
+
+			key := el.PropertyName
+			if key == nil {
+				key = el.Name
+			}
+			named = append(named, propertyName(key))
+			f.bindElement(el, value+propertyAccess(key))
+		}
+	case *ast.ArrayPattern:
+		value = f.prepare(value, kind, len(p.Elements))
+
+		for i, el := range p.Elements {
+			switch {
+			case el == nil:
+			case el.Rest:
+				f.bind(el.Name, fmt.Sprintf("%s.slice(%d)", value, i), complexValue)
+			default:
+				f.bindElement(el, fmt.Sprintf("%s[%d]", value, i))
+			}
+		}
+	default:
+		f.assign(f.g.generateJSExpression(target), value)
+	}
+}
+
+// bindElement binds one element, replacing an undefined value by its default
+func (f *flattener) bindElement(el *ast.BindingElement, value string) {
+	if el.Default == nil {
+		f.bind(el.Name, value, complexValue)
+		return
+	}
+
+	temp := f.store(value)
+	f.bind(el.Name, fmt.Sprintf("%s === void 0 ? %s : %s", temp, f.g.generateJSExpression(el.Default), temp), conditionalValue)
+}
+
+// prepare returns the code a pattern with n elements reads its value with
+func (f *flattener) prepare(value string, kind valueKind, n int) string {
+	switch {
+	case kind == simpleValue:
+		return value
+	case n != 1:
+		return f.store(value)
+	case kind == conditionalValue:
+		return "(" + value + ")"
+	default:
+		return value
+	}
+}
+
+// store assigns value to a new temporary and returns its name
+func (f *flattener) store(value string) string {
+	temp := f.temp()
+	f.assign(temp, value)
+	return temp
+}
+
+// propertyName returns the name of a property key
+func propertyName(key ast.Expression) string {
+	switch k := key.(type) {
+	case *ast.StringLiteral:
+		return k.Value
+	default:
+		return key.String()
+	}
+}
+
+// propertyAccess returns .name, or ["name"] when the key is not an
+// identifier
+func propertyAccess(key ast.Expression) string {
+	switch k := key.(type) {
+	case *ast.Identifier:
+		return "." + k.Value
+	case *ast.StringLiteral:
+		return fmt.Sprintf("[%q]", k.Value)
+	default:
+		return "[" + key.String() + "]"
+	}
+}
+
+// bindingNames returns the names a name or pattern declares
+func bindingNames(target ast.Expression) []string {
+	switch p := target.(type) {
+	case *ast.Identifier:
+		return []string{p.Value}
+	case *ast.ObjectPattern:
+		var names []string
+		for _, el := range p.Properties {
+			names = append(names, bindingNames(el.Name)...)
+		}
+		return names
+	case *ast.ArrayPattern:
+		var names []string
+		for _, el := range p.Elements {
+			if el != nil {
+				names = append(names, bindingNames(el.Name)...)
+			}
+		}
+		return names
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package codegen
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/dmarro89/ts-go-compiler/ast"
+)
+
+// generateGeneratorFunction generates a generator function for ES5, which
+// returns the generator the __generator helper makes of a state machine
+func (g *Generator) generateGeneratorFunction(fn *ast.FunctionLiteral) string {
+	g.pushTemps()
+
+	params, prologue := g.generateParameters(fn.Parameters)
+
+	var body bytes.Buffer
+	for _, stmt := range prologue {
+		body.WriteString(stmt + "\n")
+	}
+	body.WriteString(g.generateStateMachine(fn.Body))
+
+	return fmt.Sprintf("function%s(%s) %s", functionName(fn), params, indentBlock(g.popTemps()+body.String()))
+}
+
+// generateAsyncGenerator generates an async generator function for targets
+// before ES2018. It returns the async iterator the __asyncGenerator helper
+// makes of a generator, which yields the values it awaits wrapped by
+// __await: a function* from ES2015 on, and a state machine before.
+func (g *Generator) generateAsyncGenerator(fn *ast.FunctionLiteral) string {
+	g.pushTemps()
+
+	params, prologue := g.generateParameters(fn.Parameters)
+
+	// The generator is named after the function, as a name of its own
+	name := ""
+	if fn.Name != nil {
+		name = " " + g.uniqueName(fn.Name.Value)
+	}
+
+	var generator string
+	if g.downlevel(ES2015) {
+		generator = "function" + name + "() " + g.generateStateMachineFunction(fn.Body)
+	} else {
+		generator = "function*" + name + "() " + g.generateGeneratorBody(fn.Body)
+	}
+
+	var body bytes.Buffer
+	for _, stmt := range prologue {
+		body.WriteString(stmt + "\n")
+	}
+	body.WriteString(fmt.Sprintf("return %s(this, arguments, %s);\n", g.useHelper("__asyncGenerator"), generator))
+
+	return fmt.Sprintf("function%s(%s) %s", functionName(fn), params, indentBlock(g.popTemps()+body.String()))
+}
+
+// generateYield generates yield and yield*. An async generator turned into
+// a generator awaits each value before yielding it, and delegates to the
+// iterator __asyncDelegator makes of the async iterator of yield*.
+func (g *Generator) generateYield(e *ast.YieldExpression) string {
+	if g.await == "__await" {
+		if e.Delegate {
+			return g.awaitCode(fmt.Sprintf("yield* %s(%s(%s))", g.useHelper("__asyncDelegator"),
+				g.useHelper("__asyncValues"), g.generateJSExpression(e.Argument)))
+		}
+		value := "void 0"
+		if e.Argument != nil {
+			value = g.generateJSExpression(e.Argument)
+		}
+		return "yield " + g.awaitCode(value)
+	}
+
+	out := "yield"
+	if e.Delegate {
+		out += "*"
+	}
+	if e.Argument != nil {
+		out += " " + g.generateJSExpression(e.Argument)
+	}
+	return out
+}
+
+// hasReturn reports whether stmt returns from the function it is in
+func hasReturn(stmt ast.Statement) bool {
+	found := false
+	ast.Inspect(stmt, func(n ast.Node) bool {
+		switch n.(type) {
+		case *ast.ReturnStatement:
+			found = true
+		case *ast.FunctionLiteral:
+			return false
+		}
+		return !found
+	})
+	return found
+}
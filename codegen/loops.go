@@ -0,0 +1,141 @@
+package codegen
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/dmarro89/ts-go-compiler/ast"
+)
+
+// generateForOf generates a for...of loop. Before ES2015 it becomes a loop
+// over the indexes of the value, or with DownlevelIteration a loop over the
+// iterator the __values helper returns. Before ES2018 a for await loop
+// becomes a loop over the async iterator of __asyncValues. labels are the
+// labels of the loop, which stay on the loop when it is wrapped in a try
+// statement.
+func (g *Generator) generateForOf(s *ast.ForOfStatement, labels string) string {
+	if s.Await && g.downlevel(ES2018) && g.await != "" {
+		return g.generateForAwait(s, labels)
+	}
+	if !g.downlevel(ES2015) || s.Await {
+		head, prologue := g.generateLoopHead(s.Kind, s.Target)
+		await := ""
+		if s.Await {
+			await = " await"
+		}
+		return labels + fmt.Sprintf("for%s (%s of %s)", await, head, g.generateJSExpression(s.Right)) + g.generateLoopBody(prologue, s.Body)
+	}
+
+	if g.options.DownlevelIteration {
+		return g.generateForOfIterator(s, labels)
+	}
+
+	i := g.newLoopVar()
+	array := g.copyName(s.Right)
+	prologue := g.bindLoopVariable(s.Kind, s.Target, fmt.Sprintf("%s[%s]", array, i), complexValue)
+
+	return labels + fmt.Sprintf("for (var %s = 0, %s = %s; %s < %s.length; %s++)", i, array, g.generateJSExpression(s.Right), i, array, i) +
+		g.generateLoopBody(prologue, s.Body)
+}
+
+// generateForOfIterator generates a for...of loop that calls the iterator
+// of the value, closing it when the loop ends early as ES2015 does
+func (g *Generator) generateForOfIterator(s *ast.ForOfStatement, labels string) string {
+	errName := g.uniqueName("e")
+	g.declareTemp(errName)
+	returnFn := g.newTemp()
+
+	iterator := g.copyName(s.Right)
+	result := g.newName()
+	if isSimple(s.Right) {
+		result = iterator + "_1"
+	}
+	prologue := g.bindLoopVariable(s.Kind, s.Target, result+".value", complexValue)
+
+	loop := labels + fmt.Sprintf("for (var %s = %s(%s), %s = %s.next(); !%s.done; %s = %s.next())",
+		iterator, g.useHelper("__values"), g.generateJSExpression(s.Right), result, iterator, result, result, iterator) +
+		g.generateLoopBody(prologue, s.Body)
+
+	closeIterator := "try " + indentBlock(fmt.Sprintf("if (%s && !%s.done && (%s = %s.return)) %s.call(%s);\n",
+		result, result, returnFn, iterator, returnFn, iterator)) + "\n" +
+		fmt.Sprintf("finally { if (%s) throw %s.error; }\n", errName, errName)
+
+	return "try " + indentBlock(loop+"\n") + "\n" +
+		fmt.Sprintf("catch (%s_1) { %s = { error: %s_1 }; }\n", errName, errName, errName) +
+		"finally " + indentBlock(closeIterator)
+}
+
+// generateForIn generates a for...in loop, declaring its variable with var
+// before ES2015
+func (g *Generator) generateForIn(s *ast.ForInStatement) string {
+	head, prologue := g.generateLoopHead(s.Kind, s.Target)
+	return fmt.Sprintf("for (%s in %s)", head, g.generateJSExpression(s.Right)) + g.generateLoopBody(prologue, s.Body)
+}
+
+// generateLoopHead generates the variable of a loop kept as a loop. A
+// pattern the target lacks is replaced by a name, destructured by the
+// statements returned.
+func (g *Generator) generateLoopHead(kind string, target ast.Expression) (string, string) {
+	keyword := kind
+	if keyword != "" && g.downlevel(ES2015) {
+		keyword = "var"
+	}
+
+	head := g.generateBindingName(target)
+	prologue := ""
+	if g.lowerPattern(target) {
+		head = g.newName()
+		prologue = g.bindLoopVariable(kind, target, head, simpleValue)
+	}
+
+	if keyword == "" {
+		return head, prologue
+	}
+	return keyword + " " + head, prologue
+}
+
+// copyName returns the name of the variable holding the value a loop
+// iterates, made from the value itself when it is a name
+func (g *Generator) copyName(value ast.Expression) string {
+	if ident, ok := value.(*ast.Identifier); ok {
+		return g.uniqueName(ident.Value)
+	}
+	return g.newName()
+}
+
+// bindLoopVariable returns the statement that gives the loop variable, or
+// the names of its pattern, the value of the current iteration
+func (g *Generator) bindLoopVariable(kind string, target ast.Expression, value string, vk valueKind) string {
+	keyword := kind
+	if g.downlevel(ES2015) {
+		keyword = "var"
+	}
+
+	f := &flattener{g: g, declare: kind != ""}
+	f.bind(target, value, vk)
+
+	if kind == "" {
+		return strings.Join(f.assignments, ", ") + ";\n"
+	}
+	return keyword + " " + strings.Join(f.assignments, ", ") + ";\n"
+}
+
+// generateLoopBody generates the body of a loop, starting with the
+// statements of prologue. A body that is not a block goes on its own line.
+func (g *Generator) generateLoopBody(prologue string, body ast.Statement) string {
+	block, isBlock := body.(*ast.BlockStatement)
+	if prologue == "" && !isBlock {
+		return "\n" + indent(g.generateJSStatement(body))
+	}
+
+	var out bytes.Buffer
+	out.WriteString(prologue)
+	if isBlock {
+		g.writeStatements(&out, block.Statements)
+		g.writeLeadingComments(&out, block.EndComments)
+	} else {
+		g.writeStatements(&out, []ast.Statement{body})
+	}
+	return " " + indentBlock(out.String())
+}
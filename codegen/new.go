@@ -0,0 +1,57 @@
+package codegen
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dmarro89/ts-go-compiler/ast"
+)
+
+// generateNew generates new C(args). Before ES2015 spread arguments are
+// passed by binding the constructor to them, as the TypeScript compiler
+// does: new (C.bind.apply(C, __spreadArray([void 0], args, false)))().
+func (g *Generator) generateNew(e *ast.NewExpression) string {
+	callee := g.operand(e.Callee)
+	if e.Arguments == nil {
+		return "new " + callee
+	}
+
+	if g.downlevel(ES2015) && hasSpread(e.Arguments) {
+		constructor := callee
+		if !isSimple(e.Callee) {
+			constructor = g.newTemp()
+			callee = "(" + constructor + " = " + g.generateJSExpression(e.Callee) + ")"
+		}
+		// The constructor is bound to undefined as this, then to the arguments
+		args := append([]ast.Expression{&ast.Identifier{Value: "void 0"}}, e.Arguments...)
+		return fmt.Sprintf("new (%s.bind.apply(%s, %s))()", callee, constructor, g.generateSpreadList(args, false))
+	}
+
+	args := make([]string, len(e.Arguments))
+	for i, arg := range e.Arguments {
+		args[i] = g.generateJSExpression(arg)
+	}
+	return "new " + callee + "(" + strings.Join(args, ", ") + ")"
+}
+
+// newTargetPrologue returns the statement that declares _newTarget for a
+// function using new.target before ES2015: the constructor of this when
+// the function was called with new, or undefined
+func newTargetPrologue(name string) string {
+	return fmt.Sprintf("var _newTarget = this && this instanceof %s ? this.constructor : void 0;", name)
+}
+
+// usesNewTarget reports whether a function body reads new.target
+func usesNewTarget(body *ast.BlockStatement) bool {
+	found := false
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch n.(type) {
+		case *ast.MetaProperty:
+			found = true
+		case *ast.FunctionLiteral:
+			return false
+		}
+		return !found
+	})
+	return found
+}
@@ -0,0 +1,65 @@
+package codegen
+
+import "github.com/dmarro89/ts-go-compiler/ast"
+
+// lowerAssignment generates the assignment operators newer than the target:
+// x **= y before ES2016 as x = Math.pow(x, y), and the logical assignments
+// before ES2021 as the logical operator assigning only when it evaluates
+// its right side, e.g. x ||= y as x || (x = y). The object and key of a
+// property target are evaluated once.
+func (g *Generator) lowerAssignment(e *ast.AssignmentExpression) (string, bool) {
+	switch {
+	case e.Operator == "**=" && g.downlevel(ES2016):
+		first, again := g.reference(e.Target)
+		return first + " = Math.pow(" + again + ", " + g.generateJSExpression(e.Value) + ")", true
+	case (e.Operator == "&&=" || e.Operator == "||=" || e.Operator == "??=") && g.downlevel(ES2021):
+	default:
+		return "", false
+	}
+
+	first, again := g.reference(e.Target)
+	assign := "(" + again + " = " + g.generateJSExpression(e.Value) + ")"
+	switch {
+	case e.Operator != "??=":
+		return first + " " + e.Operator[:2] + " " + assign, true
+	case !g.downlevel(ES2020):
+		return first + " ?? " + assign, true
+	}
+
+	ref, check := first, first
+	if _, ok := ast.SkipParentheses(e.Target).(*ast.Identifier); !ok {
+		ref = g.newTemp()
+		check = "(" + ref + " = " + first + ")"
+	}
+	return check + " !== null && " + ref + " !== void 0 ? " + ref + " : " + assign, true
+}
+
+// reference generates a target that is both read and assigned, returning
+// the code of its first use and of the uses after it. The object and key
+// of a property are stored in temporaries by the first one, unless they
+// are names or constants.
+func (g *Generator) reference(target ast.Expression) (first string, again string) {
+	switch t := ast.SkipParentheses(target).(type) {
+	case *ast.MethodCallExpression:
+		object, objectAgain := g.referencePart(t.Object)
+		return object + "." + t.Method.Value, objectAgain + "." + t.Method.Value
+	case *ast.IndexExpression:
+		object, objectAgain := g.referencePart(t.Left)
+		index, indexAgain := g.referencePart(t.Index)
+		return object + "[" + index + "]", objectAgain + "[" + indexAgain + "]"
+	}
+	code := g.generateJSExpression(target)
+	return code, code
+}
+
+// referencePart generates the object or key of a property target, stored in
+// a temporary unless evaluating it again gives the same value
+func (g *Generator) referencePart(expr ast.Expression) (first string, again string) {
+	switch expr.(type) {
+	case *ast.Identifier, *ast.IntegerLiteral, *ast.StringLiteral, *ast.ThisExpression:
+		code := g.operand(expr)
+		return code, code
+	}
+	temp := g.newTemp()
+	return "(" + temp + " = " + g.generateJSExpression(expr) + ")", temp
+}
@@ -0,0 +1,269 @@
+package codegen
+
+import (
+	"fmt"
+
+	"github.com/dmarro89/ts-go-compiler/ast"
+)
+
+// expression rewrites an expression of a state machine body so it can be
+// generated once the awaits and yields in it have run. Each of them
+// becomes an instruction that suspends the function, after which the
+// expression reads the value the function resumed with. The operands evaluated before an await are stored in
+// temporaries first, so they keep their order; operands that only run
+// depending on a condition get cases of their own.
+func (m *stateMachine) expression(expr ast.Expression) ast.Expression {
+	if expr == nil || !suspends(expr) {
+		return expr
+	}
+
+	switch e := expr.(type) {
+	case *ast.AwaitExpression:
+		return m.emitAwait(m.code(e.Argument))
+	case *ast.YieldExpression:
+		return m.yieldExpression(e)
+	case *ast.ParenthesizedExpression:
+		return &ast.ParenthesizedExpression{Token: e.Token, Expression: m.expression(e.Expression)}
+	case *ast.PrefixExpression:
+		return &ast.PrefixExpression{Token: e.Token, Operator: e.Operator, Right: m.expression(e.Right)}
+	case *ast.PostfixExpression:
+		return &ast.PostfixExpression{Token: e.Token, Operator: e.Operator, Left: m.expression(e.Left)}
+	case *ast.NonNullExpression:
+		return m.expression(e.Expression)
+	case *ast.InfixExpression:
+		switch e.Operator {
+		case "&&", "||", "??":
+			if suspends(e.Right) {
+				return m.shortCircuit(e.Operator, m.spill(m.expression(e.Left)), e.Right)
+			}
+		case ",":
+			m.emit(m.code(e.Left) + ";")
+			return m.expression(e.Right)
+		}
+		operands := m.operands(e.Left, e.Right)
+		return &ast.InfixExpression{Token: e.Token, Operator: e.Operator, Left: operands[0], Right: operands[1]}
+	case *ast.ConditionalExpression:
+		if suspends(e.Consequence) || suspends(e.Alternative) {
+			return m.conditional(e)
+		}
+		return &ast.ConditionalExpression{Token: e.Token, Condition: m.expression(e.Condition), Consequence: e.Consequence, Alternative: e.Alternative}
+	case *ast.AssignmentExpression:
+		return m.assignmentExpression(e)
+	case *ast.CallExpression:
+		call := *e
+		if _, ok := e.Function.(*ast.Identifier); ok {
+			// A called name is not stored, which would lose the this of
+			// console.log
+			call.Arguments = m.operands(e.Arguments...)
+			return &call
+		}
+		operands := m.operands(append([]ast.Expression{e.Function}, e.Arguments...)...)
+		call.Function, call.Arguments = operands[0], operands[1:]
+		return &call
+	case *ast.MethodCallExpression:
+		call := *e
+		if e.Arguments == nil {
+			call.Object = m.expression(e.Object)
+			return &call
+		}
+		operands := m.operands(append([]ast.Expression{e.Object}, e.Arguments...)...)
+		call.Object, call.Arguments = operands[0], operands[1:]
+		return &call
+	case *ast.IndexExpression:
+		index := *e
+		operands := m.operands(e.Left, e.Index)
+		index.Left, index.Index = operands[0], operands[1]
+		return &index
+	case *ast.ArrayLiteral:
+		return &ast.ArrayLiteral{Token: e.Token, Elements: m.operands(e.Elements...)}
+	case *ast.ObjectLiteral:
+		values := make([]ast.Expression, len(e.Properties))
+		for i, p := range e.Properties {
+			values[i] = p.Value
+		}
+		values = m.operands(values...)
+
+		object := &ast.ObjectLiteral{Token: e.Token}
+		for i, p := range e.Properties {
+			prop := *p
+			prop.Value = values[i]
+			// { a } stays shorthand only while a is not replaced
+			prop.Shorthand = p.Shorthand && values[i] == p.Value
+			object.Properties = append(object.Properties, &prop)
+		}
+		return object
+	case *ast.SpreadElement:
+		return &ast.SpreadElement{Token: e.Token, Argument: m.expression(e.Argument)}
+	}
+	return expr
+}
+
+// yieldExpression suspends a generator with the value of yield, or with
+// each value of the iterable of yield*. An async generator awaits the
+// value before yielding it, and awaits the return value of yield*.
+func (m *stateMachine) yieldExpression(e *ast.YieldExpression) ast.Expression {
+	g := m.g
+	value := m.code(e.Argument)
+
+	if g.await != "__await" {
+		if e.Delegate {
+			return m.emitDelegate(g.useHelper("__values") + "(" + value + ")")
+		}
+		return m.emitYield(value)
+	}
+
+	if e.Delegate {
+		delegator := fmt.Sprintf("%s(%s(%s(%s)))", g.useHelper("__values"), g.useHelper("__asyncDelegator"), g.useHelper("__asyncValues"), value)
+		return m.emitAwait(g.generateJSExpression(m.emitDelegate(delegator)))
+	}
+	if value == "" {
+		value = "void 0"
+	}
+	return m.emitYield(g.generateJSExpression(m.emitAwait(value)))
+}
+
+// operands rewrites expressions evaluated from left to right, storing the
+// ones before the last await in temporaries
+func (m *stateMachine) operands(exprs ...ast.Expression) []ast.Expression {
+	last := -1
+	for i, expr := range exprs {
+		if expr != nil && suspends(expr) {
+			last = i
+		}
+	}
+
+	result := make([]ast.Expression, len(exprs))
+	for i, expr := range exprs {
+		switch {
+		case i < last:
+			result[i] = m.spill(m.expression(expr))
+		case i == last:
+			result[i] = m.expression(expr)
+		default:
+			result[i] = expr
+		}
+	}
+	return result
+}
+
+// spill stores the value of expr in a temporary, unless it is a constant
+func (m *stateMachine) spill(expr ast.Expression) ast.Expression {
+	switch e := expr.(type) {
+	case nil, *ast.IntegerLiteral, *ast.StringLiteral, *ast.Boolean, *ast.NullLiteral:
+		return expr
+	case *ast.SpreadElement:
+		return &ast.SpreadElement{Token: e.Token, Argument: m.spill(e.Argument)}
+	}
+
+	temp := m.g.newTemp()
+	m.emit(temp + " = " + m.g.generateJSExpression(expr) + ";")
+	return &ast.Identifier{Value: temp}
+}
+
+// shortCircuit evaluates the right operand of &&, || or ?? only when the
+// left one, already stored in a temporary, does not decide the result
+func (m *stateMachine) shortCircuit(operator string, left ast.Expression, right ast.Expression) ast.Expression {
+	temp := m.g.generateJSExpression(left)
+	if _, ok := left.(*ast.Identifier); !ok {
+		temp = m.g.newTemp()
+		m.emit(temp + " = " + m.g.generateJSExpression(left) + ";")
+	}
+
+	end := m.newLabel()
+	switch operator {
+	case "&&", "&&=":
+		m.emitBreakWhen("!"+temp, end)
+	case "||", "||=":
+		m.emitBreakWhen(temp, end)
+	default:
+		m.emitBreakWhen(temp+" !== null && "+temp+" !== void 0", end)
+	}
+	m.emit(temp + " = " + m.code(right) + ";")
+	m.markLabel(end)
+
+	return &ast.Identifier{Value: temp}
+}
+
+// conditional evaluates the branch the condition selects in a case of its
+// own, storing its value in a temporary
+func (m *stateMachine) conditional(e *ast.ConditionalExpression) ast.Expression {
+	temp := m.g.newTemp()
+	whenFalse, end := m.newLabel(), m.newLabel()
+
+	m.emitBreakWhen("!("+m.code(e.Condition)+")", whenFalse)
+	m.emit(temp + " = " + m.code(e.Consequence) + ";")
+	m.emitBreak(end)
+
+	m.markLabel(whenFalse)
+	m.emit(temp + " = " + m.code(e.Alternative) + ";")
+	m.markLabel(end)
+
+	return &ast.Identifier{Value: temp}
+}
+
+// assignmentExpression evaluates the object and key of a property target
+// before the value awaits. A compound assignment also reads the target
+// before then, and a logical assignment only awaits its value when it
+// assigns it, as a.b ??= await f() is a.b ?? (a.b = await f()).
+func (m *stateMachine) assignmentExpression(e *ast.AssignmentExpression) ast.Expression {
+	if !suspends(e.Value) {
+		assign := *e
+		assign.Target = m.expression(e.Target)
+		return &assign
+	}
+
+	switch e.Operator {
+	case "=":
+	case "&&=", "||=", "??=":
+		if ident, ok := e.Target.(*ast.Identifier); ok {
+			// The name itself holds the result
+			return m.shortCircuit(e.Operator, ident, e.Value)
+		}
+		target := m.reference(e.Target)
+		assign := &ast.AssignmentExpression{Token: e.Token, Target: target, Operator: "=", Value: e.Value}
+		return m.shortCircuit(e.Operator, target, assign)
+	default:
+		// x += await f() adds to the value x had before the await
+		target := m.reference(e.Target)
+		old := m.spill(target)
+		value := &ast.ParenthesizedExpression{Expression: m.expression(e.Value)}
+		operator := e.Operator[:len(e.Operator)-1]
+		return &ast.AssignmentExpression{Token: e.Token, Target: target, Operator: "=",
+			Value: &ast.InfixExpression{Token: e.Token, Operator: operator, Left: old, Right: value}}
+	}
+
+	assign := *e
+	switch t := e.Target.(type) {
+	case *ast.MethodCallExpression:
+		target := *t
+		operands := m.operands(t.Object, e.Value)
+		target.Object, assign.Value = operands[0], operands[1]
+		assign.Target = &target
+	case *ast.IndexExpression:
+		target := *t
+		operands := m.operands(t.Left, t.Index, e.Value)
+		target.Left, target.Index, assign.Value = operands[0], operands[1], operands[2]
+		assign.Target = &target
+	default:
+		assign.Value = m.expression(e.Value)
+	}
+	return &assign
+}
+
+// reference returns the target of an assignment whose value awaits, with
+// the object and key of a property stored in temporaries first, so it can
+// be read before the await and assigned after it
+func (m *stateMachine) reference(target ast.Expression) ast.Expression {
+	switch t := target.(type) {
+	case *ast.MethodCallExpression:
+		ref := *t
+		ref.Object = m.spill(m.expression(t.Object))
+		return &ref
+	case *ast.IndexExpression:
+		ref := *t
+		ref.Left = m.spill(m.expression(t.Left))
+		ref.Index = m.spill(m.expression(t.Index))
+		return &ref
+	}
+	return target
+}
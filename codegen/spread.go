@@ -0,0 +1,200 @@
+package codegen
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dmarro89/ts-go-compiler/ast"
+)
+
+// hasSpread reports whether a list of elements or arguments spreads one
+func hasSpread(exprs []ast.Expression) bool {
+	for _, expr := range exprs {
+		if _, ok := expr.(*ast.SpreadElement); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// generateArrayLiteral generates [a, ...b]. Before ES2015 the spread
+// elements are concatenated with __spreadArray.
+func (g *Generator) generateArrayLiteral(e *ast.ArrayLiteral) string {
+	if g.downlevel(ES2015) && hasSpread(e.Elements) {
+		return g.generateSpreadList(e.Elements, true)
+	}
+
+	elements := make([]string, len(e.Elements))
+	for i, el := range e.Elements {
+		if el != nil {
+			elements[i] = g.generateJSExpression(el)
+		}
+	}
+	out := "[" + strings.Join(elements, ", ")
+	if n := len(e.Elements); n > 0 && e.Elements[n-1] == nil {
+		out += ","
+	}
+	return out + "]"
+}
+
+// generateSpreadList generates a list with spread elements as an array,
+// e.g. [1, ...a] as __spreadArray([1], a, true). Array literals pack their
+// spread elements, which turns holes into undefined as spreading does.
+// A list that is one spread element, as in f(...args), is that element.
+func (g *Generator) generateSpreadList(exprs []ast.Expression, pack bool) string {
+	var segments []string
+	var spread []bool
+
+	var literal []string
+	flush := func() {
+		if literal != nil {
+			segments = append(segments, "["+strings.Join(literal, ", ")+"]")
+			spread = append(spread, false)
+			literal = nil
+		}
+	}
+	for _, expr := range exprs {
+		if s, ok := expr.(*ast.SpreadElement); ok {
+			flush()
+			segments = append(segments, g.generateJSExpression(s.Argument))
+			spread = append(spread, true)
+			continue
+		}
+		literal = append(literal, g.generateJSExpression(expr))
+	}
+	flush()
+
+	if !pack && len(segments) == 1 && spread[0] {
+		return segments[0]
+	}
+
+	out := "[]"
+	for i, segment := range segments {
+		if i == 0 && !spread[0] {
+			out = segment
+			continue
+		}
+		out = fmt.Sprintf("%s(%s, %s, %t)", g.useHelper("__spreadArray"), out, segment, pack && spread[i])
+	}
+	return out
+}
+
+// methodKeywords map the keywords a function starts with to the modifiers
+// of a method, longest first
+var methodKeywords = []struct{ keyword, modifiers string }{
+	{"async function*", "async *"},
+	{"async function", "async "},
+	{"function*", "*"},
+	{"function", ""},
+}
+
+// generateMethod generates a method of an object literal, e.g. async m() {}.
+// Before ES2015 it becomes a property whose value is the function. A method
+// whose function is rewritten keeps the modifiers of the rewritten
+// function, e.g. none for an async method below ES2017.
+func (g *Generator) generateMethod(p *ast.ObjectProperty) string {
+	name := g.generatePropertyName(p.Key)
+	fn := g.generateJSExpression(p.Value)
+	if g.downlevel(ES2015) {
+		return name + ": " + fn
+	}
+	for _, m := range methodKeywords {
+		if rest, ok := strings.CutPrefix(fn, m.keyword); ok {
+			return m.modifiers + name + rest
+		}
+	}
+	return name + ": " + fn
+}
+
+// generateObjectLiteral generates { a, ...b }. Before ES2018 object spread
+// becomes calls to Object.assign, or its __assign helper before ES2015, one
+// per spread or run of properties.
+func (g *Generator) generateObjectLiteral(e *ast.ObjectLiteral) string {
+	if len(e.Properties) == 0 {
+		return "{}"
+	}
+
+	props := make([]string, len(e.Properties))
+	spreads := false
+	for i, p := range e.Properties {
+		switch {
+		case p.Key == nil:
+			spreads = true
+			props[i] = g.generateJSExpression(p.Value)
+		case p.Shorthand:
+			props[i] = g.generateJSExpression(p.Value)
+		case p.Method:
+			props[i] = g.generateMethod(p)
+		default:
+			props[i] = g.generatePropertyName(p.Key) + ": " + g.generateJSExpression(p.Value)
+		}
+		props[i] = g.inlineComments(p.LeadingComments) + props[i]
+	}
+	if !spreads || !g.downlevel(ES2018) {
+		return "{ " + strings.Join(props, ", ") + " }"
+	}
+
+	var segments []string
+	var run []string
+	flush := func() {
+		if run != nil {
+			segments = append(segments, "{ "+strings.Join(run, ", ")+" }")
+			run = nil
+		}
+	}
+	for i, p := range e.Properties {
+		if spread, ok := p.Value.(*ast.SpreadElement); ok && p.Key == nil {
+			flush()
+			segments = append(segments, g.generateJSExpression(spread.Argument))
+			continue
+		}
+		run = append(run, props[i])
+	}
+	flush()
+
+	// Object.assign exists from ES2015 on
+	assign := "Object.assign"
+	if g.downlevel(ES2015) {
+		assign = g.useHelper("__assign")
+	}
+
+	out := "{}"
+	for i, segment := range segments {
+		if i == 0 && e.Properties[0].Key != nil {
+			out = segment
+			continue
+		}
+		out = fmt.Sprintf("%s(%s, %s)", assign, out, segment)
+	}
+	return out
+}
+
+// generateRestParameter generates the statements that collect the
+// arguments from index on into a rest parameter, for targets before ES2015
+func (g *Generator) generateRestParameter(param *ast.BindingElement, index int) []string {
+	name, pattern := "", false
+	if ident, ok := param.Name.(*ast.Identifier); ok {
+		name = ident.Value
+	} else {
+		name, pattern = g.newName(), true
+	}
+
+	i := g.newLoopVar()
+	element := i
+	if index > 0 {
+		element = fmt.Sprintf("%s - %d", i, index)
+	}
+	lines := []string{
+		"var " + name + " = [];",
+		fmt.Sprintf("for (var %s = %d; %s < arguments.length; %s++) {", i, index, i, i),
+		fmt.Sprintf("    %s[%s] = arguments[%s];", name, element, i),
+		"}",
+	}
+
+	if pattern {
+		f := &flattener{g: g, declare: true}
+		f.bind(param.Name, name, simpleValue)
+		lines = append(lines, "var "+strings.Join(f.assignments, ", ")+";")
+	}
+	return lines
+}
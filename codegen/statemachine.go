@@ -0,0 +1,763 @@
+package codegen
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/dmarro89/ts-go-compiler/ast"
+)
+
+// stateMachine turns the body of a generator or async function into the
+// body of the generator the __generator helper runs for ES5, which has no
+// generators.
+// The statements are split into the numbered cases of a switch on the
+// label of the state, _a.label. A case ends where the function suspends or
+// jumps, returning an instruction to __generator:
+//
+//	[2, value]  return value
+//	[3, label]  continue at the case label
+//	[4, value]  yield value, continuing at the next case, where _a.sent()
+//	            is the value sent back
+//	[5, value]  yield each value of the iterator value, then continue with
+//	            its return value as _a.sent()
+//	[7]         end a finally block
+//
+// The body runs again for each step, so its variables are declared in the
+// enclosing function.
+type stateMachine struct {
+	g     *Generator
+	state string // the parameter holding the state
+
+	cases  [][]string // the statements of each case
+	labels []int      // the case each label starts, -1 until it is marked
+	abrupt bool       // whether the current case ends with a return or throw
+
+	// jumps are the statements break and continue may leave, innermost
+	// last, and pendingLabels the labels of the loop or switch statement
+	// about to be added
+	jumps         []jumpTarget
+	pendingLabels []string
+
+	hoisted   map[string]bool
+	functions []string // the function declarations, hoisted with the variables
+}
+
+// jumpTarget is where break and continue go in a loop, switch or labeled
+// statement
+type jumpTarget struct {
+	breakLabel    int
+	continueLabel int      // -1 for switch and labeled statements
+	labels        []string // the labels of the statement
+	labeledOnly   bool     // whether only a break with a label may leave it
+}
+
+// labelPattern matches the references to labels, which are resolved once
+// every label is marked
+var labelPattern = regexp.MustCompile("\x00([0-9]+)\x00")
+
+// generateStateMachineFunction generates the body of the function a
+// helper runs for an async function or async generator, when the target is
+// ES5
+func (g *Generator) generateStateMachineFunction(body *ast.BlockStatement) string {
+	g.pushTemps()
+	machine := g.generateStateMachine(body)
+	return indentBlock(g.popTemps() + machine)
+}
+
+// generateStateMachine generates the statements that return the generator
+// a function body becomes for ES5. The variables of the body are declared
+// in the current function.
+func (g *Generator) generateStateMachine(body *ast.BlockStatement) string {
+	m := &stateMachine{g: g, state: g.newName(), cases: [][]string{nil}, hoisted: map[string]bool{}}
+	g.machine = m
+	m.statements(body.Statements)
+	for _, c := range g.keptComments(body.EndComments) {
+		m.emit(c.Text)
+	}
+	if !m.abrupt {
+		m.emit(m.returnInstruction(""))
+	}
+	g.machine = nil
+
+	var out bytes.Buffer
+	for _, fn := range m.functions {
+		out.WriteString(fn + "\n")
+	}
+	out.WriteString(fmt.Sprintf("return %s(this, function (%s) %s);\n", g.useHelper("__generator"), m.state, indentBlock(m.render())))
+
+	return out.String()
+}
+
+// render writes the cases of the machine, or the statements of the only
+// case when the function never suspends
+func (m *stateMachine) render() string {
+	var out bytes.Buffer
+	if len(m.cases) == 1 {
+		for _, line := range m.cases[0] {
+			out.WriteString(line + "\n")
+		}
+	} else {
+		var cases bytes.Buffer
+		for i, lines := range m.cases {
+			// A case of one statement keeps it on the same line
+			if len(lines) == 1 && !strings.Contains(lines[0], "\n") {
+				cases.WriteString(fmt.Sprintf("case %d: %s\n", i, lines[0]))
+				continue
+			}
+			cases.WriteString(fmt.Sprintf("case %d:\n", i))
+			var body bytes.Buffer
+			for _, line := range lines {
+				body.WriteString(line + "\n")
+			}
+			cases.WriteString(indent(body.String()))
+		}
+		out.WriteString("switch (" + m.state + ".label) " + indentBlock(cases.String()) + "\n")
+	}
+
+	return labelPattern.ReplaceAllStringFunc(out.String(), func(ref string) string {
+		label, _ := strconv.Atoi(strings.Trim(ref, "\x00"))
+		return strconv.Itoa(m.labels[label])
+	})
+}
+
+// newLabel returns a label to be marked later
+func (m *stateMachine) newLabel() int {
+	m.labels = append(m.labels, -1)
+	return len(m.labels) - 1
+}
+
+// markLabel makes label refer to the current position, starting a new case
+// unless the current one is still empty. A case that does not end abruptly
+// falls through into the new one, which sets the label of the state first.
+func (m *stateMachine) markLabel(label int) {
+	current := len(m.cases) - 1
+	if len(m.cases[current]) == 0 {
+		m.labels[label] = current
+		return
+	}
+
+	if !m.abrupt {
+		m.emit(fmt.Sprintf("%s.label = %d;", m.state, current+1))
+	}
+	m.cases = append(m.cases, nil)
+	m.labels[label] = current + 1
+	m.abrupt = false
+}
+
+// labelRef returns a reference to label, resolved when the machine is
+// rendered
+func labelRef(label int) string {
+	return fmt.Sprintf("\x00%d\x00", label)
+}
+
+// emit adds a statement to the current case. Statements after a return or
+// throw are never run and are left out.
+func (m *stateMachine) emit(stmt string) {
+	if m.abrupt {
+		return
+	}
+	current := len(m.cases) - 1
+	m.cases[current] = append(m.cases[current], stmt)
+}
+
+// emitAbrupt adds a statement that ends the current case
+func (m *stateMachine) emitAbrupt(stmt string) {
+	m.emit(stmt)
+	m.abrupt = true
+}
+
+// returnInstruction returns the statement that returns value, or returns
+// nothing when value is empty
+func (m *stateMachine) returnInstruction(value string) string {
+	if value == "" {
+		return "return [2 /*return*/];"
+	}
+	return "return [2 /*return*/, " + value + "];"
+}
+
+func (m *stateMachine) emitBreak(label int) {
+	m.emitAbrupt("return [3 /*break*/, " + labelRef(label) + "];")
+}
+
+func (m *stateMachine) emitBreakWhen(condition string, label int) {
+	m.emit("if (" + condition + ") return [3 /*break*/, " + labelRef(label) + "];")
+}
+
+// emitYield suspends the function with value, or undefined when value is
+// empty, returning the expression that reads the value it resumes with
+func (m *stateMachine) emitYield(value string) ast.Expression {
+	if value == "" {
+		m.emitAbrupt("return [4 /*yield*/];")
+	} else {
+		m.emitAbrupt("return [4 /*yield*/, " + value + "];")
+	}
+	m.markLabel(m.newLabel())
+	return m.sent()
+}
+
+// emitDelegate yields the values of the iterator value, returning the
+// expression that reads its return value
+func (m *stateMachine) emitDelegate(value string) ast.Expression {
+	m.emitAbrupt("return [5 /*yield**/, " + value + "];")
+	m.markLabel(m.newLabel())
+	return m.sent()
+}
+
+// emitAwait suspends the function until value settles, returning the
+// expression that reads its result. An async generator yields the value
+// wrapped by __await, which tells the values it awaits from the ones it
+// yields.
+func (m *stateMachine) emitAwait(value string) ast.Expression {
+	if m.g.await == "__await" {
+		value = m.g.useHelper("__await") + "(" + value + ")"
+	}
+	return m.emitYield(value)
+}
+
+// sent is the value the function resumed with
+func (m *stateMachine) sent() ast.Expression {
+	return &ast.Identifier{Value: m.state + ".sent()"}
+}
+
+// hoist declares the names bound by a declaration in the enclosing function
+func (m *stateMachine) hoist(target ast.Expression) {
+	for _, name := range bindingNames(target) {
+		if !m.hoisted[name] {
+			m.hoisted[name] = true
+			m.g.declareTemp(name)
+		}
+	}
+}
+
+// declaration returns the assignment that replaces a declaration, whose
+// names are hoisted
+func (m *stateMachine) declaration(target ast.Expression, value ast.Expression) string {
+	m.hoist(target)
+	return m.assignment(target, m.g.generateJSExpression(value), valueKindOf(value))
+}
+
+// assignment returns the statement assigning value to a name, property or
+// pattern, flattening patterns
+func (m *stateMachine) assignment(target ast.Expression, value string, kind valueKind) string {
+	f := &flattener{g: m.g}
+	f.bind(target, value, kind)
+	return strings.Join(f.assignments, ", ") + ";"
+}
+
+// bind assigns value to the variable of a loop or catch clause, declared
+// with kind, or an existing variable when kind is empty
+func (m *stateMachine) bind(kind string, target ast.Expression, value string) {
+	if kind != "" {
+		m.hoist(target)
+	}
+	m.emit(m.assignment(target, value, simpleValue))
+}
+
+func (m *stateMachine) statements(statements []ast.Statement) {
+	for _, stmt := range statements {
+		m.statement(stmt)
+	}
+}
+
+// statement adds a statement of the body. Statements that neither await
+// nor jump out of themselves are generated as they are, apart from their
+// declarations and returns.
+func (m *stateMachine) statement(stmt ast.Statement) {
+	if stmt == nil {
+		return
+	}
+	g := m.g
+
+	if es, ok := stmt.(*ast.ExpressionStatement); ok {
+		if fn, ok := es.Expression.(*ast.FunctionLiteral); ok && fn.Name != nil {
+			m.functions = append(m.functions, g.generateJSStatement(stmt))
+			return
+		}
+	}
+
+	if c, ok := stmt.(ast.Commented); ok {
+		for _, comment := range g.keptComments(c.Comments().LeadingComments) {
+			m.emit(comment.Text)
+		}
+	}
+
+	switch s := stmt.(type) {
+	case *ast.ReturnStatement:
+		value := m.code(s.ReturnValue)
+		if g.await == "__await" {
+			// An async generator awaits the value it returns
+			if value == "" {
+				value = "void 0"
+			}
+			value = g.generateJSExpression(m.emitAwait(value))
+		}
+		m.emitAbrupt(m.returnInstruction(value))
+		return
+	case *ast.ThrowStatement:
+		m.emitAbrupt("throw " + m.code(s.Argument) + ";")
+		return
+	case *ast.LetStatement:
+		if s.Value == nil {
+			// The variable is only declared, in the enclosing function
+			m.hoist(s.Target())
+			return
+		}
+	case *ast.BlockStatement:
+		// The declarations of a block are hoisted, so it has no scope left
+		// and its statements join the case, which they may end
+		m.statements(s.Statements)
+		for _, c := range g.keptComments(s.EndComments) {
+			m.emit(c.Text)
+		}
+		return
+	}
+
+	// The returns of an async generator await their value
+	if !suspends(stmt) && !hasEscapingJump(stmt) && !(g.await == "__await" && hasReturn(stmt)) {
+		m.emit(g.generateJSStatement(stmt))
+		return
+	}
+
+	switch s := stmt.(type) {
+	case *ast.ExpressionStatement:
+		expr := m.expression(s.Expression)
+		if assign, ok := expr.(*ast.AssignmentExpression); ok && g.lowerPattern(assign.Target) {
+			m.emit(g.generateDestructuringAssignment(assign, false) + ";")
+			return
+		}
+		m.emit(g.generateJSExpression(expr) + ";")
+	case *ast.LetStatement:
+		m.emit(m.declaration(s.Target(), m.expression(s.Value)))
+	case *ast.BlockStatement:
+		m.statements(s.Statements)
+	case *ast.BreakStatement:
+		m.emitBreak(m.jump(s.Label, false).breakLabel)
+	case *ast.ContinueStatement:
+		m.emitBreak(m.jump(s.Label, true).continueLabel)
+	case *ast.LabeledStatement:
+		m.labeledStatement(s)
+	case *ast.IfStatement:
+		m.ifStatement(s)
+	case *ast.SwitchStatement:
+		m.switchStatement(s)
+	case *ast.TryStatement:
+		m.tryStatement(s)
+	case *ast.ForOfStatement:
+		switch {
+		case s.Await:
+			m.forAwait(s)
+		case g.options.DownlevelIteration:
+			m.forOfIterator(s)
+		default:
+			m.forOf(s)
+		}
+	case *ast.ForInStatement:
+		m.forIn(s)
+	}
+}
+
+// code generates an expression that may await
+func (m *stateMachine) code(expr ast.Expression) string {
+	return m.g.generateJSExpression(m.expression(expr))
+}
+
+// loopBody adds the body of a loop, where break goes to end and continue
+// to next
+func (m *stateMachine) loopBody(body ast.Statement, end int, next int) {
+	m.jumps = append(m.jumps, jumpTarget{breakLabel: end, continueLabel: next, labels: m.takeLabels()})
+	m.statement(body)
+	m.jumps = m.jumps[:len(m.jumps)-1]
+}
+
+// jump returns the statement a break or continue with the label leaves, or
+// the innermost one it may leave without a label
+func (m *stateMachine) jump(label *ast.Identifier, isContinue bool) jumpTarget {
+	for i := len(m.jumps) - 1; i >= 0; i-- {
+		target := m.jumps[i]
+		switch {
+		case label != nil:
+			for _, name := range target.labels {
+				if name == label.Value {
+					return target
+				}
+			}
+		case isContinue:
+			if target.continueLabel >= 0 {
+				return target
+			}
+		case !target.labeledOnly:
+			return target
+		}
+	}
+	panic("jump without a target")
+}
+
+// labeledStatement adds a labeled statement. The labels of a loop or switch
+// statement are given to it, while any other statement gets a label of its
+// own after it, which a break with the label goes to.
+func (m *stateMachine) labeledStatement(s *ast.LabeledStatement) {
+	m.pendingLabels = append(m.pendingLabels, s.Label.Value)
+	switch s.Body.(type) {
+	case *ast.LabeledStatement, *ast.ForOfStatement, *ast.ForInStatement, *ast.SwitchStatement:
+		m.statement(s.Body)
+		return
+	}
+
+	end := m.newLabel()
+	m.jumps = append(m.jumps, jumpTarget{breakLabel: end, continueLabel: -1, labels: m.takeLabels(), labeledOnly: true})
+	m.statement(s.Body)
+	m.jumps = m.jumps[:len(m.jumps)-1]
+	m.markLabel(end)
+}
+
+// takeLabels returns the labels of the statement being added
+func (m *stateMachine) takeLabels() []string {
+	labels := m.pendingLabels
+	m.pendingLabels = nil
+	return labels
+}
+
+// ifStatement jumps over the consequence when the condition is false, and
+// over the alternative at the end of the consequence
+func (m *stateMachine) ifStatement(s *ast.IfStatement) {
+	condition := m.expression(s.Condition)
+	end := m.newLabel()
+	alternative := end
+	if s.Alternative != nil {
+		alternative = m.newLabel()
+	}
+
+	m.emitBreakWhen(m.g.negate(condition), alternative)
+	m.statement(s.Consequence)
+	if s.Alternative != nil {
+		m.emitBreak(end)
+		m.markLabel(alternative)
+		m.statement(s.Alternative)
+	}
+	m.markLabel(end)
+}
+
+// negate generates the code testing that the condition expr is false
+func (g *Generator) negate(expr ast.Expression) string {
+	code := g.operand(expr)
+	if g.isConditional(expr) {
+		return "!" + code
+	}
+	switch expr.(type) {
+	case *ast.Identifier, *ast.CallExpression, *ast.MethodCallExpression, *ast.IndexExpression, *ast.ParenthesizedExpression:
+		return "!" + code
+	}
+	return "!(" + code + ")"
+}
+
+// switchStatement jumps from a switch on the value to the case of each
+// clause, whose statements follow one another so they fall through
+func (m *stateMachine) switchStatement(s *ast.SwitchStatement) {
+	value := m.g.newTemp()
+	m.emit(value + " = " + m.code(s.Discriminant) + ";")
+
+	end := m.newLabel()
+	defaultLabel := end
+	labels := make([]int, len(s.Cases))
+	var clauses bytes.Buffer
+	for i, c := range s.Cases {
+		labels[i] = m.newLabel()
+		if c.Test == nil {
+			defaultLabel = labels[i]
+			continue
+		}
+		clauses.WriteString("case " + m.code(c.Test) + ": return [3 /*break*/, " + labelRef(labels[i]) + "];\n")
+	}
+	m.emit("switch (" + value + ") " + indentBlock(clauses.String()))
+	m.emitBreak(defaultLabel)
+
+	m.jumps = append(m.jumps, jumpTarget{breakLabel: end, continueLabel: -1, labels: m.takeLabels()})
+	for i, c := range s.Cases {
+		m.markLabel(labels[i])
+		m.statements(c.Consequent)
+	}
+	m.jumps = m.jumps[:len(m.jumps)-1]
+
+	m.markLabel(end)
+}
+
+// tryStatement registers the labels of the catch clause and finally block
+// with __generator, which jumps to them when the block throws or is left
+func (m *stateMachine) tryStatement(s *ast.TryStatement) {
+	var handler, finalizer func()
+	if h := s.Handler; h != nil {
+		handler = func() {
+			if h.Param == nil {
+				m.emit(m.g.generateJSExpression(m.sent()) + ";")
+			} else {
+				m.bind("let", h.Param, m.g.generateJSExpression(m.sent()))
+			}
+			m.statements(h.Body.Statements)
+		}
+	}
+	if s.Finalizer != nil {
+		finalizer = func() { m.statements(s.Finalizer.Statements) }
+	}
+	m.protect(func() { m.statements(s.Block.Statements) }, handler, finalizer)
+}
+
+// protect adds block, run with handler as its catch clause and finalizer
+// as its finally block, either of which may be nil
+func (m *stateMachine) protect(block func(), handler func(), finalizer func()) {
+	start := m.newLabel()
+	m.markLabel(start)
+
+	catchLabel, finallyLabel := "", ""
+	var catchStart, finallyStart int
+	if handler != nil {
+		catchStart = m.newLabel()
+		catchLabel = labelRef(catchStart)
+	}
+	if finalizer != nil {
+		finallyStart = m.newLabel()
+		finallyLabel = labelRef(finallyStart)
+	}
+	end := m.newLabel()
+
+	m.emit(fmt.Sprintf("%s.trys.push([%s, %s, %s, %s]);", m.state, labelRef(start), catchLabel, finallyLabel, labelRef(end)))
+	block()
+	m.emitBreak(end)
+
+	if handler != nil {
+		m.markLabel(catchStart)
+		handler()
+		m.emitBreak(end)
+	}
+	if finalizer != nil {
+		m.markLabel(finallyStart)
+		finalizer()
+		m.emitAbrupt("return [7 /*endfinally*/];")
+	}
+
+	m.markLabel(end)
+}
+
+// forOf loops over the indexes of the value, like for...of loops without
+// DownlevelIteration
+func (m *stateMachine) forOf(s *ast.ForOfStatement) {
+	g := m.g
+	i := g.newLoopVar()
+	array := g.copyName(s.Right)
+	g.declareTemp(i)
+	g.declareTemp(array)
+
+	m.emit(fmt.Sprintf("%s = 0, %s = %s;", i, array, m.code(s.Right)))
+	m.loop(func(_ int, end int) {
+		m.emitBreakWhen(fmt.Sprintf("!(%s < %s.length)", i, array), end)
+		m.bind(s.Kind, s.Target, fmt.Sprintf("%s[%s]", array, i))
+	}, s.Body, i+"++;")
+}
+
+// loop adds a loop: head runs before each iteration and may break to the
+// end or skip to the next iteration, and step runs after each iteration,
+// where continue goes
+func (m *stateMachine) loop(head func(next int, end int), body ast.Statement, step string) {
+	start, next, end := m.newLabel(), m.newLabel(), m.newLabel()
+
+	m.markLabel(start)
+	head(next, end)
+	m.loopBody(body, end, next)
+
+	m.markLabel(next)
+	m.emit(step)
+	m.emitBreak(start)
+
+	m.markLabel(end)
+}
+
+// forOfIterator loops over the iterator __values returns, closing it when
+// the loop ends early, like for...of loops with DownlevelIteration
+func (m *stateMachine) forOfIterator(s *ast.ForOfStatement) {
+	g := m.g
+	errName := g.uniqueName("e")
+	g.declareTemp(errName)
+	g.declareTemp(errName + "_1")
+	returnFn := g.newTemp()
+
+	iterator := g.copyName(s.Right)
+	result := iterator + "_1"
+	if !isSimple(s.Right) {
+		result = g.newName()
+	}
+	g.declareTemp(iterator)
+	g.declareTemp(result)
+
+	m.protect(func() {
+		m.emit(fmt.Sprintf("%s = %s(%s), %s = %s.next();", iterator, g.useHelper("__values"), m.code(s.Right), result, iterator))
+		m.loop(func(_ int, end int) {
+			m.emitBreakWhen(result+".done", end)
+			m.bind(s.Kind, s.Target, result+".value")
+		}, s.Body, fmt.Sprintf("%s = %s.next();", result, iterator))
+	}, m.iteratorError(errName), func() {
+		m.emit("try " + indentBlock(fmt.Sprintf("if (%s && !%s.done && (%s = %s.return)) %s.call(%s);\n",
+			result, result, returnFn, iterator, returnFn, iterator)) + "\n" +
+			fmt.Sprintf("finally { if (%s) throw %s.error; }", errName, errName))
+	})
+}
+
+// iteratorError returns the catch clause of a loop over an iterator, which
+// keeps the error to throw once the iterator is closed
+func (m *stateMachine) iteratorError(errName string) func() {
+	return func() {
+		m.emit(fmt.Sprintf("%s_1 = %s;", errName, m.g.generateJSExpression(m.sent())))
+		m.emit(fmt.Sprintf("%s = { error: %s_1 };", errName, errName))
+	}
+}
+
+// forAwait loops over the async iterator __asyncValues returns, yielding
+// the promise of each result. The iterator is closed when the loop ends
+// early, which awaits the promise its return method gives.
+func (m *stateMachine) forAwait(s *ast.ForOfStatement) {
+	g := m.g
+	pending := g.newTemp() // whether the loop body has not started yet
+	done := g.newTemp()
+	errName := g.uniqueName("e")
+	g.declareTemp(errName)
+	g.declareTemp(errName + "_1")
+	returnFn := g.newTemp()
+	value := g.newTemp()
+
+	iterator := g.copyName(s.Right)
+	result := iterator + "_1"
+	if !isSimple(s.Right) {
+		result = g.newName()
+	}
+	g.declareTemp(iterator)
+	g.declareTemp(result)
+
+	m.protect(func() {
+		m.emit(fmt.Sprintf("%s = true, %s = %s(%s);", pending, iterator, g.useHelper("__asyncValues"), m.code(s.Right)))
+		m.loop(func(_ int, end int) {
+			sent := g.generateJSExpression(m.emitAwait(iterator + ".next()"))
+			m.emitBreakWhen(fmt.Sprintf("!(%s = %s, %s = %s.done, !%s)", result, sent, done, result, done), end)
+			m.emit(fmt.Sprintf("%s = %s.value;", value, result))
+			m.emit(pending + " = false;")
+			m.bind(s.Kind, s.Target, value)
+		}, s.Body, pending+" = true;")
+	}, m.iteratorError(errName), func() {
+		m.protect(func() {
+			skip := m.newLabel()
+			m.emitBreakWhen(fmt.Sprintf("!(!%s && !%s && (%s = %s.return))", pending, done, returnFn, iterator), skip)
+			m.emit(g.generateJSExpression(m.emitAwait(fmt.Sprintf("%s.call(%s)", returnFn, iterator))) + ";")
+			m.markLabel(skip)
+		}, nil, func() {
+			m.emit(fmt.Sprintf("if (%s) throw %s.error;", errName, errName))
+		})
+	})
+}
+
+// forIn collects the keys of the object before looping over them, and
+// skips the keys deleted by the time the loop gets to them
+func (m *stateMachine) forIn(s *ast.ForInStatement) {
+	g := m.g
+	object := g.newTemp()
+	keys := g.newTemp()
+	key := g.newTemp()
+	i := g.newLoopVar()
+	g.declareTemp(i)
+
+	m.emit(fmt.Sprintf("%s = %s;", object, m.code(s.Right)))
+	m.emit(fmt.Sprintf("%s = [];", keys))
+	m.emit(fmt.Sprintf("for (%s in %s) %s.push(%s);", key, object, keys, key))
+	m.emit(i + " = 0;")
+
+	m.loop(func(next int, end int) {
+		m.emitBreakWhen(fmt.Sprintf("!(%s < %s.length)", i, keys), end)
+		m.emit(fmt.Sprintf("%s = %s[%s];", key, keys, i))
+		m.emitBreakWhen(fmt.Sprintf("!(%s in %s)", key, object), next)
+		m.bind(s.Kind, s.Target, key)
+	}, s.Body, i+"++;")
+}
+
+// suspends reports whether node suspends the function it is in, with an
+// await or yield expression or a for await loop, leaving out the functions
+// nested in it
+func suspends(node ast.Node) bool {
+	found := false
+	ast.Inspect(node, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.AwaitExpression, *ast.YieldExpression:
+			found = true
+		case *ast.ForOfStatement:
+			found = found || n.Await
+		case *ast.FunctionLiteral:
+			return n == node
+		}
+		return !found
+	})
+	return found
+}
+
+// hasEscapingJump reports whether a break or continue in stmt leaves it
+func hasEscapingJump(stmt ast.Statement) bool {
+	return escapes(stmt, false, false, nil)
+}
+
+// escapes reports whether a break or continue in stmt leaves it, where
+// inSwitch tells whether a break has a switch or loop in stmt to leave,
+// inLoop whether a continue has a loop, and labels are the labels of the
+// statements in stmt enclosing it
+func escapes(stmt ast.Statement, inSwitch bool, inLoop bool, labels []string) bool {
+	anyEscapes := func(statements []ast.Statement, inSwitch bool, inLoop bool) bool {
+		for _, s := range statements {
+			if escapes(s, inSwitch, inLoop, labels) {
+				return true
+			}
+		}
+		return false
+	}
+	isLabel := func(label *ast.Identifier) bool {
+		for _, name := range labels {
+			if name == label.Value {
+				return true
+			}
+		}
+		return false
+	}
+
+	switch s := stmt.(type) {
+	case *ast.BreakStatement:
+		if s.Label != nil {
+			return !isLabel(s.Label)
+		}
+		return !inSwitch
+	case *ast.ContinueStatement:
+		if s.Label != nil {
+			return !isLabel(s.Label)
+		}
+		return !inLoop
+	case *ast.LabeledStatement:
+		return escapes(s.Body, inSwitch, inLoop, append(labels[:len(labels):len(labels)], s.Label.Value))
+	case *ast.BlockStatement:
+		return anyEscapes(s.Statements, inSwitch, inLoop)
+	case *ast.IfStatement:
+		return escapes(s.Consequence, inSwitch, inLoop, labels) || s.Alternative != nil && escapes(s.Alternative, inSwitch, inLoop, labels)
+	case *ast.SwitchStatement:
+		for _, c := range s.Cases {
+			if anyEscapes(c.Consequent, true, inLoop) {
+				return true
+			}
+		}
+	case *ast.TryStatement:
+		if escapes(s.Block, inSwitch, inLoop, labels) {
+			return true
+		}
+		if s.Handler != nil && escapes(s.Handler.Body, inSwitch, inLoop, labels) {
+			return true
+		}
+		return s.Finalizer != nil && escapes(s.Finalizer, inSwitch, inLoop, labels)
+	case *ast.ForOfStatement:
+		return escapes(s.Body, true, true, labels)
+	case *ast.ForInStatement:
+		return escapes(s.Body, true, true, labels)
+	}
+	return false
+}
@@ -0,0 +1,120 @@
+package codegen
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/dmarro89/ts-go-compiler/ast"
+)
+
+// generateIf generates an if statement, with else on a line of its own as
+// the TypeScript compiler writes it, unless it is followed by another if
+func (g *Generator) generateIf(s *ast.IfStatement) string {
+	out := "if (" + g.generateJSExpression(s.Condition) + ")" + g.generateBranch(s.Consequence)
+	switch alt := s.Alternative.(type) {
+	case nil:
+	case *ast.IfStatement:
+		out += "\nelse " + g.generateIf(alt)
+	default:
+		out += "\nelse" + g.generateBranch(alt)
+	}
+	return out
+}
+
+// generateBranch generates a branch of an if statement: a block on the
+// same line, or another statement indented on the next
+func (g *Generator) generateBranch(body ast.Statement) string {
+	if block, ok := body.(*ast.BlockStatement); ok {
+		return " " + g.generateBlock(block)
+	}
+	return "\n" + indent(g.generateJSStatement(body))
+}
+
+// generateLabeled generates label: statement. The labels of a for...of
+// loop go on the loop, which the loop may be wrapped in.
+func (g *Generator) generateLabeled(s *ast.LabeledStatement) string {
+	labels := ""
+	var body ast.Statement = s
+	for {
+		labeled, ok := body.(*ast.LabeledStatement)
+		if !ok {
+			break
+		}
+		labels += labeled.Label.Value + ": "
+		body = labeled.Body
+	}
+
+	if loop, ok := body.(*ast.ForOfStatement); ok {
+		return g.generateForOf(loop, labels)
+	}
+	return labels + g.generateJSStatement(body)
+}
+
+// generateSwitch generates a switch statement with one clause per line and
+// the statements of each clause indented below it
+func (g *Generator) generateSwitch(s *ast.SwitchStatement) string {
+	var body bytes.Buffer
+	for _, c := range s.Cases {
+		if c.Test == nil {
+			body.WriteString("default:")
+		} else {
+			body.WriteString("case " + g.generateJSExpression(c.Test) + ":")
+		}
+
+		// case 1: { ... } keeps its block on the same line
+		if len(c.Consequent) == 1 {
+			if block, ok := c.Consequent[0].(*ast.BlockStatement); ok {
+				body.WriteString(" " + g.generateBlock(block) + "\n")
+				continue
+			}
+		}
+
+		body.WriteString("\n")
+		var statements bytes.Buffer
+		g.writeStatements(&statements, c.Consequent)
+		body.WriteString(indent(statements.String()))
+	}
+
+	return "switch (" + g.generateJSExpression(s.Discriminant) + ") " + indentBlock(body.String())
+}
+
+// generateTry generates a try statement, with catch and finally on lines of
+// their own as the TypeScript compiler writes them. Before ES2019 a catch
+// clause without a binding gets one; before ES2015 a destructured binding
+// is replaced by a name and destructured at the start of the block.
+func (g *Generator) generateTry(s *ast.TryStatement) string {
+	var out strings.Builder
+	out.WriteString("try " + g.generateBlock(s.Block))
+
+	if h := s.Handler; h != nil {
+		out.WriteString("\ncatch ")
+
+		var prologue string
+		switch {
+		case h.Param == nil && g.downlevel(ES2019):
+			out.WriteString("(" + g.newName() + ") ")
+		case h.Param == nil:
+		case g.lowerPattern(h.Param):
+			name := g.newName()
+			out.WriteString("(" + name + ") ")
+
+			f := &flattener{g: g, declare: true}
+			f.bind(h.Param, name, simpleValue)
+			prologue = "var " + strings.Join(f.assignments, ", ") + ";\n"
+		default:
+			out.WriteString("(" + g.generateBindingName(h.Param) + ") ")
+		}
+
+		var body bytes.Buffer
+		body.WriteString(prologue)
+		g.writeStatements(&body, h.Body.Statements)
+		g.writeLeadingComments(&body, h.Body.EndComments)
+		out.WriteString(indentBlock(body.String()))
+	}
+
+	if s.Finalizer != nil {
+		out.WriteString("\nfinally " + g.generateBlock(s.Finalizer))
+	}
+
+	return out.String()
+}
@@ -0,0 +1,62 @@
+package codegen
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Target is the ECMAScript version the generated JavaScript must run on.
+// Syntax newer than the target is rewritten into an equivalent older form.
+type Target int
+
+const (
+	// ESNext keeps all syntax as written. It is the default.
+	ESNext Target = iota
+	ES5
+	ES2015
+	ES2016
+	ES2017
+	ES2018
+	ES2019
+	ES2020
+	ES2021
+)
+
+var targetNames = map[Target]string{
+	ESNext: "ESNext",
+	ES5:    "ES5",
+	ES2015: "ES2015",
+	ES2016: "ES2016",
+	ES2017: "ES2017",
+	ES2018: "ES2018",
+	ES2019: "ES2019",
+	ES2020: "ES2020",
+	ES2021: "ES2021",
+}
+
+func (t Target) String() string {
+	if name, ok := targetNames[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("Target(%d)", int(t))
+}
+
+// ParseTarget returns the target with the given name, as written in the
+// target option of tsconfig.json, e.g. "es2017". ES6 is an alias of ES2015.
+func ParseTarget(name string) (Target, error) {
+	if strings.EqualFold(name, "es6") {
+		return ES2015, nil
+	}
+	for t, n := range targetNames {
+		if strings.EqualFold(name, n) {
+			return t, nil
+		}
+	}
+	return ESNext, fmt.Errorf("unknown target %q", name)
+}
+
+// downlevel reports whether syntax introduced in version must be rewritten
+// for the target
+func (g *Generator) downlevel(version Target) bool {
+	return g.options.Target != ESNext && g.options.Target < version
+}
@@ -0,0 +1,85 @@
+package codegen
+
+import (
+	"strconv"
+	"strings"
+)
+
+// tempScope holds the temporary variables of a function
+type tempScope struct {
+	count int      // the number of names taken
+	vars  []string // the names declared at the top of the function
+
+	loopVar bool           // whether _i is taken
+	unique  map[string]int // the last suffix of the names made from a name
+}
+
+// pushTemps starts the temporary variables of a new function
+func (g *Generator) pushTemps() {
+	g.temps = append(g.temps, &tempScope{})
+}
+
+// popTemps ends the current function, returning the declaration of its
+// temporary variables, if any
+func (g *Generator) popTemps() string {
+	scope := g.temps[len(g.temps)-1]
+	g.temps = g.temps[:len(g.temps)-1]
+
+	if len(scope.vars) == 0 {
+		return ""
+	}
+	return "var " + strings.Join(scope.vars, ", ") + ";\n"
+}
+
+// newTemp declares a temporary variable at the top of the current function
+func (g *Generator) newTemp() string {
+	name := g.newName()
+	scope := g.temps[len(g.temps)-1]
+	scope.vars = append(scope.vars, name)
+	return name
+}
+
+// declareTemp declares a temporary variable named by the caller at the top
+// of the current function
+func (g *Generator) declareTemp(name string) {
+	scope := g.temps[len(g.temps)-1]
+	scope.vars = append(scope.vars, name)
+}
+
+// newLoopVar returns a name for the counter of a loop the caller declares,
+// _i for the first loop of the function
+func (g *Generator) newLoopVar() string {
+	scope := g.temps[len(g.temps)-1]
+	if !scope.loopVar {
+		scope.loopVar = true
+		return "_i"
+	}
+	return g.newName()
+}
+
+// uniqueName returns a new name made from name, e.g. xs_1 then xs_2 for
+// copies of xs
+func (g *Generator) uniqueName(name string) string {
+	scope := g.temps[len(g.temps)-1]
+	if scope.unique == nil {
+		scope.unique = map[string]int{}
+	}
+	scope.unique[name]++
+	return name + "_" + strconv.Itoa(scope.unique[name])
+}
+
+// newName returns a name for a temporary variable the caller declares
+// itself, e.g. in a var statement or as a parameter. Names are _a, _b and
+// so on, like the TypeScript compiler uses.
+func (g *Generator) newName() string {
+	scope := g.temps[len(g.temps)-1]
+
+	n := scope.count
+	scope.count++
+
+	name := "_" + string(rune('a'+n%26))
+	if n >= 26 {
+		name += strconv.Itoa(n / 26)
+	}
+	return name
+}
@@ -1,18 +1,95 @@
 package compiler
 
 import (
-	"errors"
+	"fmt"
 	"os"
+	"path/filepath"
+	"slices"
 	"strings"
 
 	"github.com/dmarro89/ts-go-compiler/codegen"
+	"github.com/dmarro89/ts-go-compiler/diagnostics"
 	"github.com/dmarro89/ts-go-compiler/lexer"
 	"github.com/dmarro89/ts-go-compiler/parser"
 	"github.com/dmarro89/ts-go-compiler/typecheck"
 )
 
+// Options are the compiler options, named after their tsconfig.json
+// counterparts
+type Options struct {
+	// RemoveComments drops comments from the output, except /*! */ comments
+	RemoveComments bool
+
+	// Target is the ECMAScript version of the output; newer syntax such as
+	// optional chaining is rewritten for older targets
+	Target codegen.Target
+
+	// Strict enables the checks of strict mode, e.g. catch variables of
+	// type unknown
+	Strict bool
+
+	// NoImplicitThis reports this in functions that do not declare its
+	// type; Strict enables it
+	NoImplicitThis bool
+
+	// NoImplicitReturns reports the code paths of a function that end
+	// without returning a value when others return one
+	NoImplicitReturns bool
+
+	// StrictFunctionTypes checks the parameters of functions passed or
+	// assigned contravariantly; Strict enables it
+	StrictFunctionTypes bool
+
+	// StrictNullChecks keeps null and undefined out of the other types, so
+	// a value that may be either must be checked before it is used; Strict
+	// enables it
+	StrictNullChecks bool
+
+	// DownlevelIteration emits for...of loops that follow the iteration
+	// protocol for targets before ES2015, instead of indexing arrays
+	DownlevelIteration bool
+
+	// MaxErrors is the number of errors reported at most, the first ones in
+	// the source; 0 reports them all
+	MaxErrors int
+}
+
+// Error is the error of a compilation that found problems in the program.
+// Its Diagnostics locate them in the source, in the order they appear.
+type Error struct {
+	Diagnostics []diagnostics.Diagnostic
+
+	// Omitted is the number of errors left out of Diagnostics by MaxErrors
+	Omitted int
+
+	phase string // "parse" when the program has syntax errors, or "type"
+}
+
+func (e *Error) Error() string {
+	messages := make([]string, len(e.Diagnostics))
+	for i, d := range e.Diagnostics {
+		messages[i] = d.String()
+	}
+	msg := e.phase + " errors: " + strings.Join(messages, ", ")
+	if e.Omitted > 0 {
+		msg += fmt.Sprintf(" (and %d more)", e.Omitted)
+	}
+	return msg
+}
+
+// Errors returns the messages of the diagnostics
+func (e *Error) Errors() []string {
+	messages := make([]string, len(e.Diagnostics))
+	for i, d := range e.Diagnostics {
+		messages[i] = d.Message
+	}
+	return messages
+}
+
 // Compiler handles the compilation process
 type Compiler struct {
+	options     Options
+	diagnostics []diagnostics.Diagnostic
 }
 
 // New creates a new compiler
@@ -20,16 +97,28 @@ func New() *Compiler {
 	return &Compiler{}
 }
 
-// CompileFile compiles a TypeScript file
+// NewWithOptions creates a new compiler with the given options
+func NewWithOptions(options Options) *Compiler {
+	return &Compiler{options: options}
+}
+
+// CompileFile compiles a TypeScript file. Like tsc, it refuses to write
+// the output over the input, as compiling a .js file next to itself would.
 func (c *Compiler) CompileFile(filename string, outputFile string) error {
-	// Read input file
-	input, err := os.ReadFile(filename)
+	if sameFile(filename, outputFile) {
+		return fmt.Errorf("error TS5055: Cannot write file '%s' because it would overwrite input file.", outputFile)
+	}
+
+	// Open input file; the lexer reads it as it goes
+	input, err := os.Open(filename)
 	if err != nil {
 		return err
 	}
+	defer input.Close()
 
-	// Compile source code
-	output, err := c.Compile(string(input))
+	// Compile source code, checking .js files with their JSDoc types
+	javaScript := strings.EqualFold(filepath.Ext(filename), ".js")
+	output, err := c.compile(lexer.NewReader(input, 0), javaScript)
 	if err != nil {
 		return err
 	}
@@ -38,28 +127,110 @@ func (c *Compiler) CompileFile(filename string, outputFile string) error {
 	return os.WriteFile(outputFile, []byte(output), 0644)
 }
 
+// sameFile reports whether the paths a and b name the same file, also
+// through links when both exist
+func sameFile(a, b string) bool {
+	absA, errA := filepath.Abs(a)
+	absB, errB := filepath.Abs(b)
+	if errA == nil && errB == nil && absA == absB {
+		return true
+	}
+	infoA, errA := os.Stat(a)
+	infoB, errB := os.Stat(b)
+	return errA == nil && errB == nil && os.SameFile(infoA, infoB)
+}
+
 // Compile compiles TypeScript source code
 func (c *Compiler) Compile(input string) (string, error) {
-	// Initialize lexer
-	l := lexer.New(input)
+	return c.compile(lexer.New(input), false)
+}
+
+// Warnings returns the warnings of the last compilation, such as uses of
+// declarations marked @deprecated
+func (c *Compiler) Warnings() []string {
+	warnings := []string{}
+	for _, d := range c.diagnostics {
+		if d.Severity == diagnostics.Warning {
+			warnings = append(warnings, d.Message)
+		}
+	}
+	return warnings
+}
+
+// Diagnostics returns the problems the last compilation reported, both its
+// warnings and the errors that failed it, in the order they appear in the
+// source
+func (c *Compiler) Diagnostics() []diagnostics.Diagnostic {
+	return c.diagnostics
+}
+
+// hasErrors reports whether diags include an error
+func hasErrors(diags []diagnostics.Diagnostic) bool {
+	return slices.ContainsFunc(diags, func(d diagnostics.Diagnostic) bool { return d.Severity == diagnostics.Error })
+}
+
+// fail reports the diagnostics of a compilation, failing it when they
+// include errors. The phase is the first that found errors.
+func (c *Compiler) fail(phase string, diags []diagnostics.Diagnostic) error {
+	diags = slices.Clone(diags)
+	diagnostics.Sort(diags)
+
+	err := &Error{phase: phase}
+	for _, d := range diags {
+		if d.Severity == diagnostics.Error {
+			if c.options.MaxErrors > 0 && len(err.Diagnostics) == c.options.MaxErrors {
+				err.Omitted++
+				continue
+			}
+			err.Diagnostics = append(err.Diagnostics, d)
+		}
+		c.diagnostics = append(c.diagnostics, d)
+	}
+
+	if len(err.Diagnostics) == 0 {
+		return nil
+	}
+	return err
+}
+
+func (c *Compiler) compile(l *lexer.Lexer, javaScript bool) (string, error) {
+	c.diagnostics = nil
 
 	// Parse input
 	p := parser.New(l)
 	program := p.ParseProgram()
 
-	if len(p.Errors()) > 0 {
-		return "", errors.New("parse errors: " + strings.Join(p.Errors(), ", "))
+	if err := l.Err(); err != nil {
+		return "", err
 	}
 
-	// Type check
-	tc := typecheck.New()
-	errs := tc.Check(program)
-	if len(errs) > 0 {
-		return "", errors.New("type error: " + errs[0])
+	// Type check, also when the program has syntax errors: the statements
+	// that could not be parsed are left out, and the errors of both kinds
+	// are reported together
+	tc := typecheck.NewWithOptions(typecheck.Options{
+		JavaScript:          javaScript,
+		Strict:              c.options.Strict,
+		NoImplicitThis:      c.options.NoImplicitThis,
+		NoImplicitReturns:   c.options.NoImplicitReturns,
+		StrictFunctionTypes: c.options.StrictFunctionTypes,
+		StrictNullChecks:    c.options.StrictNullChecks,
+	})
+	tc.Check(program)
+
+	phase := "type"
+	if hasErrors(p.Diagnostics()) {
+		phase = "parse"
+	}
+	if err := c.fail(phase, append(slices.Clone(p.Diagnostics()), tc.Diagnostics()...)); err != nil {
+		return "", err
 	}
 
 	// Generate code
-	generator := codegen.New()
+	generator := codegen.NewWithOptions(codegen.Options{
+		RemoveComments:     c.options.RemoveComments,
+		Target:             c.options.Target,
+		DownlevelIteration: c.options.DownlevelIteration,
+	})
 	output := generator.GenerateJavaScript(program)
 
 	return output, nil
@@ -1,10 +1,13 @@
 package compiler
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+
+	"github.com/dmarro89/ts-go-compiler/diagnostics"
 )
 
 func TestCompile(t *testing.T) {
@@ -118,6 +121,27 @@ func TestCompileWithErrors(t *testing.T) {
 	}
 }
 
+func TestCompileErrorDiagnostics(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let x = 1 2;", "1:11 - error TS1005: ';' expected."},
+		{"let x = 1;\nx = \"s\";", "2:1 - error TS2322: Type 'string' is not assignable to type 'number'."},
+	}
+
+	for _, tt := range tests {
+		_, err := New().Compile(tt.input)
+		var compileErr *Error
+		if !errors.As(err, &compileErr) {
+			t.Fatalf("%q: expected a *Error, got %v", tt.input, err)
+		}
+		if len(compileErr.Diagnostics) == 0 || compileErr.Diagnostics[0].String() != tt.expected {
+			t.Errorf("%q: expected %q, got %v", tt.input, tt.expected, compileErr.Diagnostics)
+		}
+	}
+}
+
 func TestCompileSimpleFile(t *testing.T) {
 	// Set up the test file
 	testFile := "../testdata/test.ts"
@@ -153,3 +177,189 @@ func TestCompileNonExistentFile(t *testing.T) {
 		t.Fatal("Error was expected for non-existent file, but none was generated")
 	}
 }
+
+func TestCompileRemoveComments(t *testing.T) {
+	input := `/*! keep me */
+// drop me
+let x = 5;`
+
+	output, err := NewWithOptions(Options{RemoveComments: true}).Compile(input)
+	if err != nil {
+		t.Fatalf("compile error: %s", err)
+	}
+
+	expected := "/*! keep me */\nlet x = 5;"
+	if strings.TrimSpace(output) != expected {
+		t.Errorf("expected=%q, got=%q", expected, output)
+	}
+}
+
+func TestCompileNoImplicitReturns(t *testing.T) {
+	input := "function f(): number { switch (1) { case 1: return 1; } }"
+
+	if _, err := New().Compile(input); err != nil {
+		t.Fatalf("compile error without noImplicitReturns: %s", err)
+	}
+
+	_, err := NewWithOptions(Options{NoImplicitReturns: true}).Compile(input)
+	expected := "type errors: 1:10 - error TS7030: Not all code paths return a value."
+	if err == nil || err.Error() != expected {
+		t.Errorf("expected error %q, got %v", expected, err)
+	}
+}
+
+func TestCompileStrictNullChecks(t *testing.T) {
+	input := "let a: string | null = null;\nlet n = a.length;"
+
+	if _, err := New().Compile(input); err != nil {
+		t.Fatalf("compile error without strictNullChecks: %s", err)
+	}
+
+	expected := "type errors: 2:9 - error TS18047: 'a' is possibly 'null'."
+	for _, options := range []Options{{StrictNullChecks: true}, {Strict: true}} {
+		_, err := NewWithOptions(options).Compile(input)
+		if err == nil || err.Error() != expected {
+			t.Errorf("%+v: expected error %q, got %v", options, expected, err)
+		}
+	}
+}
+
+func TestCompileJavaScriptFileWithJSDoc(t *testing.T) {
+	tempDir := t.TempDir()
+
+	inputFile := filepath.Join(tempDir, "count.js")
+	input := "/** @type {number} */\nlet count = \"none\";"
+	if err := os.WriteFile(inputFile, []byte(input), 0644); err != nil {
+		t.Fatalf("failed to create test file: %v", err)
+	}
+
+	compiler := New()
+	err := compiler.CompileFile(inputFile, filepath.Join(tempDir, "count.out.js"))
+	if err == nil || !strings.Contains(err.Error(), "Type 'string' is not assignable to type 'number'.") {
+		t.Fatalf("expected a JSDoc type error, got %v", err)
+	}
+
+	// The same source is fine as TypeScript, where JSDoc types are ignored
+	if _, err := compiler.Compile(input); err != nil {
+		t.Errorf("unexpected error compiling as TypeScript: %v", err)
+	}
+}
+
+func TestCompileFileRefusesToOverwriteInput(t *testing.T) {
+	tempDir := t.TempDir()
+
+	inputFile := filepath.Join(tempDir, "a.js")
+	input := "let a = 1;"
+	if err := os.WriteFile(inputFile, []byte(input), 0644); err != nil {
+		t.Fatalf("failed to create test file: %v", err)
+	}
+
+	err := New().CompileFile(inputFile, filepath.Join(tempDir, ".", "a.js"))
+	expected := "error TS5055: Cannot write file '" + filepath.Join(tempDir, "a.js") + "' because it would overwrite input file."
+	if err == nil || err.Error() != expected {
+		t.Errorf("expected error %q, got %v", expected, err)
+	}
+
+	if content, err := os.ReadFile(inputFile); err != nil || string(content) != input {
+		t.Errorf("expected the input to be left as it was, got %q, %v", content, err)
+	}
+}
+
+func TestCompileDeprecatedWarning(t *testing.T) {
+	compiler := New()
+	_, err := compiler.Compile("/** @deprecated */\nlet old = 1;\nlet x = old;")
+	if err != nil {
+		t.Fatalf("compile error: %s", err)
+	}
+
+	warnings := compiler.Warnings()
+	if len(warnings) != 1 || warnings[0] != "'old' is deprecated." {
+		t.Errorf("expected a deprecation warning, got %v", warnings)
+	}
+}
+
+func TestCompileUnreachableCodeWarning(t *testing.T) {
+	compiler := New()
+	_, err := compiler.Compile("function f() {\n  return 1;\n  f();\n}")
+	if err != nil {
+		t.Fatalf("compile error: %s", err)
+	}
+
+	warnings := compiler.Warnings()
+	if len(warnings) != 1 || warnings[0] != "Unreachable code detected." {
+		t.Errorf("expected an unreachable code warning, got %v", warnings)
+	}
+}
+
+func TestCompileDiagnosticSeverities(t *testing.T) {
+	compiler := New()
+	_, err := compiler.Compile("/** @deprecated */\nlet old = 1;\nlet x = old;\nx = \"s\";")
+	if err == nil {
+		t.Fatalf("expected a type error")
+	}
+
+	diags := compiler.Diagnostics()
+	if len(diags) != 2 {
+		t.Fatalf("expected a warning and an error, got %v", diags)
+	}
+	if diags[0].Severity != diagnostics.Warning || diags[0].String() != "3:9 - warning TS6385: 'old' is deprecated." {
+		t.Errorf("expected the deprecation warning, got %v", diags[0])
+	}
+	if diags[1].Severity != diagnostics.Error || diags[1].Code != 2322 {
+		t.Errorf("expected the type error, got %v", diags[1])
+	}
+}
+
+func TestCompileReportsAllErrors(t *testing.T) {
+	input := "let a = 1;\nlet b = y;\nlet c = b + 1;\nlet d = y * 2;\na = \"s\";\nlet e = \"x\";\ne++;"
+	expected := []string{
+		"2:9 - error TS2304: Cannot find name 'y'.",
+		"5:1 - error TS2322: Type 'string' is not assignable to type 'number'.",
+		"7:1 - error TS2356: An arithmetic operand must be of type 'any', 'number', 'bigint' or an enum type.",
+	}
+
+	tests := []struct {
+		maxErrors int
+		omitted   int
+	}{
+		{0, 0},
+		{2, 1},
+		{5, 0},
+	}
+
+	for _, tt := range tests {
+		compiler := NewWithOptions(Options{MaxErrors: tt.maxErrors})
+		_, err := compiler.Compile(input)
+
+		var compileErr *Error
+		if !errors.As(err, &compileErr) {
+			t.Fatalf("MaxErrors %d: expected a *Error, got %v", tt.maxErrors, err)
+		}
+		want := expected[:len(expected)-tt.omitted]
+		if len(compileErr.Diagnostics) != len(want) || compileErr.Omitted != tt.omitted {
+			t.Fatalf("MaxErrors %d: expected %d errors and %d omitted, got %v and %d omitted",
+				tt.maxErrors, len(want), tt.omitted, compileErr.Diagnostics, compileErr.Omitted)
+		}
+		for i, d := range compileErr.Diagnostics {
+			if d.String() != want[i] {
+				t.Errorf("MaxErrors %d: errors[%d] expected=%q, got=%q", tt.maxErrors, i, want[i], d.String())
+			}
+		}
+		if diags := compiler.Diagnostics(); len(diags) != len(want) {
+			t.Errorf("MaxErrors %d: expected Diagnostics to match the error, got %v", tt.maxErrors, diags)
+		}
+	}
+
+	_, err := NewWithOptions(Options{MaxErrors: 1}).Compile(input)
+	if msg := "type errors: 2:9 - error TS2304: Cannot find name 'y'. (and 2 more)"; err == nil || err.Error() != msg {
+		t.Errorf("expected error %q, got %v", msg, err)
+	}
+}
+
+func TestCompileReportsAllParseErrors(t *testing.T) {
+	_, err := New().Compile("let c = zz;\nlet a = 1 2;\nlet b = (1;")
+	expected := "parse errors: 1:9 - error TS2304: Cannot find name 'zz'., 2:11 - error TS1005: ';' expected., 3:11 - error TS1005: ')' expected."
+	if err == nil || err.Error() != expected {
+		t.Errorf("expected error %q, got %v", expected, err)
+	}
+}
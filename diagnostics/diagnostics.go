@@ -0,0 +1,67 @@
+// Package diagnostics describes the problems found in a program, with the
+// codes and messages of the TypeScript compiler
+package diagnostics
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+)
+
+// Severity tells whether a diagnostic fails the compilation
+type Severity int
+
+const (
+	// Error is a problem that fails the compilation. It is the default.
+	Error Severity = iota
+	// Warning is a problem that does not fail the compilation, such as the
+	// use of a deprecated declaration
+	Warning
+)
+
+func (s Severity) String() string {
+	if s == Warning {
+		return "warning"
+	}
+	return "error"
+}
+
+// Diagnostic is a problem found in the source, located by the range of
+// text it applies to
+type Diagnostic struct {
+	Code     int // the TypeScript code, e.g. 1005 for TS1005; 0 when it has none
+	Message  string
+	Severity Severity
+
+	Pos    int // byte offset of the first character of the range
+	End    int // byte offset just past the range
+	Line   int // line of Pos, from 1
+	Column int // column of Pos, from 1
+}
+
+// String formats the diagnostic as line:column - error TS1005: message
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%d:%d - %s", d.Line, d.Column, d.heading())
+}
+
+// heading formats the severity, code and message: error TS1005: message
+func (d Diagnostic) heading() string {
+	if d.Code == 0 {
+		return fmt.Sprintf("%s: %s", d.Severity, d.Message)
+	}
+	return fmt.Sprintf("%s TS%d: %s", d.Severity, d.Code, d.Message)
+}
+
+// RuleID identifies the kind of the diagnostic, e.g. TS1005, or TS0 when
+// it has no code
+func (d Diagnostic) RuleID() string {
+	return fmt.Sprintf("TS%d", d.Code)
+}
+
+// Sort orders diagnostics by their position in the source. Those at the
+// same position keep the order they were found in.
+func Sort(diags []Diagnostic) {
+	slices.SortStableFunc(diags, func(a, b Diagnostic) int {
+		return cmp.Or(cmp.Compare(a.Pos, b.Pos), cmp.Compare(a.End, b.End))
+	})
+}
@@ -0,0 +1,25 @@
+package diagnostics
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSort(t *testing.T) {
+	diags := []Diagnostic{
+		{Message: "c", Pos: 20, End: 21},
+		{Message: "a", Pos: 3, End: 5},
+		{Message: "b1", Pos: 10, End: 12},
+		{Message: "b2", Pos: 10, End: 12},
+		{Message: "a0", Pos: 3, End: 4},
+	}
+	Sort(diags)
+
+	var order []string
+	for _, d := range diags {
+		order = append(order, d.Message)
+	}
+	if got := strings.Join(order, " "); got != "a0 a b1 b2 c" {
+		t.Errorf("expected a0 a b1 b2 c, got %s", got)
+	}
+}
@@ -0,0 +1,175 @@
+package diagnostics
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+)
+
+// record is a diagnostic as a line of JSON output. Lines and columns
+// start at 1, offsets at 0; the end is just past the range.
+type record struct {
+	File      string `json:"file"`
+	Line      int    `json:"line"`
+	Column    int    `json:"column"`
+	EndLine   int    `json:"endLine"`
+	EndColumn int    `json:"endColumn"`
+	Pos       int    `json:"pos"`
+	End       int    `json:"end"`
+	Severity  string `json:"severity"`
+	Code      int    `json:"code"`
+	RuleID    string `json:"ruleId"`
+	Message   string `json:"message"`
+}
+
+// printJSON prints each diagnostic as a line of JSON
+func (p *Printer) printJSON(file, source string, diags []Diagnostic) {
+	encoder := json.NewEncoder(p.w)
+	encoder.SetEscapeHTML(false)
+	for _, d := range diags {
+		endLine, endColumn := lineColumn(source, d.End)
+		encoder.Encode(record{
+			File:      file,
+			Line:      d.Line,
+			Column:    d.Column,
+			EndLine:   endLine,
+			EndColumn: endColumn,
+			Pos:       d.Pos,
+			End:       d.End,
+			Severity:  d.Severity.String(),
+			Code:      d.Code,
+			RuleID:    d.RuleID(),
+			Message:   d.Message,
+		})
+	}
+}
+
+// sarifVersion and sarifSchema identify the version of SARIF written
+const (
+	sarifVersion = "2.1.0"
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+)
+
+// The types below are the parts of a SARIF log the output uses
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	RuleIndex int             `json:"ruleIndex"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           sarifRegion           `json:"region"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn"`
+	EndLine     int `json:"endLine"`
+	EndColumn   int `json:"endColumn"`
+}
+
+// addSARIF adds the diagnostics to the results of the SARIF log, and the
+// rules of the codes not seen before, described by their first message
+func (p *Printer) addSARIF(file, source string, diags []Diagnostic) {
+	if p.sarif == nil {
+		p.sarif = &sarifRun{
+			Tool: sarifTool{Driver: sarifDriver{
+				Name:           "tsgo",
+				InformationURI: "https://github.com/dmarro89/ts-go-compiler",
+				Rules:          []sarifRule{},
+			}},
+			Results: []sarifResult{},
+		}
+		p.rules = map[string]int{}
+	}
+
+	uri := filepath.ToSlash(file)
+	for _, d := range diags {
+		id := d.RuleID()
+		index, ok := p.rules[id]
+		if !ok {
+			index = len(p.sarif.Tool.Driver.Rules)
+			p.rules[id] = index
+			p.sarif.Tool.Driver.Rules = append(p.sarif.Tool.Driver.Rules, sarifRule{ID: id, ShortDescription: sarifMessage{Text: d.Message}})
+		}
+
+		endLine, endColumn := lineColumn(source, d.End)
+		p.sarif.Results = append(p.sarif.Results, sarifResult{
+			RuleID:    id,
+			RuleIndex: index,
+			Level:     d.Severity.String(),
+			Message:   sarifMessage{Text: d.Message},
+			Locations: []sarifLocation{{PhysicalLocation: sarifPhysicalLocation{
+				ArtifactLocation: sarifArtifactLocation{URI: uri},
+				Region: sarifRegion{
+					StartLine:   d.Line,
+					StartColumn: d.Column,
+					EndLine:     endLine,
+					EndColumn:   endColumn,
+				},
+			}}},
+		})
+	}
+}
+
+// printSARIF prints the SARIF log of all the diagnostics added, which has a
+// single run of the compiler
+func (p *Printer) printSARIF() {
+	if p.sarif == nil {
+		p.addSARIF("", "", nil)
+	}
+	encoder := json.NewEncoder(p.w)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(sarifLog{Schema: sarifSchema, Version: sarifVersion, Runs: []sarifRun{*p.sarif}})
+}
+
+// lineColumn returns the line and column of the offset i of source, both
+// starting at 1
+func lineColumn(source string, i int) (line, column int) {
+	i = clamp(i, source)
+	line = strings.Count(source[:i], "\n") + 1
+	column = i - strings.LastIndexByte(source[:i], '\n')
+	return line, column
+}
@@ -0,0 +1,238 @@
+package diagnostics
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ANSI escape sequences of the colors used by pretty output, the same as
+// tsc's
+const (
+	reset   = "\x1b[0m"
+	red     = "\x1b[91m"
+	yellow  = "\x1b[93m"
+	cyan    = "\x1b[96m"
+	gray    = "\x1b[90m"
+	reverse = "\x1b[7m"
+)
+
+// maxFrameLines is the number of source lines a code frame shows; the
+// middle lines of longer ranges are elided
+const maxFrameLines = 5
+
+// Format is the format diagnostics are printed in
+type Format int
+
+const (
+	// Text is for people to read. It is the default.
+	Text Format = iota
+	// JSON prints a line of JSON for each diagnostic
+	JSON
+	// SARIF prints a SARIF 2.1.0 log of all the diagnostics, for code
+	// scanning tools
+	SARIF
+)
+
+var formatNames = map[Format]string{
+	Text:  "text",
+	JSON:  "json",
+	SARIF: "sarif",
+}
+
+func (f Format) String() string {
+	if name, ok := formatNames[f]; ok {
+		return name
+	}
+	return fmt.Sprintf("Format(%d)", int(f))
+}
+
+// ParseFormat returns the format with the given name, e.g. "sarif"
+func ParseFormat(name string) (Format, error) {
+	for f, n := range formatNames {
+		if strings.EqualFold(name, n) {
+			return f, nil
+		}
+	}
+	return Text, fmt.Errorf("unknown format %q", name)
+}
+
+// Options controls how diagnostics are printed
+type Options struct {
+	Format Format
+
+	// Pretty prints each diagnostic of the Text format in color, followed by
+	// the source lines it points to with the range underlined, as tsc
+	// --pretty does. Otherwise diagnostics are printed one per line as
+	// file(line,column): message.
+	Pretty bool
+}
+
+// Printer prints the diagnostics of one or more files, counting the errors
+// for the summary
+type Printer struct {
+	w       io.Writer
+	options Options
+
+	errors  int
+	files   int
+	omitted int
+
+	// sarif collects the results of the SARIF log, written by Summary;
+	// rules indexes its rules by id
+	sarif *sarifRun
+	rules map[string]int
+}
+
+// NewPrinter creates a Printer writing to w
+func NewPrinter(w io.Writer, options Options) *Printer {
+	return &Printer{w: w, options: options}
+}
+
+// Print prints the diagnostics found in file, whose text is source. The
+// SARIF format only collects them, for Summary to print.
+func (p *Printer) Print(file, source string, diags []Diagnostic) {
+	errors := 0
+	for _, d := range diags {
+		if d.Severity == Error {
+			errors++
+		}
+	}
+	if errors > 0 {
+		p.errors += errors
+		p.files++
+	}
+
+	switch p.options.Format {
+	case JSON:
+		p.printJSON(file, source, diags)
+		return
+	case SARIF:
+		p.addSARIF(file, source, diags)
+		return
+	}
+
+	for _, d := range diags {
+		if !p.options.Pretty {
+			fmt.Fprintf(p.w, "%s(%d,%d): %s\n", file, d.Line, d.Column, d.heading())
+			continue
+		}
+		fmt.Fprintf(p.w, "%s%s%s:%s%d%s:%s%d%s - %s\n\n",
+			cyan, file, reset, yellow, d.Line, reset, yellow, d.Column, reset, d.prettyHeading())
+		p.printFrame(source, d)
+		fmt.Fprintln(p.w)
+	}
+}
+
+// Omit counts n errors that were found but not printed, e.g. those over a
+// maximum number of errors, for Summary to report
+func (p *Printer) Omit(n int) {
+	p.omitted += n
+}
+
+// Summary ends the output. The Text format prints the number of errors,
+// e.g. Found 2 errors (and 1 more), or nothing when there were none; SARIF
+// prints the log.
+func (p *Printer) Summary() {
+	more := ""
+	if p.omitted > 0 {
+		more = fmt.Sprintf(" (and %d more)", p.omitted)
+	}
+
+	switch {
+	case p.options.Format == SARIF:
+		p.printSARIF()
+	case p.options.Format == JSON, p.errors == 0:
+		return
+	case p.errors == 1:
+		fmt.Fprintf(p.w, "Found 1 error%s.\n", more)
+	case p.files > 1:
+		fmt.Fprintf(p.w, "Found %d errors in %d files%s.\n", p.errors, p.files, more)
+	default:
+		fmt.Fprintf(p.w, "Found %d errors%s.\n", p.errors, more)
+	}
+}
+
+// prettyHeading is heading in color
+func (d Diagnostic) prettyHeading() string {
+	color := d.color()
+	if d.Code == 0 {
+		return color + d.Severity.String() + reset + gray + ": " + reset + d.Message
+	}
+	return fmt.Sprintf("%s%s%s%s TS%d: %s%s", color, d.Severity, reset, gray, d.Code, reset, d.Message)
+}
+
+// color returns the color of the severity of d
+func (d Diagnostic) color() string {
+	if d.Severity == Warning {
+		return yellow
+	}
+	return red
+}
+
+// printFrame prints the lines of source the range of d covers, numbered in
+// a gutter, with the range underlined by ~
+func (p *Printer) printFrame(source string, d Diagnostic) {
+	pos, end := clamp(d.Pos, source), clamp(d.End, source)
+	if end < pos {
+		end = pos
+	}
+
+	first := strings.Count(source[:pos], "\n") + 1
+	last := first + strings.Count(source[pos:end], "\n")
+	width := len(strconv.Itoa(last))
+
+	start := strings.LastIndexByte(source[:pos], '\n') + 1
+	for line := first; line <= last; line++ {
+		stop := strings.IndexByte(source[start:], '\n')
+		if stop < 0 {
+			stop = len(source)
+		} else {
+			stop += start
+		}
+		text := strings.TrimSuffix(source[start:stop], "\r")
+
+		elided := last-first+1 > maxFrameLines && line > first+1 && line < last-1
+		switch {
+		case elided && line == first+2:
+			fmt.Fprintf(p.w, "%s%s%s %s\n", reverse, strings.Repeat(".", width), reset, "...")
+		case !elided:
+			from, to := 0, len(text)
+			if line == first {
+				from = pos - start
+			}
+			if line == last {
+				to = min(end-start, len(text))
+			}
+			fmt.Fprintf(p.w, "%s%*d%s %s\n", reverse, width, line, reset, text)
+			fmt.Fprintf(p.w, "%s%*s%s %s%s%s%s\n", reverse, width, "", reset, indent(text, from), d.color(), underline(from, to), reset)
+		}
+		start = stop + 1
+	}
+}
+
+// clamp keeps the offset i within source
+func clamp(i int, source string) int {
+	return max(0, min(i, len(source)))
+}
+
+// indent returns the blanks that line the underline up with column from
+// of text, keeping its tabs so they expand the same
+func indent(text string, from int) string {
+	var b strings.Builder
+	for i := 0; i < from; i++ {
+		if i < len(text) && text[i] == '\t' {
+			b.WriteByte('\t')
+		} else {
+			b.WriteByte(' ')
+		}
+	}
+	return b.String()
+}
+
+// underline returns the ~ under the columns from up to to, at least one so
+// an empty range, such as the end of the input, is still marked
+func underline(from, to int) string {
+	return strings.Repeat("~", max(1, to-from))
+}
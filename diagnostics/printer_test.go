@@ -0,0 +1,226 @@
+package diagnostics
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestPrint(t *testing.T) {
+	source := "let a = 1;\nlet b = a 2;\n"
+	diags := []Diagnostic{
+		{Code: 1005, Message: "';' expected.", Pos: 21, End: 22, Line: 2, Column: 11},
+		{Message: "Numeric literal '99999999999999999999' cannot be represented.", Pos: 0, End: 3, Line: 1, Column: 1},
+	}
+
+	var out strings.Builder
+	p := NewPrinter(&out, Options{})
+	p.Print("a.ts", source, diags)
+	p.Summary()
+
+	expected := "a.ts(2,11): error TS1005: ';' expected.\n" +
+		"a.ts(1,1): error: Numeric literal '99999999999999999999' cannot be represented.\n" +
+		"Found 2 errors.\n"
+	if out.String() != expected {
+		t.Errorf("expected=%q, got=%q", expected, out.String())
+	}
+}
+
+func TestPrintPretty(t *testing.T) {
+	tests := []struct {
+		source   string
+		diag     Diagnostic
+		expected string
+	}{
+		{
+			"let a = 1;\nlet b = a 2;\n",
+			Diagnostic{Code: 1005, Message: "';' expected.", Pos: 21, End: 22, Line: 2, Column: 11},
+			"\x1b[96ma.ts\x1b[0m:\x1b[93m2\x1b[0m:\x1b[93m11\x1b[0m - \x1b[91merror\x1b[0m\x1b[90m TS1005: \x1b[0m';' expected.\n\n" +
+				"\x1b[7m2\x1b[0m let b = a 2;\n" +
+				"\x1b[7m \x1b[0m           \x1b[91m~\x1b[0m\n\n",
+		},
+		{
+			"\tx = \"s\";",
+			Diagnostic{Code: 2322, Message: "Type 'string' is not assignable to type 'number'.", Pos: 1, End: 2, Line: 1, Column: 2},
+			"\x1b[96ma.ts\x1b[0m:\x1b[93m1\x1b[0m:\x1b[93m2\x1b[0m - \x1b[91merror\x1b[0m\x1b[90m TS2322: \x1b[0mType 'string' is not assignable to type 'number'.\n\n" +
+				"\x1b[7m1\x1b[0m \tx = \"s\";\n" +
+				"\x1b[7m \x1b[0m \t\x1b[91m~\x1b[0m\n\n",
+		},
+		{
+			"f(a,\n  b);",
+			Diagnostic{Code: 1005, Message: "')' expected.", Pos: 0, End: 9, Line: 1, Column: 1},
+			"\x1b[96ma.ts\x1b[0m:\x1b[93m1\x1b[0m:\x1b[93m1\x1b[0m - \x1b[91merror\x1b[0m\x1b[90m TS1005: \x1b[0m')' expected.\n\n" +
+				"\x1b[7m1\x1b[0m f(a,\n" +
+				"\x1b[7m \x1b[0m \x1b[91m~~~~\x1b[0m\n" +
+				"\x1b[7m2\x1b[0m   b);\n" +
+				"\x1b[7m \x1b[0m \x1b[91m~~~~\x1b[0m\n\n",
+		},
+	}
+
+	for _, tt := range tests {
+		var out strings.Builder
+		NewPrinter(&out, Options{Pretty: true}).Print("a.ts", tt.source, []Diagnostic{tt.diag})
+		if out.String() != tt.expected {
+			t.Errorf("%q: expected=%q, got=%q", tt.source, tt.expected, out.String())
+		}
+	}
+}
+
+func TestPrintElidesLongRanges(t *testing.T) {
+	source := "a\nb\nc\nd\ne\nf\ng"
+	var out strings.Builder
+	NewPrinter(&out, Options{Pretty: true}).Print("a.ts", source, []Diagnostic{{Code: 1005, Message: "m", Pos: 0, End: len(source), Line: 1, Column: 1}})
+
+	frame := out.String()
+	for _, line := range []string{"1\x1b[0m a\n", "2\x1b[0m b\n", ".\x1b[0m ...\n", "6\x1b[0m f\n", "7\x1b[0m g\n"} {
+		if !strings.Contains(frame, line) {
+			t.Errorf("expected %q in %q", line, frame)
+		}
+	}
+	if strings.Contains(frame, " d\n") {
+		t.Errorf("expected the middle lines to be elided, got %q", frame)
+	}
+}
+
+func TestSummary(t *testing.T) {
+	d := Diagnostic{Code: 1005, Message: "m", Line: 1, Column: 1}
+	tests := []struct {
+		files    [][]Diagnostic
+		omitted  int
+		expected string
+	}{
+		{nil, 0, ""},
+		{[][]Diagnostic{{d}}, 0, "Found 1 error.\n"},
+		{[][]Diagnostic{{d, d}}, 0, "Found 2 errors.\n"},
+		{[][]Diagnostic{{d}, {d, d}}, 0, "Found 3 errors in 2 files.\n"},
+		{[][]Diagnostic{{d}}, 1, "Found 1 error (and 1 more).\n"},
+		{[][]Diagnostic{{d}, {d, d}}, 2, "Found 3 errors in 2 files (and 2 more).\n"},
+	}
+
+	for _, tt := range tests {
+		var out strings.Builder
+		p := NewPrinter(&out, Options{})
+		for _, diags := range tt.files {
+			p.Print("a.ts", "", diags)
+		}
+		p.Omit(tt.omitted)
+		out.Reset()
+		p.Summary()
+		if out.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, out.String())
+		}
+	}
+}
+
+func TestPrintJSON(t *testing.T) {
+	source := "old();\nlet b = a 2;\n"
+	diags := []Diagnostic{
+		{Code: 6385, Message: "'old' is deprecated.", Severity: Warning, Pos: 0, End: 3, Line: 1, Column: 1},
+		{Code: 1005, Message: "';' expected.", Pos: 17, End: 18, Line: 2, Column: 11},
+	}
+
+	var out strings.Builder
+	p := NewPrinter(&out, Options{Format: JSON})
+	p.Print("src/a.ts", source, diags)
+	p.Summary()
+
+	expected := `{"file":"src/a.ts","line":1,"column":1,"endLine":1,"endColumn":4,"pos":0,"end":3,"severity":"warning","code":6385,"ruleId":"TS6385","message":"'old' is deprecated."}` + "\n" +
+		`{"file":"src/a.ts","line":2,"column":11,"endLine":2,"endColumn":12,"pos":17,"end":18,"severity":"error","code":1005,"ruleId":"TS1005","message":"';' expected."}` + "\n"
+	if out.String() != expected {
+		t.Errorf("expected=%q, got=%q", expected, out.String())
+	}
+}
+
+func TestPrintSARIF(t *testing.T) {
+	var out strings.Builder
+	p := NewPrinter(&out, Options{Format: SARIF})
+	p.Print("a.ts", "let b = a 2;\nlet c = d 3;", []Diagnostic{
+		{Code: 1005, Message: "';' expected.", Pos: 10, End: 11, Line: 1, Column: 11},
+		{Code: 1005, Message: "';' expected.", Pos: 23, End: 24, Line: 2, Column: 11},
+	})
+	p.Print("b.ts", "old();", []Diagnostic{
+		{Code: 6385, Message: "'old' is deprecated.", Severity: Warning, Pos: 0, End: 3, Line: 1, Column: 1},
+	})
+	if out.Len() != 0 {
+		t.Fatalf("expected the log to be printed by Summary, got %q", out.String())
+	}
+	p.Summary()
+
+	var log struct {
+		Version string `json:"version"`
+		Runs    []struct {
+			Tool struct {
+				Driver struct {
+					Name  string `json:"name"`
+					Rules []struct {
+						ID string `json:"id"`
+					} `json:"rules"`
+				} `json:"driver"`
+			} `json:"tool"`
+			Results []struct {
+				RuleID    string `json:"ruleId"`
+				RuleIndex int    `json:"ruleIndex"`
+				Level     string `json:"level"`
+				Message   struct {
+					Text string `json:"text"`
+				} `json:"message"`
+				Locations []struct {
+					PhysicalLocation struct {
+						ArtifactLocation struct {
+							URI string `json:"uri"`
+						} `json:"artifactLocation"`
+						Region struct {
+							StartLine, StartColumn, EndLine, EndColumn int
+						} `json:"region"`
+					} `json:"physicalLocation"`
+				} `json:"locations"`
+			} `json:"results"`
+		} `json:"runs"`
+	}
+	if err := json.Unmarshal([]byte(out.String()), &log); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, out.String())
+	}
+
+	if log.Version != "2.1.0" || len(log.Runs) != 1 {
+		t.Fatalf("expected a SARIF 2.1.0 log with one run, got version %q with %d runs", log.Version, len(log.Runs))
+	}
+	run := log.Runs[0]
+	if run.Tool.Driver.Name != "tsgo" || len(run.Tool.Driver.Rules) != 2 ||
+		run.Tool.Driver.Rules[0].ID != "TS1005" || run.Tool.Driver.Rules[1].ID != "TS6385" {
+		t.Errorf("expected the rules TS1005 and TS6385, got %+v", run.Tool.Driver)
+	}
+
+	expected := []struct {
+		rule      string
+		index     int
+		level     string
+		uri       string
+		line, col int
+	}{
+		{"TS1005", 0, "error", "a.ts", 1, 11},
+		{"TS1005", 0, "error", "a.ts", 2, 11},
+		{"TS6385", 1, "warning", "b.ts", 1, 1},
+	}
+	if len(run.Results) != len(expected) {
+		t.Fatalf("expected %d results, got %d", len(expected), len(run.Results))
+	}
+	for i, e := range expected {
+		r := run.Results[i]
+		loc := r.Locations[0].PhysicalLocation
+		if r.RuleID != e.rule || r.RuleIndex != e.index || r.Level != e.level || loc.ArtifactLocation.URI != e.uri ||
+			loc.Region.StartLine != e.line || loc.Region.StartColumn != e.col || loc.Region.EndLine != e.line {
+			t.Errorf("results[%d]: expected %+v, got %+v", i, e, r)
+		}
+	}
+}
+
+func TestSummaryCountsErrors(t *testing.T) {
+	var out strings.Builder
+	p := NewPrinter(&out, Options{})
+	p.Print("a.ts", "old();", []Diagnostic{{Code: 6385, Message: "'old' is deprecated.", Severity: Warning, Line: 1, Column: 1}})
+	out.Reset()
+	p.Summary()
+	if out.String() != "" {
+		t.Errorf("expected no summary for warnings, got %q", out.String())
+	}
+}
@@ -1,32 +1,104 @@
 package lexer
 
 import (
+	"io"
+	"iter"
+	"slices"
 	"unicode"
 
 	"github.com/dmarro89/ts-go-compiler/token"
 )
 
+// Mode controls what the lexer returns
+type Mode uint
+
+const (
+	// ScanTrivia makes NextToken return whitespace, line terminators and
+	// comments as WHITESPACE, NEWLINE and COMMENT tokens instead of skipping
+	// them, for tools that need to reproduce the source exactly
+	ScanTrivia Mode = 1 << iota
+)
+
+// chunkSize is how much is read at a time from an io.Reader
+const chunkSize = 64 * 1024
+
 // Lexer is responsible for scanning the source code
 type Lexer struct {
-	input        string
+	input        []byte
+	reader       io.Reader // source of further input, nil once exhausted
+	mode         Mode
 	position     int  // current position in input (points to current character)
 	readPosition int  // current reading position in input (after current character)
 	ch           byte // current character under examination
 	line         int  // current line
 	column       int  // current column
+
+	newlineBefore bool // a line terminator was skipped before the current token
+	err           error
 }
 
 // New creates a new Lexer
 func New(input string) *Lexer {
-	l := &Lexer{input: input, line: 1, column: 0}
+	return NewWithMode(input, 0)
+}
+
+// NewWithMode creates a new Lexer with the given mode
+func NewWithMode(input string, mode Mode) *Lexer {
+	l := &Lexer{input: []byte(input), mode: mode, line: 1, column: 0}
+	l.readChar()
+	return l
+}
+
+// NewReader creates a new Lexer reading its input from r. The input is read
+// in chunks as scanning advances, so it is never held as one large string.
+// Read errors other than io.EOF are returned by Err.
+func NewReader(r io.Reader, mode Mode) *Lexer {
+	l := &Lexer{reader: r, mode: mode, line: 1, column: 0}
 	l.readChar()
 	return l
 }
 
+// Err returns the first error returned by the reader, if any
+func (l *Lexer) Err() error {
+	return l.err
+}
+
+// fill reads from the reader until the input holds more than n bytes. It
+// returns false when the input ends before that.
+func (l *Lexer) fill(n int) bool {
+	for len(l.input) <= n {
+		if l.reader == nil {
+			return false
+		}
+
+		l.input = slices.Grow(l.input, chunkSize)
+		read, err := l.reader.Read(l.input[len(l.input):cap(l.input)])
+		l.input = l.input[:len(l.input)+read]
+
+		if err != nil {
+			if err != io.EOF {
+				l.err = err
+			}
+			l.reader = nil
+		}
+	}
+	return true
+}
+
+// text returns the input between two positions as a string
+func (l *Lexer) text(start int, end int) string {
+	return string(l.input[start:end])
+}
+
 // readChar reads the next character and advances the position in the input
 func (l *Lexer) readChar() {
-	if l.readPosition >= len(l.input) {
+	if !l.fill(l.readPosition) {
+		// The end of the input is one column past its last character
+		if l.position < len(l.input) || l.column == 0 {
+			l.column++
+		}
 		l.ch = 0
+		l.position = len(l.input)
 		return
 	}
 
@@ -42,48 +114,155 @@ func (l *Lexer) readChar() {
 
 // peekChar returns the next character without advancing the position
 func (l *Lexer) peekChar() byte {
-	if l.readPosition >= len(l.input) {
+	if !l.fill(l.readPosition) {
 		return 0
 	}
 	return l.input[l.readPosition]
 }
 
-// NextToken returns the next token from the input
+// NextToken returns the next token from the input. Comments skipped on the
+// way are attached to the returned token.
 func (l *Lexer) NextToken() token.Token {
-	// Skip whitespace
+	if l.mode&ScanTrivia != 0 {
+		if tok, ok := l.readTrivia(); ok {
+			return tok
+		}
+	}
+
+	// Skip whitespace and comments
 	l.skipWhitespace()
 
+	var comments []token.Token
+	for l.ch == '/' && (l.peekChar() == '/' || l.peekChar() == '*') {
+		comments = append(comments, l.readComment())
+		l.skipWhitespace()
+	}
+
 	// Save current position for the token
-	var tok token.Token
+	line, column, pos := l.line, l.column, l.position
+
+	tok := l.readToken()
+	tok.Line = line
+	tok.Column = column
+	tok.Pos = pos
+	tok.End = l.position
+	tok.NewlineBefore = l.newlineBefore
+	tok.Comments = comments
+	l.newlineBefore = false
+	return tok
+}
+
+// All returns an iterator over the remaining tokens, up to but not
+// including EOF
+func (l *Lexer) All() iter.Seq[token.Token] {
+	return func(yield func(token.Token) bool) {
+		for {
+			tok := l.NextToken()
+			if tok.Type == token.EOF || !yield(tok) {
+				return
+			}
+		}
+	}
+}
+
+// State is a snapshot of the lexer's position, taken by Save
+type State struct {
+	position      int
+	readPosition  int
+	ch            byte
+	line          int
+	column        int
+	newlineBefore bool
+}
+
+// Save returns the current state of the lexer, so that a parser can scan
+// ahead and go back with Restore
+func (l *Lexer) Save() State {
+	return State{
+		position:      l.position,
+		readPosition:  l.readPosition,
+		ch:            l.ch,
+		line:          l.line,
+		column:        l.column,
+		newlineBefore: l.newlineBefore,
+	}
+}
+
+// Restore moves the lexer back to a state returned by Save
+func (l *Lexer) Restore(s State) {
+	l.position = s.position
+	l.readPosition = s.readPosition
+	l.ch = s.ch
+	l.line = s.line
+	l.column = s.column
+	l.newlineBefore = s.newlineBefore
+}
+
+// readTrivia reads whitespace, a line terminator or a comment as a token
+// when scanning trivia. It returns false when the current character starts
+// a regular token.
+func (l *Lexer) readTrivia() (token.Token, bool) {
+	tok := token.Token{Line: l.line, Column: l.column, Pos: l.position, NewlineBefore: l.newlineBefore}
+
+	switch {
+	case isLineTerminator(l.ch):
+		tok.Type = token.NEWLINE
+		if l.ch == '\r' && l.peekChar() == '\n' {
+			l.readChar()
+		}
+		l.readChar()
+		l.newlineBefore = true
+	case unicode.IsSpace(rune(l.ch)):
+		tok.Type = token.WHITESPACE
+		for unicode.IsSpace(rune(l.ch)) && !isLineTerminator(l.ch) {
+			l.readChar()
+		}
+	case l.ch == '/' && (l.peekChar() == '/' || l.peekChar() == '*'):
+		return l.readComment(), true
+	default:
+		return tok, false
+	}
+
+	tok.Literal = l.text(tok.Pos, l.position)
+	return tok, true
+}
+
+// readComment reads a line or block comment into a COMMENT token
+func (l *Lexer) readComment() token.Token {
+	tok := token.Token{
+		Type:          token.COMMENT,
+		Line:          l.line,
+		Column:        l.column,
+		Pos:           l.position,
+		NewlineBefore: l.newlineBefore,
+	}
+
+	if l.peekChar() == '/' {
+		l.skipLineComment()
+	} else {
+		l.skipBlockComment()
+	}
 
-	tok.Line = l.line
-	tok.Column = l.column
+	tok.Literal = l.text(tok.Pos, l.position)
+	return tok
+}
+
+// readToken scans the token starting at the current character
+func (l *Lexer) readToken() token.Token {
+	var tok token.Token
 
 	switch l.ch {
-	case '=':
-		tok = token.Token{Type: token.ASSIGN, Literal: string(l.ch)}
-	case '+':
-		tok = token.Token{Type: token.PLUS, Literal: string(l.ch)}
-	case '-':
-		tok = token.Token{Type: token.MINUS, Literal: string(l.ch)}
-	case '!':
-		tok = token.Token{Type: token.BANG, Literal: string(l.ch)}
-	case '*':
-		tok = token.Token{Type: token.ASTERISK, Literal: string(l.ch)}
-	case '/':
-		// Handle comments
-		if l.peekChar() == '/' {
-			l.skipLineComment()
-			return l.NextToken()
-		} else if l.peekChar() == '*' {
-			l.skipBlockComment()
-			return l.NextToken()
-		}
-		tok = token.Token{Type: token.SLASH, Literal: string(l.ch)}
-	case '<':
-		tok = token.Token{Type: token.LT, Literal: string(l.ch)}
+	case '=', '+', '-', '!', '*', '/', '%', '<', '|', '&', '^', '~', '?':
+		tok = l.readOperator(operators)
 	case '>':
+		// Always a single '>' so that nested type arguments like
+		// Array<Array<number>> close properly; in expressions the parser
+		// re-scans it with ReScanGreater.
 		tok = token.Token{Type: token.GT, Literal: string(l.ch)}
+	case '[':
+		tok = token.Token{Type: token.LBRACKET, Literal: string(l.ch)}
+	case ']':
+		tok = token.Token{Type: token.RBRACKET, Literal: string(l.ch)}
 	case ',':
 		tok = token.Token{Type: token.COMMA, Literal: string(l.ch)}
 	case ';':
@@ -99,7 +278,13 @@ func (l *Lexer) NextToken() token.Token {
 	case '}':
 		tok = token.Token{Type: token.RBRACE, Literal: string(l.ch)}
 	case '.':
-		tok = token.Token{Type: token.DOT, Literal: string(l.ch)}
+		if l.hasPrefix("...") {
+			l.readChar()
+			l.readChar()
+			tok = token.Token{Type: token.ELLIPSIS, Literal: "..."}
+		} else {
+			tok = token.Token{Type: token.DOT, Literal: string(l.ch)}
+		}
 	case '"':
 		tok.Type = token.STRING
 		tok.Literal = l.readString('"')
@@ -127,8 +312,115 @@ func (l *Lexer) NextToken() token.Token {
 	return tok
 }
 
+type operator struct {
+	text      string
+	tokenType token.TokenType
+}
+
+// operators lists the operators starting with the characters handled by
+// readOperator, longest first
+var operators = []operator{
+	{"**=", token.EXPONENT_ASSIGN},
+	{"<<=", token.LSHIFT_ASSIGN},
+	{"===", token.STRICT_EQ},
+	{"!==", token.STRICT_NOT_EQ},
+	{"&&=", token.AND_ASSIGN},
+	{"||=", token.OR_ASSIGN},
+	{"??=", token.NULLISH_ASSIGN},
+	{"==", token.EQ},
+	{"=>", token.ARROW},
+	{"!=", token.NOT_EQ},
+	{"<=", token.LT_EQ},
+	{"<<", token.LSHIFT},
+	{"**", token.EXPONENT},
+	{"++", token.INCREMENT},
+	{"--", token.DECREMENT},
+	{"&&", token.AND},
+	{"||", token.OR},
+	{"??", token.NULLISH},
+	{"?.", token.QUESTION_DOT},
+	{"+=", token.PLUS_ASSIGN},
+	{"-=", token.MINUS_ASSIGN},
+	{"*=", token.ASTERISK_ASSIGN},
+	{"/=", token.SLASH_ASSIGN},
+	{"%=", token.PERCENT_ASSIGN},
+	{"&=", token.AMPERSAND_ASSIGN},
+	{"|=", token.PIPE_ASSIGN},
+	{"^=", token.CARET_ASSIGN},
+	{"=", token.ASSIGN},
+	{"+", token.PLUS},
+	{"-", token.MINUS},
+	{"!", token.BANG},
+	{"*", token.ASTERISK},
+	{"/", token.SLASH},
+	{"%", token.PERCENT},
+	{"<", token.LT},
+	{"|", token.PIPE},
+	{"&", token.AMPERSAND},
+	{"^", token.CARET},
+	{"~", token.TILDE},
+	{"?", token.QUESTION},
+}
+
+// greaterOperators lists the operators starting with '>', longest first
+var greaterOperators = []operator{
+	{">>>=", token.URSHIFT_ASSIGN},
+	{">>>", token.URSHIFT},
+	{">>=", token.RSHIFT_ASSIGN},
+	{">>", token.RSHIFT},
+	{">=", token.GT_EQ},
+	{">", token.GT},
+}
+
+// readOperator reads the longest operator of ops at the current character,
+// leaving the lexer on its last character
+func (l *Lexer) readOperator(ops []operator) token.Token {
+	for _, op := range ops {
+		if !l.hasPrefix(op.text) {
+			continue
+		}
+		// a?.5:1 is a conditional, not an optional chain
+		if op.tokenType == token.QUESTION_DOT && l.fill(l.position+2) && isDigit(l.input[l.position+2]) {
+			continue
+		}
+		for i := 1; i < len(op.text); i++ {
+			l.readChar()
+		}
+		return token.Token{Type: op.tokenType, Literal: op.text}
+	}
+	return token.Token{Type: token.ILLEGAL, Literal: string(l.ch)}
+}
+
+// hasPrefix reports whether the input continues with s at the current
+// character
+func (l *Lexer) hasPrefix(s string) bool {
+	if !l.fill(l.position + len(s) - 1) {
+		return false
+	}
+	return string(l.input[l.position:l.position+len(s)]) == s
+}
+
+// ReScanGreater rescans a GT token as the longest operator starting with
+// '>', such as >= or >>>=. The parser calls it where an operator is
+// expected, since only there '>>' is a shift rather than two closing
+// brackets.
+func (l *Lexer) ReScanGreater(tok token.Token) token.Token {
+	l.resetTo(tok)
+
+	scanned := l.readOperator(greaterOperators)
+	l.readChar()
+
+	tok.Type = scanned.Type
+	tok.Literal = scanned.Literal
+	tok.End = l.position
+	return tok
+}
+
 func (l *Lexer) skipWhitespace() {
 	for unicode.IsSpace(rune(l.ch)) {
+		if isLineTerminator(l.ch) {
+			l.newlineBefore = true
+		}
 		l.readChar()
 	}
 }
@@ -155,6 +447,10 @@ func (l *Lexer) skipBlockComment() {
 			// End of file inside a block comment
 			break
 		}
+		if isLineTerminator(l.ch) {
+			// A multi-line comment counts as a line terminator for ASI
+			l.newlineBefore = true
+		}
 		if l.ch == '*' && l.peekChar() == '/' {
 			// End of block comment
 			l.readChar() // skip '*'
@@ -171,7 +467,7 @@ func (l *Lexer) readIdentifier() string {
 	for isLetter(l.ch) {
 		l.readChar()
 	}
-	return l.input[position:l.position]
+	return l.text(position, l.position)
 }
 
 // readNumber reads a number
@@ -180,7 +476,7 @@ func (l *Lexer) readNumber() string {
 	for isDigit(l.ch) {
 		l.readChar()
 	}
-	return l.input[position:l.position]
+	return l.text(position, l.position)
 }
 
 // readString reads a string delimited by quotes
@@ -192,7 +488,7 @@ func (l *Lexer) readString(quote byte) string {
 		l.readChar()
 	}
 
-	result := l.input[position:l.position]
+	result := l.text(position, l.position)
 	return result
 }
 
@@ -227,6 +523,48 @@ func lookupIdent(ident string) token.TokenType {
 		return token.CONSOLE
 	case "log":
 		return token.LOG
+	case "in":
+		return token.IN
+	case "instanceof":
+		return token.INSTANCEOF
+	case "typeof":
+		return token.TYPEOF
+	case "void":
+		return token.VOID
+	case "delete":
+		return token.DELETE
+	case "null":
+		return token.NULL
+	case "true":
+		return token.TRUE
+	case "false":
+		return token.FALSE
+	case "switch":
+		return token.SWITCH
+	case "case":
+		return token.CASE
+	case "default":
+		return token.DEFAULT
+	case "break":
+		return token.BREAK
+	case "try":
+		return token.TRY
+	case "catch":
+		return token.CATCH
+	case "finally":
+		return token.FINALLY
+	case "throw":
+		return token.THROW
+	case "for":
+		return token.FOR
+	case "continue":
+		return token.CONTINUE
+	case "new":
+		return token.NEW
+	case "this":
+		return token.THIS
+	case "super":
+		return token.SUPER
 	case ".":
 		return token.DOT
 	default:
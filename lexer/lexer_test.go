@@ -1,7 +1,10 @@
 package lexer
 
 import (
+	"errors"
+	"strings"
 	"testing"
+	"testing/iotest"
 
 	"github.com/dmarro89/ts-go-compiler/token"
 )
@@ -190,7 +193,7 @@ func TestNextTokenOperators(t *testing.T) {
 		{token.LET, "let"},
 		{token.IDENT, "b"},
 		{token.ASSIGN, "="},
-		{token.IDENT, "true"},
+		{token.TRUE, "true"},
 		// ... continue with other tokens
 	}
 
@@ -246,3 +249,295 @@ func TestComment(t *testing.T) {
 		}
 	}
 }
+
+func TestNewlineBefore(t *testing.T) {
+	input := `a b
+	c /* one
+	two */ d // trailing
+	e`
+
+	tests := []struct {
+		expectedLiteral string
+		expectedNewline bool
+	}{
+		{"a", false},
+		{"b", false},
+		{"c", true},
+		{"d", true},
+		{"e", true},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+				i, tt.expectedLiteral, tok.Literal)
+		}
+
+		if tok.NewlineBefore != tt.expectedNewline {
+			t.Errorf("tests[%d] - NewlineBefore wrong for %q. expected=%t, got=%t",
+				i, tok.Literal, tt.expectedNewline, tok.NewlineBefore)
+		}
+	}
+}
+
+func TestCommentsAttachedToTokens(t *testing.T) {
+	input := `/*! license */
+	// doc
+	let a = 5; // trailing
+	`
+
+	l := New(input)
+
+	let := l.NextToken()
+	if len(let.Comments) != 2 {
+		t.Fatalf("expected 2 comments before let, got %d", len(let.Comments))
+	}
+	if let.Comments[0].Type != token.COMMENT || let.Comments[0].Literal != "/*! license */" {
+		t.Errorf("first comment wrong. got=%q", let.Comments[0].Literal)
+	}
+	if let.Comments[1].Literal != "// doc" || !let.Comments[1].NewlineBefore {
+		t.Errorf("second comment wrong. got=%q newlineBefore=%t",
+			let.Comments[1].Literal, let.Comments[1].NewlineBefore)
+	}
+	if let.Comments[1].Line != 2 || let.Comments[1].Column != 2 {
+		t.Errorf("second comment position wrong. got line=%d column=%d",
+			let.Comments[1].Line, let.Comments[1].Column)
+	}
+
+	for i := 0; i < 4; i++ {
+		l.NextToken()
+	}
+
+	eof := l.NextToken()
+	if eof.Type != token.EOF {
+		t.Fatalf("expected EOF, got %q", eof.Literal)
+	}
+	if len(eof.Comments) != 1 || eof.Comments[0].Literal != "// trailing" || eof.Comments[0].NewlineBefore {
+		t.Errorf("expected the trailing comment on EOF, got %v", eof.Comments)
+	}
+}
+
+func TestAll(t *testing.T) {
+	l := New("let a = 1;")
+
+	var types []token.TokenType
+	for tok := range l.All() {
+		types = append(types, tok.Type)
+	}
+
+	expected := []token.TokenType{token.LET, token.IDENT, token.ASSIGN, token.INT, token.SEMICOLON}
+	if len(types) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, types)
+	}
+	for i, tt := range types {
+		if tt != expected[i] {
+			t.Errorf("token %d expected=%s, got=%s", i, expected[i], tt)
+		}
+	}
+
+	// Breaking out of the loop stops scanning
+	l = New("a b c")
+	for tok := range l.All() {
+		if tok.Literal == "b" {
+			break
+		}
+	}
+	if tok := l.NextToken(); tok.Literal != "c" {
+		t.Errorf("expected scanning to resume at c, got %q", tok.Literal)
+	}
+}
+
+func TestScanTrivia(t *testing.T) {
+	input := "let a = 1; // one\r\n\t/* two */ a\n"
+
+	l := NewWithMode(input, ScanTrivia)
+
+	var out string
+	var types []token.TokenType
+	for tok := range l.All() {
+		out += tok.Literal
+		types = append(types, tok.Type)
+	}
+
+	// The trivia tokens and the regular tokens reproduce the input
+	if out != input {
+		t.Errorf("expected the tokens to reproduce %q, got %q", input, out)
+	}
+
+	expected := []token.TokenType{
+		token.LET, token.WHITESPACE, token.IDENT, token.WHITESPACE, token.ASSIGN,
+		token.WHITESPACE, token.INT, token.SEMICOLON, token.WHITESPACE, token.COMMENT,
+		token.NEWLINE, token.WHITESPACE, token.COMMENT, token.WHITESPACE, token.IDENT,
+		token.NEWLINE,
+	}
+	if len(types) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, types)
+	}
+	for i, tt := range types {
+		if tt != expected[i] {
+			t.Errorf("token %d expected=%s, got=%s", i, expected[i], tt)
+		}
+	}
+}
+
+func TestSaveRestore(t *testing.T) {
+	l := New("a\nb c")
+	l.NextToken()
+
+	state := l.Save()
+	b := l.NextToken()
+	l.NextToken()
+
+	l.Restore(state)
+	again := l.NextToken()
+	if again.Literal != b.Literal || again.Line != b.Line || again.Column != b.Column ||
+		again.Pos != b.Pos || again.NewlineBefore != b.NewlineBefore {
+		t.Errorf("expected %+v after restore, got %+v", b, again)
+	}
+}
+
+func TestNewReader(t *testing.T) {
+	input := `let re = /a+b/g; // comment
+	let s = "hello world";
+	console.log(s);`
+
+	// Reading one byte at a time exercises every buffer boundary
+	fromReader := NewReader(iotest.OneByteReader(strings.NewReader(input)), 0)
+	fromString := New(input)
+
+	for {
+		expected := fromString.NextToken()
+		tok := fromReader.NextToken()
+
+		if tok.Type != expected.Type || tok.Literal != expected.Literal || tok.Pos != expected.Pos {
+			t.Fatalf("expected %s %q at %d, got %s %q at %d",
+				expected.Type, expected.Literal, expected.Pos, tok.Type, tok.Literal, tok.Pos)
+		}
+		if tok.Type == token.SLASH {
+			fromString.NextToken()
+			fromReader.NextToken()
+			expected = fromString.ReScanRegExp(expected)
+			tok = fromReader.ReScanRegExp(tok)
+			if tok.Literal != expected.Literal {
+				t.Fatalf("expected regexp %q, got %q", expected.Literal, tok.Literal)
+			}
+		}
+		if tok.Type == token.EOF {
+			break
+		}
+	}
+
+	if err := fromReader.Err(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	failing := NewReader(iotest.ErrReader(errors.New("disk on fire")), 0)
+	if tok := failing.NextToken(); tok.Type != token.EOF {
+		t.Errorf("expected EOF from a failing reader, got %s", tok.Type)
+	}
+	if failing.Err() == nil || failing.Err().Error() != "disk on fire" {
+		t.Errorf("expected the read error, got %v", failing.Err())
+	}
+}
+
+func TestNextTokenExtendedOperators(t *testing.T) {
+	input := `a ** b **= c ?? d ??= e ||= f &&= g === h !== i <= j %= k << l >>> m ~n ++ -- ? :`
+
+	expected := []token.TokenType{
+		token.IDENT, token.EXPONENT, token.IDENT, token.EXPONENT_ASSIGN, token.IDENT,
+		token.NULLISH, token.IDENT, token.NULLISH_ASSIGN, token.IDENT, token.OR_ASSIGN,
+		token.IDENT, token.AND_ASSIGN, token.IDENT, token.STRICT_EQ, token.IDENT,
+		token.STRICT_NOT_EQ, token.IDENT, token.LT_EQ, token.IDENT, token.PERCENT_ASSIGN,
+		token.IDENT, token.LSHIFT, token.IDENT, token.GT, token.GT, token.GT, token.IDENT,
+		token.TILDE, token.IDENT, token.INCREMENT, token.DECREMENT, token.QUESTION,
+		token.COLON, token.EOF,
+	}
+
+	l := New(input)
+	for i, tt := range expected {
+		tok := l.NextToken()
+		if tok.Type != tt {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%s, got=%s (%q)", i, tt, tok.Type, tok.Literal)
+		}
+	}
+}
+
+func TestReScanGreater(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected token.TokenType
+		literal  string
+	}{
+		{"> 1", token.GT, ">"},
+		{">= 1", token.GT_EQ, ">="},
+		{">> 1", token.RSHIFT, ">>"},
+		{">>= 1", token.RSHIFT_ASSIGN, ">>="},
+		{">>> 1", token.URSHIFT, ">>>"},
+		{">>>= 1", token.URSHIFT_ASSIGN, ">>>="},
+	}
+
+	for _, tt := range tests {
+		l := New(tt.input)
+		tok := l.ReScanGreater(l.NextToken())
+		if tok.Type != tt.expected || tok.Literal != tt.literal {
+			t.Errorf("%q: expected %s %q, got %s %q", tt.input, tt.expected, tt.literal, tok.Type, tok.Literal)
+		}
+
+		next := l.NextToken()
+		if next.Type != token.INT {
+			t.Errorf("%q: expected INT after rescanning, got %s", tt.input, next.Type)
+		}
+	}
+}
+
+func TestOptionalChainToken(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []token.TokenType
+	}{
+		{"a?.b", []token.TokenType{token.IDENT, token.QUESTION_DOT, token.IDENT}},
+		{"a?.[0]", []token.TokenType{token.IDENT, token.QUESTION_DOT, token.LBRACKET, token.INT, token.RBRACKET}},
+		{"a?.5:1", []token.TokenType{token.IDENT, token.QUESTION, token.DOT, token.INT, token.COLON, token.INT}},
+		{"a ?? null", []token.TokenType{token.IDENT, token.NULLISH, token.NULL}},
+	}
+
+	for _, tt := range tests {
+		l := New(tt.input)
+		for i, expected := range tt.expected {
+			if tok := l.NextToken(); tok.Type != expected {
+				t.Errorf("%q: tokens[%d] expected=%s, got=%s", tt.input, i, expected, tok.Type)
+			}
+		}
+	}
+}
+
+func TestTokenRange(t *testing.T) {
+	input := "let ab = \"x\";\n**= c"
+	expected := []struct {
+		typ          token.TokenType
+		pos, end     int
+		line, column int
+	}{
+		{token.LET, 0, 3, 1, 1},
+		{token.IDENT, 4, 6, 1, 5},
+		{token.ASSIGN, 7, 8, 1, 8},
+		{token.STRING, 9, 12, 1, 10},
+		{token.SEMICOLON, 12, 13, 1, 13},
+		{token.EXPONENT_ASSIGN, 14, 17, 2, 1},
+		{token.IDENT, 18, 19, 2, 5},
+		{token.EOF, 19, 19, 2, 6},
+	}
+
+	l := New(input)
+	for i, e := range expected {
+		tok := l.NextToken()
+		if tok.Type != e.typ || tok.Pos != e.pos || tok.End != e.end || tok.Line != e.line || tok.Column != e.column {
+			t.Errorf("tokens[%d]: expected %s [%d,%d) at %d:%d, got %s [%d,%d) at %d:%d", i,
+				e.typ, e.pos, e.end, e.line, e.column, tok.Type, tok.Pos, tok.End, tok.Line, tok.Column)
+		}
+	}
+}
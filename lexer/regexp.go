@@ -0,0 +1,606 @@
+package lexer
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/dmarro89/ts-go-compiler/token"
+)
+
+// regExpMessage is an error of TypeScript's diagnostic catalog found in a
+// regular expression. Its text is a format for the arguments of the error.
+type regExpMessage struct {
+	code int
+	text string
+}
+
+var (
+	errUnknownFlag          = regExpMessage{1499, "Unknown regular expression flag."}
+	errDuplicateFlag        = regExpMessage{1500, "Duplicate regular expression flag."}
+	errUnicodeFlags         = regExpMessage{1502, "The Unicode (u) flag and the Unicode Sets (v) flag cannot be set simultaneously."}
+	errNumbersOutOfOrder    = regExpMessage{1506, "Numbers out of order in quantifier."}
+	errNothingToRepeat      = regExpMessage{1507, "There is nothing available for repetition."}
+	errUnexpectedCharacter  = regExpMessage{1508, "Unexpected '%c'. Did you mean to escape it with backslash?"}
+	errControlLetter        = regExpMessage{1512, "'\\c' must be followed by an ASCII letter."}
+	errUndeterminedEscape   = regExpMessage{1513, "Undetermined character escape."}
+	errGroupNameExpected    = regExpMessage{1514, "Expected a capturing group name."}
+	errDuplicateGroupName   = regExpMessage{1515, "Named capturing groups with the same name must be mutually exclusive to each other."}
+	errClassRangeBound      = regExpMessage{1516, "A character class range must not be bounded by another character class."}
+	errRangeOutOfOrder      = regExpMessage{1517, "Range out of order in character class."}
+	errPropertyNameExpected = regExpMessage{1523, "Expected a Unicode property name."}
+	errNoGroupNamed         = regExpMessage{1532, "There is no capturing group named '%s' in this regular expression."}
+	errNoSuchGroup          = regExpMessage{1533, "This backreference refers to a group that does not exist. There are only %d capturing groups in this regular expression."}
+	errInvalidEscape        = regExpMessage{1535, "This character cannot be escaped in a regular expression. If you meant to escape it as part of a character class, try escaping it with a backslash instead."}
+	errHexDigitExpected     = regExpMessage{1125, "Hexadecimal digit expected."}
+	errExpected             = regExpMessage{1005, "'%c' expected."}
+)
+
+// RegExpError describes a problem found in a regular expression literal
+type RegExpError struct {
+	Pos     int // byte offset inside the literal, counting the opening '/'
+	Code    int // the code of the error in TypeScript's diagnostic catalog
+	Message string
+}
+
+func newRegExpError(pos int, m regExpMessage, args ...any) RegExpError {
+	return RegExpError{Pos: pos, Code: m.code, Message: fmt.Sprintf(m.text, args...)}
+}
+
+func (e RegExpError) Error() string {
+	return e.Message
+}
+
+// ReScanRegExp rescans a SLASH token as the start of a regular expression
+// literal. Only the parser knows whether a '/' begins an expression, so it
+// calls this when it finds a slash in prefix position. The lexer is moved
+// back to the slash and left just after the literal's flags.
+//
+// An unterminated literal is returned as an ILLEGAL token holding the text
+// scanned so far.
+func (l *Lexer) ReScanRegExp(tok token.Token) token.Token {
+	l.resetTo(tok)
+	l.readChar() // skip the opening '/'
+
+	inClass := false
+	for {
+		if l.ch == 0 || isLineTerminator(l.ch) {
+			tok.Type = token.ILLEGAL
+			tok.Literal = l.text(tok.Pos, l.position)
+			tok.End = l.position
+			return tok
+		}
+		if l.ch == '\\' {
+			l.readChar()
+			if l.ch == 0 || isLineTerminator(l.ch) {
+				continue
+			}
+		} else if l.ch == '[' {
+			inClass = true
+		} else if l.ch == ']' {
+			inClass = false
+		} else if l.ch == '/' && !inClass {
+			break
+		}
+		l.readChar()
+	}
+
+	l.readChar() // skip the closing '/'
+	for isLetter(l.ch) || isDigit(l.ch) {
+		l.readChar()
+	}
+
+	tok.Type = token.REGEXP
+	tok.Literal = l.text(tok.Pos, l.position)
+	tok.End = l.position
+	return tok
+}
+
+// resetTo moves the lexer back to the first character of tok
+func (l *Lexer) resetTo(tok token.Token) {
+	l.position = tok.Pos
+	l.readPosition = tok.Pos
+	l.line = tok.Line
+	l.column = tok.Column - 1
+	l.readChar()
+}
+
+func isLineTerminator(ch byte) bool {
+	return ch == '\n' || ch == '\r'
+}
+
+// SplitRegExp splits the literal /pattern/flags into its pattern and flags
+func SplitRegExp(literal string) (pattern string, flags string) {
+	end := strings.LastIndexByte(literal, '/')
+	if end <= 0 {
+		return strings.TrimPrefix(literal, "/"), ""
+	}
+	return literal[1:end], literal[end+1:]
+}
+
+// ValidateRegExp checks the flags and the pattern syntax of a regular
+// expression literal such as /ab+c/gi and returns every problem found.
+func ValidateRegExp(literal string) []RegExpError {
+	pattern, flags := SplitRegExp(literal)
+	flagsPos := len(literal) - len(flags)
+
+	var errs []RegExpError
+	seen := map[byte]bool{}
+	for i := 0; i < len(flags); i++ {
+		f := flags[i]
+		switch {
+		case !strings.ContainsRune("dgimsuvy", rune(f)):
+			errs = append(errs, newRegExpError(flagsPos+i, errUnknownFlag))
+		case seen[f]:
+			errs = append(errs, newRegExpError(flagsPos+i, errDuplicateFlag))
+		case (f == 'u' && seen['v']) || (f == 'v' && seen['u']):
+			errs = append(errs, newRegExpError(flagsPos+i, errUnicodeFlags))
+		}
+		seen[f] = true
+	}
+
+	v := &regExpValidator{
+		pattern:     pattern,
+		offset:      1,
+		unicodeMode: seen['u'] || seen['v'],
+		groupNames:  map[string]bool{},
+	}
+	v.validate()
+
+	return append(errs, v.errors...)
+}
+
+// regExpValidator is a small recursive descent parser over the pattern
+// grammar of ECMAScript regular expressions. Outside of Unicode mode it
+// accepts the web-compatibility extensions of Annex B, like engines do.
+type regExpValidator struct {
+	pattern     string
+	pos         int
+	offset      int
+	unicodeMode bool
+	errors      []RegExpError
+
+	groupCount      int
+	groupNames      map[string]bool
+	namedReferences []regExpReference
+	maxBackref      regExpReference
+}
+
+type regExpReference struct {
+	pos   int
+	name  string
+	index int
+}
+
+func (v *regExpValidator) validate() {
+	v.scanDisjunction()
+	for v.pos < len(v.pattern) {
+		// Only a stray ')' can stop the top-level disjunction
+		v.error(v.pos, errUnexpectedCharacter, ')')
+		v.pos++
+		v.scanDisjunction()
+	}
+
+	for _, ref := range v.namedReferences {
+		if !v.groupNames[ref.name] {
+			v.error(ref.pos, errNoGroupNamed, ref.name)
+		}
+	}
+	if v.unicodeMode && v.maxBackref.index > v.groupCount {
+		v.error(v.maxBackref.pos, errNoSuchGroup, v.groupCount)
+	}
+}
+
+func (v *regExpValidator) error(pos int, m regExpMessage, args ...any) {
+	v.errors = append(v.errors, newRegExpError(v.offset+pos, m, args...))
+}
+
+func (v *regExpValidator) peek() byte {
+	if v.pos >= len(v.pattern) {
+		return 0
+	}
+	return v.pattern[v.pos]
+}
+
+func (v *regExpValidator) scanDisjunction() {
+	v.scanAlternative()
+	for v.peek() == '|' {
+		v.pos++
+		v.scanAlternative()
+	}
+}
+
+func (v *regExpValidator) scanAlternative() {
+	for v.pos < len(v.pattern) {
+		start := v.pos
+		quantifiable := true
+
+		switch ch := v.pattern[v.pos]; ch {
+		case '|', ')':
+			return
+		case '^', '$':
+			v.pos++
+			quantifiable = false
+		case '\\':
+			v.pos++
+			if v.peek() == 'b' || v.peek() == 'B' {
+				v.pos++
+				quantifiable = false
+			} else {
+				v.scanAtomEscape(start)
+			}
+		case '(':
+			quantifiable = v.scanGroup()
+		case '[':
+			v.scanCharacterClass()
+		case '*', '+', '?':
+			v.error(start, errNothingToRepeat)
+			v.pos++
+			continue
+		case '{':
+			if v.scanBracedQuantifier(start) {
+				v.error(start, errNothingToRepeat)
+				continue
+			}
+			if v.unicodeMode {
+				v.error(start, errUnexpectedCharacter, '{')
+			}
+			v.pos++
+		case ']', '}':
+			if v.unicodeMode {
+				v.error(start, errUnexpectedCharacter, ch)
+			}
+			v.pos++
+		default:
+			v.pos++
+		}
+
+		v.scanQuantifier(quantifiable)
+	}
+}
+
+// scanQuantifier consumes an optional quantifier following an atom
+func (v *regExpValidator) scanQuantifier(quantifiable bool) {
+	start := v.pos
+	switch v.peek() {
+	case '*', '+', '?':
+		v.pos++
+	case '{':
+		if !v.scanBracedQuantifier(start) {
+			return
+		}
+	default:
+		return
+	}
+
+	if !quantifiable {
+		v.error(start, errNothingToRepeat)
+	}
+	if v.peek() == '?' {
+		v.pos++ // lazy quantifier
+	}
+}
+
+// scanBracedQuantifier consumes {n}, {n,} or {n,m}. Outside of Unicode mode a
+// brace that does not start a valid quantifier is a literal character, so
+// nothing is consumed and false is returned.
+func (v *regExpValidator) scanBracedQuantifier(start int) bool {
+	i := start + 1
+	min, i := v.scanDigits(i)
+	if min == "" {
+		return false
+	}
+	max := min
+	if i < len(v.pattern) && v.pattern[i] == ',' {
+		max, i = v.scanDigits(i + 1)
+	}
+	if i >= len(v.pattern) || v.pattern[i] != '}' {
+		return false
+	}
+	v.pos = i + 1
+
+	if max != "" {
+		lo, _ := strconv.ParseFloat(min, 64)
+		hi, _ := strconv.ParseFloat(max, 64)
+		if lo > hi {
+			v.error(start, errNumbersOutOfOrder)
+		}
+	}
+	return true
+}
+
+func (v *regExpValidator) scanDigits(i int) (string, int) {
+	start := i
+	for i < len(v.pattern) && isDigit(v.pattern[i]) {
+		i++
+	}
+	return v.pattern[start:i], i
+}
+
+// scanGroup consumes a parenthesized group and reports whether a quantifier
+// may follow it
+func (v *regExpValidator) scanGroup() bool {
+	v.pos++ // skip '('
+	quantifiable := true
+
+	if v.peek() == '?' {
+		v.pos++
+		switch v.peek() {
+		case ':':
+			v.pos++
+		case '=', '!':
+			v.pos++
+			// Annex B allows quantified lookaheads outside of Unicode mode
+			quantifiable = !v.unicodeMode
+		case '<':
+			v.pos++
+			if v.peek() == '=' || v.peek() == '!' {
+				v.pos++
+				quantifiable = false
+			} else {
+				v.scanGroupName()
+				v.groupCount++
+			}
+		default:
+			v.scanModifiers()
+		}
+	} else {
+		v.groupCount++
+	}
+
+	v.scanDisjunction()
+
+	if v.peek() != ')' {
+		v.error(v.pos, errExpected, ')')
+		return quantifiable
+	}
+	v.pos++
+	return quantifiable
+}
+
+// scanModifiers consumes the flags of a modifiers group like (?i-m:...)
+func (v *regExpValidator) scanModifiers() {
+	seen := map[byte]bool{}
+	for v.pos < len(v.pattern) {
+		ch := v.pattern[v.pos]
+		switch {
+		case ch == ':':
+			v.pos++
+			return
+		case ch == '-':
+		case ch == 'i' || ch == 'm' || ch == 's':
+			if seen[ch] {
+				v.error(v.pos, errDuplicateFlag)
+			}
+			seen[ch] = true
+		case isLetter(ch):
+			v.error(v.pos, errUnknownFlag)
+		default:
+			v.error(v.pos, errExpected, ':')
+			return
+		}
+		v.pos++
+	}
+	v.error(v.pos, errExpected, ':')
+}
+
+// hasNamedGroups reports whether the pattern declares a named group, which
+// turns \k into a named backreference outside of Unicode mode
+func (v *regExpValidator) hasNamedGroups() bool {
+	rest := v.pattern
+	for {
+		i := strings.Index(rest, "(?<")
+		if i < 0 {
+			return false
+		}
+		rest = rest[i+3:]
+		if rest != "" && rest[0] != '=' && rest[0] != '!' {
+			return true
+		}
+	}
+}
+
+func (v *regExpValidator) scanGroupName() {
+	start := v.pos
+	name, ok := v.scanName()
+	if !ok {
+		v.error(start, errGroupNameExpected)
+		return
+	}
+	if v.groupNames[name] {
+		v.error(start, errDuplicateGroupName)
+	}
+	v.groupNames[name] = true
+}
+
+// scanName consumes name> and returns the name
+func (v *regExpValidator) scanName() (string, bool) {
+	start := v.pos
+	for v.pos < len(v.pattern) && (isLetter(v.pattern[v.pos]) || isDigit(v.pattern[v.pos]) || v.pattern[v.pos] == '$') {
+		v.pos++
+	}
+	name := v.pattern[start:v.pos]
+	if name == "" || isDigit(name[0]) || v.peek() != '>' {
+		return "", false
+	}
+	v.pos++
+	return name, true
+}
+
+// scanAtomEscape consumes the escape sequence after a '\' outside a class
+func (v *regExpValidator) scanAtomEscape(start int) {
+	ch := v.peek()
+	switch {
+	case ch == 0:
+		v.error(start, errUndeterminedEscape)
+	case ch == 'k' && (v.unicodeMode || v.hasNamedGroups()):
+		v.pos++
+		if v.peek() != '<' {
+			v.error(start, errGroupNameExpected)
+			return
+		}
+		v.pos++
+		nameStart := v.pos
+		name, ok := v.scanName()
+		if !ok {
+			v.error(nameStart, errGroupNameExpected)
+			return
+		}
+		v.namedReferences = append(v.namedReferences, regExpReference{pos: start, name: name})
+	case ch >= '1' && ch <= '9':
+		digits, end := v.scanDigits(v.pos)
+		v.pos = end
+		n, _ := strconv.Atoi(digits)
+		if n > v.maxBackref.index {
+			v.maxBackref = regExpReference{pos: start, index: n}
+		}
+	default:
+		v.scanCharacterEscape(start)
+	}
+}
+
+// scanCharacterEscape consumes escapes valid both inside and outside classes
+func (v *regExpValidator) scanCharacterEscape(start int) {
+	ch := v.peek()
+	v.pos++
+
+	switch ch {
+	case 'd', 'D', 's', 'S', 'w', 'W', 'f', 'n', 'r', 't', 'v', '0':
+	case 'c':
+		if isLetter(v.peek()) && v.peek() != '_' {
+			v.pos++
+		} else if v.unicodeMode {
+			v.error(start, errControlLetter)
+		}
+	case 'x':
+		if !v.scanHexDigits(2) && v.unicodeMode {
+			v.error(start, errHexDigitExpected)
+		}
+	case 'u':
+		if v.peek() == '{' && v.unicodeMode {
+			v.pos++
+			digits := v.pos
+			for isHexDigit(v.peek()) {
+				v.pos++
+			}
+			if v.pos == digits || v.peek() != '}' {
+				v.error(start, errHexDigitExpected)
+				return
+			}
+			v.pos++
+		} else if !v.scanHexDigits(4) && v.unicodeMode {
+			v.error(start, errHexDigitExpected)
+		}
+	case 'p', 'P':
+		if !v.unicodeMode {
+			return
+		}
+		if v.peek() != '{' {
+			v.error(start, errPropertyNameExpected)
+			return
+		}
+		end := strings.IndexByte(v.pattern[v.pos:], '}')
+		if end <= 1 {
+			v.error(start, errPropertyNameExpected)
+			return
+		}
+		v.pos += end + 1
+	default:
+		if v.unicodeMode && !strings.ContainsRune(`^$\.*+?()[]{}|/-`, rune(ch)) {
+			v.error(start, errInvalidEscape)
+		}
+	}
+}
+
+func (v *regExpValidator) scanHexDigits(n int) bool {
+	for i := 0; i < n; i++ {
+		if v.pos+i >= len(v.pattern) || !isHexDigit(v.pattern[v.pos+i]) {
+			return false
+		}
+	}
+	v.pos += n
+	return true
+}
+
+// scanCharacterClass consumes [...] and checks the order of its ranges
+func (v *regExpValidator) scanCharacterClass() {
+	start := v.pos
+	v.pos++ // skip '['
+	if v.peek() == '^' {
+		v.pos++
+	}
+
+	for v.pos < len(v.pattern) && v.pattern[v.pos] != ']' {
+		lowPos := v.pos
+		low, lowOK := v.scanClassAtom()
+		if v.peek() != '-' || v.pos+1 >= len(v.pattern) || v.pattern[v.pos+1] == ']' {
+			continue
+		}
+		v.pos++ // skip '-'
+		high, highOK := v.scanClassAtom()
+		if !lowOK || !highOK {
+			if v.unicodeMode {
+				v.error(lowPos, errClassRangeBound)
+			}
+			continue
+		}
+		if low > high {
+			v.error(lowPos, errRangeOutOfOrder)
+		}
+	}
+
+	if v.peek() != ']' {
+		v.error(start, errExpected, ']')
+		return
+	}
+	v.pos++
+}
+
+// scanClassAtom consumes one class atom and returns the code unit it stands
+// for; ok is false for class escapes such as \d that match a set
+func (v *regExpValidator) scanClassAtom() (rune, bool) {
+	start := v.pos
+	ch := v.pattern[v.pos]
+	if ch != '\\' {
+		v.pos++
+		return rune(ch), true
+	}
+
+	v.pos++
+	esc := v.peek()
+	switch esc {
+	case 0:
+		v.error(start, errUndeterminedEscape)
+		return 0, false
+	case 'd', 'D', 's', 'S', 'w', 'W', 'p', 'P':
+		v.scanCharacterEscape(start)
+		return 0, false
+	case 'b':
+		v.pos++
+		return '\b', true
+	case '-':
+		v.pos++
+		return '-', true
+	case 'n':
+		v.pos++
+		return '\n', true
+	case 't':
+		v.pos++
+		return '\t', true
+	case 'r':
+		v.pos++
+		return '\r', true
+	case 'x', 'u':
+		v.scanCharacterEscape(start)
+		if n, err := strconv.ParseUint(strings.Trim(v.pattern[start+2:v.pos], "{}"), 16, 32); err == nil {
+			return rune(n), true
+		}
+		return rune(esc), true
+	}
+	v.scanCharacterEscape(start)
+	return rune(esc), true
+}
+
+func isHexDigit(ch byte) bool {
+	return isDigit(ch) || (ch >= 'a' && ch <= 'f') || (ch >= 'A' && ch <= 'F')
+}
@@ -0,0 +1,116 @@
+package lexer
+
+import (
+	"testing"
+
+	"github.com/dmarro89/ts-go-compiler/token"
+)
+
+func TestReScanRegExp(t *testing.T) {
+	input := `let re = /[/]a\/b+c/gi;`
+
+	l := New(input)
+	for i := 0; i < 3; i++ {
+		l.NextToken()
+	}
+
+	slash := l.NextToken()
+	if slash.Type != token.SLASH {
+		t.Fatalf("expected SLASH, got %q", slash.Literal)
+	}
+
+	// The lexer has already scanned past the slash, as the parser's peek would
+	l.NextToken()
+
+	tok := l.ReScanRegExp(slash)
+	if tok.Type != token.REGEXP {
+		t.Fatalf("expected REGEXP, got %q", tok.Literal)
+	}
+	if tok.Literal != `/[/]a\/b+c/gi` {
+		t.Errorf("literal wrong. got=%q", tok.Literal)
+	}
+	if tok.Line != 1 || tok.Column != 10 || tok.Pos != 9 {
+		t.Errorf("position wrong. got line=%d column=%d pos=%d", tok.Line, tok.Column, tok.Pos)
+	}
+
+	next := l.NextToken()
+	if next.Type != token.SEMICOLON {
+		t.Errorf("expected SEMICOLON after regexp, got %q", next.Literal)
+	}
+}
+
+func TestReScanUnterminatedRegExp(t *testing.T) {
+	l := New("/abc\nlet")
+	tok := l.ReScanRegExp(l.NextToken())
+	if tok.Type != token.ILLEGAL {
+		t.Fatalf("expected ILLEGAL, got %q", tok.Literal)
+	}
+	if next := l.NextToken(); next.Type != token.LET {
+		t.Errorf("expected scanning to resume on the next line, got %q", next.Literal)
+	}
+}
+
+func TestValidateRegExp(t *testing.T) {
+	tests := []struct {
+		literal  string
+		expected []string
+	}{
+		{`/ab+c/gi`, nil},
+		{`/(?<year>\d{4})-\k<year>/u`, nil},
+		{`/[a-z0-9_]{2,8}?$/`, nil},
+		{`/a{,5}/`, nil}, // a literal brace outside of Unicode mode
+		{`/(?<=\$)\d+(?!px)/`, nil},
+		{`/abc/x`, []string{"Unknown regular expression flag."}},
+		{`/abc/gg`, []string{"Duplicate regular expression flag."}},
+		{`/abc/uv`, []string{"The Unicode (u) flag and the Unicode Sets (v) flag cannot be set simultaneously."}},
+		{`/*a/`, []string{"There is nothing available for repetition."}},
+		{`/^*/`, []string{"There is nothing available for repetition."}},
+		{`/a{5,2}/`, []string{"Numbers out of order in quantifier."}},
+		{`/(ab/`, []string{"')' expected."}},
+		{`/ab)/`, []string{"Unexpected ')'. Did you mean to escape it with backslash?"}},
+		{`/[z-a]/`, []string{"Range out of order in character class."}},
+		{`/(?<a>x)(?<a>y)/`, []string{"Named capturing groups with the same name must be mutually exclusive to each other."}},
+		{`/(?<a>x)\k<b>/`, []string{"There is no capturing group named 'b' in this regular expression."}},
+		{`/(a)\2/u`, []string{"This backreference refers to a group that does not exist. There are only 1 capturing groups in this regular expression."}},
+		{`/\a/u`, []string{"This character cannot be escaped in a regular expression. If you meant to escape it as part of a character class, try escaping it with a backslash instead."}},
+		{`/a{/u`, []string{"Unexpected '{'. Did you mean to escape it with backslash?"}},
+		{`/(?i:a)/`, nil},
+		{`/(?x)/`, []string{"Unknown regular expression flag.", "':' expected."}},
+	}
+
+	for _, tt := range tests {
+		errs := ValidateRegExp(tt.literal)
+		if len(errs) != len(tt.expected) {
+			t.Errorf("%s: expected %d errors, got %v", tt.literal, len(tt.expected), errs)
+			continue
+		}
+		for i, err := range errs {
+			if err.Message != tt.expected[i] {
+				t.Errorf("%s: expected error %q, got %q", tt.literal, tt.expected[i], err.Message)
+			}
+			if err.Code == 0 {
+				t.Errorf("%s: error %q has no code", tt.literal, err.Message)
+			}
+		}
+	}
+}
+
+func TestRegExpErrorCodes(t *testing.T) {
+	tests := []struct {
+		literal string
+		code    int
+	}{
+		{`/a)/`, 1508},
+		{`/(?<n>a)\k<m>/`, 1532},
+		{`/(?x)/`, 1499},
+		{`/\q/u`, 1535},
+		{`/[a/`, 1005},
+	}
+
+	for _, tt := range tests {
+		errs := ValidateRegExp(tt.literal)
+		if len(errs) == 0 || errs[0].Code != tt.code {
+			t.Errorf("%s: expected code %d, got %v", tt.literal, tt.code, errs)
+		}
+	}
+}
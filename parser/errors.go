@@ -0,0 +1,144 @@
+package parser
+
+import (
+	"fmt"
+
+	"github.com/dmarro89/ts-go-compiler/diagnostics"
+	"github.com/dmarro89/ts-go-compiler/lexer"
+	"github.com/dmarro89/ts-go-compiler/token"
+)
+
+// message is a syntax error of TypeScript's diagnostic catalog. Its text is
+// a format for the arguments of the error.
+type message struct {
+	code int
+	text string
+}
+
+var (
+	errExpected                 = message{1005, "'%s' expected."}
+	errIdentifierExpected       = message{1003, "Identifier expected."}
+	errReservedWord             = message{1359, "Identifier expected. '%s' is a reserved word that cannot be used here."}
+	errExpressionExpected       = message{1109, "Expression expected."}
+	errTypeExpected             = message{1110, "Type expected."}
+	errInvalidCharacter         = message{1127, "Invalid character."}
+	errUnexpectedToken          = message{1012, "Unexpected token."}
+	errLineBreak                = message{1142, "Line break not permitted here."}
+	errUnterminatedRegExp       = message{1161, "Unterminated regular expression literal."}
+	errInvalidNumber            = message{0, "Numeric literal '%s' cannot be represented."}
+	errPropertyExpected         = message{1136, "Property assignment expected."}
+	errUnaryExponent            = message{17006, "An unary expression with the '%s' operator is not allowed in the left-hand side of an exponentiation expression. Consider enclosing the expression in parentheses."}
+	errMixedNullish             = message{5076, "'%s' and '%s' operations cannot be mixed without parentheses."}
+	errAwaitOutsideAsync        = message{1308, "'await' expressions are only allowed within async functions and at the top levels of modules."}
+	errForAwaitOutsideAsync     = message{1103, "'for await' loops are only allowed within async functions and at the top levels of modules."}
+	errYieldOutsideGenerator    = message{1163, "A 'yield' expression is only allowed in a generator body."}
+	errRestParameterDefault     = message{1048, "A rest parameter cannot have an initializer."}
+	errRestParameterLast        = message{1014, "A rest parameter must be last in a parameter list."}
+	errRestElementLast          = message{2462, "A rest element must be last in a destructuring pattern."}
+	errThisParameterFirst       = message{2680, "A 'this' parameter must be the first parameter."}
+	errInvalidAssignmentTarget  = message{2364, "The left-hand side of an assignment expression must be a variable or a property access."}
+	errInvalidMetaProperty      = message{17012, "'%s' is not a valid meta-property for keyword 'new'. Did you mean 'target'?"}
+	errNewTargetOutside         = message{17013, "Meta-property 'new.target' is only allowed in the body of a function declaration, function expression, or constructor."}
+	errSuperAlone               = message{1034, "'super' must be followed by an argument list or member access."}
+	errDuplicateDefault         = message{1113, "A 'default' clause cannot appear more than once in a 'switch' statement."}
+	errCaseExpected             = message{1130, "'case' or 'default' expected."}
+	errCatchExpected            = message{1472, "'catch' or 'finally' expected."}
+	errBreakOutside             = message{1105, "A 'break' statement can only be used within an enclosing iteration or switch statement."}
+	errConstInitializer         = message{1155, "'%s' declarations must be initialized."}
+	errDestructuringInitializer = message{1182, "A destructuring declaration must have an initializer."}
+	errDefiniteWithInitializer  = message{1263, "Declarations with initializers cannot also have definite assignment assertions."}
+	errDefiniteWithoutType      = message{1264, "Declarations with definite assignment assertions must also have type annotations."}
+	errContinueOutside          = message{1104, "A 'continue' statement can only be used within an enclosing iteration statement."}
+	errDuplicateLabel           = message{1114, "Duplicate label '%s'."}
+	errBreakLabel               = message{1116, "A 'break' statement can only jump to a label of an enclosing statement."}
+	errContinueLabel            = message{1115, "A 'continue' statement can only jump to a label of an enclosing iteration statement."}
+	errArrowFunction            = message{0, "Arrow functions are not supported. Use a function expression instead."}
+)
+
+// reservedWords are the reserved words the lexer reads as identifiers,
+// which cannot name a declaration
+var reservedWords = map[string]bool{
+	"class": true, "debugger": true, "do": true, "enum": true, "export": true,
+	"extends": true, "import": true, "while": true, "with": true,
+}
+
+// Diagnostics returns the syntax errors found, in the order they were found
+func (p *Parser) Diagnostics() []diagnostics.Diagnostic {
+	return p.diagnostics
+}
+
+// Errors returns the messages of the syntax errors found
+func (p *Parser) Errors() []string {
+	messages := make([]string, len(p.diagnostics))
+	for i, d := range p.diagnostics {
+		messages[i] = d.Message
+	}
+	return messages
+}
+
+// errorAt reports an error spanning tok
+func (p *Parser) errorAt(tok token.Token, m message, args ...any) {
+	p.errorRange(tok, tok.End, m, args...)
+}
+
+// errorRange reports an error spanning from the start of tok to end
+func (p *Parser) errorRange(tok token.Token, end int, m message, args ...any) {
+	p.diagnostics = append(p.diagnostics, diagnostics.Diagnostic{
+		Code:    m.code,
+		Message: fmt.Sprintf(m.text, args...),
+		Pos:     tok.Pos,
+		End:     end,
+		Line:    tok.Line,
+		Column:  tok.Column,
+	})
+}
+
+// regExpError reports an error lexer.ValidateRegExp found in the literal
+// tok, at the character it points to
+func (p *Parser) regExpError(tok token.Token, err lexer.RegExpError) {
+	p.diagnostics = append(p.diagnostics, diagnostics.Diagnostic{
+		Code:    err.Code,
+		Message: err.Message,
+		Pos:     tok.Pos + err.Pos,
+		End:     tok.Pos + err.Pos + 1,
+		Line:    tok.Line,
+		Column:  tok.Column + err.Pos,
+	})
+}
+
+// peekError reports that peekToken is not the expected t. An identifier
+// expected where a reserved word is found says so.
+func (p *Parser) peekError(t token.TokenType) {
+	switch {
+	case t != token.IDENT:
+		p.errorAt(p.peekToken, errExpected, t.Text())
+	case isReserved(p.peekToken):
+		p.errorAt(p.peekToken, errReservedWord, p.peekToken.Literal)
+	default:
+		p.errorAt(p.peekToken, errIdentifierExpected)
+	}
+}
+
+// noPrefixParseFnError reports a token that cannot start an expression
+func (p *Parser) noPrefixParseFnError(tok token.Token) {
+	if tok.Type == token.ILLEGAL {
+		p.errorAt(tok, errInvalidCharacter)
+		return
+	}
+	p.errorAt(tok, errExpressionExpected)
+}
+
+// checkBindingName reports a declared name that is a reserved word
+func (p *Parser) checkBindingName(tok token.Token) {
+	if isReserved(tok) {
+		p.errorAt(tok, errReservedWord, tok.Literal)
+	}
+}
+
+// isReserved reports whether tok is a reserved word, such as if or class
+func isReserved(tok token.Token) bool {
+	if tok.Type == token.IDENT {
+		return reservedWords[tok.Literal]
+	}
+	return isIdentifierName(tok) && tok.Type != token.LET && tok.Type != token.CONSOLE && tok.Type != token.LOG
+}
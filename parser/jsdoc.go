@@ -0,0 +1,194 @@
+package parser
+
+import (
+	"strings"
+
+	"github.com/dmarro89/ts-go-compiler/ast"
+)
+
+// ParseJSDoc parses a /** */ comment into its description and block tags.
+// It returns nil for any other kind of comment. Tags whose {type} cannot be
+// parsed keep a nil Type, as JSDoc is documentation first.
+func ParseJSDoc(comment *ast.Comment) *ast.JSDoc {
+	text := comment.Text
+	if !strings.HasPrefix(text, "/**") || text == "/**/" {
+		return nil
+	}
+
+	doc := &ast.JSDoc{Comment: comment}
+
+	var description []string
+	var sections []string
+	for _, line := range jsdocLines(text) {
+		for _, part := range splitTags(line) {
+			switch {
+			case strings.HasPrefix(part, "@"):
+				sections = append(sections, part)
+			case len(sections) > 0:
+				sections[len(sections)-1] += "\n" + part
+			default:
+				description = append(description, part)
+			}
+		}
+	}
+	doc.Description = strings.TrimSpace(strings.Join(description, "\n"))
+
+	var typedef *ast.JSDocTypedefTag
+	for _, section := range sections {
+		tag := parseJSDocTag(section)
+
+		// @property tags describe the @typedef they follow
+		if prop, ok := tag.(*ast.JSDocPropertyTag); ok && typedef != nil {
+			typedef.Properties = append(typedef.Properties, prop)
+			continue
+		}
+		if t, ok := tag.(*ast.JSDocTypedefTag); ok {
+			typedef = t
+		}
+
+		doc.Tags = append(doc.Tags, tag)
+	}
+
+	return doc
+}
+
+// jsdocLines strips the comment delimiters and the leading * of each line
+func jsdocLines(text string) []string {
+	body := strings.TrimSuffix(strings.TrimPrefix(text, "/**"), "*/")
+
+	lines := strings.Split(body, "\n")
+	for i, line := range lines {
+		line = strings.TrimSpace(line)
+		line = strings.TrimPrefix(line, "*")
+		lines[i] = strings.TrimSpace(line)
+	}
+	return lines
+}
+
+// splitTags splits a line before each tag it holds, so that a comment can
+// put several tags on one line. An @ starts a tag after whitespace and
+// outside braces, which leaves {@link} and e-mail addresses alone.
+func splitTags(line string) []string {
+	var parts []string
+	depth, start := 0, 0
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+		case '@':
+			if depth == 0 && i > start && (line[i-1] == ' ' || line[i-1] == '\t') {
+				parts = append(parts, strings.TrimSpace(line[start:i]))
+				start = i
+			}
+		}
+	}
+	return append(parts, line[start:])
+}
+
+// parseJSDocTag parses one "@name rest" section
+func parseJSDocTag(section string) ast.JSDocTag {
+	name := section[1:]
+	rest := ""
+	if i := strings.IndexAny(name, " \t\n{"); i >= 0 {
+		name, rest = name[:i], strings.TrimSpace(name[i:])
+	}
+
+	switch name {
+	case "param", "arg", "argument":
+		typ, rest := parseJSDocTypeExpression(rest)
+		tag := &ast.JSDocParamTag{Type: typ}
+		tag.Name, tag.Optional, tag.Default, tag.Description = parseJSDocName(rest)
+		return tag
+	case "property", "prop":
+		typ, rest := parseJSDocTypeExpression(rest)
+		tag := &ast.JSDocPropertyTag{Type: typ}
+		tag.Name, tag.Optional, _, tag.Description = parseJSDocName(rest)
+		return tag
+	case "returns", "return":
+		typ, rest := parseJSDocTypeExpression(rest)
+		return &ast.JSDocReturnTag{Type: typ, Description: rest}
+	case "type":
+		typ, _ := parseJSDocTypeExpression(rest)
+		return &ast.JSDocTypeTag{Type: typ}
+	case "this":
+		typ, _ := parseJSDocTypeExpression(rest)
+		return &ast.JSDocThisTag{Type: typ}
+	case "template":
+		constraint, rest := parseJSDocTypeExpression(rest)
+		tag := &ast.JSDocTemplateTag{Constraint: constraint}
+		names, _, _ := strings.Cut(rest, "\n")
+		for _, n := range strings.Split(names, ",") {
+			if n = strings.TrimSpace(n); n != "" {
+				tag.TypeParameters = append(tag.TypeParameters, strings.Fields(n)[0])
+			}
+		}
+		return tag
+	case "deprecated":
+		return &ast.JSDocDeprecatedTag{Description: rest}
+	case "typedef":
+		typ, rest := parseJSDocTypeExpression(rest)
+		tag := &ast.JSDocTypedefTag{Type: typ}
+		if fields := strings.Fields(rest); len(fields) > 0 {
+			tag.Name = fields[0]
+		}
+		return tag
+	default:
+		return &ast.JSDocUnknownTag{Name: name, Text: rest}
+	}
+}
+
+// parseJSDocTypeExpression parses a leading {type} and returns the text after
+// it. The type is nil when there is none or it is malformed.
+func parseJSDocTypeExpression(text string) (ast.TypeNode, string) {
+	if !strings.HasPrefix(text, "{") {
+		return nil, text
+	}
+
+	depth := 0
+	for i, ch := range text {
+		switch ch {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				typ, errs := ParseJSDocType(text[1:i])
+				if len(errs) > 0 {
+					typ = nil
+				}
+				return typ, strings.TrimSpace(text[i+1:])
+			}
+		}
+	}
+
+	return nil, text
+}
+
+// parseJSDocName parses "name description" or "[name=default] description",
+// where the description may be introduced by a dash
+func parseJSDocName(text string) (name string, optional bool, def string, description string) {
+	if strings.HasPrefix(text, "[") {
+		end := strings.IndexByte(text, ']')
+		if end < 0 {
+			end = len(text)
+		}
+		name = text[1:end]
+		optional = true
+		if n, d, ok := strings.Cut(name, "="); ok {
+			name, def = strings.TrimSpace(n), strings.TrimSpace(d)
+		}
+		text = text[min(end+1, len(text)):]
+	} else {
+		end := strings.IndexAny(text, " \t\n")
+		if end < 0 {
+			end = len(text)
+		}
+		name, text = text[:end], text[end:]
+	}
+
+	description = strings.TrimSpace(text)
+	description = strings.TrimSpace(strings.TrimPrefix(description, "-"))
+	return name, optional, def, description
+}
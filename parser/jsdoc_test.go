@@ -0,0 +1,139 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/dmarro89/ts-go-compiler/ast"
+	"github.com/dmarro89/ts-go-compiler/lexer"
+)
+
+func TestParseJSDoc(t *testing.T) {
+	input := `/**
+ * Adds two numbers.
+ * Works with integers.
+ *
+ * @template T, U
+ * @param {number} a - the first operand
+ * @param {number} [b=1] the second operand
+ * @returns {number} the sum
+ * @deprecated use plus instead
+ * @see plus
+ */
+function add(a, b) { return a; }`
+
+	p := New(lexer.New(input))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	fn := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.FunctionLiteral)
+	doc := fn.JSDoc
+	if doc == nil {
+		t.Fatalf("function has no JSDoc")
+	}
+
+	if doc.Description != "Adds two numbers.\nWorks with integers." {
+		t.Errorf("description wrong. got=%q", doc.Description)
+	}
+
+	expected := []string{
+		"@template T, U",
+		"@param {number} a the first operand",
+		"@param {number} [b=1] the second operand",
+		"@returns {number} the sum",
+		"@deprecated use plus instead",
+		"@see plus",
+	}
+	if len(doc.Tags) != len(expected) {
+		t.Fatalf("expected %d tags, got %d", len(expected), len(doc.Tags))
+	}
+	for i, tag := range doc.Tags {
+		if tag.String() != expected[i] {
+			t.Errorf("tag %d expected=%q, got=%q", i, expected[i], tag.String())
+		}
+	}
+
+	b := doc.Param("b")
+	if b == nil || !b.Optional || b.Default != "1" {
+		t.Errorf("param b wrong. got=%+v", b)
+	}
+	if doc.Deprecated() == nil || doc.Deprecated().Description != "use plus instead" {
+		t.Errorf("deprecated tag wrong. got=%+v", doc.Deprecated())
+	}
+	if len(fn.Parameters) != 2 || fn.Parameters[1].Name.String() != "b" {
+		t.Errorf("parameters wrong. got=%v", fn.Parameters)
+	}
+}
+
+func TestParseJSDocTagsOnOneLine(t *testing.T) {
+	doc := ParseJSDoc(&ast.Comment{Text: "/** Echoes x, see {@link id}. @template T @param {T} x @returns {T} */"})
+
+	expected := []string{"@template T", "@param {T} x", "@returns {T}"}
+	if doc.Description != "Echoes x, see {@link id}." {
+		t.Errorf("description wrong. got=%q", doc.Description)
+	}
+	if len(doc.Tags) != len(expected) {
+		t.Fatalf("expected %d tags, got %d", len(expected), len(doc.Tags))
+	}
+	for i, tag := range doc.Tags {
+		if tag.String() != expected[i] {
+			t.Errorf("tag %d expected=%q, got=%q", i, expected[i], tag.String())
+		}
+	}
+}
+
+func TestParseJSDocTypedef(t *testing.T) {
+	comment := &ast.Comment{Text: `/**
+ * @typedef {Object} Point
+ * @property {number} x
+ * @property {number} [y] - optional
+ */`}
+
+	doc := ParseJSDoc(comment)
+	if doc == nil || len(doc.Tags) != 1 {
+		t.Fatalf("expected a single typedef tag, got %+v", doc)
+	}
+
+	typedef, ok := doc.Tags[0].(*ast.JSDocTypedefTag)
+	if !ok {
+		t.Fatalf("tag not *ast.JSDocTypedefTag. got=%T", doc.Tags[0])
+	}
+	if typedef.Name != "Point" || len(typedef.Properties) != 2 {
+		t.Fatalf("typedef wrong. got=%s", typedef)
+	}
+	if !typedef.Properties[1].Optional || typedef.Properties[1].Description != "optional" {
+		t.Errorf("property y wrong. got=%+v", typedef.Properties[1])
+	}
+}
+
+func TestJSDocAttachment(t *testing.T) {
+	input := `/** @type {string} */
+let a = "x";
+/* not a JSDoc comment */
+let b = 1;
+/** first */
+// in between
+/** @deprecated */
+let c = 2;`
+
+	p := New(lexer.New(input))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	a := program.Statements[0].(*ast.LetStatement)
+	if a.JSDoc == nil || a.JSDoc.Tag("type").String() != "@type {string}" {
+		t.Errorf("a has the wrong JSDoc: %v", a.JSDoc)
+	}
+
+	if b := program.Statements[1].(*ast.LetStatement); b.JSDoc != nil {
+		t.Errorf("b should have no JSDoc, got %v", b.JSDoc)
+	}
+
+	// Only a JSDoc comment right before the declaration is attached
+	if c := program.Statements[2].(*ast.LetStatement); c.JSDoc == nil || c.JSDoc.Deprecated() == nil {
+		t.Errorf("c has the wrong JSDoc: %v", c.JSDoc)
+	}
+
+	if ParseJSDoc(&ast.Comment{Text: "/**/"}) != nil {
+		t.Errorf("/**/ is not a JSDoc comment")
+	}
+}
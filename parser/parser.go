@@ -1,10 +1,10 @@
 package parser
 
 import (
-	"fmt"
 	"strconv"
 
 	"github.com/dmarro89/ts-go-compiler/ast"
+	"github.com/dmarro89/ts-go-compiler/diagnostics"
 	"github.com/dmarro89/ts-go-compiler/lexer"
 	"github.com/dmarro89/ts-go-compiler/token"
 )
@@ -13,24 +13,78 @@ import (
 const (
 	_ int = iota
 	LOWEST
+	COMMA       // a, b
+	ASSIGN      // = or +=
+	CONDITIONAL // a ? b : c
+	COALESCE    // ??
+	LOGICAL_OR  // ||
+	LOGICAL_AND // &&
+	BITWISE_OR  // |
+	BITWISE_XOR // ^
+	BITWISE_AND // &
 	EQUALS      // ==
 	LESSGREATER // > or <
+	SHIFT       // << or >>
 	SUM         // +
 	PRODUCT     // *
+	EXPONENT    // **
 	PREFIX      // -X or !X
+	POSTFIX     // X++
 	CALL        // myFunction(X)
 )
 
 var precedences = map[token.TokenType]int{
-	token.EQ:       EQUALS,
-	token.NOT_EQ:   EQUALS,
-	token.LT:       LESSGREATER,
-	token.GT:       LESSGREATER,
-	token.PLUS:     SUM,
-	token.MINUS:    SUM,
-	token.SLASH:    PRODUCT,
-	token.ASTERISK: PRODUCT,
-	token.LPAREN:   CALL,
+	token.COMMA:            COMMA,
+	token.ASSIGN:           ASSIGN,
+	token.PLUS_ASSIGN:      ASSIGN,
+	token.MINUS_ASSIGN:     ASSIGN,
+	token.ASTERISK_ASSIGN:  ASSIGN,
+	token.SLASH_ASSIGN:     ASSIGN,
+	token.PERCENT_ASSIGN:   ASSIGN,
+	token.EXPONENT_ASSIGN:  ASSIGN,
+	token.LSHIFT_ASSIGN:    ASSIGN,
+	token.RSHIFT_ASSIGN:    ASSIGN,
+	token.URSHIFT_ASSIGN:   ASSIGN,
+	token.AMPERSAND_ASSIGN: ASSIGN,
+	token.PIPE_ASSIGN:      ASSIGN,
+	token.CARET_ASSIGN:     ASSIGN,
+	token.AND_ASSIGN:       ASSIGN,
+	token.OR_ASSIGN:        ASSIGN,
+	token.NULLISH_ASSIGN:   ASSIGN,
+	token.ARROW:            ASSIGN,
+	token.QUESTION:         CONDITIONAL,
+	token.NULLISH:          COALESCE,
+	token.OR:               LOGICAL_OR,
+	token.AND:              LOGICAL_AND,
+	token.PIPE:             BITWISE_OR,
+	token.CARET:            BITWISE_XOR,
+	token.AMPERSAND:        BITWISE_AND,
+	token.EQ:               EQUALS,
+	token.NOT_EQ:           EQUALS,
+	token.STRICT_EQ:        EQUALS,
+	token.STRICT_NOT_EQ:    EQUALS,
+	token.LT:               LESSGREATER,
+	token.GT:               LESSGREATER,
+	token.LT_EQ:            LESSGREATER,
+	token.GT_EQ:            LESSGREATER,
+	token.INSTANCEOF:       LESSGREATER,
+	token.IN:               LESSGREATER,
+	token.LSHIFT:           SHIFT,
+	token.RSHIFT:           SHIFT,
+	token.URSHIFT:          SHIFT,
+	token.PLUS:             SUM,
+	token.MINUS:            SUM,
+	token.SLASH:            PRODUCT,
+	token.ASTERISK:         PRODUCT,
+	token.PERCENT:          PRODUCT,
+	token.EXPONENT:         EXPONENT,
+	token.INCREMENT:        POSTFIX,
+	token.DECREMENT:        POSTFIX,
+	token.BANG:             POSTFIX,
+	token.LPAREN:           CALL,
+	token.LBRACKET:         CALL,
+	token.DOT:              CALL,
+	token.QUESTION_DOT:     CALL,
 }
 
 type (
@@ -43,42 +97,92 @@ type Parser struct {
 	l              *lexer.Lexer
 	curToken       token.Token
 	peekToken      token.Token
-	errors         []string
+	diagnostics    []diagnostics.Diagnostic
 	prefixParseFns map[token.TokenType]prefixParseFn
 	infixParseFns  map[token.TokenType]infixParseFn
+
+	// Number of comments of curToken and peekToken already attached to a node
+	curCommentsTaken  int
+	peekCommentsTaken int
+
+	jsdoc     bool // parsing a type inside a JSDoc comment
+	typeDepth int  // number of types being parsed, where '>' is never a shift
+
+	// breakTargets is the number of statements a break may leave: the
+	// loops and switch statements enclosing the current statement.
+	// continueTargets counts the loops only.
+	breakTargets    int
+	continueTargets int
+
+	// labels are the labels of the statements enclosing the current one
+	labels []label
+
+	// inFunction is set while parsing the body of a function, and inAsync
+	// while parsing the body of an async function. await is an operator in
+	// async functions and at the top level, where modules allow it, and
+	// yield in the body of a generator, where inGenerator is set.
+	inFunction  bool
+	inAsync     bool
+	inGenerator bool
+
+	// resumed is set when recovering from an error stopped on the first
+	// token of the next statement, which must not be skipped
+	resumed bool
 }
 
 // New creates a new Parser
 func New(l *lexer.Lexer) *Parser {
 	p := &Parser{
-		l:      l,
-		errors: []string{},
+		l: l,
 	}
 
 	p.prefixParseFns = make(map[token.TokenType]prefixParseFn)
 	p.registerPrefix(token.IDENT, p.parseIdentifier)
 	p.registerPrefix(token.INT, p.parseIntegerLiteral)
 	p.registerPrefix(token.STRING, p.parseStringLiteral)
+	p.registerPrefix(token.SLASH, p.parseRegExpLiteral)
+	p.registerPrefix(token.SLASH_ASSIGN, p.parseRegExpLiteral)
 	p.registerPrefix(token.MINUS, p.parsePrefixExpression)
+	p.registerPrefix(token.PLUS, p.parsePrefixExpression)
 	p.registerPrefix(token.BANG, p.parsePrefixExpression)
+	p.registerPrefix(token.TILDE, p.parsePrefixExpression)
+	p.registerPrefix(token.TYPEOF, p.parsePrefixExpression)
+	p.registerPrefix(token.VOID, p.parsePrefixExpression)
+	p.registerPrefix(token.DELETE, p.parsePrefixExpression)
+	p.registerPrefix(token.INCREMENT, p.parsePrefixExpression)
+	p.registerPrefix(token.DECREMENT, p.parsePrefixExpression)
+	p.registerPrefix(token.NULL, p.parseNullLiteral)
+	p.registerPrefix(token.LBRACKET, p.parseArrayLiteral)
+	p.registerPrefix(token.LBRACE, p.parseObjectLiteral)
 	p.registerPrefix(token.TRUE, p.parseBoolean)
 	p.registerPrefix(token.FALSE, p.parseBoolean)
 	p.registerPrefix(token.LPAREN, p.parseGroupedExpression)
 	p.registerPrefix(token.CONSOLE, p.parseConsoleLog)
 	p.registerPrefix(token.FUNCTION, p.parseFunction)
 	p.registerPrefix(token.LET, p.parseLetExpression)
+	p.registerPrefix(token.NEW, p.parseNewExpression)
+	p.registerPrefix(token.THIS, p.parseThisExpression)
+	p.registerPrefix(token.SUPER, p.parseSuperExpression)
 
 	p.infixParseFns = make(map[token.TokenType]infixParseFn)
-	p.registerInfix(token.PLUS, p.parseInfixExpression)
-	p.registerInfix(token.MINUS, p.parseInfixExpression)
-	p.registerInfix(token.SLASH, p.parseInfixExpression)
-	p.registerInfix(token.ASTERISK, p.parseInfixExpression)
-	p.registerInfix(token.EQ, p.parseInfixExpression)
-	p.registerInfix(token.NOT_EQ, p.parseInfixExpression)
-	p.registerInfix(token.LT, p.parseInfixExpression)
-	p.registerInfix(token.GT, p.parseInfixExpression)
+	for tokenType, precedence := range precedences {
+		switch precedence {
+		case ASSIGN:
+			p.registerInfix(tokenType, p.parseAssignmentExpression)
+		case POSTFIX, CALL, CONDITIONAL:
+		default:
+			p.registerInfix(tokenType, p.parseInfixExpression)
+		}
+	}
+	p.registerInfix(token.QUESTION, p.parseConditionalExpression)
+	p.registerInfix(token.ARROW, p.parseArrowFunction)
+	p.registerInfix(token.INCREMENT, p.parsePostfixExpression)
+	p.registerInfix(token.DECREMENT, p.parsePostfixExpression)
+	p.registerInfix(token.BANG, p.parseNonNullExpression)
 	p.registerInfix(token.DOT, p.parseMethodCall)
 	p.registerInfix(token.LPAREN, p.parseCallExpression)
+	p.registerInfix(token.LBRACKET, p.parseIndexExpression)
+	p.registerInfix(token.QUESTION_DOT, p.parseOptionalChain)
 
 	// Read two tokens, so curToken and peekToken are both set
 	p.nextToken()
@@ -90,6 +194,11 @@ func New(l *lexer.Lexer) *Parser {
 func (p *Parser) nextToken() {
 	p.curToken = p.peekToken
 	p.peekToken = p.l.NextToken()
+	if p.peekToken.Type == token.GT && p.typeDepth == 0 {
+		p.peekToken = p.l.ReScanGreater(p.peekToken)
+	}
+	p.curCommentsTaken = p.peekCommentsTaken
+	p.peekCommentsTaken = 0
 }
 
 // ParseProgram parses the program
@@ -98,62 +207,305 @@ func (p *Parser) ParseProgram() *ast.Program {
 	program.Statements = []ast.Statement{}
 
 	for !p.curTokenIs(token.EOF) {
-		stmt := p.parseStatement()
-		if stmt != nil {
-			program.Statements = append(program.Statements, stmt)
-		}
-		p.nextToken()
+		program.Statements = append(program.Statements, p.parseStatement())
+		p.nextStatement()
 	}
+	program.EndComments = p.takeLeadingComments()
 
 	return program
 }
 
+// parseStatement parses the statement starting at curToken. It never
+// returns nil: a statement that cannot be parsed becomes a MissingNode, and
+// parsing resumes after it.
 func (p *Parser) parseStatement() ast.Statement {
+	leading := p.takeLeadingComments()
+	start, errors := p.curToken, len(p.diagnostics)
+
+	var stmt ast.Statement
 	switch p.curToken.Type {
-	case token.LET:
-		return p.parseLetStatement()
+	case token.LET, token.CONST, token.VAR:
+		if s := p.parseLetStatement(); s != nil {
+			stmt = s
+		}
+	case token.LBRACE:
+		stmt = p.parseBlockStatement()
 	case token.RETURN:
-		return p.parseReturnStatement()
+		if s := p.parseReturnStatement(); s != nil {
+			stmt = s
+		}
+	case token.IF:
+		if s := p.parseIfStatement(); s != nil {
+			stmt = s
+		}
+	case token.SWITCH:
+		if s := p.parseSwitchStatement(); s != nil {
+			stmt = s
+		}
+	case token.BREAK:
+		if s := p.parseBreakStatement(); s != nil {
+			stmt = s
+		}
+	case token.CONTINUE:
+		if s := p.parseContinueStatement(); s != nil {
+			stmt = s
+		}
+	case token.FOR:
+		if s := p.parseForStatement(); s != nil {
+			stmt = s
+		}
+	case token.THROW:
+		if s := p.parseThrowStatement(); s != nil {
+			stmt = s
+		}
+	case token.TRY:
+		if s := p.parseTryStatement(); s != nil {
+			stmt = s
+		}
+	case token.IDENT:
+		if p.peekTokenIs(token.COLON) {
+			if s := p.parseLabeledStatement(); s != nil {
+				stmt = s
+			}
+		} else if s := p.parseExpressionStatement(); s != nil {
+			stmt = s
+		}
 	default:
-		return p.parseExpressionStatement()
+		if s := p.parseExpressionStatement(); s != nil {
+			stmt = s
+		}
+	}
+
+	if stmt == nil {
+		if len(p.diagnostics) == errors {
+			p.errorAt(start, errUnexpectedToken)
+		}
+		p.synchronize(start)
+		return &ast.MissingNode{Token: start}
 	}
+
+	if c, ok := stmt.(ast.Commented); ok {
+		c.Comments().LeadingComments = leading
+		c.Comments().TrailingComments = p.takeTrailingComments()
+	}
+	attachJSDoc(stmt, leading)
+
+	return stmt
 }
 
-func (p *Parser) parseLetStatement() *ast.LetStatement {
-	stmt := &ast.LetStatement{Token: p.curToken}
+// synchronize skips the rest of a statement that could not be parsed. It
+// stops on the ';' ending it, or before the '}' closing the enclosing block
+// or the next line, unless it is inside brackets. Brackets opened
+// on the way are skipped along with their contents, while a statement
+// starting with a stray closing bracket skips that bracket alone. A block
+// ends the statement too, as in if (a { b; } c;, unless an operator or ';'
+// continues it.
+func (p *Parser) synchronize(start token.Token) {
+	if p.curToken.Pos == start.Pos {
+		switch p.curToken.Type {
+		case token.RBRACE, token.RPAREN, token.RBRACKET:
+			return
+		}
+	} else if p.curToken.NewlineBefore && startsStatement(p.curToken.Type) {
+		// The statement was cut short by the next one
+		p.resumed = true
+		return
+	} else if p.curTokenIs(token.RBRACE) && p.endsAfterBlock() {
+		// The statement failed right after a block, as in try { } z;
+		return
+	}
 
-	if !p.expectPeek(token.IDENT) {
-		return nil
+	depth := 0
+	for !p.peekTokenIs(token.EOF) {
+		if depth == 0 {
+			if p.curTokenIs(token.SEMICOLON) || p.peekTokenIs(token.RBRACE) ||
+				p.peekTokenIs(token.CASE) || p.peekTokenIs(token.DEFAULT) {
+				return
+			}
+			if p.peekToken.NewlineBefore {
+				return
+			}
+		}
+
+		p.nextToken()
+		switch p.curToken.Type {
+		case token.LBRACE, token.LPAREN, token.LBRACKET:
+			depth++
+		case token.RBRACE, token.RPAREN, token.RBRACKET:
+			if depth > 0 {
+				depth--
+				if depth == 0 && p.curTokenIs(token.RBRACE) && p.endsAfterBlock() {
+					return
+				}
+			}
+		}
 	}
+}
+
+// endsAfterBlock reports whether a statement that failed ends with the
+// block closed by curToken, because nothing after it continues the
+// statement
+func (p *Parser) endsAfterBlock() bool {
+	return !p.peekTokenIs(token.SEMICOLON) && p.peekPrecedence() == LOWEST
+}
 
-	stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+// nextStatement moves to the token after the statement just parsed, unless
+// error recovery already stopped on it
+func (p *Parser) nextStatement() {
+	if p.resumed {
+		p.resumed = false
+		return
+	}
+	p.nextToken()
+}
 
-	if !p.expectPeek(token.ASSIGN) {
+// startsStatement reports whether a token can only start a statement
+func startsStatement(t token.TokenType) bool {
+	switch t {
+	case token.LET, token.CONST, token.VAR, token.RETURN, token.IF, token.SWITCH, token.BREAK, token.CONTINUE,
+		token.FOR, token.THROW, token.TRY, token.FUNCTION:
+		return true
+	}
+	return false
+}
+
+// attachJSDoc parses the JSDoc comment right before a declaration and
+// attaches it to the declaration
+func attachJSDoc(stmt ast.Statement, leading []*ast.Comment) {
+	if len(leading) == 0 {
+		return
+	}
+	doc := ParseJSDoc(leading[len(leading)-1])
+	if doc == nil {
+		return
+	}
+
+	switch s := stmt.(type) {
+	case *ast.LetStatement:
+		s.JSDoc = doc
+	case *ast.ExpressionStatement:
+		if fn, ok := s.Expression.(*ast.FunctionLiteral); ok {
+			fn.JSDoc = doc
+		}
+	}
+}
+
+// takeLeadingComments returns the comments before curToken that are not
+// attached to a node yet
+func (p *Parser) takeLeadingComments() []*ast.Comment {
+	comments := p.curToken.Comments[p.curCommentsTaken:]
+	p.curCommentsTaken = len(p.curToken.Comments)
+	return newComments(comments)
+}
+
+// takeTrailingComments returns the comments before peekToken that are on the
+// same line as curToken, which trail the node ending at curToken
+func (p *Parser) takeTrailingComments() []*ast.Comment {
+	comments := p.peekToken.Comments[p.peekCommentsTaken:]
+	n := 0
+	for n < len(comments) && !comments[n].NewlineBefore {
+		n++
+	}
+	p.peekCommentsTaken += n
+	return newComments(comments[:n])
+}
+
+func newComments(tokens []token.Token) []*ast.Comment {
+	if len(tokens) == 0 {
 		return nil
 	}
+	comments := make([]*ast.Comment, len(tokens))
+	for i, tok := range tokens {
+		comments[i] = &ast.Comment{Token: tok, Text: tok.Literal}
+	}
+	return comments
+}
 
-	p.nextToken()
+func (p *Parser) parseLetStatement() *ast.LetStatement {
+	stmt := &ast.LetStatement{Token: p.curToken}
+	start := p.peekToken
 
-	stmt.Value = p.parseExpression(LOWEST)
+	if p.peekTokenIs(token.LBRACE) || p.peekTokenIs(token.LBRACKET) {
+		p.nextToken()
+		stmt.Pattern = p.parseBindingName()
+		if stmt.Pattern == nil {
+			return nil
+		}
+	} else {
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+		p.checkBindingName(p.curToken)
+		stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	}
 
-	if p.peekTokenIs(token.SEMICOLON) {
+	// let x!: T
+	var bang token.Token
+	if stmt.Name != nil && p.peekTokenIs(token.BANG) && !p.peekToken.NewlineBefore {
+		p.nextToken()
+		bang = p.curToken
+		stmt.Definite = true
+	}
+
+	if p.peekTokenIs(token.COLON) {
+		p.nextToken()
 		p.nextToken()
+		stmt.Type = p.parseType()
+		if stmt.Type == nil {
+			return nil
+		}
+	}
+
+	if !p.peekTokenIs(token.ASSIGN) {
+		switch {
+		case !p.canInsertSemicolon():
+			p.peekError(token.ASSIGN)
+			return nil
+		case stmt.Pattern != nil:
+			p.errorAt(start, errDestructuringInitializer)
+		case stmt.Token.Type == token.CONST:
+			p.errorAt(start, errConstInitializer, "const")
+		case stmt.Definite && stmt.Type == nil:
+			p.errorAt(bang, errDefiniteWithoutType)
+		}
+		p.parseSemicolon()
+		return stmt
+	}
+	if stmt.Definite {
+		p.errorAt(bang, errDefiniteWithInitializer)
+	}
+
+	p.nextToken()
+	p.nextToken()
+
+	stmt.Value = p.parseExpression(COMMA)
+	if stmt.Value == nil {
+		return nil
 	}
 
+	p.parseSemicolon()
+
 	return stmt
 }
 
 func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
 	stmt := &ast.ReturnStatement{Token: p.curToken}
 
+	// return [no LineTerminator here] Expression
+	if p.canInsertSemicolon() {
+		p.parseSemicolon()
+		return stmt
+	}
+
 	p.nextToken()
 
 	stmt.ReturnValue = p.parseExpression(LOWEST)
-
-	if p.peekTokenIs(token.SEMICOLON) {
-		p.nextToken()
+	if stmt.ReturnValue == nil {
+		return nil
 	}
 
+	p.parseSemicolon()
+
 	return stmt
 }
 
@@ -161,28 +513,71 @@ func (p *Parser) parseExpressionStatement() *ast.ExpressionStatement {
 	stmt := &ast.ExpressionStatement{Token: p.curToken}
 
 	stmt.Expression = p.parseExpression(LOWEST)
+	if stmt.Expression == nil {
+		return nil
+	}
 
+	// A function declaration ends with its body and needs no semicolon
+	if _, ok := stmt.Expression.(*ast.FunctionLiteral); ok && p.curTokenIs(token.RBRACE) {
+		if p.peekTokenIs(token.SEMICOLON) {
+			p.nextToken()
+		}
+		return stmt
+	}
+
+	p.parseSemicolon()
+
+	return stmt
+}
+
+// parseSemicolon ends a statement. An explicit ';' is consumed; otherwise a
+// semicolon is inserted automatically when the next token is '}', the end of
+// the input, or is separated from the statement by a line terminator, as
+// described in ECMAScript 12.10 (Automatic Semicolon Insertion).
+func (p *Parser) parseSemicolon() {
 	if p.peekTokenIs(token.SEMICOLON) {
 		p.nextToken()
+		return
+	}
+	if !p.canInsertSemicolon() {
+		p.peekError(token.SEMICOLON)
 	}
+}
 
-	return stmt
+// canInsertSemicolon reports whether a semicolon may be inserted before the
+// peek token. return and throw also use it to decide whether an operand
+// follows them.
+func (p *Parser) canInsertSemicolon() bool {
+	return p.peekTokenIs(token.SEMICOLON) || p.peekTokenIs(token.RBRACE) ||
+		p.peekTokenIs(token.EOF) || p.peekToken.NewlineBefore
 }
 
 func (p *Parser) parseExpression(precedence int) ast.Expression {
+	prefixToken := p.curToken
 	prefix := p.prefixParseFns[p.curToken.Type]
 	if prefix == nil {
-		p.noPrefixParseFnError(p.curToken.Type)
+		p.noPrefixParseFnError(p.curToken)
 		return nil
 	}
+	// Comments right before the expression, as in f(/* a */ 1), stay with
+	// the leftmost operand
+	leading := p.takeLeadingComments()
 	leftExp := prefix()
+	if c, ok := leftExp.(ast.Commented); ok && len(leading) > 0 {
+		c.Comments().LeadingComments = append(leading, c.Comments().LeadingComments...)
+	}
 
-	for !p.peekTokenIs(token.SEMICOLON) && precedence < p.peekPrecedence() {
+	for leftExp != nil && !p.peekTokenIs(token.SEMICOLON) && precedence < p.peekPrecedence() {
 		infix := p.infixParseFns[p.peekToken.Type]
 		if infix == nil {
 			return leftExp
 		}
 
+		// -x ** y is ambiguous, so the operand of ** cannot be unary
+		if _, ok := leftExp.(*ast.PrefixExpression); ok && p.peekTokenIs(token.EXPONENT) && isUnaryOperator(prefixToken.Type) {
+			p.errorRange(prefixToken, p.curToken.End, errUnaryExponent, prefixToken.Literal)
+		}
+
 		p.nextToken()
 
 		leftExp = infix(leftExp)
@@ -191,17 +586,126 @@ func (p *Parser) parseExpression(precedence int) ast.Expression {
 	return leftExp
 }
 
+func isUnaryOperator(t token.TokenType) bool {
+	switch t {
+	case token.MINUS, token.PLUS, token.BANG, token.TILDE, token.TYPEOF, token.VOID, token.DELETE:
+		return true
+	}
+	return false
+}
+
 func (p *Parser) parseIdentifier() ast.Expression {
+	switch p.curToken.Literal {
+	case "async":
+		// async [no LineTerminator here] function
+		if p.peekTokenIs(token.FUNCTION) && !p.peekToken.NewlineBefore {
+			p.nextToken()
+			return p.parseFunctionLiteral(true)
+		}
+		// async x => x, the only way an identifier can follow async
+		if p.peekTokenIs(token.IDENT) && !p.peekToken.NewlineBefore {
+			p.errorAt(p.curToken, errArrowFunction)
+			return nil
+		}
+	case "await":
+		if p.awaitAllowed() {
+			return p.parseAwaitExpression()
+		}
+		if p.startsOperand(p.peekToken) {
+			p.errorAt(p.curToken, errAwaitOutsideAsync)
+			return p.parseAwaitExpression()
+		}
+	case "yield":
+		if p.inGenerator {
+			return p.parseYieldExpression()
+		}
+		if p.startsOperand(p.peekToken) {
+			p.errorAt(p.curToken, errYieldOutsideGenerator)
+			return p.parseYieldExpression()
+		}
+	}
 	return &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
 }
 
+// parseArrowFunction reports an arrow function, async or not, whose
+// parameters have been parsed as the expression before the =>. Arrow
+// functions are not supported.
+func (p *Parser) parseArrowFunction(params ast.Expression) ast.Expression {
+	p.errorAt(p.curToken, errArrowFunction)
+	return nil
+}
+
+// awaitAllowed reports whether await is an operator where the parser is
+func (p *Parser) awaitAllowed() bool {
+	return p.inAsync || !p.inFunction
+}
+
+// startsOperand reports whether tok, following await in a function that is
+// not async, can only be read as the operand of a misplaced await, as in
+// await fetch(url). Tokens that continue an expression, like ( in
+// await(x), leave await a plain name.
+func (p *Parser) startsOperand(tok token.Token) bool {
+	if tok.NewlineBefore {
+		return false
+	}
+	_, prefix := p.prefixParseFns[tok.Type]
+	_, infix := p.infixParseFns[tok.Type]
+	return prefix && !infix
+}
+
+// parseAwaitExpression parses await and its operand, a unary expression
+func (p *Parser) parseAwaitExpression() ast.Expression {
+	expression := &ast.AwaitExpression{Token: p.curToken}
+
+	p.nextToken()
+
+	expression.Argument = p.parseExpression(PREFIX)
+	if expression.Argument == nil {
+		return nil
+	}
+	return expression
+}
+
+// parseYieldExpression parses yield, yield* and their operand, an
+// assignment expression. A yield followed by a line break, or by a token
+// that ends the expression, yields undefined.
+func (p *Parser) parseYieldExpression() ast.Expression {
+	expression := &ast.YieldExpression{Token: p.curToken}
+
+	if p.peekTokenIs(token.ASTERISK) && !p.peekToken.NewlineBefore {
+		p.nextToken()
+		expression.Delegate = true
+	} else if !p.startsYieldOperand(p.peekToken) {
+		return expression
+	}
+
+	p.nextToken()
+	expression.Argument = p.parseExpression(ASSIGN - 1)
+	if expression.Argument == nil {
+		return nil
+	}
+	return expression
+}
+
+// startsYieldOperand reports whether tok begins the operand of a yield
+func (p *Parser) startsYieldOperand(tok token.Token) bool {
+	if tok.NewlineBefore {
+		return false
+	}
+	switch tok.Type {
+	case token.RPAREN, token.RBRACKET, token.RBRACE, token.COMMA, token.SEMICOLON, token.COLON, token.EOF:
+		return false
+	}
+	_, prefix := p.prefixParseFns[tok.Type]
+	return prefix
+}
+
 func (p *Parser) parseIntegerLiteral() ast.Expression {
 	lit := &ast.IntegerLiteral{Token: p.curToken}
 
 	value, err := strconv.ParseInt(p.curToken.Literal, 0, 64)
 	if err != nil {
-		msg := fmt.Sprintf("could not parse %q as integer", p.curToken.Literal)
-		p.errors = append(p.errors, msg)
+		p.errorAt(p.curToken, errInvalidNumber, p.curToken.Literal)
 		return nil
 	}
 
@@ -213,6 +717,30 @@ func (p *Parser) parseStringLiteral() ast.Expression {
 	return &ast.StringLiteral{Token: p.curToken, Value: p.curToken.Literal}
 }
 
+// parseRegExpLiteral handles a '/' where an expression begins, which can
+// only be the start of a regular expression literal
+func (p *Parser) parseRegExpLiteral() ast.Expression {
+	tok := p.l.ReScanRegExp(p.curToken)
+	p.curToken = tok
+	p.peekToken = p.l.NextToken()
+
+	if tok.Type != token.REGEXP {
+		p.errorAt(tok, errUnterminatedRegExp)
+		return nil
+	}
+
+	for _, err := range lexer.ValidateRegExp(tok.Literal) {
+		p.regExpError(tok, err)
+	}
+
+	pattern, flags := lexer.SplitRegExp(tok.Literal)
+	return &ast.RegExpLiteral{Token: tok, Pattern: pattern, Flags: flags}
+}
+
+func (p *Parser) parseNullLiteral() ast.Expression {
+	return &ast.NullLiteral{Token: p.curToken}
+}
+
 func (p *Parser) parseBoolean() ast.Expression {
 	return &ast.Boolean{Token: p.curToken, Value: p.curTokenIs(token.TRUE)}
 }
@@ -226,6 +754,9 @@ func (p *Parser) parsePrefixExpression() ast.Expression {
 	p.nextToken()
 
 	expression.Right = p.parseExpression(PREFIX)
+	if expression.Right == nil {
+		return nil
+	}
 	return expression
 }
 
@@ -237,22 +768,125 @@ func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
 	}
 
 	precedence := p.curPrecedence()
+	if p.curTokenIs(token.EXPONENT) {
+		// ** is right associative
+		precedence--
+	}
 	p.nextToken()
 	expression.Right = p.parseExpression(precedence)
+	if expression.Right == nil {
+		return nil
+	}
+
+	p.checkNullishMixing(expression)
+
+	return expression
+}
+
+// checkNullishMixing reports ?? used together with || or && without
+// parentheses, which JavaScript does not allow
+func (p *Parser) checkNullishMixing(expression *ast.InfixExpression) {
+	isLogical := func(e ast.Expression) (string, bool) {
+		infix, ok := e.(*ast.InfixExpression)
+		if !ok {
+			return "", false
+		}
+		return infix.Operator, infix.Operator == "||" || infix.Operator == "&&" || infix.Operator == "??"
+	}
+
+	if expression.Operator != "??" && expression.Operator != "||" && expression.Operator != "&&" {
+		return
+	}
+	for _, operand := range []ast.Expression{expression.Left, expression.Right} {
+		op, ok := isLogical(operand)
+		if !ok || (op == "??") == (expression.Operator == "??") {
+			continue
+		}
+		other := op
+		if op == "??" {
+			other = expression.Operator
+		}
+		p.errorAt(expression.Token, errMixedNullish, other, "??")
+	}
+}
+
+// parseAssignmentExpression handles = and the compound assignments, which
+// are right associative
+func (p *Parser) parseAssignmentExpression(target ast.Expression) ast.Expression {
+	expression := &ast.AssignmentExpression{
+		Token:    p.curToken,
+		Operator: p.curToken.Literal,
+		Target:   target,
+	}
+
+	// [a, b] = [b, a] destructures: the literal on the left is a pattern
+	if expression.Operator == "=" {
+		switch target.(type) {
+		case *ast.ArrayLiteral, *ast.ObjectLiteral:
+			expression.Target = p.toAssignmentPattern(target)
+			if expression.Target == nil {
+				return nil
+			}
+		}
+	}
+
+	p.nextToken()
+	expression.Value = p.parseExpression(ASSIGN - 1)
+	if expression.Value == nil {
+		return nil
+	}
+
+	return expression
+}
+
+// parseConditionalExpression handles condition ? consequence : alternative
+func (p *Parser) parseConditionalExpression(condition ast.Expression) ast.Expression {
+	expression := &ast.ConditionalExpression{Token: p.curToken, Condition: condition}
+
+	p.nextToken()
+	expression.Consequence = p.parseExpression(ASSIGN - 1)
+	if expression.Consequence == nil {
+		return nil
+	}
+
+	if !p.expectPeek(token.COLON) {
+		return nil
+	}
+
+	p.nextToken()
+	expression.Alternative = p.parseExpression(ASSIGN - 1)
+	if expression.Alternative == nil {
+		return nil
+	}
 
 	return expression
 }
 
+// parsePostfixExpression handles x++ and x--
+func (p *Parser) parsePostfixExpression(left ast.Expression) ast.Expression {
+	return &ast.PostfixExpression{Token: p.curToken, Operator: p.curToken.Literal, Left: left}
+}
+
+// parseNonNullExpression handles x!
+func (p *Parser) parseNonNullExpression(left ast.Expression) ast.Expression {
+	return &ast.NonNullExpression{Token: p.curToken, Expression: left}
+}
+
 func (p *Parser) parseGroupedExpression() ast.Expression {
+	expression := &ast.ParenthesizedExpression{Token: p.curToken}
+
 	p.nextToken()
 
-	exp := p.parseExpression(LOWEST)
+	expression.Expression = p.parseExpression(LOWEST)
+	if expression.Expression == nil {
+		return nil
+	}
 
 	if !p.expectPeek(token.RPAREN) {
 		return nil
 	}
 
-	return exp
+	return expression
 }
 
 func (p *Parser) curTokenIs(t token.TokenType) bool {
@@ -274,6 +908,11 @@ func (p *Parser) expectPeek(t token.TokenType) bool {
 }
 
 func (p *Parser) peekPrecedence() int {
+	// LeftHandSideExpression [no LineTerminator here] ++, and the same for
+	// the ! of x!
+	if (p.peekTokenIs(token.INCREMENT) || p.peekTokenIs(token.DECREMENT) || p.peekTokenIs(token.BANG)) && p.peekToken.NewlineBefore {
+		return LOWEST
+	}
 	if p, ok := precedences[p.peekToken.Type]; ok {
 		return p
 	}
@@ -295,16 +934,6 @@ func (p *Parser) registerInfix(tokenType token.TokenType, fn infixParseFn) {
 	p.infixParseFns[tokenType] = fn
 }
 
-func (p *Parser) Errors() []string {
-	return p.errors
-}
-
-func (p *Parser) peekError(t token.TokenType) {
-	msg := fmt.Sprintf("expected next token to be %d, got %d instead",
-		t, p.peekToken.Type)
-	p.errors = append(p.errors, msg)
-}
-
 // parseLetExpression handles let expressions as identifiers
 func (p *Parser) parseLetExpression() ast.Expression {
 	return p.parseIdentifier()
@@ -312,30 +941,136 @@ func (p *Parser) parseLetExpression() ast.Expression {
 
 // parseFunction handles function expressions
 func (p *Parser) parseFunction() ast.Expression {
-	function := &ast.FunctionLiteral{Token: p.curToken}
+	return p.parseFunctionLiteral(false)
+}
 
-	if !p.expectPeek(token.IDENT) {
-		return nil
+// parseFunctionLiteral parses a function from the function keyword on,
+// which follows async for async functions and is followed by * for
+// generators
+func (p *Parser) parseFunctionLiteral(async bool) ast.Expression {
+	function := &ast.FunctionLiteral{Token: p.curToken, Async: async}
+
+	if p.peekTokenIs(token.ASTERISK) {
+		p.nextToken()
+		function.Generator = true
+	}
+
+	// The name is optional in function expressions
+	if p.peekTokenIs(token.IDENT) {
+		p.nextToken()
+		p.checkBindingName(p.curToken)
+		function.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
 	}
 
-	function.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	return p.parseFunctionRest(function)
+}
 
+// parseFunctionRest parses the parameters, return type and body of a
+// function, from the '(' after its name on
+func (p *Parser) parseFunctionRest(function *ast.FunctionLiteral) ast.Expression {
 	if !p.expectPeek(token.LPAREN) {
 		return nil
 	}
 
-	if !p.expectPeek(token.RPAREN) {
+	function.Parameters = p.parseFunctionParameters(function)
+	if function.Parameters == nil {
 		return nil
 	}
 
+	if p.peekTokenIs(token.COLON) {
+		p.nextToken()
+		p.nextToken()
+		function.ReturnType = p.parseType()
+		if function.ReturnType == nil {
+			return nil
+		}
+	}
+
 	if !p.expectPeek(token.LBRACE) {
 		return nil
 	}
 
+	// A break or continue cannot leave the function, and await and yield
+	// only apply to the function they are written in
+	breakTargets, continueTargets, labels := p.breakTargets, p.continueTargets, p.labels
+	inFunction, inAsync, inGenerator := p.inFunction, p.inAsync, p.inGenerator
+	p.breakTargets, p.continueTargets, p.labels = 0, 0, nil
+	p.inFunction, p.inAsync, p.inGenerator = true, function.Async, function.Generator
 	function.Body = p.parseBlockStatement()
+	p.breakTargets, p.continueTargets, p.labels = breakTargets, continueTargets, labels
+	p.inFunction, p.inAsync, p.inGenerator = inFunction, inAsync, inGenerator
+
 	return function
 }
 
+// parseFunctionParameters parses the parameters up to the closing ')'. A
+// leading this: T declares the type of this in the function and is not
+// one of the parameters.
+func (p *Parser) parseFunctionParameters(function *ast.FunctionLiteral) []*ast.BindingElement {
+	params := []*ast.BindingElement{}
+
+	for !p.peekTokenIs(token.RPAREN) {
+		p.nextToken()
+
+		if p.curTokenIs(token.THIS) {
+			if len(params) > 0 || function.ThisType != nil {
+				p.errorAt(p.curToken, errThisParameterFirst)
+				return nil
+			}
+			if !p.expectPeek(token.COLON) {
+				return nil
+			}
+			p.nextToken()
+			function.ThisType = p.parseType()
+			if function.ThisType == nil {
+				return nil
+			}
+			if !p.peekTokenIs(token.RPAREN) && !p.expectPeek(token.COMMA) {
+				return nil
+			}
+			continue
+		}
+
+		param := &ast.BindingElement{Token: p.curToken}
+		param.LeadingComments = p.takeLeadingComments()
+		if p.curTokenIs(token.ELLIPSIS) {
+			param.Rest = true
+			p.nextToken()
+		}
+		param.Name = p.parseBindingName()
+		if param.Name == nil {
+			return nil
+		}
+		if p.peekTokenIs(token.COLON) {
+			p.nextToken()
+			p.nextToken()
+			param.Type = p.parseType()
+			if param.Type == nil {
+				return nil
+			}
+		}
+		if !param.Rest && !p.parseBindingDefault(param) {
+			return nil
+		}
+		params = append(params, param)
+
+		if param.Rest && p.peekTokenIs(token.ASSIGN) {
+			p.errorAt(p.peekToken, errRestParameterDefault)
+			return nil
+		}
+		if param.Rest && !p.peekTokenIs(token.RPAREN) {
+			p.errorRange(param.Token, p.curToken.End, errRestParameterLast)
+			return nil
+		}
+		if !p.peekTokenIs(token.RPAREN) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+	p.nextToken()
+
+	return params
+}
+
 // parseBlockStatement analyzes a block of statements
 func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 	block := &ast.BlockStatement{Token: p.curToken}
@@ -344,11 +1079,10 @@ func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 	p.nextToken()
 
 	for !p.curTokenIs(token.RBRACE) && !p.curTokenIs(token.EOF) {
-		stmt := p.parseStatement()
-		block.Statements = append(block.Statements, stmt)
-
-		p.nextToken()
+		block.Statements = append(block.Statements, p.parseStatement())
+		p.nextStatement()
 	}
+	block.EndComments = p.takeLeadingComments()
 
 	return block
 }
@@ -357,12 +1091,16 @@ func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 func (p *Parser) parseCallExpression(function ast.Expression) ast.Expression {
 	exp := &ast.CallExpression{Token: p.curToken, Function: function}
 	exp.Arguments = p.parseExpressionList(token.RPAREN)
+	if exp.Arguments == nil {
+		return nil
+	}
 	return exp
 }
 
-// parseExpressionList handles the arguments of a function call
+// parseExpressionList handles the arguments of a function call. It returns
+// nil when one of them cannot be parsed.
 func (p *Parser) parseExpressionList(end token.TokenType) []ast.Expression {
-	var args []ast.Expression
+	args := []ast.Expression{}
 
 	if p.peekTokenIs(end) {
 		p.nextToken()
@@ -370,12 +1108,19 @@ func (p *Parser) parseExpressionList(end token.TokenType) []ast.Expression {
 	}
 
 	p.nextToken()
-	args = append(args, p.parseExpression(LOWEST))
+	arg := p.parseElement()
+	if arg == nil {
+		return nil
+	}
+	args = append(args, arg)
 
 	for p.peekTokenIs(token.COMMA) {
 		p.nextToken()
 		p.nextToken()
-		args = append(args, p.parseExpression(LOWEST))
+		if arg = p.parseElement(); arg == nil {
+			return nil
+		}
+		args = append(args, arg)
 	}
 
 	if !p.expectPeek(end) {
@@ -390,8 +1135,9 @@ func (p *Parser) parseMethodCall(obj ast.Expression) ast.Expression {
 	call := &ast.MethodCallExpression{Token: p.curToken, Object: obj}
 
 	p.nextToken()
-	if !p.curTokenIs(token.IDENT) {
-		p.errors = append(p.errors, "expected method name after dot")
+	// Keywords are valid property names, as in promise.catch()
+	if !p.curTokenIs(token.IDENT) && !isIdentifierName(p.curToken) {
+		p.errorAt(p.curToken, errIdentifierExpected)
 		return nil
 	}
 
@@ -403,10 +1149,152 @@ func (p *Parser) parseMethodCall(obj ast.Expression) ast.Expression {
 
 	p.nextToken()
 	call.Arguments = p.parseExpressionList(token.RPAREN)
+	if call.Arguments == nil {
+		return nil
+	}
 
 	return call
 }
 
+// parseNewExpression parses new with its callee and arguments, or the
+// new.target meta-property
+func (p *Parser) parseNewExpression() ast.Expression {
+	if p.peekTokenIs(token.DOT) {
+		return p.parseMetaProperty()
+	}
+
+	expression := &ast.NewExpression{Token: p.curToken}
+
+	p.nextToken()
+	expression.Callee = p.parseNewCallee()
+	if expression.Callee == nil {
+		return nil
+	}
+
+	if p.peekTokenIs(token.LPAREN) {
+		p.nextToken()
+		expression.Arguments = p.parseExpressionList(token.RPAREN)
+		if expression.Arguments == nil {
+			return nil
+		}
+	}
+	return expression
+}
+
+// parseNewCallee parses the constructor new calls, whose property accesses
+// stop at the first argument list: new a.b(c) calls new on a.b
+func (p *Parser) parseNewCallee() ast.Expression {
+	var callee ast.Expression
+	if p.curTokenIs(token.NEW) {
+		callee = p.parseNewExpression()
+	} else {
+		prefix := p.prefixParseFns[p.curToken.Type]
+		if prefix == nil {
+			p.noPrefixParseFnError(p.curToken)
+			return nil
+		}
+		callee = prefix()
+	}
+
+	for callee != nil {
+		switch {
+		case p.peekTokenIs(token.DOT):
+			p.nextToken()
+			access := &ast.MethodCallExpression{Token: p.curToken, Object: callee}
+			p.nextToken()
+			if !p.curTokenIs(token.IDENT) && !isIdentifierName(p.curToken) {
+				p.errorAt(p.curToken, errIdentifierExpected)
+				return nil
+			}
+			access.Method = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+			callee = access
+		case p.peekTokenIs(token.LBRACKET):
+			p.nextToken()
+			callee = p.parseIndexExpression(callee)
+		default:
+			return callee
+		}
+	}
+	return nil
+}
+
+// parseMetaProperty parses new.target, which only has a value inside a
+// function
+func (p *Parser) parseMetaProperty() ast.Expression {
+	meta := &ast.MetaProperty{Token: p.curToken}
+
+	p.nextToken()
+	p.nextToken()
+	meta.Property = p.curToken.Literal
+	if meta.Property != "target" {
+		p.errorAt(p.curToken, errInvalidMetaProperty, meta.Property)
+	}
+
+	if !p.inFunction {
+		p.errorRange(meta.Token, p.curToken.End, errNewTargetOutside)
+	}
+	return meta
+}
+
+func (p *Parser) parseThisExpression() ast.Expression {
+	return &ast.ThisExpression{Token: p.curToken}
+}
+
+// parseSuperExpression parses super, which must be called or have one of
+// its properties read
+func (p *Parser) parseSuperExpression() ast.Expression {
+	if !p.peekTokenIs(token.LPAREN) && !p.peekTokenIs(token.DOT) && !p.peekTokenIs(token.LBRACKET) {
+		p.errorAt(p.curToken, errSuperAlone)
+	}
+	return &ast.SuperExpression{Token: p.curToken}
+}
+
+// parseIndexExpression handles element access, e.g. a[0]
+func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
+	exp := &ast.IndexExpression{Token: p.curToken, Left: left}
+
+	p.nextToken()
+	exp.Index = p.parseExpression(LOWEST)
+	if exp.Index == nil {
+		return nil
+	}
+
+	if !p.expectPeek(token.RBRACKET) {
+		return nil
+	}
+
+	return exp
+}
+
+// parseOptionalChain handles the three forms of ?.: a?.b, a?.(b) and a?.[b]
+func (p *Parser) parseOptionalChain(left ast.Expression) ast.Expression {
+	switch p.peekToken.Type {
+	case token.LPAREN:
+		p.nextToken()
+		call, ok := p.parseCallExpression(left).(*ast.CallExpression)
+		if !ok {
+			return nil
+		}
+		call.Optional = true
+		return call
+	case token.LBRACKET:
+		p.nextToken()
+		index, ok := p.parseIndexExpression(left).(*ast.IndexExpression)
+		if !ok {
+			return nil
+		}
+		index.Optional = true
+		return index
+	default:
+		call, ok := p.parseMethodCall(left).(*ast.MethodCallExpression)
+		if !ok {
+			return nil
+		}
+		call.Optional = true
+		return call
+	}
+}
+
 // parseConsoleLog handles console.log
 func (p *Parser) parseConsoleLog() ast.Expression {
 	consoleExp := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
@@ -419,7 +1307,7 @@ func (p *Parser) parseConsoleLog() ast.Expression {
 	p.nextToken() // consume LOG
 
 	if !p.curTokenIs(token.LOG) {
-		p.errors = append(p.errors, "expected LOG after console.")
+		p.errorAt(p.curToken, errExpected, "log")
 		return nil
 	}
 
@@ -434,33 +1322,9 @@ func (p *Parser) parseConsoleLog() ast.Expression {
 
 	p.nextToken() // consume LPAREN
 	logExp.Arguments = p.parseExpressionList(token.RPAREN)
-
-	return logExp
-}
-
-func (p *Parser) noPrefixParseFnError(t token.TokenType) {
-	tokenName := "UNKNOWN"
-
-	tokenNames := map[token.TokenType]string{
-		token.IDENT:    "IDENT",
-		token.INT:      "INT",
-		token.STRING:   "STRING",
-		token.MINUS:    "MINUS",
-		token.BANG:     "BANG",
-		token.TRUE:     "TRUE",
-		token.FALSE:    "FALSE",
-		token.LPAREN:   "LPAREN",
-		token.CONSOLE:  "CONSOLE",
-		token.FUNCTION: "FUNCTION",
-		token.LET:      "LET",
-		token.DOT:      "DOT",
-		token.LOG:      "LOG",
-	}
-
-	if name, ok := tokenNames[t]; ok {
-		tokenName = name
+	if logExp.Arguments == nil {
+		return nil
 	}
 
-	msg := fmt.Sprintf("no prefix parse function for %s (type: %d) found", tokenName, t)
-	p.errors = append(p.errors, msg)
+	return logExp
 }
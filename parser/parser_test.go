@@ -229,3 +229,844 @@ function test() {
 		t.Fatal("program.Statements does not contain any statements")
 	}
 }
+
+func TestRegExpLiteralExpression(t *testing.T) {
+	input := `let re = /ab+c/gi;
+	let half = a / 2 / b;`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 2 {
+		t.Fatalf("program.Statements does not contain 2 statements. got=%d",
+			len(program.Statements))
+	}
+
+	re, ok := program.Statements[0].(*ast.LetStatement).Value.(*ast.RegExpLiteral)
+	if !ok {
+		t.Fatalf("exp not *ast.RegExpLiteral. got=%T", program.Statements[0].(*ast.LetStatement).Value)
+	}
+	if re.Pattern != "ab+c" || re.Flags != "gi" {
+		t.Errorf("regexp wrong. pattern=%q flags=%q", re.Pattern, re.Flags)
+	}
+
+	if got := program.Statements[1].String(); got != "let half = ((a / 2) / b);" {
+		t.Errorf("division parsed wrong. got=%q", got)
+	}
+}
+
+func TestRegExpLiteralErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let re = /abc/gx;", "Unknown regular expression flag."},
+		{"let re = /(abc/;", "')' expected."},
+		{"let re = /abc", "Unterminated regular expression literal."},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 || errors[0] != tt.expected {
+			t.Errorf("%s: expected error %q, got %v", tt.input, tt.expected, errors)
+		}
+	}
+}
+
+func TestAutomaticSemicolonInsertion(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		// A line break after return ends the statement
+		{"return\nx", []string{"return ;", "x"}},
+		{"return x\nlet y = 1", []string{"return x;", "let y = 1;"}},
+		{"let a = 1\nlet b = 2", []string{"let a = 1;", "let b = 2;"}},
+		// No semicolon is inserted when the next line continues the expression
+		{"let a = b\n(c)", []string{"let a = b(c);"}},
+		{"let a = b\n+ c", []string{"let a = (b + c);"}},
+		{"let a = b\n/c/g", []string{"let a = ((b / c) / g);"}},
+		// '}' and the end of input end a statement
+		{"function f() { return a }", []string{"function f() { return a; }"}},
+		{"a", []string{"a"}},
+		{"function f() {} f()", []string{"function f() {  }", "f()"}},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != len(tt.expected) {
+			t.Errorf("%q: expected %d statements, got %d (%s)", tt.input,
+				len(tt.expected), len(program.Statements), program.String())
+			continue
+		}
+		for i, stmt := range program.Statements {
+			if stmt.String() != tt.expected[i] {
+				t.Errorf("%q: statement %d expected=%q, got=%q", tt.input, i, tt.expected[i], stmt.String())
+			}
+		}
+	}
+}
+
+func TestMissingSemicolon(t *testing.T) {
+	inputs := []string{
+		"let a = 1 let b = 2",
+		"a b",
+		"return a b",
+	}
+
+	for _, input := range inputs {
+		p := New(lexer.New(input))
+		p.ParseProgram()
+
+		if len(p.Errors()) == 0 {
+			t.Errorf("%q: expected a missing semicolon error", input)
+		}
+	}
+}
+
+func TestCommentTrivia(t *testing.T) {
+	input := `/*! license */
+// first
+let a = 1; // after a
+/* before return */
+function f() {
+	return a; /* after return */
+	// dangling
+}
+// end of file`
+
+	p := New(lexer.New(input))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 2 {
+		t.Fatalf("program.Statements does not contain 2 statements. got=%d",
+			len(program.Statements))
+	}
+
+	let := program.Statements[0].(*ast.LetStatement)
+	testComments(t, "let leading", let.LeadingComments, "/*! license */", "// first")
+	testComments(t, "let trailing", let.TrailingComments, "// after a")
+
+	fn := program.Statements[1].(*ast.ExpressionStatement)
+	testComments(t, "function leading", fn.LeadingComments, "/* before return */")
+
+	body := fn.Expression.(*ast.FunctionLiteral).Body
+	ret := body.Statements[0].(*ast.ReturnStatement)
+	testComments(t, "return leading", ret.LeadingComments)
+	testComments(t, "return trailing", ret.TrailingComments, "/* after return */")
+	testComments(t, "block end", body.EndComments, "// dangling")
+
+	testComments(t, "program end", program.EndComments, "// end of file")
+}
+
+func testComments(t *testing.T, what string, comments []*ast.Comment, expected ...string) {
+	t.Helper()
+	if len(comments) != len(expected) {
+		t.Errorf("%s: expected %d comments, got %d", what, len(expected), len(comments))
+		return
+	}
+	for i, c := range comments {
+		if c.Text != expected[i] {
+			t.Errorf("%s: comment %d expected=%q, got=%q", what, i, expected[i], c.Text)
+		}
+	}
+}
+
+func TestOperatorPrecedence(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"a || b && c;", "(a || (b && c))"},
+		{"a | b ^ c & d;", "(a | (b ^ (c & d)))"},
+		{"a == b < c;", "(a == (b < c))"},
+		{"a << b + c;", "(a << (b + c))"},
+		{"a >> b >>> c >= d;", "(((a >> b) >>> c) >= d)"},
+		{"a * b % c;", "((a * b) % c)"},
+		{"a ** b ** c;", "(a ** (b ** c))"},
+		{"(-a) ** b;", "((-a) ** b)"},
+		{"a ?? b;", "(a ?? b)"},
+		{"a ? b : c ? d : e;", "(a ? b : (c ? d : e))"},
+		{"a = b = c + 1;", "(a = (b = (c + 1)))"},
+		{"a += b || c;", "(a += (b || c))"},
+		{"a ??= b, c;", "((a ??= b) , c)"},
+		{"x in y instanceof z;", "((x in y) instanceof z)"},
+		{"typeof a === \"string\";", "((typeof a) === \"string\")"},
+		{"void 0;", "(void 0)"},
+		{"delete a.b;", "(delete a.b)"},
+		{"!a++;", "(!(a++))"},
+		{"--a + b;", "((--a) + b)"},
+		{"~a;", "(~a)"},
+		{"f(a, b = 1);", "f(a, (b = 1))"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if actual := program.String(); actual != tt.expected {
+			t.Errorf("%s: expected=%q, got=%q", tt.input, tt.expected, actual)
+		}
+	}
+}
+
+func TestOperatorErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"-a ** b;", "An unary expression with the '-' operator is not allowed in the left-hand side of an exponentiation expression. Consider enclosing the expression in parentheses."},
+		{"a ?? b || c;", "'||' and '??' operations cannot be mixed without parentheses."},
+		{"a && b ?? c;", "'&&' and '??' operations cannot be mixed without parentheses."},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 || errors[0] != tt.expected {
+			t.Errorf("%s: expected error %q, got %v", tt.input, tt.expected, errors)
+		}
+	}
+
+	p := New(lexer.New("a ?? (b || c);"))
+	p.ParseProgram()
+	checkParserErrors(t, p)
+}
+
+func TestPostfixRestrictedProduction(t *testing.T) {
+	p := New(lexer.New("a\n++b"))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 2 {
+		t.Fatalf("expected 2 statements, got %d: %q", len(program.Statements), program.String())
+	}
+	if program.Statements[1].String() != "(++b)" {
+		t.Errorf("expected ++ to apply to b, got %q", program.Statements[1].String())
+	}
+}
+
+func TestOptionalChainExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"a?.b;", "a?.b"},
+		{"a?.b?.(c)?.[d];", "a?.b?.(c)?.[d]"},
+		{"a?.b.c(1)[0];", "a?.b.c(1)[0]"},
+		{"a?.b ?? null;", "(a?.b ?? null)"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if actual := program.String(); actual != tt.expected {
+			t.Errorf("%s: expected=%q, got=%q", tt.input, tt.expected, actual)
+		}
+	}
+
+	p := New(lexer.New("a?.b.c;"))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	expr := program.Statements[0].(*ast.ExpressionStatement).Expression
+	outer, ok := expr.(*ast.MethodCallExpression)
+	if !ok || outer.Optional || !ast.IsOptionalChain(outer) {
+		t.Errorf("expected a.b.c to be a non-optional link of an optional chain, got %#v", expr)
+	}
+}
+
+func TestDestructuringDeclarations(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"const { a, b: [c, ...d] = [] } = obj;", "const { a, b: [c, ...d] = [] } = obj;"},
+		{"let [x, , y = 1, ...rest] = list;", "let [x, , y = 1, ...rest] = list;"},
+		{"var { a: { b }, ...others } = obj;", "var { a: { b }, ...others } = obj;"},
+		{"let { \"a-b\": ab, 0: zero, delete: del } = obj;", "let { \"a-b\": ab, 0: zero, delete: del } = obj;"},
+		{"let [a,,] = list;", "let [a, ,] = list;"},
+		{"function f({ a, b } = {}, [c] = [1]) {}", "function f({ a, b } = {}, [c] = [1]) {  }"},
+		{"[a, b] = [b, a];", "([a, b] = [b, a])"},
+		{"({ a, b: { c = 1 }, ...rest } = obj);", "({ a, b: { c = 1 }, ...rest } = obj)"},
+		{"[o.a, o[0]] = pair;", "([o.a, o[0]] = pair)"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if actual := program.String(); actual != tt.expected {
+			t.Errorf("%s: expected=%q, got=%q", tt.input, tt.expected, actual)
+		}
+	}
+
+	p := New(lexer.New("let { a, b: [c] } = obj;"))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.LetStatement)
+	pattern, ok := stmt.Pattern.(*ast.ObjectPattern)
+	if !ok || stmt.Name != nil || len(pattern.Properties) != 2 {
+		t.Fatalf("expected an object pattern with 2 properties, got %#v", stmt.Pattern)
+	}
+	if _, ok := pattern.Properties[1].Name.(*ast.ArrayPattern); !ok {
+		t.Errorf("expected b to be destructured by an array pattern, got %T", pattern.Properties[1].Name)
+	}
+}
+
+func TestDestructuringErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let [...a, b] = list;", "A rest element must be last in a destructuring pattern."},
+		{"let { ...a, b } = obj;", "A rest element must be last in a destructuring pattern."},
+		{"[a + 1] = list;", "The left-hand side of an assignment expression must be a variable or a property access."},
+		{"let { \"a\" } = obj;", "':' expected."},
+		{"let [1] = list;", "Identifier expected."},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 || errors[0] != tt.expected {
+			t.Errorf("%s: expected error %q, got %v", tt.input, tt.expected, errors)
+		}
+	}
+}
+
+func TestSpreadAndRest(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"f(...args);", "f(...args)"},
+		{"o.m(1, ...a, ...b);", "o.m(1, ...a, ...b)"},
+		{"let a = [...b, 1, ...c];", "let a = [...b, 1, ...c];"},
+		{"let o = { ...a, b: 1 };", "let o = { ...a, b: 1 };"},
+		{"function f(a, ...rest) {}", "function f(a, ...rest) {  }"},
+		{"function f(...[a, b]) {}", "function f(...[a, b]) {  }"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if actual := program.String(); actual != tt.expected {
+			t.Errorf("%s: expected=%q, got=%q", tt.input, tt.expected, actual)
+		}
+	}
+
+	errors := []struct {
+		input    string
+		expected string
+	}{
+		{"function f(...a, b) {}", "A rest parameter must be last in a parameter list."},
+		{"function f(...a = []) {}", "A rest parameter cannot have an initializer."},
+	}
+
+	for _, tt := range errors {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+
+		errs := p.Errors()
+		if len(errs) == 0 || errs[0] != tt.expected {
+			t.Errorf("%s: expected error %q, got %v", tt.input, tt.expected, errs)
+		}
+	}
+}
+
+func TestSwitchTryThrow(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"switch (x) { case 1: case 2: f(); break; default: g() }", "switch (x) { case 1: case 2: f() break; default: g() }"},
+		{"switch (x) {}", "switch (x) { }"},
+		{"try { f(); } catch (e) { g(e); } finally { h(); }", "try { f() } catch (e) { g(e) } finally { h() }"},
+		{"try { f(); } catch { }", "try { f() } catch {  }"},
+		{"try { f(); } catch ({ message }) { }", "try { f() } catch ({ message }) {  }"},
+		{"try { f(); } finally { }", "try { f() } finally {  }"},
+		{"throw new_error(1);", "throw new_error(1);"},
+		{"p.catch(f).finally(g);", "p.catch(f).finally(g)"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if actual := program.String(); actual != tt.expected {
+			t.Errorf("%s: expected=%q, got=%q", tt.input, tt.expected, actual)
+		}
+	}
+
+	p := New(lexer.New("switch (x) { case 1: f(); default: g(); h(); }"))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt, ok := program.Statements[0].(*ast.SwitchStatement)
+	if !ok || len(stmt.Cases) != 2 {
+		t.Fatalf("expected a switch statement with 2 clauses, got %#v", program.Statements[0])
+	}
+	if stmt.Cases[1].Test != nil || len(stmt.Cases[1].Consequent) != 2 {
+		t.Errorf("expected a default clause with 2 statements, got %s", stmt.Cases[1])
+	}
+}
+
+func TestSwitchTryThrowErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"switch (x) { default: f(); default: g(); }", "A 'default' clause cannot appear more than once in a 'switch' statement."},
+		{"switch (x) { f(); }", "'case' or 'default' expected."},
+		{"break;", "A 'break' statement can only be used within an enclosing iteration or switch statement."},
+		{"switch (x) { case 1: function f() { break; } }", "A 'break' statement can only be used within an enclosing iteration or switch statement."},
+		{"throw\nerr;", "Line break not permitted here."},
+		{"throw;", "Expression expected."},
+		{"try { f(); }", "'catch' or 'finally' expected."},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 || errors[0] != tt.expected {
+			t.Errorf("%s: expected error %q, got %v", tt.input, tt.expected, errors)
+		}
+	}
+}
+
+func TestForOfForIn(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"for (const x of xs) f(x);", "for (const x of xs) f(x)"},
+		{"for (let [k, v] of m.entries()) { continue; }", "for (let [k, v] of m.entries()) { continue; }"},
+		{"for await (const { a } of stream) {}", "for await (const { a } of stream) {  }"},
+		{"for (x of xs) break;", "for (x of xs) break;"},
+		{"for ([a, b] of pairs) {}", "for ([a, b] of pairs) {  }"},
+		{"for (o.p of xs) {}", "for (o.p of xs) {  }"},
+		{"for (var k in o) {}", "for (var k in o) {  }"},
+		{"for (k in a, b) {}", "for (k in (a , b)) {  }"},
+		{"for (const of of ofs) {}", "for (const of of ofs) {  }"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if actual := program.String(); actual != tt.expected {
+			t.Errorf("%s: expected=%q, got=%q", tt.input, tt.expected, actual)
+		}
+	}
+
+	errors := []struct {
+		input    string
+		expected string
+	}{
+		{"for (const x = 1; x < 2; x++) {}", "'in' or 'of' expected."},
+		{"for await (const k in o) {}", "'of' expected."},
+		{"for (f() of xs) {}", "The left-hand side of an assignment expression must be a variable or a property access."},
+		{"continue;", "A 'continue' statement can only be used within an enclosing iteration statement."},
+		{"switch (x) { case 1: continue; }", "A 'continue' statement can only be used within an enclosing iteration statement."},
+	}
+
+	for _, tt := range errors {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+
+		errs := p.Errors()
+		if len(errs) == 0 || errs[0] != tt.expected {
+			t.Errorf("%s: expected error %q, got %v", tt.input, tt.expected, errs)
+		}
+	}
+}
+
+func TestAsyncAwait(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"async function f(p) { return await p; }", "async function f(p) { return (await p); }"},
+		{"async function f() { for await (const x of xs) {} }", "async function f() { for await (const x of xs) {  } }"},
+		{"let v = await load();", "let v = (await load());"},
+		{"await a + b;", "((await a) + b)"},
+		{"let f = async function () {};", "let f = async function() {  };"},
+		{"async\nfunction f() {}", "asyncfunction f() {  }"},
+		{"function f() { let await = 1; return await; }", "function f() { let await = 1;return await; }"},
+		{"let o = { async m(p) { return await p; }, async *g() {}, *h() {}, async: 1, async() {} };", "let o = { async m(p) { return (await p); }, async *g() {  }, *h() {  }, async: 1, async() {  } };"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if actual := program.String(); actual != tt.expected {
+			t.Errorf("%s: expected=%q, got=%q", tt.input, tt.expected, actual)
+		}
+	}
+
+	errors := []struct {
+		input    string
+		expected string
+	}{
+		{"function f() { await g(); }", "'await' expressions are only allowed within async functions and at the top levels of modules."},
+		{"async function f() { function g() { await h(); } }", "'await' expressions are only allowed within async functions and at the top levels of modules."},
+		{"function f(xs) { for await (const x of xs) {} }", "'for await' loops are only allowed within async functions and at the top levels of modules."},
+		{"let o = { m() { await g(); } };", "'await' expressions are only allowed within async functions and at the top levels of modules."},
+		{"let f = async (x) => x;", "Arrow functions are not supported. Use a function expression instead."},
+		{"let f = async x => x;", "Arrow functions are not supported. Use a function expression instead."},
+	}
+
+	for _, tt := range errors {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+
+		errs := p.Errors()
+		if len(errs) == 0 || errs[0] != tt.expected {
+			t.Errorf("%s: expected error %q, got %v", tt.input, tt.expected, errs)
+		}
+	}
+}
+
+func TestGenerators(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"function* g() { const x = yield 1; }", "function* g() { const x = (yield 1); }"},
+		{"function* g() { yield; yield* xs; }", "function* g() { (yield)(yield* xs) }"},
+		{"function* g() { f(yield, 1); }", "function* g() { f((yield), 1) }"},
+		{"function* g() { yield a, b; }", "function* g() { ((yield a) , b) }"},
+		{"function* g() { yield\nx; }", "function* g() { (yield)x }"},
+		{"async function* g() { yield await p; }", "async function* g() { (yield (await p)) }"},
+		{"let yield = 1; yield * 2;", "let yield = 1;(yield * 2)"},
+		{"function* g() { function f() { return yield; } }", "function* g() { function f() { return yield; } }"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if actual := program.String(); actual != tt.expected {
+			t.Errorf("%s: expected=%q, got=%q", tt.input, tt.expected, actual)
+		}
+	}
+
+	p := New(lexer.New("function f() { yield 1; }"))
+	p.ParseProgram()
+	expected := "A 'yield' expression is only allowed in a generator body."
+	if errs := p.Errors(); len(errs) == 0 || errs[0] != expected {
+		t.Errorf("expected error %q, got %v", expected, errs)
+	}
+}
+
+func TestFunctionReturnType(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"function f(): number { return 1; }", "function f(): number { return 1; }"},
+		{"let f = function(a): string | null { return a; };", "let f = function(a): string | null { return a; };"},
+		{"async function f(): Promise<void> {}", "async function f(): Promise<void> {  }"},
+		{"function f(): [number, string[]] { }", "function f(): [number, string[]] {  }"},
+		{"function f(): { a: number } { }", "function f(): { a: number; } {  }"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if actual := program.String(); actual != tt.expected {
+			t.Errorf("%s: expected=%q, got=%q", tt.input, tt.expected, actual)
+		}
+	}
+
+	p := New(lexer.New("function f(): = { }"))
+	p.ParseProgram()
+	expected := "Type expected."
+	if errs := p.Errors(); len(errs) == 0 || errs[0] != expected {
+		t.Errorf("expected error %q, got %v", expected, errs)
+	}
+}
+
+func TestIfStatement(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"if (x) { f(); }", "if (x) { f() }"},
+		{"if (x) f(); else g();", "if (x) f() else g()"},
+		{"if (x) return_(); else if (y) { g(); } else { }", "if (x) return_() else if (y) { g() } else {  }"},
+		{"if (x)\nf()\nelse\ng()", "if (x) f() else g()"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if actual := program.String(); actual != tt.expected {
+			t.Errorf("%s: expected=%q, got=%q", tt.input, tt.expected, actual)
+		}
+	}
+
+	p := New(lexer.New("if (x) { if (y) f(); else g(); }"))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	outer, ok := program.Statements[0].(*ast.IfStatement)
+	if !ok || outer.Alternative != nil {
+		t.Fatalf("expected an if statement without else, got %#v", program.Statements[0])
+	}
+	inner := outer.Consequence.(*ast.BlockStatement).Statements[0].(*ast.IfStatement)
+	if inner.Alternative == nil {
+		t.Errorf("expected the else to belong to the inner if statement")
+	}
+}
+
+func TestNonNullAndDefiniteAssignment(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"x!.y;", "x!.y"},
+		{"f()!(a!);", "f()!(a!)"},
+		{"-x!;", "(-x!)"},
+		{"a!\n!b;", "a!(!b)"},
+		{"a != b;", "(a != b)"},
+		{"let x: number;", "let x: number;"},
+		{"let x: string | null = null;", "let x: string | null = null;"},
+		{"let x!: number;", "let x!: number;"},
+		{"var y;", "var y;"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if actual := program.String(); actual != tt.expected {
+			t.Errorf("%s: expected=%q, got=%q", tt.input, tt.expected, actual)
+		}
+	}
+
+	errorTests := []struct {
+		input    string
+		expected string
+	}{
+		{"let x!: number = 1;", "1:6 - error TS1263: Declarations with initializers cannot also have definite assignment assertions."},
+		{"let x!;", "1:6 - error TS1264: Declarations with definite assignment assertions must also have type annotations."},
+		{"const x: number;", "1:7 - error TS1155: 'const' declarations must be initialized."},
+		{"let { a };", "1:5 - error TS1182: A destructuring declaration must have an initializer."},
+	}
+
+	for _, tt := range errorTests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+
+		diags := p.Diagnostics()
+		if len(diags) != 1 || diags[0].String() != tt.expected {
+			t.Errorf("%s: expected error %q, got %v", tt.input, tt.expected, p.Errors())
+		}
+	}
+}
+
+func TestLabeledStatement(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"a: for (x of xs) { continue a; }", "a: for (x of xs) { continue a; }"},
+		{"a: b: for (x of xs) { for (y of ys) { continue a; } }", "a: b: for (x of xs) { for (y of ys) { continue a; } }"},
+		{"a: { break a; }", "a: { break a; }"},
+		{"a: for (x of xs) break\na;", "a: for (x of xs) break;a"},
+		{"a: f(); a: g();", "a: f()a: g()"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if actual := program.String(); actual != tt.expected {
+			t.Errorf("%s: expected=%q, got=%q", tt.input, tt.expected, actual)
+		}
+	}
+
+	errorTests := []struct {
+		input    string
+		expected string
+	}{
+		{"a: a: f();", "1:4 - error TS1114: Duplicate label 'a'."},
+		{"for (x of xs) { break a; }", "1:23 - error TS1116: A 'break' statement can only jump to a label of an enclosing statement."},
+		{"a: { for (x of xs) { continue a; } }", "1:31 - error TS1115: A 'continue' statement can only jump to a label of an enclosing iteration statement."},
+		{"a: for (x of xs) { function f() { break a; } }", "1:41 - error TS1116: A 'break' statement can only jump to a label of an enclosing statement."},
+	}
+
+	for _, tt := range errorTests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+
+		diags := p.Diagnostics()
+		if len(diags) == 0 || diags[0].String() != tt.expected {
+			t.Errorf("%s: expected error %q, got %v", tt.input, tt.expected, p.Errors())
+		}
+	}
+}
+
+func TestNewThisSuper(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"new C(1, ...xs);", "new C(1, ...xs)"},
+		{"new C;", "new C"},
+		{"new a.b.C();", "new a.b.C()"},
+		{"new C().x;", "new C().x"},
+		{"new C(1).m(2);", "new C(1).m(2)"},
+		{"new new C()();", "new new C()()"},
+		{"function F() { return new.target; }", "function F() { return new.target; }"},
+		{"function F() { this.x = super.m(super[k]); }", "function F() { (this.x = super.m(super[k])) }"},
+		{"function f(this: number, a: string, ...b: number[]) {}", "function f(this: number, a: string, ...b: number[]) {  }"},
+		{"function f(a: number = 1, { b }: { b: string }) {}", "function f(a: number = 1, { b }: { b: string; }) {  }"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if actual := program.String(); actual != tt.expected {
+			t.Errorf("%s: expected=%q, got=%q", tt.input, tt.expected, actual)
+		}
+	}
+
+	errorTests := []struct {
+		input    string
+		expected string
+	}{
+		{"function f() { new.foo; }", "'foo' is not a valid meta-property for keyword 'new'. Did you mean 'target'?"},
+		{"new.target;", "Meta-property 'new.target' is only allowed in the body of a function declaration, function expression, or constructor."},
+		{"super;", "'super' must be followed by an argument list or member access."},
+		{"function f(a, this: number) {}", "A 'this' parameter must be the first parameter."},
+	}
+
+	for _, tt := range errorTests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+		if errs := p.Errors(); len(errs) == 0 || errs[0] != tt.expected {
+			t.Errorf("%s: expected error %q, got %v", tt.input, tt.expected, errs)
+		}
+	}
+}
+
+func TestErrorRecovery(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string // the statements, "" for a MissingNode
+		errors   int
+	}{
+		{"let = 1;\nf();", []string{"", "f()"}, 1},
+		{"f(1, ); g();", []string{"", "g()"}, 1},
+		{"o.m(1 2); c();", []string{"", "c()"}, 1},
+		{"}} z();", []string{"", "", "z()"}, 2},
+		{"function f() { let = 1; g(); } h();", []string{"function f() { g() }", "h()"}, 1},
+		{"function f() { x = (1; }\nf();", []string{"function f() {  }", "f()"}, 1},
+		{"let a = [1,\nconst b = 2;", []string{"", "const b = 2;"}, 1},
+		{"{ let }", []string{"{  }"}, 1},
+		{"switch (x) { case 1: f(; case 2: g(); }", []string{"switch (x) { case 1:  case 2: g() }"}, 1},
+		{"for (const x of xs) let = 1\nf()", []string{"for (const x of xs) ", "f()"}, 1},
+		{"function f() { g(1,\nreturn 2; }", []string{"function f() { return 2; }"}, 1},
+		{"if (a { b; } c;", []string{"", "c"}, 1},
+		{"try { } z;", []string{"", "z"}, 1},
+		{"for (const of x) {} q;", []string{"", "q"}, 1},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+
+		if len(p.Errors()) != tt.errors {
+			t.Errorf("%s: expected %d errors, got %v", tt.input, tt.errors, p.Errors())
+		}
+		if len(program.Statements) != len(tt.expected) {
+			t.Errorf("%s: expected %d statements, got %d", tt.input, len(tt.expected), len(program.Statements))
+			continue
+		}
+		for i, stmt := range program.Statements {
+			if stmt == nil {
+				t.Fatalf("%s: statement %d is nil", tt.input, i)
+			}
+			_, missing := stmt.(*ast.MissingNode)
+			if missing != (tt.expected[i] == "") {
+				t.Errorf("%s: statement %d expected MissingNode=%v, got %T", tt.input, i, tt.expected[i] == "", stmt)
+			}
+			if stmt.String() != tt.expected[i] {
+				t.Errorf("%s: statement %d expected=%q, got=%q", tt.input, i, tt.expected[i], stmt.String())
+			}
+		}
+	}
+}
+
+func TestDiagnostics(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		pos, end int
+	}{
+		{"let x = 1 let y = 2;", "1:11 - error TS1005: ';' expected.", 10, 13},
+		{"let x = (1;", "1:11 - error TS1005: ')' expected.", 10, 11},
+		{"let a = 1;\nlet b = 2 3;", "2:11 - error TS1005: ';' expected.", 21, 22},
+		{"let class = 1;", "1:5 - error TS1359: Identifier expected. 'class' is a reserved word that cannot be used here.", 4, 9},
+		{"let if = 1;", "1:5 - error TS1359: Identifier expected. 'if' is a reserved word that cannot be used here.", 4, 6},
+		{"function while() {}", "1:10 - error TS1359: Identifier expected. 'while' is a reserved word that cannot be used here.", 9, 14},
+		{"let [1] = list;", "1:6 - error TS1003: Identifier expected.", 5, 6},
+		{"let x =", "1:8 - error TS1109: Expression expected.", 7, 7},
+		{"f(1, #);", "1:6 - error TS1127: Invalid character.", 5, 6},
+		{"let re = /abc/gx;", "1:16 - error TS1499: Unknown regular expression flag.", 15, 16},
+		{"-a ** b;", "1:1 - error TS17006: An unary expression with the '-' operator is not allowed in the left-hand side of an exponentiation expression. Consider enclosing the expression in parentheses.", 0, 2},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+
+		diags := p.Diagnostics()
+		if len(diags) == 0 {
+			t.Errorf("%q: expected %q, got no errors", tt.input, tt.expected)
+			continue
+		}
+		d := diags[0]
+		if d.String() != tt.expected || d.Pos != tt.pos || d.End != tt.end {
+			t.Errorf("%q: expected %q [%d,%d), got %q [%d,%d)", tt.input, tt.expected, tt.pos, tt.end, d.String(), d.Pos, d.End)
+		}
+	}
+}
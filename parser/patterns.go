@@ -0,0 +1,460 @@
+package parser
+
+import (
+	"unicode"
+
+	"github.com/dmarro89/ts-go-compiler/ast"
+	"github.com/dmarro89/ts-go-compiler/token"
+)
+
+// parseArrayLiteral parses [a, , ...b]
+func (p *Parser) parseArrayLiteral() ast.Expression {
+	array := &ast.ArrayLiteral{Token: p.curToken, Elements: []ast.Expression{}}
+
+	for !p.peekTokenIs(token.RBRACKET) {
+		if p.peekTokenIs(token.COMMA) {
+			// A hole, as in [1, , 3]
+			p.nextToken()
+			array.Elements = append(array.Elements, nil)
+			continue
+		}
+
+		p.nextToken()
+		el := p.parseElement()
+		if el == nil {
+			return nil
+		}
+		array.Elements = append(array.Elements, el)
+
+		if !p.peekTokenIs(token.RBRACKET) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+	p.nextToken()
+
+	return array
+}
+
+// parseElement parses an array element or argument, which may be spread
+func (p *Parser) parseElement() ast.Expression {
+	if !p.curTokenIs(token.ELLIPSIS) {
+		return p.parseExpression(COMMA)
+	}
+
+	spread := &ast.SpreadElement{Token: p.curToken}
+	p.nextToken()
+	spread.Argument = p.parseExpression(COMMA)
+	if spread.Argument == nil {
+		return nil
+	}
+	return spread
+}
+
+// parseObjectLiteral parses { a: 1, b, ...c }
+func (p *Parser) parseObjectLiteral() ast.Expression {
+	object := &ast.ObjectLiteral{Token: p.curToken, Properties: []*ast.ObjectProperty{}}
+
+	for !p.peekTokenIs(token.RBRACE) {
+		p.nextToken()
+		prop := p.parseObjectProperty()
+		if prop == nil {
+			return nil
+		}
+		object.Properties = append(object.Properties, prop)
+
+		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+	p.nextToken()
+
+	return object
+}
+
+func (p *Parser) parseObjectProperty() *ast.ObjectProperty {
+	prop := &ast.ObjectProperty{Token: p.curToken}
+	prop.LeadingComments = p.takeLeadingComments()
+
+	if p.curTokenIs(token.ELLIPSIS) {
+		prop.Value = p.parseElement()
+		if prop.Value == nil {
+			return nil
+		}
+		return prop
+	}
+
+	// async and * start a method, unless async is the name of a property
+	async := p.curToken.Literal == "async" && !p.peekToken.NewlineBefore && !p.peekTokenIs(token.COLON) &&
+		!p.peekTokenIs(token.COMMA) && !p.peekTokenIs(token.RBRACE) && !p.peekTokenIs(token.ASSIGN) && !p.peekTokenIs(token.LPAREN)
+	if async {
+		p.nextToken()
+	}
+	generator := p.curTokenIs(token.ASTERISK)
+	if generator {
+		p.nextToken()
+	}
+
+	prop.Key = p.parsePropertyName()
+	if prop.Key == nil {
+		return nil
+	}
+
+	// A method, e.g. async m() {}, is a property whose value is the function
+	if async || generator || p.peekTokenIs(token.LPAREN) {
+		fn := &ast.FunctionLiteral{Token: p.curToken, Async: async, Generator: generator}
+		prop.Method = true
+		if prop.Value = p.parseFunctionRest(fn); prop.Value == nil {
+			return nil
+		}
+		return prop
+	}
+
+	if p.peekTokenIs(token.COLON) {
+		p.nextToken()
+		p.nextToken()
+		prop.Value = p.parseExpression(COMMA)
+		if prop.Value == nil {
+			return nil
+		}
+		return prop
+	}
+
+	if prop.Token.Type != token.IDENT {
+		p.peekError(token.COLON)
+		return nil
+	}
+	prop.Value = prop.Key
+	prop.Shorthand = true
+
+	// { a = 1 } is only valid as a destructuring pattern, where it gives a
+	// default. It is kept as an assignment until the literal becomes one.
+	if p.peekTokenIs(token.ASSIGN) {
+		p.nextToken()
+		assign := &ast.AssignmentExpression{Token: p.curToken, Target: prop.Key, Operator: "="}
+		p.nextToken()
+		assign.Value = p.parseExpression(COMMA)
+		if assign.Value == nil {
+			return nil
+		}
+		prop.Value = assign
+	}
+
+	return prop
+}
+
+// parsePropertyName parses the key of a property, which can be a name,
+// including keywords, a string or a number
+func (p *Parser) parsePropertyName() ast.Expression {
+	switch {
+	case p.curTokenIs(token.STRING):
+		return p.parseStringLiteral()
+	case p.curTokenIs(token.INT):
+		return p.parseIntegerLiteral()
+	case p.curTokenIs(token.IDENT) || isIdentifierName(p.curToken):
+		return &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	}
+
+	p.errorAt(p.curToken, errPropertyExpected)
+	return nil
+}
+
+// isIdentifierName reports whether tok is a keyword, which can be used as a
+// property name, e.g. a.delete or { in: 1 }
+func isIdentifierName(tok token.Token) bool {
+	if tok.Type == token.IDENT || tok.Literal == "" {
+		return false
+	}
+	for _, ch := range tok.Literal {
+		if !unicode.IsLetter(ch) {
+			return false
+		}
+	}
+	return true
+}
+
+// parseBindingName parses the name of a declaration or parameter: an
+// identifier or a destructuring pattern
+func (p *Parser) parseBindingName() ast.Expression {
+	switch p.curToken.Type {
+	case token.IDENT:
+		p.checkBindingName(p.curToken)
+		return &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	case token.LBRACE:
+		return p.parseObjectBindingPattern()
+	case token.LBRACKET:
+		return p.parseArrayBindingPattern()
+	}
+
+	if isReserved(p.curToken) {
+		p.errorAt(p.curToken, errReservedWord, p.curToken.Literal)
+		return nil
+	}
+	p.errorAt(p.curToken, errIdentifierExpected)
+	return nil
+}
+
+// parseObjectBindingPattern parses { a, b: c = 1, ...rest }
+func (p *Parser) parseObjectBindingPattern() ast.Expression {
+	pattern := &ast.ObjectPattern{Token: p.curToken, Properties: []*ast.BindingElement{}}
+
+	for !p.peekTokenIs(token.RBRACE) {
+		p.nextToken()
+		el := &ast.BindingElement{Token: p.curToken}
+
+		switch {
+		case p.curTokenIs(token.ELLIPSIS):
+			if !p.expectPeek(token.IDENT) {
+				return nil
+			}
+			p.checkBindingName(p.curToken)
+			el.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+			el.Rest = true
+		default:
+			key := p.parsePropertyName()
+			if key == nil {
+				return nil
+			}
+			if p.peekTokenIs(token.COLON) {
+				p.nextToken()
+				p.nextToken()
+				el.PropertyName = key
+				el.Name = p.parseBindingName()
+				if el.Name == nil {
+					return nil
+				}
+			} else if _, ok := key.(*ast.Identifier); ok && el.Token.Type == token.IDENT {
+				el.Name = key
+			} else {
+				p.peekError(token.COLON)
+				return nil
+			}
+			if !p.parseBindingDefault(el) {
+				return nil
+			}
+		}
+
+		pattern.Properties = append(pattern.Properties, el)
+		if !p.endBindingElement(el, token.RBRACE) {
+			return nil
+		}
+	}
+	p.nextToken()
+
+	return pattern
+}
+
+// parseArrayBindingPattern parses [a, , b = 1, ...rest]
+func (p *Parser) parseArrayBindingPattern() ast.Expression {
+	pattern := &ast.ArrayPattern{Token: p.curToken, Elements: []*ast.BindingElement{}}
+
+	for !p.peekTokenIs(token.RBRACKET) {
+		if p.peekTokenIs(token.COMMA) {
+			p.nextToken()
+			pattern.Elements = append(pattern.Elements, nil)
+			continue
+		}
+
+		p.nextToken()
+		el := &ast.BindingElement{Token: p.curToken}
+		if p.curTokenIs(token.ELLIPSIS) {
+			el.Rest = true
+			p.nextToken()
+		}
+		el.Name = p.parseBindingName()
+		if el.Name == nil {
+			return nil
+		}
+		if !el.Rest && !p.parseBindingDefault(el) {
+			return nil
+		}
+
+		pattern.Elements = append(pattern.Elements, el)
+		if !p.endBindingElement(el, token.RBRACKET) {
+			return nil
+		}
+	}
+	p.nextToken()
+
+	return pattern
+}
+
+// parseBindingDefault parses the optional = default after a binding name
+func (p *Parser) parseBindingDefault(el *ast.BindingElement) bool {
+	if !p.peekTokenIs(token.ASSIGN) {
+		return true
+	}
+
+	p.nextToken()
+	p.nextToken()
+	el.Default = p.parseExpression(COMMA)
+	return el.Default != nil
+}
+
+// endBindingElement consumes the comma after an element of a pattern. A
+// rest element must be the last one.
+func (p *Parser) endBindingElement(el *ast.BindingElement, end token.TokenType) bool {
+	if p.peekTokenIs(end) {
+		return true
+	}
+	if el.Rest {
+		p.errorAt(el.Token, errRestElementLast)
+		return false
+	}
+	return p.expectPeek(token.COMMA)
+}
+
+// toAssignmentPattern turns the array or object literal on the left of = into
+// the pattern it stands for
+func (p *Parser) toAssignmentPattern(expr ast.Expression) ast.Expression {
+	switch e := expr.(type) {
+	case *ast.ArrayLiteral:
+		pattern := &ast.ArrayPattern{Token: e.Token, Elements: []*ast.BindingElement{}}
+		for i, el := range e.Elements {
+			if el == nil {
+				pattern.Elements = append(pattern.Elements, nil)
+				continue
+			}
+			be := p.toBindingElement(el)
+			if be == nil {
+				return nil
+			}
+			if be.Rest && i != len(e.Elements)-1 {
+				p.errorAt(be.Token, errRestElementLast)
+				return nil
+			}
+			pattern.Elements = append(pattern.Elements, be)
+		}
+		return pattern
+	case *ast.ObjectLiteral:
+		pattern := &ast.ObjectPattern{Token: e.Token, Properties: []*ast.BindingElement{}}
+		for i, prop := range e.Properties {
+			be := p.toBindingElement(prop.Value)
+			if be == nil {
+				return nil
+			}
+			if be.Rest && i != len(e.Properties)-1 {
+				p.errorAt(be.Token, errRestElementLast)
+				return nil
+			}
+			if !prop.Shorthand {
+				be.PropertyName = prop.Key
+			}
+			pattern.Properties = append(pattern.Properties, be)
+		}
+		return pattern
+	}
+
+	return p.toAssignmentTarget(expr)
+}
+
+// toBindingElement turns an element of a literal into an element of a
+// pattern: ...a becomes a rest element and a = 1 a default
+func (p *Parser) toBindingElement(expr ast.Expression) *ast.BindingElement {
+	el := &ast.BindingElement{Token: firstToken(expr)}
+
+	switch e := expr.(type) {
+	case *ast.SpreadElement:
+		el.Rest = true
+		expr = e.Argument
+	case *ast.AssignmentExpression:
+		if e.Operator == "=" {
+			el.Default = e.Value
+			expr = e.Target
+		}
+	}
+
+	el.Name = p.toAssignmentPattern(expr)
+	if el.Name == nil {
+		return nil
+	}
+	return el
+}
+
+// toAssignmentTarget checks that expr can be assigned to by a destructuring
+// assignment
+func (p *Parser) toAssignmentTarget(expr ast.Expression) ast.Expression {
+	switch e := expr.(type) {
+	case *ast.Identifier, *ast.IndexExpression, *ast.ObjectPattern, *ast.ArrayPattern:
+		if !ast.IsOptionalChain(e) {
+			return e
+		}
+	case *ast.MethodCallExpression:
+		if e.Arguments == nil && !e.Optional && !ast.IsOptionalChain(e.Object) {
+			return e
+		}
+	}
+
+	p.errorAt(startToken(expr, p.curToken), errInvalidAssignmentTarget)
+	return nil
+}
+
+// startToken returns the first token of expr, where errors about it are
+// reported, or fallback when it is not known
+func startToken(expr ast.Expression, fallback token.Token) token.Token {
+	switch e := expr.(type) {
+	case *ast.Identifier:
+		return e.Token
+	case *ast.IntegerLiteral:
+		return e.Token
+	case *ast.StringLiteral:
+		return e.Token
+	case *ast.Boolean:
+		return e.Token
+	case *ast.NullLiteral:
+		return e.Token
+	case *ast.ThisExpression:
+		return e.Token
+	case *ast.PrefixExpression:
+		return e.Token
+	case *ast.ParenthesizedExpression:
+		return e.Token
+	case *ast.ArrayLiteral:
+		return e.Token
+	case *ast.ObjectLiteral:
+		return e.Token
+	case *ast.FunctionLiteral:
+		return e.Token
+	case *ast.NewExpression:
+		return e.Token
+	case *ast.InfixExpression:
+		return startToken(e.Left, fallback)
+	case *ast.PostfixExpression:
+		return startToken(e.Left, fallback)
+	case *ast.NonNullExpression:
+		return startToken(e.Expression, fallback)
+	case *ast.AssignmentExpression:
+		return startToken(e.Target, fallback)
+	case *ast.ConditionalExpression:
+		return startToken(e.Condition, fallback)
+	case *ast.CallExpression:
+		return startToken(e.Function, fallback)
+	case *ast.MethodCallExpression:
+		return startToken(e.Object, fallback)
+	case *ast.IndexExpression:
+		return startToken(e.Left, fallback)
+	}
+	return fallback
+}
+
+// firstToken returns the first token of an expression
+func firstToken(expr ast.Expression) token.Token {
+	switch e := expr.(type) {
+	case *ast.Identifier:
+		return e.Token
+	case *ast.SpreadElement:
+		return e.Token
+	case *ast.ArrayLiteral:
+		return e.Token
+	case *ast.ObjectLiteral:
+		return e.Token
+	case *ast.AssignmentExpression:
+		return firstToken(e.Target)
+	case *ast.MethodCallExpression:
+		return firstToken(e.Object)
+	case *ast.IndexExpression:
+		return firstToken(e.Left)
+	case *ast.CallExpression:
+		return firstToken(e.Function)
+	}
+	return token.Token{Type: token.ILLEGAL, Literal: expr.TokenLiteral()}
+}
@@ -0,0 +1,332 @@
+package parser
+
+import (
+	"github.com/dmarro89/ts-go-compiler/ast"
+	"github.com/dmarro89/ts-go-compiler/token"
+)
+
+// parseSwitchStatement parses switch (x) { case a: ... default: ... }
+func (p *Parser) parseSwitchStatement() *ast.SwitchStatement {
+	stmt := &ast.SwitchStatement{Token: p.curToken}
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+	p.nextToken()
+	stmt.Discriminant = p.parseExpression(LOWEST)
+	if stmt.Discriminant == nil || !p.expectPeek(token.RPAREN) || !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+	p.nextToken()
+
+	p.breakTargets++
+	defer func() { p.breakTargets-- }()
+
+	hasDefault := false
+	for !p.curTokenIs(token.RBRACE) {
+		clause := &ast.SwitchCase{Token: p.curToken}
+
+		switch p.curToken.Type {
+		case token.CASE:
+			p.nextToken()
+			clause.Test = p.parseExpression(LOWEST)
+			if clause.Test == nil {
+				return nil
+			}
+		case token.DEFAULT:
+			if hasDefault {
+				p.errorAt(p.curToken, errDuplicateDefault)
+				return nil
+			}
+			hasDefault = true
+		case token.EOF:
+			p.errorAt(p.curToken, errExpected, "}")
+			return nil
+		default:
+			p.errorAt(p.curToken, errCaseExpected)
+			return nil
+		}
+
+		if !p.expectPeek(token.COLON) {
+			return nil
+		}
+		p.nextToken()
+
+		for !p.curTokenIs(token.CASE) && !p.curTokenIs(token.DEFAULT) &&
+			!p.curTokenIs(token.RBRACE) && !p.curTokenIs(token.EOF) {
+			clause.Consequent = append(clause.Consequent, p.parseStatement())
+			p.nextStatement()
+		}
+
+		stmt.Cases = append(stmt.Cases, clause)
+	}
+
+	return stmt
+}
+
+// parseIfStatement parses if (condition) statement else statement
+func (p *Parser) parseIfStatement() *ast.IfStatement {
+	stmt := &ast.IfStatement{Token: p.curToken}
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+	p.nextToken()
+	stmt.Condition = p.parseExpression(LOWEST)
+	if stmt.Condition == nil || !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+	p.nextToken()
+	stmt.Consequence = p.parseStatement()
+
+	if p.peekTokenIs(token.ELSE) {
+		p.nextToken()
+		p.nextToken()
+		stmt.Alternative = p.parseStatement()
+	}
+
+	return stmt
+}
+
+// label is the label of a statement enclosing the one being parsed
+type label struct {
+	name string
+	// loop is set when the labeled statement is a loop, which continue
+	// may name, and chained when it is another labeled statement
+	loop    bool
+	chained bool
+}
+
+// parseLabeledStatement parses label: statement
+func (p *Parser) parseLabeledStatement() *ast.LabeledStatement {
+	stmt := &ast.LabeledStatement{Token: p.curToken}
+	stmt.Label = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	if p.findLabel(stmt.Label.Value) != nil {
+		p.errorAt(p.curToken, errDuplicateLabel, stmt.Label.Value)
+		return nil
+	}
+	p.nextToken()
+	p.nextToken()
+
+	p.labels = append(p.labels, label{name: stmt.Label.Value})
+	defer func() { p.labels = p.labels[:len(p.labels)-1] }()
+
+	switch {
+	case p.curTokenIs(token.FOR):
+		// A loop gives continue all the labels right before it
+		for i := len(p.labels) - 1; i >= 0; i-- {
+			p.labels[i].loop = true
+			if i == 0 || !p.labels[i-1].chained {
+				break
+			}
+		}
+	case p.curTokenIs(token.IDENT) && p.peekTokenIs(token.COLON):
+		p.labels[len(p.labels)-1].chained = true
+	}
+
+	stmt.Body = p.parseStatement()
+	return stmt
+}
+
+// findLabel returns the enclosing label with the name, or nil
+func (p *Parser) findLabel(name string) *label {
+	for i := range p.labels {
+		if p.labels[i].name == name {
+			return &p.labels[i]
+		}
+	}
+	return nil
+}
+
+// parseJumpLabel parses the label after break or continue, which must be
+// on the same line, or returns nil
+func (p *Parser) parseJumpLabel() *ast.Identifier {
+	if !p.peekTokenIs(token.IDENT) || p.peekToken.NewlineBefore {
+		return nil
+	}
+	p.nextToken()
+	return &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+}
+
+// parseBreakStatement parses break; and break label;
+func (p *Parser) parseBreakStatement() *ast.BreakStatement {
+	stmt := &ast.BreakStatement{Token: p.curToken}
+
+	if stmt.Label = p.parseJumpLabel(); stmt.Label != nil {
+		if p.findLabel(stmt.Label.Value) == nil {
+			p.errorAt(p.curToken, errBreakLabel)
+			return nil
+		}
+	} else if p.breakTargets == 0 {
+		p.errorAt(p.curToken, errBreakOutside)
+		return nil
+	}
+
+	p.parseSemicolon()
+	return stmt
+}
+
+// parseThrowStatement parses throw expression;
+func (p *Parser) parseThrowStatement() *ast.ThrowStatement {
+	stmt := &ast.ThrowStatement{Token: p.curToken}
+
+	// throw [no LineTerminator here] Expression
+	if p.peekToken.NewlineBefore {
+		p.errorAt(p.curToken, errLineBreak)
+		return nil
+	}
+	if p.canInsertSemicolon() {
+		p.errorAt(p.peekToken, errExpressionExpected)
+		return nil
+	}
+
+	p.nextToken()
+	stmt.Argument = p.parseExpression(LOWEST)
+	if stmt.Argument == nil {
+		return nil
+	}
+
+	p.parseSemicolon()
+	return stmt
+}
+
+// parseTryStatement parses try { } catch (e) { } finally { }
+func (p *Parser) parseTryStatement() *ast.TryStatement {
+	stmt := &ast.TryStatement{Token: p.curToken}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+	stmt.Block = p.parseBlockStatement()
+
+	if p.peekTokenIs(token.CATCH) {
+		p.nextToken()
+		stmt.Handler = &ast.CatchClause{Token: p.curToken}
+
+		// The binding is optional: catch { }
+		if p.peekTokenIs(token.LPAREN) {
+			p.nextToken()
+			p.nextToken()
+			stmt.Handler.Param = p.parseBindingName()
+			if stmt.Handler.Param == nil || !p.expectPeek(token.RPAREN) {
+				return nil
+			}
+		}
+
+		if !p.expectPeek(token.LBRACE) {
+			return nil
+		}
+		stmt.Handler.Body = p.parseBlockStatement()
+	}
+
+	if p.peekTokenIs(token.FINALLY) {
+		p.nextToken()
+		if !p.expectPeek(token.LBRACE) {
+			return nil
+		}
+		stmt.Finalizer = p.parseBlockStatement()
+	}
+
+	if stmt.Handler == nil && stmt.Finalizer == nil {
+		p.errorAt(p.peekToken, errCatchExpected)
+		return nil
+	}
+
+	return stmt
+}
+
+// parseContinueStatement parses continue; and continue label;
+func (p *Parser) parseContinueStatement() *ast.ContinueStatement {
+	stmt := &ast.ContinueStatement{Token: p.curToken}
+
+	if stmt.Label = p.parseJumpLabel(); stmt.Label != nil {
+		if l := p.findLabel(stmt.Label.Value); l == nil || !l.loop {
+			p.errorAt(p.curToken, errContinueLabel)
+			return nil
+		}
+	} else if p.continueTargets == 0 {
+		p.errorAt(p.curToken, errContinueOutside)
+		return nil
+	}
+
+	p.parseSemicolon()
+	return stmt
+}
+
+// parseForStatement parses for (x of xs), for await (x of xs) and
+// for (k in o) loops. The head either declares the loop variable with let,
+// const or var, or assigns an existing variable, property or pattern.
+func (p *Parser) parseForStatement() ast.Statement {
+	tok := p.curToken
+
+	// await is not a keyword outside async functions
+	await := p.peekTokenIs(token.IDENT) && p.peekToken.Literal == "await"
+	if await {
+		p.nextToken()
+		if !p.awaitAllowed() {
+			p.errorAt(p.curToken, errForAwaitOutsideAsync)
+		}
+	}
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+	p.nextToken()
+
+	kind := ""
+	var target ast.Expression
+	switch p.curToken.Type {
+	case token.LET, token.CONST, token.VAR:
+		kind = p.curToken.Literal
+		p.nextToken()
+		target = p.parseBindingName()
+	default:
+		// Stop before in, which would be parsed as an operator
+		target = p.parseExpression(LESSGREATER)
+		switch target.(type) {
+		case nil:
+		case *ast.ArrayLiteral, *ast.ObjectLiteral:
+			target = p.toAssignmentPattern(target)
+		default:
+			target = p.toAssignmentTarget(target)
+		}
+	}
+	if target == nil {
+		return nil
+	}
+
+	var right ast.Expression
+	of := p.peekTokenIs(token.IDENT) && p.peekToken.Literal == "of"
+	switch {
+	case of:
+		p.nextToken()
+		p.nextToken()
+		right = p.parseExpression(COMMA)
+	case p.peekTokenIs(token.IN) && !await:
+		p.nextToken()
+		p.nextToken()
+		right = p.parseExpression(LOWEST)
+	case await:
+		p.errorAt(p.peekToken, errExpected, "of")
+		return nil
+	default:
+		p.errorAt(p.peekToken, errExpected, "in' or 'of")
+		return nil
+	}
+	if right == nil || !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+	p.nextToken()
+
+	p.breakTargets++
+	p.continueTargets++
+	body := p.parseStatement()
+	p.breakTargets--
+	p.continueTargets--
+
+	if of {
+		return &ast.ForOfStatement{Token: tok, Await: await, Kind: kind, Target: target, Right: right, Body: body}
+	}
+	return &ast.ForInStatement{Token: tok, Kind: kind, Target: target, Right: right, Body: body}
+}
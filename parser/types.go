@@ -0,0 +1,301 @@
+package parser
+
+import (
+	"github.com/dmarro89/ts-go-compiler/ast"
+	"github.com/dmarro89/ts-go-compiler/lexer"
+	"github.com/dmarro89/ts-go-compiler/token"
+)
+
+// ParseJSDocType parses the type expression found between the braces of a
+// JSDoc tag, such as the string|number in @type {string|number}
+func ParseJSDocType(input string) (ast.TypeNode, []string) {
+	p := New(lexer.New(input))
+	p.jsdoc = true
+
+	t := p.parseType()
+	if t != nil && !p.peekTokenIs(token.EOF) {
+		p.errorAt(p.peekToken, errUnexpectedToken)
+	}
+	if len(p.diagnostics) > 0 {
+		return nil, p.Errors()
+	}
+	return t, nil
+}
+
+// parseType parses the type starting at the current token
+func (p *Parser) parseType() ast.TypeNode {
+	p.typeDepth++
+	defer func() { p.typeDepth-- }()
+
+	tok := p.curToken
+
+	// A leading | is allowed before the first member of a union
+	if p.curTokenIs(token.PIPE) {
+		p.nextToken()
+	}
+
+	first := p.parsePostfixType()
+	if first == nil || !p.peekTokenIs(token.PIPE) {
+		return first
+	}
+
+	union := &ast.UnionTypeNode{Token: tok, Types: []ast.TypeNode{first}}
+	for p.peekTokenIs(token.PIPE) {
+		p.nextToken()
+		p.nextToken()
+
+		t := p.parsePostfixType()
+		if t == nil {
+			return nil
+		}
+		union.Types = append(union.Types, t)
+	}
+
+	return union
+}
+
+// parsePostfixType parses a type followed by any number of [] suffixes
+func (p *Parser) parsePostfixType() ast.TypeNode {
+	t := p.parsePrimaryType()
+
+	for t != nil && p.peekTokenIs(token.LBRACKET) {
+		p.nextToken()
+		t = &ast.ArrayTypeNode{Token: p.curToken, ElementType: t}
+
+		if !p.expectPeek(token.RBRACKET) {
+			return nil
+		}
+	}
+
+	// JSDoc writes nullable types as T? as well as ?T
+	if t != nil && p.jsdoc && p.peekTokenIs(token.QUESTION) {
+		p.nextToken()
+		t = nullableType(p.curToken, t)
+	}
+
+	return t
+}
+
+func (p *Parser) parsePrimaryType() ast.TypeNode {
+	switch p.curToken.Type {
+	case token.IDENT, token.CONSOLE, token.LOG:
+		return p.parseTypeReference()
+	case token.VOID, token.NULL:
+		return &ast.TypeReference{Token: p.curToken, Name: p.curToken.Literal}
+	case token.STRING:
+		return &ast.LiteralTypeNode{Token: p.curToken, Literal: p.parseStringLiteral()}
+	case token.INT:
+		lit := p.parseIntegerLiteral()
+		if lit == nil {
+			return nil
+		}
+		return &ast.LiteralTypeNode{Token: p.curToken, Literal: lit}
+	case token.TRUE, token.FALSE:
+		return &ast.LiteralTypeNode{Token: p.curToken, Literal: p.parseBoolean()}
+	case token.LPAREN:
+		p.nextToken()
+		t := p.parseType()
+		if t == nil || !p.expectPeek(token.RPAREN) {
+			return nil
+		}
+		return t
+	case token.LBRACKET:
+		return p.parseTupleType()
+	case token.LBRACE:
+		return p.parseObjectType()
+	}
+
+	if p.jsdoc {
+		switch p.curToken.Type {
+		case token.ASTERISK:
+			// {*} is the JSDoc spelling of any
+			return &ast.TypeReference{Token: p.curToken, Name: "any"}
+		case token.QUESTION:
+			tok := p.curToken
+			if p.peekTokenIs(token.EOF) || p.peekTokenIs(token.RPAREN) || p.peekTokenIs(token.COMMA) {
+				// A lone ? is the unknown type
+				return &ast.TypeReference{Token: tok, Name: "unknown"}
+			}
+			p.nextToken()
+			t := p.parsePrimaryType()
+			if t == nil {
+				return nil
+			}
+			return nullableType(tok, t)
+		case token.BANG:
+			// {!T} is a non-nullable T, which is T itself
+			p.nextToken()
+			return p.parsePrimaryType()
+		case token.FUNCTION:
+			return p.parseJSDocFunctionType()
+		case token.ELLIPSIS:
+			// {...number} types a rest parameter as number[]
+			tok := p.curToken
+			p.nextToken()
+			t := p.parsePrimaryType()
+			if t == nil {
+				return nil
+			}
+			return &ast.ArrayTypeNode{Token: tok, ElementType: t}
+		}
+	}
+
+	p.errorAt(p.curToken, errTypeExpected)
+	return nil
+}
+
+// parseTypeReference parses a possibly qualified type name with optional
+// type arguments, e.g. Array<string> or NS.Type
+func (p *Parser) parseTypeReference() ast.TypeNode {
+	ref := &ast.TypeReference{Token: p.curToken, Name: p.curToken.Literal}
+
+	for p.peekTokenIs(token.DOT) {
+		p.nextToken()
+		if p.jsdoc && p.peekTokenIs(token.LT) {
+			// JSDoc also accepts Array.<string>
+			break
+		}
+		p.nextToken()
+		if !p.curTokenIs(token.IDENT) {
+			p.errorAt(p.curToken, errIdentifierExpected)
+			return nil
+		}
+		ref.Name += "." + p.curToken.Literal
+	}
+
+	if !p.peekTokenIs(token.LT) {
+		return ref
+	}
+
+	p.nextToken()
+	for {
+		p.nextToken()
+		arg := p.parseType()
+		if arg == nil {
+			return nil
+		}
+		ref.TypeArguments = append(ref.TypeArguments, arg)
+
+		if !p.peekTokenIs(token.COMMA) {
+			break
+		}
+		p.nextToken()
+	}
+
+	if !p.expectPeek(token.GT) {
+		return nil
+	}
+
+	return ref
+}
+
+// parseTupleType parses [number, string]
+func (p *Parser) parseTupleType() ast.TypeNode {
+	tuple := &ast.TupleTypeNode{Token: p.curToken}
+
+	for !p.peekTokenIs(token.RBRACKET) {
+		p.nextToken()
+		t := p.parseType()
+		if t == nil {
+			return nil
+		}
+		tuple.Elements = append(tuple.Elements, t)
+
+		if !p.peekTokenIs(token.RBRACKET) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+	p.nextToken()
+
+	return tuple
+}
+
+// parseObjectType parses { a: number, b?: string }. Properties are separated
+// by commas or semicolons.
+func (p *Parser) parseObjectType() ast.TypeNode {
+	object := &ast.ObjectTypeNode{Token: p.curToken}
+
+	for !p.peekTokenIs(token.RBRACE) {
+		p.nextToken()
+		name := p.parsePropertyName()
+		if name == nil {
+			return nil
+		}
+		prop := &ast.PropertySignature{Token: p.curToken, Name: p.curToken.Literal}
+		if lit, ok := name.(*ast.StringLiteral); ok {
+			prop.Name = lit.Value
+		}
+
+		if p.peekTokenIs(token.QUESTION) {
+			p.nextToken()
+			prop.Optional = true
+		}
+		if p.peekTokenIs(token.COLON) {
+			p.nextToken()
+			p.nextToken()
+			prop.Type = p.parseType()
+			if prop.Type == nil {
+				return nil
+			}
+		}
+		object.Properties = append(object.Properties, prop)
+
+		if p.peekTokenIs(token.COMMA) || p.peekTokenIs(token.SEMICOLON) {
+			p.nextToken()
+		} else if !p.peekTokenIs(token.RBRACE) {
+			p.peekError(token.RBRACE)
+			return nil
+		}
+	}
+	p.nextToken()
+
+	return object
+}
+
+// parseJSDocFunctionType parses function(number, string): boolean
+func (p *Parser) parseJSDocFunctionType() ast.TypeNode {
+	fn := &ast.FunctionTypeNode{Token: p.curToken}
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+
+	if p.peekTokenIs(token.RPAREN) {
+		p.nextToken()
+	} else {
+		for {
+			p.nextToken()
+			param := p.parseType()
+			if param == nil {
+				return nil
+			}
+			fn.Parameters = append(fn.Parameters, param)
+
+			if !p.peekTokenIs(token.COMMA) {
+				break
+			}
+			p.nextToken()
+		}
+
+		if !p.expectPeek(token.RPAREN) {
+			return nil
+		}
+	}
+
+	if p.peekTokenIs(token.COLON) {
+		p.nextToken()
+		p.nextToken()
+		fn.ReturnType = p.parseType()
+		if fn.ReturnType == nil {
+			return nil
+		}
+	}
+
+	return fn
+}
+
+// nullableType builds T | null for the JSDoc ?T and T? forms
+func nullableType(tok token.Token, t ast.TypeNode) ast.TypeNode {
+	null := &ast.TypeReference{Token: tok, Name: "null"}
+	return &ast.UnionTypeNode{Token: tok, Types: []ast.TypeNode{t, null}}
+}
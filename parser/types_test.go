@@ -0,0 +1,59 @@
+package parser
+
+import (
+	"testing"
+)
+
+func TestParseJSDocType(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"number", "number"},
+		{"string|number", "string | number"},
+		{"|string|number", "string | number"},
+		{"string[][]", "string[][]"},
+		{"(string|number)[]", "(string | number)[]"},
+		{"Array<string>", "Array<string>"},
+		{"Array.<string>", "Array<string>"},
+		{"Map<string, number[]>", "Map<string, number[]>"},
+		{"NS.Type", "NS.Type"},
+		{`"a"|"b"`, `"a" | "b"`},
+		{"true|false", "true | false"},
+		{"*", "any"},
+		{"?", "unknown"},
+		{"?number", "number | null"},
+		{"number?", "number | null"},
+		{"!Object", "Object"},
+		{"function(number, string): boolean", "function(number, string): boolean"},
+		{"function()", "function()"},
+		{"[number, string]", "[number, string]"},
+		{"[]", "[]"},
+		{"...number", "number[]"},
+		{"{a: number, b?: string}", "{ a: number; b?: string; }"},
+		{`{"a": number; b}`, "{ a: number; b; }"},
+		{"{}", "{}"},
+		{"{a: {b: string}}[]", "{ a: { b: string; }; }[]"},
+	}
+
+	for _, tt := range tests {
+		typ, errs := ParseJSDocType(tt.input)
+		if len(errs) > 0 {
+			t.Errorf("%q: unexpected errors %v", tt.input, errs)
+			continue
+		}
+		if typ.String() != tt.expected {
+			t.Errorf("%q: expected=%q, got=%q", tt.input, tt.expected, typ.String())
+		}
+	}
+}
+
+func TestParseJSDocTypeErrors(t *testing.T) {
+	inputs := []string{"", "number|", "Array<string", "string number", "{a: number", "{a b}"}
+
+	for _, input := range inputs {
+		if _, errs := ParseJSDocType(input); len(errs) == 0 {
+			t.Errorf("%q: expected errors", input)
+		}
+	}
+}
@@ -1,5 +1,7 @@
 package token
 
+import "strconv"
+
 type TokenType int
 
 const (
@@ -10,24 +12,75 @@ const (
 	IDENT  // variable names, functions, etc.
 	INT    // integer numbers
 	STRING // strings
+	REGEXP // regular expression literals, e.g. /ab+c/gi
+
+	COMMENT // a // or /* */ comment, attached to the token that follows it
+
+	// Trivia, only returned when the lexer scans trivia
+	WHITESPACE // spaces and tabs
+	NEWLINE    // a line terminator
 
 	// Equals and not equals
-	EQ     // ==
-	NOT_EQ // !=
+	EQ            // ==
+	NOT_EQ        // !=
+	STRICT_EQ     // ===
+	STRICT_NOT_EQ // !==
 
 	// Operators
-	ASSIGN   // =
-	PLUS     // +
-	MINUS    // -
-	BANG     // !
-	ASTERISK // *
-	SLASH    // /
+	ASSIGN    // =
+	PLUS      // +
+	MINUS     // -
+	BANG      // !
+	ASTERISK  // *
+	SLASH     // /
+	PERCENT   // %
+	EXPONENT  // **
+	INCREMENT // ++
+	DECREMENT // --
+
+	LT    // <
+	GT    // >
+	LT_EQ // <=
+	GT_EQ // >=
+
+	// Bitwise operators
+	PIPE      // |
+	AMPERSAND // &
+	CARET     // ^
+	TILDE     // ~
+	LSHIFT    // <<
+	RSHIFT    // >>
+	URSHIFT   // >>>
+
+	// Logical operators
+	AND      // &&
+	OR       // ||
+	NULLISH  // ??
+	QUESTION // ?
 
-	LT // <
-	GT // >
+	QUESTION_DOT // ?.
+	ARROW        // =>
+
+	// Compound assignments
+	PLUS_ASSIGN      // +=
+	MINUS_ASSIGN     // -=
+	ASTERISK_ASSIGN  // *=
+	SLASH_ASSIGN     // /=
+	PERCENT_ASSIGN   // %=
+	EXPONENT_ASSIGN  // **=
+	LSHIFT_ASSIGN    // <<=
+	RSHIFT_ASSIGN    // >>=
+	URSHIFT_ASSIGN   // >>>=
+	AMPERSAND_ASSIGN // &=
+	PIPE_ASSIGN      // |=
+	CARET_ASSIGN     // ^=
+	AND_ASSIGN       // &&=
+	OR_ASSIGN        // ||=
+	NULLISH_ASSIGN   // ??=
 
 	// Delimiters
 	COMMA     // ,
+	ELLIPSIS  // ...
 	SEMICOLON // ;
 	COLON     // :
 
@@ -36,6 +89,9 @@ const (
 	LBRACE // {
 	RBRACE // }
 
+	LBRACKET // [
+	RBRACKET // ]
+
 	// Keywords
 	FUNCTION
 	LET
@@ -50,14 +106,256 @@ const (
 
 	TRUE
 	FALSE
+
+	IN
+	INSTANCEOF
+	TYPEOF
+	VOID
+	DELETE
+
+	NULL
+
+	SWITCH
+	CASE
+	DEFAULT
+	BREAK
+	TRY
+	CATCH
+	FINALLY
+	THROW
+
+	FOR
+	CONTINUE
+
+	NEW
+	THIS
+	SUPER
 )
 
+var tokenNames = [...]string{
+	ILLEGAL:          "ILLEGAL",
+	EOF:              "EOF",
+	IDENT:            "IDENT",
+	INT:              "INT",
+	STRING:           "STRING",
+	REGEXP:           "REGEXP",
+	COMMENT:          "COMMENT",
+	WHITESPACE:       "WHITESPACE",
+	NEWLINE:          "NEWLINE",
+	EQ:               "EQ",
+	NOT_EQ:           "NOT_EQ",
+	STRICT_EQ:        "STRICT_EQ",
+	STRICT_NOT_EQ:    "STRICT_NOT_EQ",
+	ASSIGN:           "ASSIGN",
+	PLUS:             "PLUS",
+	MINUS:            "MINUS",
+	BANG:             "BANG",
+	ASTERISK:         "ASTERISK",
+	SLASH:            "SLASH",
+	PERCENT:          "PERCENT",
+	EXPONENT:         "EXPONENT",
+	INCREMENT:        "INCREMENT",
+	DECREMENT:        "DECREMENT",
+	LT:               "LT",
+	GT:               "GT",
+	LT_EQ:            "LT_EQ",
+	GT_EQ:            "GT_EQ",
+	PIPE:             "PIPE",
+	AMPERSAND:        "AMPERSAND",
+	CARET:            "CARET",
+	TILDE:            "TILDE",
+	LSHIFT:           "LSHIFT",
+	RSHIFT:           "RSHIFT",
+	URSHIFT:          "URSHIFT",
+	AND:              "AND",
+	OR:               "OR",
+	NULLISH:          "NULLISH",
+	QUESTION:         "QUESTION",
+	QUESTION_DOT:     "QUESTION_DOT",
+	ARROW:            "ARROW",
+	PLUS_ASSIGN:      "PLUS_ASSIGN",
+	MINUS_ASSIGN:     "MINUS_ASSIGN",
+	ASTERISK_ASSIGN:  "ASTERISK_ASSIGN",
+	SLASH_ASSIGN:     "SLASH_ASSIGN",
+	PERCENT_ASSIGN:   "PERCENT_ASSIGN",
+	EXPONENT_ASSIGN:  "EXPONENT_ASSIGN",
+	LSHIFT_ASSIGN:    "LSHIFT_ASSIGN",
+	RSHIFT_ASSIGN:    "RSHIFT_ASSIGN",
+	URSHIFT_ASSIGN:   "URSHIFT_ASSIGN",
+	AMPERSAND_ASSIGN: "AMPERSAND_ASSIGN",
+	PIPE_ASSIGN:      "PIPE_ASSIGN",
+	CARET_ASSIGN:     "CARET_ASSIGN",
+	AND_ASSIGN:       "AND_ASSIGN",
+	OR_ASSIGN:        "OR_ASSIGN",
+	NULLISH_ASSIGN:   "NULLISH_ASSIGN",
+	COMMA:            "COMMA",
+	ELLIPSIS:         "ELLIPSIS",
+	SEMICOLON:        "SEMICOLON",
+	COLON:            "COLON",
+	LPAREN:           "LPAREN",
+	RPAREN:           "RPAREN",
+	LBRACE:           "LBRACE",
+	RBRACE:           "RBRACE",
+	LBRACKET:         "LBRACKET",
+	RBRACKET:         "RBRACKET",
+	FUNCTION:         "FUNCTION",
+	LET:              "LET",
+	CONST:            "CONST",
+	VAR:              "VAR",
+	RETURN:           "RETURN",
+	IF:               "IF",
+	ELSE:             "ELSE",
+	CONSOLE:          "CONSOLE",
+	LOG:              "LOG",
+	DOT:              "DOT",
+	TRUE:             "TRUE",
+	FALSE:            "FALSE",
+	IN:               "IN",
+	INSTANCEOF:       "INSTANCEOF",
+	TYPEOF:           "TYPEOF",
+	VOID:             "VOID",
+	DELETE:           "DELETE",
+	NULL:             "NULL",
+	SWITCH:           "SWITCH",
+	CASE:             "CASE",
+	DEFAULT:          "DEFAULT",
+	BREAK:            "BREAK",
+	TRY:              "TRY",
+	CATCH:            "CATCH",
+	FINALLY:          "FINALLY",
+	THROW:            "THROW",
+	FOR:              "FOR",
+	CONTINUE:         "CONTINUE",
+	NEW:              "NEW",
+	THIS:             "THIS",
+	SUPER:            "SUPER",
+}
+
+// String returns the name of the token type, e.g. SEMICOLON
+func (t TokenType) String() string {
+	if t >= 0 && int(t) < len(tokenNames) && tokenNames[t] != "" {
+		return tokenNames[t]
+	}
+	return "TokenType(" + strconv.Itoa(int(t)) + ")"
+}
+
+// tokenTexts are the source texts of the token types that are always
+// written the same way
+var tokenTexts = [...]string{
+	EQ:               "==",
+	NOT_EQ:           "!=",
+	STRICT_EQ:        "===",
+	STRICT_NOT_EQ:    "!==",
+	ASSIGN:           "=",
+	PLUS:             "+",
+	MINUS:            "-",
+	BANG:             "!",
+	ASTERISK:         "*",
+	SLASH:            "/",
+	PERCENT:          "%",
+	EXPONENT:         "**",
+	INCREMENT:        "++",
+	DECREMENT:        "--",
+	LT:               "<",
+	GT:               ">",
+	LT_EQ:            "<=",
+	GT_EQ:            ">=",
+	PIPE:             "|",
+	AMPERSAND:        "&",
+	CARET:            "^",
+	TILDE:            "~",
+	LSHIFT:           "<<",
+	RSHIFT:           ">>",
+	URSHIFT:          ">>>",
+	AND:              "&&",
+	OR:               "||",
+	NULLISH:          "??",
+	QUESTION:         "?",
+	QUESTION_DOT:     "?.",
+	ARROW:            "=>",
+	PLUS_ASSIGN:      "+=",
+	MINUS_ASSIGN:     "-=",
+	ASTERISK_ASSIGN:  "*=",
+	SLASH_ASSIGN:     "/=",
+	PERCENT_ASSIGN:   "%=",
+	EXPONENT_ASSIGN:  "**=",
+	LSHIFT_ASSIGN:    "<<=",
+	RSHIFT_ASSIGN:    ">>=",
+	URSHIFT_ASSIGN:   ">>>=",
+	AMPERSAND_ASSIGN: "&=",
+	PIPE_ASSIGN:      "|=",
+	CARET_ASSIGN:     "^=",
+	AND_ASSIGN:       "&&=",
+	OR_ASSIGN:        "||=",
+	NULLISH_ASSIGN:   "??=",
+	COMMA:            ",",
+	ELLIPSIS:         "...",
+	SEMICOLON:        ";",
+	COLON:            ":",
+	LPAREN:           "(",
+	RPAREN:           ")",
+	LBRACE:           "{",
+	RBRACE:           "}",
+	LBRACKET:         "[",
+	RBRACKET:         "]",
+	FUNCTION:         "function",
+	LET:              "let",
+	CONST:            "const",
+	VAR:              "var",
+	RETURN:           "return",
+	IF:               "if",
+	ELSE:             "else",
+	CONSOLE:          "console",
+	LOG:              "log",
+	DOT:              ".",
+	TRUE:             "true",
+	FALSE:            "false",
+	IN:               "in",
+	INSTANCEOF:       "instanceof",
+	TYPEOF:           "typeof",
+	VOID:             "void",
+	DELETE:           "delete",
+	NULL:             "null",
+	SWITCH:           "switch",
+	CASE:             "case",
+	DEFAULT:          "default",
+	BREAK:            "break",
+	TRY:              "try",
+	CATCH:            "catch",
+	FINALLY:          "finally",
+	THROW:            "throw",
+	FOR:              "for",
+	CONTINUE:         "continue",
+	NEW:              "new",
+	THIS:             "this",
+	SUPER:            "super",
+}
+
+// Text returns how the token type is written in the source, e.g. ; for
+// SEMICOLON, or "" when it varies, as for identifiers
+func (t TokenType) Text() string {
+	if t >= 0 && int(t) < len(tokenTexts) {
+		return tokenTexts[t]
+	}
+	return ""
+}
+
 // Token represents a token in our lexer
 type Token struct {
 	Type    TokenType
 	Literal string
 	Line    int
 	Column  int
+	Pos     int // byte offset of the first character in the input
+	End     int // byte offset just past the last character
+
+	// NewlineBefore is set when a line terminator separates the token from
+	// the previous one; the parser needs it for automatic semicolon insertion.
+	NewlineBefore bool
+
+	// Comments holds the COMMENT tokens found between the previous token and
+	// this one, in source order.
+	Comments []Token
 }
 
 func NewToken(t TokenType, l string, line int, column int) *Token {
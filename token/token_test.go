@@ -0,0 +1,31 @@
+package token
+
+import "testing"
+
+func TestTokenTypeString(t *testing.T) {
+	tests := []struct {
+		tokenType TokenType
+		expected  string
+	}{
+		{ILLEGAL, "ILLEGAL"},
+		{EOF, "EOF"},
+		{IDENT, "IDENT"},
+		{SEMICOLON, "SEMICOLON"},
+		{FALSE, "FALSE"},
+		{TokenType(-1), "TokenType(-1)"},
+		{TokenType(10000), "TokenType(10000)"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.tokenType.String(); got != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, got)
+		}
+	}
+
+	// Every token type has a name
+	for tt := ILLEGAL; int(tt) < len(tokenNames); tt++ {
+		if tokenNames[tt] == "" {
+			t.Errorf("token type %d has no name", tt)
+		}
+	}
+}
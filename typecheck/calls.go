@@ -0,0 +1,156 @@
+package typecheck
+
+import (
+	"fmt"
+
+	"github.com/dmarro89/ts-go-compiler/ast"
+	"github.com/dmarro89/ts-go-compiler/token"
+)
+
+// argument is the type of one argument of a call. Spreading a tuple gives
+// one argument per element; spreading an array gives a spread argument of
+// unknown count.
+type argument struct {
+	Expr   ast.Expression // nil for the elements of a spread tuple
+	At     token.Token    // where errors about the argument are reported
+	Type   Type
+	Spread bool
+}
+
+// checkArguments checks the arguments of a call, expanding spread tuples
+func (tc *TypeChecker) checkArguments(args []ast.Expression) []argument {
+	var result []argument
+	for _, arg := range args {
+		spread, ok := arg.(*ast.SpreadElement)
+		if !ok {
+			result = append(result, argument{Expr: arg, At: startToken(arg), Type: tc.checkExpression(arg)})
+			continue
+		}
+
+		t := tc.checkExpression(spread.Argument)
+		if tuple, ok := t.(*TupleType); ok {
+			for _, el := range tuple.Elements {
+				result = append(result, argument{At: spread.Token, Type: el})
+			}
+			continue
+		}
+		result = append(result, argument{At: spread.Token, Type: tc.iteratedType(spread.Token, t), Spread: true})
+	}
+	return result
+}
+
+// checkCallable reports calling callee, of type t, when it is not a
+// function. A value of type any, or of a union whose members are not all
+// known, may be called; unknown must be narrowed first.
+func (tc *TypeChecker) checkCallable(callee ast.Expression, t Type) {
+	switch t := t.(type) {
+	case *FunctionType, *UnionType:
+	case *BasicType:
+		switch t.Name {
+		case "any", "null", "undefined":
+		case "unknown":
+			tc.reportUnknown(callee)
+		default:
+			tc.errorAt(startToken(callee), errNoCallSignatures, apparentType(t))
+		}
+	default:
+		tc.errorAt(startToken(callee), errNoCallSignatures, apparentType(t))
+	}
+}
+
+// apparentType is the type whose members a value of type t has: the
+// wrapper objects of the primitives, e.g. Number for number
+func apparentType(t Type) Type {
+	if l, ok := t.(*LiteralType); ok {
+		t = l.Base
+	}
+	if b, ok := t.(*BasicType); ok {
+		switch b.Name {
+		case "number":
+			return &BasicType{Name: "Number"}
+		case "string":
+			return &BasicType{Name: "String"}
+		case "boolean":
+			return &BasicType{Name: "Boolean"}
+		}
+	}
+	return t
+}
+
+// checkCall checks arguments against the parameters of the function called,
+// reporting a wrong number of arguments at callee
+func (tc *TypeChecker) checkCall(callee token.Token, fn *FunctionType, args []argument) {
+	rest := -1
+	required := 0
+	for i, p := range fn.Parameters {
+		switch {
+		case p.Rest:
+			rest = i
+		case !p.Optional:
+			required = i + 1
+		}
+	}
+
+	for i, arg := range args {
+		if arg.Spread && (rest < 0 || i < rest) {
+			tc.errorAt(arg.At, errSpreadArgument)
+			return
+		}
+
+		var param Type
+		switch {
+		case rest >= 0 && i >= rest:
+			param = tc.iteratedType(arg.At, fn.Parameters[rest].Type)
+		case i < len(fn.Parameters):
+			param = fn.Parameters[i].Type
+		default:
+			continue
+		}
+		if !tc.isAssignableValue(arg.Expr, arg.Type, param) {
+			tc.addRelationError(arg.At, arg.Type, param, errArgumentType, arg.Type, param)
+		}
+	}
+
+	n := len(args)
+	if n > 0 && args[n-1].Spread {
+		return
+	}
+	switch {
+	case rest >= 0 && n < required:
+		tc.errorAt(callee, errTooFewArguments, required, n)
+	case rest < 0 && (n < required || n > len(fn.Parameters)):
+		expected := fmt.Sprint(required)
+		if required != len(fn.Parameters) {
+			expected = fmt.Sprintf("%d-%d", required, len(fn.Parameters))
+		}
+		if n > len(fn.Parameters) {
+			tc.errorAt(args[len(fn.Parameters)].At, errArgumentCount, expected, n)
+			return
+		}
+		tc.errorAt(callee, errArgumentCount, expected, n)
+	}
+}
+
+// restParameterType is the type of a rest parameter declared with type t,
+// which is already an array type when written as @param {number[]} args
+func restParameterType(t Type) Type {
+	switch t.(type) {
+	case *ArrayType, *TupleType:
+		return t
+	}
+	return &ArrayType{ElementType: t}
+}
+
+// tupleElement returns the type of tuple[index]. A constant index selects
+// one element; any other index may be any of them.
+func (tc *TypeChecker) tupleElement(tuple *TupleType, index ast.Expression) Type {
+	lit, ok := ast.SkipParentheses(index).(*ast.IntegerLiteral)
+	if !ok {
+		return tc.iteratedType(startToken(index), tuple)
+	}
+	if lit.Value >= int64(len(tuple.Elements)) {
+		tc.errorAt(lit.Token, errTupleIndex, tuple, len(tuple.Elements), lit.Value)
+		return &BasicType{Name: "undefined"}
+	}
+	return tuple.Elements[lit.Value]
+}
@@ -0,0 +1,96 @@
+package typecheck
+
+import "github.com/dmarro89/ts-go-compiler/ast"
+
+// globalConstructors are the constructors of the library that may be used
+// with new without being declared, other than the generic library types
+var globalConstructors = map[string]Type{
+	"Object":     &BasicType{Name: "any"},
+	"Array":      &ArrayType{ElementType: &BasicType{Name: "any"}},
+	"RegExp":     &BasicType{Name: "RegExp"},
+	"Date":       &BasicType{Name: "any"},
+	"Error":      &BasicType{Name: "any"},
+	"TypeError":  &BasicType{Name: "any"},
+	"RangeError": &BasicType{Name: "any"},
+	"WeakMap":    &BasicType{Name: "any"},
+	"WeakSet":    &BasicType{Name: "any"},
+}
+
+// checkNewExpression checks new C(args). Functions may be called with new,
+// which makes an object of unknown type; library constructors make their
+// instance type.
+func (tc *TypeChecker) checkNewExpression(e *ast.NewExpression) Type {
+	if ident, ok := e.Callee.(*ast.Identifier); ok {
+		if tc.env.Lookup(ident.Value) == nil {
+			if t := libraryInstanceType(ident.Value); t != nil {
+				tc.checkArguments(e.Arguments)
+				return t
+			}
+		}
+	}
+
+	calleeType := tc.checkExpression(e.Callee)
+	args := tc.checkArguments(e.Arguments)
+
+	switch {
+	case isBasic(calleeType, "any"), isBasic(calleeType, "unknown"):
+	default:
+		if fn, ok := calleeType.(*FunctionType); ok {
+			tc.checkCall(startToken(e.Callee), fn, args)
+		} else {
+			tc.errorAt(startToken(e.Callee), errNotConstructable, calleeType)
+		}
+	}
+	return &BasicType{Name: "any"}
+}
+
+// libraryInstanceType returns the type of the objects the named library
+// constructor makes, or nil when it is not one. The type arguments of a
+// generic type are not inferred from the arguments, and are any.
+func libraryInstanceType(name string) Type {
+	if params, ok := genericTypes[name]; ok {
+		t := &GenericType{Name: name}
+		for range params {
+			t.TypeArguments = append(t.TypeArguments, &BasicType{Name: "any"})
+		}
+		return t
+	}
+	return globalConstructors[name]
+}
+
+// thisType returns the type of this in fn: the type of its this parameter
+// or @this tag, or any for a constructor. It is nil when this is
+// implicitly any.
+func (tc *TypeChecker) thisType(fn *ast.FunctionLiteral) Type {
+	if fn.ThisType != nil {
+		return tc.resolveTypeNode(fn.ThisType)
+	}
+	if t := tc.jsdocThisType(fn.JSDoc); t != nil {
+		return t
+	}
+	if tc.isConstructor(fn.JSDoc) {
+		return &BasicType{Name: "any"}
+	}
+	return nil
+}
+
+// checkThisExpression returns the type of this, reporting it under
+// NoImplicitThis when the function it is in does not declare its type
+func (tc *TypeChecker) checkThisExpression(e *ast.ThisExpression) Type {
+	if tc.fn == nil {
+		return &BasicType{Name: "any"}
+	}
+	if tc.fn.thisType != nil {
+		return tc.fn.thisType
+	}
+	if tc.options.NoImplicitThis || tc.options.Strict {
+		tc.errorAt(e.Token, errImplicitThis)
+	}
+	return &BasicType{Name: "any"}
+}
+
+// checkSuperProperty reports super.x, which needs a class to refer to
+func (tc *TypeChecker) checkSuperProperty(e *ast.SuperExpression) Type {
+	tc.errorAt(e.Token, errSuperProperty)
+	return &BasicType{Name: "any"}
+}
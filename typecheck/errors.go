@@ -0,0 +1,289 @@
+package typecheck
+
+import (
+	"fmt"
+
+	"github.com/dmarro89/ts-go-compiler/ast"
+	"github.com/dmarro89/ts-go-compiler/diagnostics"
+	"github.com/dmarro89/ts-go-compiler/token"
+)
+
+// message is an error of TypeScript's diagnostic catalog. Its text is a
+// format for the arguments of the error.
+type message struct {
+	code int
+	text string
+}
+
+var (
+	errSpreadArgument   = message{2556, "A spread argument must either have a tuple type or be passed to a rest parameter."}
+	errArgumentType     = message{2345, "Argument of type '%s' is not assignable to parameter of type '%s'."}
+	errTooFewArguments  = message{2555, "Expected at least %d arguments, but got %d."}
+	errArgumentCount    = message{2554, "Expected %s arguments, but got %d."}
+	errTupleIndex       = message{2493, "Tuple type '%s' of length '%d' has no element at index '%d'."}
+	errNotConstructable = message{2351, "This expression is not constructable. Type '%s' has no construct signatures."}
+	errImplicitThis     = message{2683, "'this' implicitly has type 'any' because it does not have a type annotation."}
+	errSuperProperty    = message{2660, "'super' can only be referenced in members of derived classes or object literal expressions."}
+	// errSuperCall is reported for super(...): without classes there is no
+	// constructor it may be called from
+	errSuperCall               = message{2337, "Super calls are not permitted outside constructors or in nested functions inside constructors."}
+	errVoidGenerator           = message{2505, "A generator cannot have a 'void' type annotation."}
+	errAsyncReturnType         = message{1064, "The return type of an async function or method must be the global Promise<T> type. Did you mean to write '%s'?"}
+	errNotAssignable           = message{2322, "Type '%s' is not assignable to type '%s'."}
+	errDeleteOperand           = message{2703, "The operand of a 'delete' operator must be a property reference."}
+	errArithmeticOperand       = message{2356, "An arithmetic operand must be of type 'any', 'number', 'bigint' or an enum type."}
+	errIncrementOperand        = message{2357, "The operand of an increment or decrement operator must be a variable or a property access."}
+	errOperatorTypes           = message{2365, "Operator '%s' cannot be applied to types '%s' and '%s'."}
+	errInLeft                  = message{2360, "The left-hand side of an 'in' expression must be a private identifier or of type 'any', 'string', 'number', or 'symbol'."}
+	errInRight                 = message{2361, "The right-hand side of an 'in' expression must not be a primitive."}
+	errInstanceofLeft          = message{2358, "The left-hand side of an 'instanceof' expression must be of type 'any', an object type or a type parameter."}
+	errInstanceofRight         = message{2359, "The right-hand side of an 'instanceof' expression must be either of type 'any', a class, a function, or other type assignable to the 'Function' interface type."}
+	errPlusOperands            = message{2365, "Operator '+' cannot be applied to types '%s' and '%s'."}
+	errArithmeticLeft          = message{2362, "The left-hand side of an arithmetic operation must be of type 'any', 'number', 'bigint' or an enum type."}
+	errArithmeticRight         = message{2363, "The right-hand side of an arithmetic operation must be of type 'any', 'number', 'bigint' or an enum type."}
+	errInvalidAssignmentTarget = message{2364, "The left-hand side of an assignment expression must be a variable or a property access."}
+	errOptionalAssignment      = message{2779, "The left-hand side of an assignment expression may not be an optional property access."}
+	errOptionalIncrement       = message{2777, "The operand of an increment or decrement operator may not be an optional property access."}
+	errNoProperty              = message{2339, "Property '%s' does not exist on type '%s'."}
+	errNotIterable             = message{2488, "Type '%s' must have a '[Symbol.iterator]()' method that returns an iterator."}
+	errSpreadType              = message{2698, "Spread types may only be created from object types."}
+	errShorthandInitializer    = message{1312, "Did you mean to use a ':'? An '=' can only follow a property name when the containing object literal is part of a destructuring pattern."}
+	errNotComparable           = message{2678, "Type '%s' is not comparable to type '%s'."}
+	errNotAsyncIterable        = message{2504, "Type '%s' must have a '[Symbol.asyncIterator]()' method that returns an async iterator."}
+	errForInRight              = message{2407, "The right-hand side of a 'for...in' statement must be of type 'any', an object type or a type parameter, but here has type '%s'."}
+	errForInPattern            = message{2491, "The left-hand side of a 'for...in' statement cannot be a destructuring pattern."}
+	errForInLeft               = message{2405, "The left-hand side of a 'for...in' statement must be of type 'string' or 'any'."}
+	errUnknownName             = message{18046, "'%s' is of type 'unknown'."}
+	errUnknownObject           = message{2571, "Object is of type 'unknown'."}
+	errUnknownExpression       = message{0, "unknown expression type: %T"}
+	errNotCallable             = message{2348, "Value of type 'typeof %s' is not callable. Did you mean to include 'new'?"}
+	errAwaitedArguments        = message{2314, "Generic type 'Awaited<T>' requires 1 type argument(s)."}
+	errCannotFindName          = message{2304, "Cannot find name '%s'."}
+	errTypeArguments           = message{2314, "Generic type '%s<%s>' requires %d type argument(s)."}
+	errTypeArgumentRange       = message{2707, "Generic type '%s<%s>' requires between %d and %d type arguments."}
+	errRedeclareBlockScoped    = message{2451, "Cannot redeclare block-scoped variable '%s'."}
+	errDuplicateFunction       = message{2393, "Duplicate function implementation."}
+	errDuplicateIdentifier     = message{2300, "Duplicate identifier '%s'."}
+	errUsedBeforeDeclaration   = message{2448, "Block-scoped variable '%s' used before its declaration."}
+	errMustReturnValue         = message{2355, "A function whose declared type is neither 'undefined', 'void', nor 'any' must return a value."}
+	errNotAllPathsReturn       = message{7030, "Not all code paths return a value."}
+	errLacksEndingReturn       = message{2366, "Function lacks ending return statement and return type does not include 'undefined'."}
+	errNoOverlap               = message{2367, "This comparison appears to be unintentional because the types '%s' and '%s' have no overlap."}
+	errMissingProperty         = message{2741, "Property '%s' is missing in type '%s' but required in type '%s'."}
+	errPropertyTypes           = message{2326, "Types of property '%s' are incompatible."}
+	errOptionalProperty        = message{2327, "Property '%s' is optional in type '%s' but required in type '%s'."}
+	errParameterTypes          = message{2328, "Types of parameters '%s' and '%s' are incompatible."}
+	errTooFewTargetArguments   = message{2849, "Target signature provides too few arguments. Expected %d or more, but got %d."}
+	errTupleTooShort           = message{2618, "Source has %d element(s) but target requires %d."}
+	errTupleTooLong            = message{2619, "Source has %d element(s) but target allows only %d."}
+	errTupleElement            = message{2626, "Type at position %d in source is not compatible with type at position %d in target."}
+	errNoCallSignatures        = message{2349, "This expression is not callable. Type '%s' has no call signatures."}
+	errPossiblyUndefinedName   = message{18048, "'%s' is possibly 'undefined'."}
+	errPossiblyNullName        = message{18047, "'%s' is possibly 'null'."}
+	errPossiblyNullishName     = message{18049, "'%s' is possibly 'null' or 'undefined'."}
+	errPossiblyUndefined       = message{2532, "Object is possibly 'undefined'."}
+	errPossiblyNull            = message{2531, "Object is possibly 'null'."}
+	errPossiblyNullish         = message{2533, "Object is possibly 'null' or 'undefined'."}
+	errInvokePossiblyUndefined = message{2722, "Cannot invoke an object which is possibly 'undefined'."}
+	errInvokePossiblyNull      = message{2721, "Cannot invoke an object which is possibly 'null'."}
+	errInvokePossiblyNullish   = message{2723, "Cannot invoke an object which is possibly 'null' or 'undefined'."}
+	errUsedBeforeAssigned      = message{2454, "Variable '%s' is used before being assigned."}
+
+	warnDeprecated  = message{6385, "'%s' is deprecated."}
+	warnUnreachable = message{7027, "Unreachable code detected."}
+	warnUnusedLabel = message{7028, "Unused label."}
+)
+
+// errorType is the type of an expression whose error has been reported,
+// such as an undeclared name. It behaves as any, so the error does not
+// cascade into errors about every use of the expression.
+var errorType Type = &BasicType{Name: "any"}
+
+// Diagnostics returns the errors and warnings found by Check, in the order
+// they were found
+func (tc *TypeChecker) Diagnostics() []diagnostics.Diagnostic {
+	return tc.diagnostics
+}
+
+// Errors returns the messages of the errors found by Check
+func (tc *TypeChecker) Errors() []string {
+	return tc.messages(diagnostics.Error)
+}
+
+// Warnings returns the messages of the problems found by Check that do not
+// fail the compilation, such as the use of deprecated declarations
+func (tc *TypeChecker) Warnings() []string {
+	return tc.messages(diagnostics.Warning)
+}
+
+// messages returns the messages of the diagnostics of the given severity
+func (tc *TypeChecker) messages(severity diagnostics.Severity) []string {
+	messages := []string{}
+	for _, d := range tc.diagnostics {
+		if d.Severity == severity {
+			messages = append(messages, d.Message)
+		}
+	}
+	return messages
+}
+
+// errorAt reports an error spanning tok
+func (tc *TypeChecker) errorAt(tok token.Token, m message, args ...any) {
+	tc.report(tok, diagnostics.Error, m, args...)
+}
+
+// addWarning reports a problem spanning tok that does not fail the
+// compilation
+func (tc *TypeChecker) addWarning(tok token.Token, m message, args ...any) {
+	tc.report(tok, diagnostics.Warning, m, args...)
+}
+
+func (tc *TypeChecker) report(tok token.Token, severity diagnostics.Severity, m message, args ...any) {
+	tc.diagnose(tok, severity, m.code, fmt.Sprintf(m.text, args...))
+}
+
+// diagnose adds a diagnostic with the given code and text spanning tok
+func (tc *TypeChecker) diagnose(tok token.Token, severity diagnostics.Severity, code int, text string) {
+	tc.diagnostics = append(tc.diagnostics, diagnostics.Diagnostic{
+		Code:     code,
+		Message:  text,
+		Severity: severity,
+		Pos:      tok.Pos,
+		End:      tok.End,
+		Line:     tok.Line,
+		Column:   tok.Column,
+	})
+}
+
+// statementToken returns the token the errors of stmt are reported at: the
+// name it declares, or its first token
+func statementToken(stmt ast.Statement) token.Token {
+	switch s := stmt.(type) {
+	case *ast.LetStatement:
+		if s.Name != nil {
+			return s.Name.Token
+		}
+		return s.Token
+	case *ast.ReturnStatement:
+		return s.Token
+	case *ast.ExpressionStatement:
+		return s.Token
+	case *ast.BlockStatement:
+		return s.Token
+	case *ast.IfStatement:
+		return s.Token
+	case *ast.SwitchStatement:
+		return s.Token
+	case *ast.ThrowStatement:
+		return s.Token
+	case *ast.TryStatement:
+		return s.Token
+	case *ast.ForOfStatement:
+		return s.Token
+	case *ast.ForInStatement:
+		return s.Token
+	case *ast.LabeledStatement:
+		return s.Token
+	case *ast.BreakStatement:
+		return s.Token
+	case *ast.ContinueStatement:
+		return s.Token
+	}
+	return token.Token{}
+}
+
+// startToken returns the first token of expr, where the errors about the
+// expression are reported
+func startToken(expr ast.Expression) token.Token {
+	switch e := expr.(type) {
+	case *ast.InfixExpression:
+		return startToken(e.Left)
+	case *ast.AssignmentExpression:
+		return startToken(e.Target)
+	case *ast.ConditionalExpression:
+		return startToken(e.Condition)
+	case *ast.PostfixExpression:
+		return startToken(e.Left)
+	case *ast.NonNullExpression:
+		return startToken(e.Expression)
+	case *ast.CallExpression:
+		return startToken(e.Function)
+	case *ast.MethodCallExpression:
+		return startToken(e.Object)
+	case *ast.IndexExpression:
+		return startToken(e.Left)
+	case *ast.Identifier:
+		return e.Token
+	case *ast.IntegerLiteral:
+		return e.Token
+	case *ast.StringLiteral:
+		return e.Token
+	case *ast.RegExpLiteral:
+		return e.Token
+	case *ast.Boolean:
+		return e.Token
+	case *ast.NullLiteral:
+		return e.Token
+	case *ast.ThisExpression:
+		return e.Token
+	case *ast.SuperExpression:
+		return e.Token
+	case *ast.PrefixExpression:
+		return e.Token
+	case *ast.ParenthesizedExpression:
+		return e.Token
+	case *ast.FunctionLiteral:
+		return e.Token
+	case *ast.AwaitExpression:
+		return e.Token
+	case *ast.YieldExpression:
+		return e.Token
+	case *ast.NewExpression:
+		return e.Token
+	case *ast.MetaProperty:
+		return e.Token
+	case *ast.ArrayLiteral:
+		return e.Token
+	case *ast.ObjectLiteral:
+		return e.Token
+	case *ast.SpreadElement:
+		return e.Token
+	case *ast.ObjectPattern:
+		return e.Token
+	case *ast.ArrayPattern:
+		return e.Token
+	case *ast.MissingNode:
+		return e.Token
+	}
+	return token.Token{}
+}
+
+// typeToken returns the first token of the type node
+func typeToken(node ast.TypeNode) token.Token {
+	switch n := node.(type) {
+	case *ast.ArrayTypeNode:
+		return typeToken(n.ElementType)
+	case *ast.TypeReference:
+		return n.Token
+	case *ast.UnionTypeNode:
+		return n.Token
+	case *ast.LiteralTypeNode:
+		return n.Token
+	case *ast.TupleTypeNode:
+		return n.Token
+	case *ast.ObjectTypeNode:
+		return n.Token
+	case *ast.FunctionTypeNode:
+		return n.Token
+	}
+	return token.Token{}
+}
+
+// typeErrorToken returns where errors about the type node are reported: its
+// first token, or the statement for a type written in a JSDoc comment
+func (tc *TypeChecker) typeErrorToken(node ast.TypeNode) token.Token {
+	if tc.inJSDoc {
+		return tc.at
+	}
+	return typeToken(node)
+}
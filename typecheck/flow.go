@@ -0,0 +1,608 @@
+package typecheck
+
+import "github.com/dmarro89/ts-go-compiler/ast"
+
+// FlowKind tells what a flow node stands for
+type FlowKind int
+
+const (
+	// FlowStart is the start of the program or function Node
+	FlowStart FlowKind = iota
+	// FlowUnreachable follows a return, throw, break or continue: the code
+	// it flows to never runs
+	FlowUnreachable
+	// FlowLabel joins its antecedents, e.g. the branches of an if statement
+	FlowLabel
+	// FlowLoopLabel starts each iteration of a loop, joining the flow
+	// before the loop with the ends of the iterations
+	FlowLoopLabel
+	// FlowAssignment gives the variable Name a value. Node is what assigns
+	// it: a declaration, an assignment expression, a loop or a try
+	// statement catching into it. A let declared without a value is
+	// unassigned by it.
+	FlowAssignment
+	// FlowCondition is where the condition Node is known to be Assume
+	FlowCondition
+	// FlowSwitchClause enters the clause Clause of the switch statement Node
+	// by matching its case, or the default clause when no case matches. -1
+	// leaves a switch without a default clause that no case matched.
+	FlowSwitchClause
+)
+
+// FlowNode is a point of the control flow of a function, which runs after
+// one of its antecedents
+type FlowNode struct {
+	Kind        FlowKind
+	Node        ast.Node
+	Name        *ast.Identifier
+	Assume      bool
+	Clause      int
+	Antecedents []*FlowNode
+}
+
+// Reachable reports whether the code at the node may run
+func (n *FlowNode) Reachable() bool {
+	return n.Kind != FlowUnreachable
+}
+
+// FlowGraph is the control flow of a program: the flow node reached before
+// each statement and at each identifier used as a value. Following the
+// antecedents back from a reference gives the conditions and assignments
+// its variable went through.
+type FlowGraph struct {
+	flows map[ast.Node]*FlowNode
+	ends  map[*ast.FunctionLiteral]*FlowNode
+
+	unreachable  []ast.Statement
+	unusedLabels []*ast.LabeledStatement
+}
+
+// BuildFlowGraph builds the control flow graph of a program, including the
+// functions in it
+func BuildFlowGraph(program *ast.Program) *FlowGraph {
+	b := &flowBuilder{
+		graph: &FlowGraph{
+			flows: map[ast.Node]*FlowNode{},
+			ends:  map[*ast.FunctionLiteral]*FlowNode{},
+		},
+		unreachable: &FlowNode{Kind: FlowUnreachable},
+		used:        map[*ast.LabeledStatement]bool{},
+	}
+	b.current = &FlowNode{Kind: FlowStart, Node: program}
+	b.statements(program.Statements)
+
+	for _, s := range b.labeled {
+		if !b.used[s] {
+			b.graph.unusedLabels = append(b.graph.unusedLabels, s)
+		}
+	}
+	return b.graph
+}
+
+// FlowAt returns the flow node reached before a statement, or where an
+// identifier is used, or nil when the graph has none for it
+func (g *FlowGraph) FlowAt(node ast.Node) *FlowNode {
+	return g.flows[node]
+}
+
+// IsReachable reports whether a statement or the use of an identifier may
+// run
+func (g *FlowGraph) IsReachable(node ast.Node) bool {
+	flow := g.flows[node]
+	return flow == nil || flow.Reachable()
+}
+
+// EndReachable reports whether the end of the body of fn can be reached,
+// so the function may return without a return statement
+func (g *FlowGraph) EndReachable(fn *ast.FunctionLiteral) bool {
+	return g.endReachable(fn, nil)
+}
+
+// endReachable reports whether the end of the body of fn can be reached
+// without leaving one of the exhaustive switch statements by matching no
+// case
+func (g *FlowGraph) endReachable(fn *ast.FunctionLiteral, exhaustive map[*ast.SwitchStatement]bool) bool {
+	end := g.ends[fn]
+	if end == nil || len(exhaustive) == 0 {
+		return end == nil || end.Reachable()
+	}
+	return reachableWithout(end, exhaustive, map[*FlowNode]bool{})
+}
+
+// reachableWithout reports whether flow can be reached from the start
+// without leaving an exhaustive switch statement by matching no case. seen
+// holds the nodes already followed, which add no new way in.
+func reachableWithout(flow *FlowNode, exhaustive map[*ast.SwitchStatement]bool, seen map[*FlowNode]bool) bool {
+	if !flow.Reachable() || seen[flow] {
+		return false
+	}
+	seen[flow] = true
+
+	switch flow.Kind {
+	case FlowStart:
+		return true
+	case FlowSwitchClause:
+		if flow.Clause < 0 && exhaustive[flow.Node.(*ast.SwitchStatement)] {
+			return false
+		}
+	}
+	for _, a := range flow.Antecedents {
+		if reachableWithout(a, exhaustive, seen) {
+			return true
+		}
+	}
+	return false
+}
+
+// Unreachable returns the first statement of each run of statements that
+// cannot be reached, in source order. Function declarations, which are
+// hoisted, are left out.
+func (g *FlowGraph) Unreachable() []ast.Statement {
+	return g.unreachable
+}
+
+// UnusedLabels returns the labeled statements no break or continue names
+func (g *FlowGraph) UnusedLabels() []*ast.LabeledStatement {
+	return g.unusedLabels
+}
+
+// flowBuilder builds a flow graph, statement by statement
+type flowBuilder struct {
+	graph       *FlowGraph
+	current     *FlowNode // the flow reached so far
+	unreachable *FlowNode
+
+	// targets are the statements break and continue may leave, innermost
+	// last, and pendingLabels the labels of the loop or switch statement
+	// about to be built
+	targets       []flowTarget
+	pendingLabels []*ast.LabeledStatement
+
+	// labeled holds the labeled statements met, and used those a break or
+	// continue names
+	labeled []*ast.LabeledStatement
+	used    map[*ast.LabeledStatement]bool
+
+	// silent is set inside unreachable code that has been recorded
+	silent bool
+}
+
+// flowTarget is where break and continue go in a loop, switch or labeled
+// statement
+type flowTarget struct {
+	breakLabel    *FlowNode
+	continueLabel *FlowNode // nil for switch and labeled statements
+	labels        []*ast.LabeledStatement
+	labeledOnly   bool // whether only a break with a label may leave it
+}
+
+func (b *flowBuilder) newLabel() *FlowNode {
+	return &FlowNode{Kind: FlowLabel}
+}
+
+// addAntecedent makes label reachable from flow, unless flow is unreachable
+func (b *flowBuilder) addAntecedent(label *FlowNode, flow *FlowNode) {
+	if !flow.Reachable() || !label.Reachable() {
+		return
+	}
+	for _, a := range label.Antecedents {
+		if a == flow {
+			return
+		}
+	}
+	label.Antecedents = append(label.Antecedents, flow)
+}
+
+// finish returns the flow after a label: unreachable when nothing reaches
+// it, or its only antecedent
+func (b *flowBuilder) finish(label *FlowNode) *FlowNode {
+	switch len(label.Antecedents) {
+	case 0:
+		return b.unreachable
+	case 1:
+		return label.Antecedents[0]
+	}
+	return label
+}
+
+// flowAfter returns a node of the given kind following the current flow,
+// or the unreachable node when the current flow is unreachable
+func (b *flowBuilder) flowAfter(node *FlowNode) *FlowNode {
+	if !b.current.Reachable() {
+		return b.unreachable
+	}
+	node.Antecedents = []*FlowNode{b.current}
+	return node
+}
+
+// assign adds the assignment of each name to the flow
+func (b *flowBuilder) assign(names []*ast.Identifier, node ast.Node) {
+	for _, name := range names {
+		b.current = b.flowAfter(&FlowNode{Kind: FlowAssignment, Node: node, Name: name})
+	}
+}
+
+func (b *flowBuilder) statements(statements []ast.Statement) {
+	silent := b.silent
+	defer func() { b.silent = silent }()
+
+	for _, stmt := range statements {
+		if !b.current.Reachable() && !b.silent && !isFunctionDeclaration(stmt) {
+			b.graph.unreachable = append(b.graph.unreachable, stmt)
+			b.silent = true
+		}
+		b.statement(stmt)
+	}
+}
+
+// isFunctionDeclaration reports whether stmt declares a function
+func isFunctionDeclaration(stmt ast.Statement) bool {
+	if es, ok := stmt.(*ast.ExpressionStatement); ok {
+		fn, ok := es.Expression.(*ast.FunctionLiteral)
+		return ok && fn.Name != nil
+	}
+	return false
+}
+
+func (b *flowBuilder) statement(stmt ast.Statement) {
+	if stmt == nil {
+		return
+	}
+	b.graph.flows[stmt] = b.current
+
+	switch s := stmt.(type) {
+	case *ast.LetStatement:
+		if s.Value != nil {
+			b.expression(s.Value)
+			b.expression(s.Target())
+			b.assign(boundNames(s.Target()), s)
+		} else if declarationFlags(s.Token.Literal)&BlockScopedVariable != 0 {
+			// A var keeps its value when declared again, but a let starts
+			// each iteration of a loop unassigned
+			b.assign(boundNames(s.Target()), s)
+		}
+	case *ast.ReturnStatement:
+		b.expression(s.ReturnValue)
+		b.current = b.unreachable
+	case *ast.ThrowStatement:
+		b.expression(s.Argument)
+		b.current = b.unreachable
+	case *ast.ExpressionStatement:
+		b.expression(s.Expression)
+	case *ast.BlockStatement:
+		b.statements(s.Statements)
+	case *ast.IfStatement:
+		b.ifStatement(s)
+	case *ast.SwitchStatement:
+		b.switchStatement(s)
+	case *ast.TryStatement:
+		b.tryStatement(s)
+	case *ast.ForOfStatement:
+		b.expression(s.Right)
+		b.loop(s, s.Kind, s.Target, s.Body)
+	case *ast.ForInStatement:
+		b.expression(s.Right)
+		b.loop(s, s.Kind, s.Target, s.Body)
+	case *ast.LabeledStatement:
+		b.labeledStatement(s)
+	case *ast.BreakStatement:
+		if target := b.jump(s.Label, false); target != nil {
+			b.addAntecedent(target.breakLabel, b.current)
+		}
+		b.current = b.unreachable
+	case *ast.ContinueStatement:
+		if target := b.jump(s.Label, true); target != nil {
+			b.addAntecedent(target.continueLabel, b.current)
+		}
+		b.current = b.unreachable
+	}
+}
+
+// ifStatement joins the branches of an if statement, each starting where
+// the condition has the value that leads to it
+func (b *flowBuilder) ifStatement(s *ast.IfStatement) {
+	consequence, alternative, end := b.newLabel(), b.newLabel(), b.newLabel()
+	b.condition(s.Condition, consequence, alternative)
+
+	b.current = b.finish(consequence)
+	b.statement(s.Consequence)
+	b.addAntecedent(end, b.current)
+
+	b.current = b.finish(alternative)
+	if s.Alternative != nil {
+		b.statement(s.Alternative)
+	}
+	b.addAntecedent(end, b.current)
+	b.current = b.finish(end)
+}
+
+// switchStatement enters each clause by matching its case or by falling
+// through from the clause before it. The end is reached by a break, by the
+// last clause, or by matching no case when there is no default clause.
+func (b *flowBuilder) switchStatement(s *ast.SwitchStatement) {
+	b.expression(s.Discriminant)
+	before := b.current
+	end := b.newLabel()
+
+	b.targets = append(b.targets, flowTarget{breakLabel: end, labels: b.takeLabels()})
+	defer func() { b.targets = b.targets[:len(b.targets)-1] }()
+
+	for _, c := range s.Cases {
+		b.current = before
+		b.expression(c.Test)
+	}
+
+	previous := b.unreachable
+	hasDefault := false
+	for i, c := range s.Cases {
+		hasDefault = hasDefault || c.Test == nil
+
+		b.current = before
+		entry := b.newLabel()
+		b.addAntecedent(entry, b.flowAfter(&FlowNode{Kind: FlowSwitchClause, Node: s, Clause: i}))
+		b.addAntecedent(entry, previous)
+		b.current = b.finish(entry)
+
+		b.statements(c.Consequent)
+		previous = b.current
+	}
+	b.addAntecedent(end, previous)
+
+	if !hasDefault {
+		b.current = before
+		b.addAntecedent(end, b.flowAfter(&FlowNode{Kind: FlowSwitchClause, Node: s, Clause: -1}))
+	}
+	b.current = b.finish(end)
+}
+
+// tryStatement builds a try statement. Anything in the block may throw, so
+// the catch clause is entered from the start or the end of the block, and
+// the finally block from any of them. The end is reached when the block
+// or the catch clause completes, and so does the finally block.
+func (b *flowBuilder) tryStatement(s *ast.TryStatement) {
+	before := b.current
+	b.statement(s.Block)
+	blockEnd := b.current
+
+	handlerEnd := b.unreachable
+	if h := s.Handler; h != nil {
+		entry := b.newLabel()
+		b.addAntecedent(entry, before)
+		b.addAntecedent(entry, blockEnd)
+		b.current = b.finish(entry)
+		if h.Param != nil {
+			b.expression(h.Param)
+			b.assign(boundNames(h.Param), s)
+		}
+		b.statement(h.Body)
+		handlerEnd = b.current
+	}
+
+	end := b.newLabel()
+	b.addAntecedent(end, blockEnd)
+	b.addAntecedent(end, handlerEnd)
+	if s.Finalizer == nil {
+		b.current = b.finish(end)
+		return
+	}
+
+	completes := len(end.Antecedents) > 0
+	entry := b.newLabel()
+	b.addAntecedent(entry, before)
+	for _, a := range end.Antecedents {
+		b.addAntecedent(entry, a)
+	}
+	b.current = b.finish(entry)
+	b.statement(s.Finalizer)
+	if !completes {
+		b.current = b.unreachable
+	}
+}
+
+// loop builds a for...of or for...in loop, whose target is assigned at the
+// start of each iteration. The loop ends before any iteration, or by a
+// break.
+func (b *flowBuilder) loop(s ast.Statement, kind string, target ast.Expression, body ast.Statement) {
+	start := b.unreachable
+	if b.current.Reachable() {
+		start = &FlowNode{Kind: FlowLoopLabel, Antecedents: []*FlowNode{b.current}}
+	}
+	end := b.newLabel()
+	b.addAntecedent(end, start)
+
+	b.targets = append(b.targets, flowTarget{breakLabel: end, continueLabel: start, labels: b.takeLabels()})
+	defer func() { b.targets = b.targets[:len(b.targets)-1] }()
+
+	b.current = start
+	b.expression(target)
+	if kind != "" || ast.IsPattern(target) {
+		b.assign(boundNames(target), s)
+	} else if ident, ok := ast.SkipParentheses(target).(*ast.Identifier); ok {
+		b.assign([]*ast.Identifier{ident}, s)
+	}
+
+	b.statement(body)
+	b.addAntecedent(start, b.current)
+	b.current = b.finish(end)
+}
+
+// labeledStatement gives its label to the loop or switch statement it
+// labels. Any other statement ends where a break with the label goes.
+func (b *flowBuilder) labeledStatement(s *ast.LabeledStatement) {
+	b.labeled = append(b.labeled, s)
+	b.pendingLabels = append(b.pendingLabels, s)
+	switch s.Body.(type) {
+	case *ast.LabeledStatement, *ast.ForOfStatement, *ast.ForInStatement, *ast.SwitchStatement:
+		b.statement(s.Body)
+		return
+	}
+
+	end := b.newLabel()
+	b.targets = append(b.targets, flowTarget{breakLabel: end, labels: b.takeLabels(), labeledOnly: true})
+	b.statement(s.Body)
+	b.targets = b.targets[:len(b.targets)-1]
+
+	b.addAntecedent(end, b.current)
+	b.current = b.finish(end)
+}
+
+// takeLabels returns the labels of the statement being built
+func (b *flowBuilder) takeLabels() []*ast.LabeledStatement {
+	labels := b.pendingLabels
+	b.pendingLabels = nil
+	return labels
+}
+
+// jump returns the statement a break or continue with the label leaves,
+// or the innermost one it may leave without a label, marking the label
+// used. It is nil for a jump the parser has reported.
+func (b *flowBuilder) jump(label *ast.Identifier, isContinue bool) *flowTarget {
+	for i := len(b.targets) - 1; i >= 0; i-- {
+		target := &b.targets[i]
+		switch {
+		case label != nil:
+			for _, s := range target.labels {
+				if s.Label.Value == label.Value {
+					b.used[s] = true
+					if isContinue && target.continueLabel == nil {
+						return nil
+					}
+					return target
+				}
+			}
+		case isContinue:
+			if target.continueLabel != nil {
+				return target
+			}
+		case !target.labeledOnly:
+			return target
+		}
+	}
+	return nil
+}
+
+// function builds the flow of a function, which starts anew, apart from
+// the flow of the code around it
+func (b *flowBuilder) function(fn *ast.FunctionLiteral) {
+	current, targets, pendingLabels, silent := b.current, b.targets, b.pendingLabels, b.silent
+	defer func() { b.current, b.targets, b.pendingLabels, b.silent = current, targets, pendingLabels, silent }()
+
+	b.current = &FlowNode{Kind: FlowStart, Node: fn}
+	b.targets, b.pendingLabels, b.silent = nil, nil, false
+	for _, param := range fn.Parameters {
+		b.expression(param.Name)
+		b.expression(param.Default)
+	}
+	if fn.Body != nil {
+		b.statements(fn.Body.Statements)
+	}
+	b.graph.ends[fn] = b.current
+}
+
+// expression adds an expression to the flow, recording the flow at each
+// identifier in it. The operators that may skip their operands branch.
+func (b *flowBuilder) expression(expr ast.Expression) {
+	if expr == nil {
+		return
+	}
+	ast.Inspect(expr, func(n ast.Node) bool {
+		switch e := n.(type) {
+		case *ast.Identifier:
+			b.graph.flows[e] = b.current
+		case *ast.FunctionLiteral:
+			b.function(e)
+			return false
+		case *ast.AssignmentExpression:
+			b.assignment(e)
+			return false
+		case *ast.ConditionalExpression:
+			consequence, alternative, end := b.newLabel(), b.newLabel(), b.newLabel()
+			b.condition(e.Condition, consequence, alternative)
+			b.current = b.finish(consequence)
+			b.expression(e.Consequence)
+			b.addAntecedent(end, b.current)
+			b.current = b.finish(alternative)
+			b.expression(e.Alternative)
+			b.addAntecedent(end, b.current)
+			b.current = b.finish(end)
+			return false
+		case *ast.InfixExpression:
+			switch e.Operator {
+			case "&&", "||":
+				right, end := b.newLabel(), b.newLabel()
+				if e.Operator == "&&" {
+					b.condition(e.Left, right, end)
+				} else {
+					b.condition(e.Left, end, right)
+				}
+				b.current = b.finish(right)
+				b.expression(e.Right)
+				b.addAntecedent(end, b.current)
+				b.current = b.finish(end)
+				return false
+			case "??":
+				b.expression(e.Left)
+				end := b.newLabel()
+				b.addAntecedent(end, b.current)
+				b.expression(e.Right)
+				b.addAntecedent(end, b.current)
+				b.current = b.finish(end)
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// assignment adds an assignment expression: the value is computed before
+// the names of the target are assigned
+func (b *flowBuilder) assignment(e *ast.AssignmentExpression) {
+	b.expression(e.Target)
+	b.expression(e.Value)
+	if ident, ok := ast.SkipParentheses(e.Target).(*ast.Identifier); ok {
+		b.assign([]*ast.Identifier{ident}, e)
+	} else if ast.IsPattern(e.Target) {
+		b.assign(boundNames(e.Target), e)
+	}
+}
+
+// condition adds a condition, whose flow goes on to whenTrue where it is
+// true and to whenFalse where it is false. The operands of !, && and ||
+// are conditions themselves. A literal true or false only goes on to one
+// of them.
+func (b *flowBuilder) condition(expr ast.Expression, whenTrue *FlowNode, whenFalse *FlowNode) {
+	switch e := ast.SkipParentheses(expr).(type) {
+	case *ast.PrefixExpression:
+		if e.Operator == "!" {
+			b.condition(e.Right, whenFalse, whenTrue)
+			return
+		}
+	case *ast.InfixExpression:
+		switch e.Operator {
+		case "&&":
+			right := b.newLabel()
+			b.condition(e.Left, right, whenFalse)
+			b.current = b.finish(right)
+			b.condition(e.Right, whenTrue, whenFalse)
+			return
+		case "||":
+			right := b.newLabel()
+			b.condition(e.Left, whenTrue, right)
+			b.current = b.finish(right)
+			b.condition(e.Right, whenTrue, whenFalse)
+			return
+		}
+	}
+
+	b.expression(expr)
+	if lit, ok := ast.SkipParentheses(expr).(*ast.Boolean); ok {
+		if lit.Value {
+			b.addAntecedent(whenTrue, b.current)
+		} else {
+			b.addAntecedent(whenFalse, b.current)
+		}
+		return
+	}
+	b.addAntecedent(whenTrue, b.flowAfter(&FlowNode{Kind: FlowCondition, Node: expr, Assume: true}))
+	b.addAntecedent(whenFalse, b.flowAfter(&FlowNode{Kind: FlowCondition, Node: expr, Assume: false}))
+}
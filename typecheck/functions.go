@@ -0,0 +1,288 @@
+package typecheck
+
+import (
+	"github.com/dmarro89/ts-go-compiler/ast"
+	"github.com/dmarro89/ts-go-compiler/token"
+)
+
+// functionContext describes the function whose body is being checked
+type functionContext struct {
+	node *ast.FunctionLiteral // the function itself
+
+	async      bool
+	returnType Type // the declared return type, Promise<T> for async functions
+
+	// A generator yields values of yieldType, is resumed with values of
+	// nextType and returns a value of returnType
+	generator bool
+	yieldType Type
+	nextType  Type
+
+	// thisType is the type of this, nil when it is implicitly any
+	thisType Type
+
+	// inferred is set when the function declares no return type, which is
+	// then the union of the types of the values it returns, and for a
+	// generator of those it yields
+	inferred bool
+	returns  []Type
+	yields   []Type
+
+	// hasReturnValue is set once a return statement with a value is
+	// checked; bareReturns are the return statements without one
+	hasReturnValue bool
+	bareReturns    []token.Token
+}
+
+// checkFunction returns the type of a function, checking its body the
+// first time. The return type of a function that declares none is
+// inferred from its body, and an async function declared to return
+// anything but a promise is reported.
+func (tc *TypeChecker) checkFunction(fn *ast.FunctionLiteral) Type {
+	ft := tc.functionType(fn)
+	if tc.bodies[fn] {
+		return ft
+	}
+	tc.bodies[fn] = true
+
+	if node := tc.returnTypeNode(fn); node != nil && (fn.Async || fn.Generator) {
+		at := tc.at
+		if fn.ReturnType != nil {
+			at = typeToken(node)
+		}
+		ref, ok := node.(*ast.TypeReference)
+		switch {
+		case fn.Generator:
+			if ok && ref.Name == "void" {
+				tc.errorAt(at, errVoidGenerator)
+			}
+		case !ok || ref.Name != "Promise":
+			tc.errorAt(at, errAsyncReturnType, ft.ReturnType)
+		}
+	}
+	tc.checkFunctionBody(fn, ft)
+	return ft
+}
+
+// checkDeclaredFunction checks the body of the function declaration of
+// sym when it is used before the declaration, so that its return type is
+// known. The body is checked in the scope of the declaration.
+func (tc *TypeChecker) checkDeclaredFunction(sym *Symbol) {
+	fn := sym.body
+	sym.body = nil
+
+	env, outer, at := tc.env, tc.fn, tc.at
+	tc.env, tc.fn, tc.at = sym.scope, sym.scope.fn, fn.Token
+	defer func() { tc.env, tc.fn, tc.at = env, outer, at }()
+
+	tc.checkFunction(fn)
+}
+
+// returnTypeNode returns the return type annotation of a function, or the
+// type of its @returns tag, or nil when it declares none
+func (tc *TypeChecker) returnTypeNode(fn *ast.FunctionLiteral) ast.TypeNode {
+	if fn.ReturnType != nil {
+		return fn.ReturnType
+	}
+	return tc.jsdocReturnTypeNode(fn.JSDoc)
+}
+
+// checkFunctionBody checks the statements of a function in a scope of its
+// own, where the parameters have the types of ft. When the function
+// declares no return type, ft is given the one inferred from its body.
+func (tc *TypeChecker) checkFunctionBody(fn *ast.FunctionLiteral, ft *FunctionType) {
+	outer := tc.fn
+	tc.fn = &functionContext{
+		node:       fn,
+		async:      fn.Async,
+		returnType: ft.ReturnType,
+		generator:  fn.Generator,
+		thisType:   tc.thisType(fn),
+		inferred:   tc.returnTypeNode(fn) == nil,
+	}
+	if fn.Generator {
+		tc.fn.yieldType, tc.fn.returnType, tc.fn.nextType = generatorTypes(ft.ReturnType)
+	}
+	defer func() { tc.fn = outer }()
+
+	tc.inScope(FunctionScope, func() {
+		tc.declareTemplates(fn.JSDoc)
+		for i, param := range fn.Parameters {
+			tc.bindPattern(param.Name, ft.Parameters[i].Type, FunctionScopedVariable|ParameterVariable)
+		}
+		tc.checkBlockStatement(fn.Body)
+	})
+
+	if tc.fn.inferred {
+		ft.ReturnType = tc.inferredReturnType()
+		tc.fn.returnType = ft.ReturnType
+	}
+	tc.checkReturnPaths(fn)
+}
+
+// inferredReturnType is the return type of the function being checked
+// that declares none: the union of the types of the values it returns, or
+// void when it returns none. An async function returns a promise of it,
+// and a generator a Generator of the values it yields and returns, or an
+// AsyncGenerator when it is async.
+func (tc *TypeChecker) inferredReturnType() Type {
+	var t Type = &BasicType{Name: "void"}
+	if len(tc.fn.returns) > 0 {
+		t = newUnionType(tc.fn.returns...)
+	}
+	if tc.fn.generator {
+		var yield Type = &BasicType{Name: "never"}
+		if len(tc.fn.yields) > 0 {
+			yield = newUnionType(tc.fn.yields...)
+		}
+		name := "Generator"
+		if tc.fn.async {
+			name = "AsyncGenerator"
+		}
+		return &GenericType{Name: name, TypeArguments: []Type{yield, t, &BasicType{Name: "unknown"}}}
+	}
+	if tc.fn.async {
+		return promiseType(t)
+	}
+	return t
+}
+
+// checkReturnPaths reports the ends of the function being checked that
+// return no value although its return type needs one: a function
+// declaring a return type that never returns a value, with strict null
+// checks one whose end can be reached, and under NoImplicitReturns the
+// return statements without a value and an end that can be reached in a
+// function that returns values.
+func (tc *TypeChecker) checkReturnPaths(fn *ast.FunctionLiteral) {
+	if fn.Generator {
+		return
+	}
+	returnType := tc.fn.returnType
+	if tc.fn.async {
+		returnType = awaitedType(returnType)
+	}
+	if !needsReturnValue(returnType) {
+		return
+	}
+
+	at := fn.Token
+	if fn.Name != nil {
+		at = fn.Name.Token
+	}
+	ends := tc.flow == nil || tc.flow.endReachable(fn, tc.exhaustive)
+	switch {
+	case !tc.fn.inferred && !tc.fn.hasReturnValue:
+		if ends {
+			tc.errorAt(at, errMustReturnValue)
+		}
+	case !tc.fn.inferred && tc.strictNullChecks() && ends:
+		// The end of the function returns undefined, which the return
+		// type must include
+		if fn.ReturnType != nil {
+			at = typeToken(fn.ReturnType)
+		}
+		tc.errorAt(at, errLacksEndingReturn)
+	case tc.options.NoImplicitReturns:
+		for _, tok := range tc.fn.bareReturns {
+			tc.errorAt(tok, errNotAllPathsReturn)
+		}
+		if ends {
+			tc.errorAt(at, errNotAllPathsReturn)
+		}
+	}
+}
+
+// needsReturnValue reports whether a function returning t must return a
+// value, which it does unless t includes void, undefined, any or unknown
+func needsReturnValue(t Type) bool {
+	for _, member := range unionMembers(t) {
+		if isBasic(member, "void") || isBasic(member, "undefined") || isBasic(member, "any") || isBasic(member, "unknown") {
+			return false
+		}
+	}
+	return true
+}
+
+// checkReturnValue checks a returned value of type t against the return
+// type of the function. An async function may return the value of its
+// promise or another promise of it.
+func (tc *TypeChecker) checkReturnValue(value ast.Expression, t Type) {
+	expected := tc.fn.returnType
+	if tc.fn.async {
+		t, expected = awaitedType(t), awaitedType(expected)
+	}
+	tc.checkAssignableValue(tc.at, value, t, expected)
+}
+
+// checkYieldExpression checks a yielded value against the type the
+// generator yields. yield gives the value the generator is resumed with,
+// and yield* the value the iterable it delegates to returns.
+func (tc *TypeChecker) checkYieldExpression(e *ast.YieldExpression) Type {
+	var t Type = &BasicType{Name: "undefined"}
+	at := e.Token
+	if e.Argument != nil {
+		t = tc.checkExpression(e.Argument)
+		at = startToken(e.Argument)
+	}
+	if tc.fn == nil || !tc.fn.generator {
+		return &BasicType{Name: "any"}
+	}
+
+	if e.Delegate {
+		var element Type
+		if tc.fn.async {
+			element = tc.asyncIteratedType(at, t)
+		} else {
+			element = tc.iteratedType(at, t)
+		}
+		if tc.fn.inferred {
+			tc.fn.yields = append(tc.fn.yields, element)
+		} else {
+			tc.checkAssignable(at, element, tc.fn.yieldType)
+		}
+		if g, ok := t.(*GenericType); ok && (g.Name == "Generator" || g.Name == "AsyncGenerator") {
+			return g.TypeArguments[1]
+		}
+		return &BasicType{Name: "any"}
+	}
+
+	if tc.fn.async {
+		t = awaitedType(t)
+	}
+	if tc.fn.inferred {
+		tc.fn.yields = append(tc.fn.yields, t)
+	} else {
+		tc.checkAssignableValue(at, e.Argument, t, tc.fn.yieldType)
+	}
+	return tc.fn.nextType
+}
+
+// generatorType is the type of a generator function with no declared
+// return type until its body is checked
+func generatorType(async bool) Type {
+	name := "Generator"
+	if async {
+		name = "AsyncGenerator"
+	}
+	return &GenericType{Name: name, TypeArguments: []Type{
+		&BasicType{Name: "any"}, &BasicType{Name: "any"}, &BasicType{Name: "any"},
+	}}
+}
+
+// generatorTypes returns the types a generator declared to return t
+// yields, returns and is resumed with. An iterable leaves the last two to
+// any.
+func generatorTypes(t Type) (yield Type, result Type, next Type) {
+	anyType := &BasicType{Name: "any"}
+	g, ok := t.(*GenericType)
+	if !ok {
+		return anyType, anyType, anyType
+	}
+	switch g.Name {
+	case "Generator", "AsyncGenerator", "Iterator", "AsyncIterator":
+		return g.TypeArguments[0], g.TypeArguments[1], g.TypeArguments[2]
+	case "Iterable", "IterableIterator", "AsyncIterable", "AsyncIterableIterator":
+		return g.TypeArguments[0], anyType, anyType
+	}
+	return anyType, anyType, anyType
+}
@@ -0,0 +1,150 @@
+package typecheck
+
+import (
+	"github.com/dmarro89/ts-go-compiler/ast"
+	"github.com/dmarro89/ts-go-compiler/parser"
+)
+
+// JSDoc types only apply to JavaScript files; TypeScript files use their
+// own annotations and ignore them, like tsc does.
+
+// jsdocType returns the type given by a @type tag, or nil
+func (tc *TypeChecker) jsdocType(doc *ast.JSDoc) Type {
+	if !tc.options.JavaScript || doc == nil {
+		return nil
+	}
+	if tag, ok := doc.Tag("type").(*ast.JSDocTypeTag); ok && tag.Type != nil {
+		return tc.resolveJSDocType(tag.Type)
+	}
+	return nil
+}
+
+// jsdocParamType returns the type of the named parameter given by a @param
+// tag, or nil
+func (tc *TypeChecker) jsdocParamType(doc *ast.JSDoc, name string) (Type, bool) {
+	if !tc.options.JavaScript || doc == nil {
+		return nil, false
+	}
+	if tag := doc.Param(name); tag != nil && tag.Type != nil {
+		return tc.resolveJSDocType(tag.Type), tag.Optional
+	}
+	return nil, false
+}
+
+// jsdocReturnType returns the type given by a @returns tag, or nil
+func (tc *TypeChecker) jsdocReturnType(doc *ast.JSDoc) Type {
+	if node := tc.jsdocReturnTypeNode(doc); node != nil {
+		return tc.resolveJSDocType(node)
+	}
+	return nil
+}
+
+// jsdocReturnTypeNode returns the type of a @returns tag as written, or nil
+func (tc *TypeChecker) jsdocReturnTypeNode(doc *ast.JSDoc) ast.TypeNode {
+	if !tc.options.JavaScript || doc == nil {
+		return nil
+	}
+	if tag, ok := doc.Tag("returns").(*ast.JSDocReturnTag); ok && tag.Type != nil {
+		return tag.Type
+	}
+	return nil
+}
+
+// jsdocThisType returns the type given by a @this tag, or nil
+func (tc *TypeChecker) jsdocThisType(doc *ast.JSDoc) Type {
+	if !tc.options.JavaScript || doc == nil {
+		return nil
+	}
+	if tag, ok := doc.Tag("this").(*ast.JSDocThisTag); ok && tag.Type != nil {
+		return tc.resolveJSDocType(tag.Type)
+	}
+	return nil
+}
+
+// resolveJSDocType resolves a type written in a JSDoc comment
+func (tc *TypeChecker) resolveJSDocType(node ast.TypeNode) Type {
+	outer := tc.inJSDoc
+	tc.inJSDoc = true
+	defer func() { tc.inJSDoc = outer }()
+	return tc.resolveTypeNode(node)
+}
+
+// declareTemplates declares the type parameters of the @template tags in
+// the current scope. Type arguments are not inferred, so a type parameter
+// stands for its constraint, or any when it has none.
+func (tc *TypeChecker) declareTemplates(doc *ast.JSDoc) {
+	if !tc.options.JavaScript || doc == nil {
+		return
+	}
+	for _, tag := range doc.Tags {
+		template, ok := tag.(*ast.JSDocTemplateTag)
+		if !ok {
+			continue
+		}
+		var t Type = &BasicType{Name: "any"}
+		if template.Constraint != nil {
+			t = tc.resolveJSDocType(template.Constraint)
+		}
+		for _, name := range template.TypeParameters {
+			tc.env.SetType(name, t)
+		}
+	}
+}
+
+// isConstructor reports whether a function is documented as a class with
+// a @class or @constructor tag
+func (tc *TypeChecker) isConstructor(doc *ast.JSDoc) bool {
+	if !tc.options.JavaScript || doc == nil {
+		return false
+	}
+	return doc.Tag("class") != nil || doc.Tag("constructor") != nil
+}
+
+// declareTypedefs registers the @typedef tags of the program's comments as
+// type aliases
+func (tc *TypeChecker) declareTypedefs(statements []ast.Statement) {
+	if !tc.options.JavaScript {
+		return
+	}
+
+	outer := tc.at
+	defer func() { tc.at = outer }()
+
+	for _, stmt := range statements {
+		c, ok := stmt.(ast.Commented)
+		if !ok {
+			continue
+		}
+		tc.at = statementToken(stmt)
+		for _, comment := range c.Comments().LeadingComments {
+			doc := parser.ParseJSDoc(comment)
+			if doc == nil {
+				continue
+			}
+			for _, tag := range doc.Tags {
+				if typedef, ok := tag.(*ast.JSDocTypedefTag); ok && typedef.Name != "" {
+					tc.env.SetType(typedef.Name, tc.typedefType(typedef))
+				}
+			}
+		}
+	}
+}
+
+func (tc *TypeChecker) typedefType(typedef *ast.JSDocTypedefTag) Type {
+	if len(typedef.Properties) == 0 {
+		if typedef.Type == nil {
+			return &BasicType{Name: "any"}
+		}
+		return tc.resolveJSDocType(typedef.Type)
+	}
+
+	obj := &ObjectType{}
+	for _, prop := range typedef.Properties {
+		p := &Property{Name: prop.Name, Type: &BasicType{Name: "any"}, Optional: prop.Optional}
+		if prop.Type != nil {
+			p.Type = tc.resolveJSDocType(prop.Type)
+		}
+		obj.Properties = append(obj.Properties, p)
+	}
+	return obj
+}
@@ -0,0 +1,205 @@
+package typecheck
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/dmarro89/ts-go-compiler/lexer"
+	"github.com/dmarro89/ts-go-compiler/parser"
+)
+
+func checkJS(t *testing.T, input string, javaScript bool) *TypeChecker {
+	t.Helper()
+
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) > 0 {
+		t.Fatalf("parser errors: %v", p.Errors())
+	}
+
+	tc := NewWithOptions(Options{JavaScript: javaScript})
+	tc.Check(program)
+	return tc
+}
+
+func TestJSDocTypeInJavaScript(t *testing.T) {
+	input := `/** @type {number} */
+let count = "none";
+/** @type {string|number} */
+let id = 5;`
+
+	tc := checkJS(t, input, true)
+
+	expected := []string{"Type 'string' is not assignable to type 'number'."}
+	if len(tc.Errors()) != len(expected) || tc.Errors()[0] != expected[0] {
+		t.Fatalf("expected errors %v, got %v", expected, tc.Errors())
+	}
+
+	id, _ := tc.env.Get("id")
+	if id.String() != "string | number" {
+		t.Errorf("expected id to be of type string | number, got %s", id)
+	}
+}
+
+func TestJSDocTypeIgnoredInTypeScript(t *testing.T) {
+	tc := checkJS(t, `/** @type {number} */
+let count = "none";`, false)
+
+	if len(tc.Errors()) > 0 {
+		t.Fatalf("unexpected errors: %v", tc.Errors())
+	}
+	count, _ := tc.env.Get("count")
+	if count.String() != "string" {
+		t.Errorf("expected count to be of type string, got %s", count)
+	}
+}
+
+func TestJSDocObjectType(t *testing.T) {
+	input := `/** @type {{a: number, b?: string}} */
+let o = {a: "x"};
+/** @type {{a: number, b?: string}} */
+let p = {a: 1};`
+
+	tc := checkJS(t, input, true)
+
+	if len(tc.Errors()) != 1 || !strings.HasPrefix(tc.Errors()[0], "Type '{ a: string; }' is not assignable to type '{ a: number; b?: string; }'.") {
+		t.Fatalf("expected one assignment error, got %v", tc.Errors())
+	}
+}
+
+func TestJSDocFunctionType(t *testing.T) {
+	input := `let total = add(1, 2);
+/**
+ * @param {number} a
+ * @param {number} [b]
+ * @returns {number}
+ */
+function add(a, b) { return a; }`
+
+	tc := checkJS(t, input, true)
+	if len(tc.Errors()) > 0 {
+		t.Fatalf("unexpected errors: %v", tc.Errors())
+	}
+
+	add, _ := tc.env.Get("add")
+	if add.String() != "(a: number, b?: number) => number" {
+		t.Errorf("expected add to be (a: number, b?: number) => number, got %s", add)
+	}
+	total, _ := tc.env.Get("total")
+	if total.String() != "number" {
+		t.Errorf("expected total to be of type number, got %s", total)
+	}
+}
+
+func TestJSDocTemplate(t *testing.T) {
+	input := `/** @template T @param {T} x @returns {T} */
+function id(x) {
+	/** @type {T} */
+	let y = x;
+	return y;
+}
+/**
+ * @template {string} K
+ * @param {K} key
+ */
+function size(key) { return key.length; }
+size(1);
+/** @type {T} */
+let z = 1;`
+
+	tc := checkJS(t, input, true)
+
+	expected := []string{
+		"Argument of type 'number' is not assignable to parameter of type 'string'.",
+		"Cannot find name 'T'.",
+	}
+	if len(tc.Errors()) != len(expected) {
+		t.Fatalf("expected errors %v, got %v", expected, tc.Errors())
+	}
+	for i, err := range tc.Errors() {
+		if err != expected[i] {
+			t.Errorf("expected error %q, got %q", expected[i], err)
+		}
+	}
+}
+
+func TestJSDocErrorPosition(t *testing.T) {
+	input := "let n = 1;\n/** @param {Missing} x */\nfunction f(x) {}\n/** @typedef {Unknown} Alias */\nlet m = 2;"
+
+	tc := checkJS(t, input, true)
+
+	var actual []string
+	for _, d := range tc.Diagnostics() {
+		actual = append(actual, d.String())
+	}
+	expected := []string{
+		"5:5 - error TS2304: Cannot find name 'Unknown'.",
+		"3:1 - error TS2304: Cannot find name 'Missing'.",
+	}
+	if strings.Join(actual, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected %v, got %v", expected, actual)
+	}
+}
+
+func TestJSDocTypedef(t *testing.T) {
+	input := `/**
+ * @typedef {Object} Point
+ * @property {number} x
+ * @property {number} [y]
+ */
+
+/** @typedef {Point[]} Path */
+
+/** @type {Point} */
+let origin = 0;
+/** @type {Path} */
+let path = origin;`
+
+	tc := checkJS(t, input, true)
+
+	path, _ := tc.env.Get("path")
+	if path.String() != "{ x: number; y?: number; }[]" {
+		t.Errorf("expected path to be of type { x: number; y?: number; }[], got %s", path)
+	}
+
+	expected := []string{
+		"Type 'number' is not assignable to type '{ x: number; y?: number; }'.",
+		"Type '{ x: number; y?: number; }' is not assignable to type '{ x: number; y?: number; }[]'.",
+	}
+	if len(tc.Errors()) != len(expected) {
+		t.Fatalf("expected errors %v, got %v", expected, tc.Errors())
+	}
+	for i, err := range tc.Errors() {
+		if err != expected[i] {
+			t.Errorf("expected error %q, got %q", expected[i], err)
+		}
+	}
+}
+
+func TestDeprecatedWarning(t *testing.T) {
+	input := `/** @deprecated use next instead */
+function old() { return 1; }
+/** @deprecated */
+let legacy = 1;
+let a = old();
+let b = legacy;
+let c = a;`
+
+	for _, javaScript := range []bool{false, true} {
+		tc := checkJS(t, input, javaScript)
+		if len(tc.Errors()) > 0 {
+			t.Fatalf("unexpected errors: %v", tc.Errors())
+		}
+
+		expected := []string{"'old' is deprecated.", "'legacy' is deprecated."}
+		warnings := tc.Warnings()
+		if len(warnings) != len(expected) {
+			t.Fatalf("expected warnings %v, got %v", expected, warnings)
+		}
+		for i, w := range warnings {
+			if w != expected[i] {
+				t.Errorf("expected warning %q, got %q", expected[i], w)
+			}
+		}
+	}
+}
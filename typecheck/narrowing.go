@@ -0,0 +1,388 @@
+package typecheck
+
+import (
+	"slices"
+
+	"github.com/dmarro89/ts-go-compiler/ast"
+)
+
+// flowType returns the type of the variable sym where ident uses it: its
+// declared type, narrowed by the conditions and assignments the flow goes
+// through to get there. With strict null checks a variable that may not
+// have been assigned yet is reported.
+func (tc *TypeChecker) flowType(ident *ast.Identifier, sym *Symbol) Type {
+	declared := tc.env.typeOf(sym)
+	if tc.flow == nil || sym.Flags&(FunctionScopedVariable|BlockScopedVariable) == 0 || isBasic(declared, "any") {
+		return declared
+	}
+	flow := tc.flow.FlowAt(ident)
+	if flow == nil || !flow.Reachable() {
+		return declared
+	}
+
+	// A variable starts undefined, unless it is a parameter or nothing but
+	// undefined can be read from it unassigned
+	w := &flowWalker{tc: tc, sym: sym, declared: declared, initial: declared, types: map[*FlowNode]Type{}, loops: map[*FlowNode]bool{}}
+	if sym.scope.fn != nil {
+		w.container = sym.scope.fn.node
+	}
+	assumeAssigned := sym.Flags&ParameterVariable != 0 || sym.definite || tc.asserted[ident] ||
+		!tc.strictNullChecks() || isBasic(declared, "unknown") || isBasic(declared, "void")
+	if !assumeAssigned {
+		w.initial = newUnionType(declared, &BasicType{Name: "undefined"})
+	}
+
+	t, _ := w.typeAt(flow)
+	if !assumeAssigned && !includesUndefined(declared) && includesUndefined(t) {
+		tc.errorAt(ident.Token, errUsedBeforeAssigned, ident.Value)
+		return declared
+	}
+	return t
+}
+
+// includesUndefined reports whether t has undefined as a member
+func includesUndefined(t Type) bool {
+	_, undefined := nullishMembers(t)
+	return undefined
+}
+
+// flowWalker follows the flow back from a use of the variable sym to find
+// its type there
+type flowWalker struct {
+	tc       *TypeChecker
+	sym      *Symbol
+	declared Type
+	initial  Type // the type before the variable is assigned
+
+	// container is the function declaring the variable, nil for the
+	// program. Before it starts the variable has its declared type.
+	container *ast.FunctionLiteral
+
+	// types holds the types found at the nodes visited, and loops the loops
+	// whose type is being found
+	types map[*FlowNode]Type
+	loops map[*FlowNode]bool
+}
+
+// typeAt returns the type of the variable at flow. Inside a loop whose type
+// is being found the type is incomplete: the loop adds nothing to it yet,
+// and it is not kept.
+func (w *flowWalker) typeAt(flow *FlowNode) (t Type, complete bool) {
+	if t, ok := w.types[flow]; ok {
+		return t, true
+	}
+
+	complete = true
+	switch flow.Kind {
+	case FlowStart:
+		t = w.declared
+		if fn, ok := flow.Node.(*ast.FunctionLiteral); ok && fn == w.container || !ok && w.container == nil {
+			t = w.initial
+		}
+	case FlowUnreachable:
+		t = &BasicType{Name: "never"}
+	case FlowAssignment:
+		if !w.refersTo(flow.Name) {
+			return w.typeAt(flow.Antecedents[0])
+		}
+		t = w.assignedType(flow)
+	case FlowCondition:
+		t, complete = w.typeAt(flow.Antecedents[0])
+		t = w.tc.narrowByCondition(t, w, flow.Node.(ast.Expression), flow.Assume)
+	case FlowSwitchClause:
+		t, complete = w.typeAt(flow.Antecedents[0])
+		t = w.narrowBySwitch(t, flow.Node.(*ast.SwitchStatement), flow.Clause)
+	case FlowLoopLabel:
+		if w.loops[flow] {
+			return &BasicType{Name: "never"}, false
+		}
+		w.loops[flow] = true
+		t, complete = w.join(flow.Antecedents)
+		delete(w.loops, flow)
+		// The loop is complete once its own iterations are taken into
+		// account, unless it is nested in another loop being walked
+		complete = complete || len(w.loops) == 0
+	default:
+		t, complete = w.join(flow.Antecedents)
+	}
+
+	if complete {
+		w.types[flow] = t
+	}
+	return t, complete
+}
+
+// join returns the union of the types at each antecedent, whose members
+// keep the order of the declared type
+func (w *flowWalker) join(antecedents []*FlowNode) (Type, bool) {
+	var types []Type
+	complete := true
+	for _, a := range antecedents {
+		t, ok := w.typeAt(a)
+		types = append(types, t)
+		complete = complete && ok
+	}
+
+	joined := unionMembers(newUnionType(types...))
+	var ordered []Type
+	for _, member := range unionMembers(w.declared) {
+		for _, t := range joined {
+			if t.String() == member.String() {
+				ordered = append(ordered, t)
+			}
+		}
+	}
+	for _, t := range joined {
+		if !slices.ContainsFunc(ordered, func(o Type) bool { return o.String() == t.String() }) {
+			ordered = append(ordered, t)
+		}
+	}
+	return newUnionType(ordered...), complete
+}
+
+// refersTo reports whether ident names the variable. An identifier not
+// checked yet, in a loop, is taken for it by its name.
+func (w *flowWalker) refersTo(ident *ast.Identifier) bool {
+	if sym, ok := w.tc.symbols[ident]; ok {
+		return sym == w.sym
+	}
+	return ident.Value == w.sym.Name
+}
+
+// assignedType returns the type of the variable once the assignment flow
+// has run. A declaration without a value leaves it unassigned, while a
+// declaration with one and an assignment narrow it to the members of its
+// declared type the value may be. Other assignments, e.g. by destructuring,
+// give it its declared type.
+func (w *flowWalker) assignedType(flow *FlowNode) Type {
+	switch n := flow.Node.(type) {
+	case *ast.LetStatement:
+		if n.Value == nil {
+			return w.initial
+		}
+		if value, ok := w.tc.assigned[n]; ok && n.Name == flow.Name {
+			return w.tc.assignedType(w.declared, value)
+		}
+	case *ast.AssignmentExpression:
+		if value, ok := w.tc.assigned[n]; ok && n.Target == ast.Expression(flow.Name) {
+			return w.tc.assignedType(w.declared, value)
+		}
+	}
+	return w.declared
+}
+
+// narrowBySwitch narrows t, the type of the variable where a switch on it
+// enters clause i, to the value of its case. The default clause, or -1,
+// is entered by the values no case matches.
+func (w *flowWalker) narrowBySwitch(t Type, s *ast.SwitchStatement, i int) Type {
+	ident, ok := ast.SkipParentheses(s.Discriminant).(*ast.Identifier)
+	if !ok || !w.refersTo(ident) {
+		return t
+	}
+	if i >= 0 && s.Cases[i].Test != nil {
+		if lit := literalType(s.Cases[i].Test); lit != nil {
+			return narrowToCase(t, lit)
+		}
+		return t
+	}
+
+	var cases []Type
+	for _, c := range s.Cases {
+		if lit := literalType(c.Test); c.Test != nil && lit != nil {
+			cases = append(cases, lit)
+		}
+	}
+	return removeCases(t, cases)
+}
+
+// assignedType returns the type of a variable declared as declared once a
+// value of type value is assigned to it: the members of a declared union
+// the value may be, or the whole declared type
+func (tc *TypeChecker) assignedType(declared Type, value Type) Type {
+	if _, ok := declared.(*UnionType); !ok {
+		return declared
+	}
+	narrowed := filterMembers(declared, func(member Type) bool {
+		for _, v := range unionMembers(value) {
+			if tc.isAssignable(v, member) {
+				return true
+			}
+		}
+		return false
+	})
+	if isBasic(narrowed, "never") {
+		return declared
+	}
+	return narrowed
+}
+
+// narrowByCondition narrows t, the type of the variable w walks for, to
+// the type it has where condition is true, or false when assumeTrue is not
+// set. A variable tested for truthiness, or compared with != null, is
+// neither null nor undefined where the test passes.
+func (tc *TypeChecker) narrowByCondition(t Type, w *flowWalker, condition ast.Expression, assumeTrue bool) Type {
+	if isBasic(t, "unknown") {
+		return t
+	}
+	switch e := ast.SkipParentheses(condition).(type) {
+	case *ast.Identifier:
+		if assumeTrue && w.refersTo(e) {
+			return removeNullish(t)
+		}
+	case *ast.InfixExpression:
+		switch e.Operator {
+		case "==", "!=", "===", "!==":
+			return narrowNullComparison(t, w, e, assumeTrue)
+		}
+	}
+	return t
+}
+
+// narrowNullComparison narrows a variable compared with null or undefined.
+// == and != match both of them, while === and !== match the one written.
+func narrowNullComparison(t Type, w *flowWalker, e *ast.InfixExpression, assumeTrue bool) Type {
+	ident, value := nullComparison(e.Left, e.Right)
+	if ident == nil {
+		ident, value = nullComparison(e.Right, e.Left)
+	}
+	if ident == nil || !w.refersTo(ident) {
+		return t
+	}
+
+	loose := e.Operator == "==" || e.Operator == "!="
+	equal := (e.Operator == "==" || e.Operator == "===") == assumeTrue
+	return filterMembers(t, func(member Type) bool {
+		matches := isBasic(member, value) || loose && (isBasic(member, "null") || isBasic(member, "undefined"))
+		return matches == equal
+	})
+}
+
+// nullComparison returns the variable left compares with null or
+// undefined, and which of the two right is, or nil
+func nullComparison(left ast.Expression, right ast.Expression) (*ast.Identifier, string) {
+	ident, ok := ast.SkipParentheses(left).(*ast.Identifier)
+	if !ok {
+		return nil, ""
+	}
+	if !isNullishLiteral(right) {
+		return nil, ""
+	}
+	if _, ok := ast.SkipParentheses(right).(*ast.NullLiteral); ok {
+		return ident, "null"
+	}
+	return ident, "undefined"
+}
+
+// isNullishLiteral reports whether expr is null or undefined itself
+func isNullishLiteral(expr ast.Expression) bool {
+	switch e := ast.SkipParentheses(expr).(type) {
+	case *ast.NullLiteral:
+		return true
+	case *ast.Identifier:
+		return e.Value == "undefined"
+	}
+	return false
+}
+
+// filterMembers returns the members of t that keep reports true for, or
+// never when there are none
+func filterMembers(t Type, keep func(Type) bool) Type {
+	var kept []Type
+	for _, member := range unionMembers(t) {
+		if keep(member) {
+			kept = append(kept, member)
+		}
+	}
+	if len(kept) == 0 {
+		return &BasicType{Name: "never"}
+	}
+	return newUnionType(kept...)
+}
+
+// nullishMembers reports whether t includes null and whether it includes
+// undefined
+func nullishMembers(t Type) (null bool, undefined bool) {
+	for _, member := range unionMembers(t) {
+		null = null || isBasic(member, "null")
+		undefined = undefined || isBasic(member, "undefined")
+	}
+	return null, undefined
+}
+
+// nonNullObject returns the type t of object, whose property is used,
+// without null and undefined. With strict null checks an object that may
+// be null or undefined is reported, by its name when it has one.
+func (tc *TypeChecker) nonNullObject(object ast.Expression, t Type) Type {
+	null, undefined := nullishMembers(t)
+	if !null && !undefined {
+		return t
+	}
+	if tc.strictNullChecks() {
+		if name, ok := entityName(object); ok {
+			tc.errorAt(startToken(object), nullishMessage(null, undefined, errPossiblyNullName, errPossiblyUndefinedName, errPossiblyNullishName), name)
+		} else {
+			tc.errorAt(startToken(object), nullishMessage(null, undefined, errPossiblyNull, errPossiblyUndefined, errPossiblyNullish))
+		}
+	}
+	return withoutNullish(t)
+}
+
+// nonNullCallee returns the type t of the function callee called without
+// null and undefined, reporting a function that may be either with strict
+// null checks. Once reported, a callee that is no function either is the
+// error type, so that the call is not reported twice.
+func (tc *TypeChecker) nonNullCallee(callee ast.Expression, t Type) Type {
+	null, undefined := nullishMembers(t)
+	if !null && !undefined {
+		return t
+	}
+	t = withoutNullish(t)
+	if tc.strictNullChecks() {
+		tc.errorAt(startToken(callee), nullishMessage(null, undefined, errInvokePossiblyNull, errInvokePossiblyUndefined, errInvokePossiblyNullish))
+		if _, ok := t.(*FunctionType); !ok {
+			return errorType
+		}
+	}
+	return t
+}
+
+// withoutNullish drops null and undefined from t. Nothing is left of null
+// or undefined alone, whose use has been reported, so it becomes the error
+// type.
+func withoutNullish(t Type) Type {
+	t = removeNullish(t)
+	if isBasic(t, "never") {
+		return errorType
+	}
+	return t
+}
+
+// nullishMessage returns the message for a value that may be null, may be
+// undefined, or may be both
+func nullishMessage(null bool, undefined bool, nullMessage message, undefinedMessage message, both message) message {
+	switch {
+	case null && undefined:
+		return both
+	case null:
+		return nullMessage
+	default:
+		return undefinedMessage
+	}
+}
+
+// entityName returns the name TypeScript shows for a variable or a
+// property of one, e.g. o.a, or false for other expressions
+func entityName(expr ast.Expression) (string, bool) {
+	switch e := ast.SkipParentheses(expr).(type) {
+	case *ast.Identifier:
+		return e.Value, true
+	case *ast.MethodCallExpression:
+		if e.Arguments != nil || e.Optional {
+			return "", false
+		}
+		if object, ok := entityName(e.Object); ok {
+			return object + "." + e.Method.Value, true
+		}
+	}
+	return "", false
+}
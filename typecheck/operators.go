@@ -0,0 +1,312 @@
+package typecheck
+
+import "github.com/dmarro89/ts-go-compiler/ast"
+
+// checkPrefixExpression checks the unary operators
+func (tc *TypeChecker) checkPrefixExpression(expr *ast.PrefixExpression) Type {
+	switch expr.Operator {
+	case "++", "--":
+		return tc.checkIncrement(expr.Right)
+	case "delete":
+		if !isPropertyAccess(expr.Right) {
+			tc.errorAt(startToken(expr.Right), errDeleteOperand)
+		}
+		tc.checkExpression(expr.Right)
+		return &BasicType{Name: "boolean"}
+	}
+
+	tc.checkExpression(expr.Right)
+
+	switch expr.Operator {
+	case "!":
+		return &BasicType{Name: "boolean"}
+	case "typeof":
+		return &BasicType{Name: "string"}
+	case "void":
+		return &BasicType{Name: "undefined"}
+	default: // - + ~
+		return &BasicType{Name: "number"}
+	}
+}
+
+// checkIncrement checks the operand of ++ and --, which must be a numeric
+// variable or property
+func (tc *TypeChecker) checkIncrement(operand ast.Expression) Type {
+	t := tc.checkExpression(operand)
+
+	if !isNumeric(t) {
+		tc.errorAt(startToken(operand), errArithmeticOperand)
+	}
+	tc.checkAssignmentTarget(operand, errIncrementOperand, errOptionalIncrement)
+
+	return &BasicType{Name: "number"}
+}
+
+// checkInfixExpression checks the binary operators
+func (tc *TypeChecker) checkInfixExpression(expr *ast.InfixExpression) Type {
+	left := tc.checkExpression(expr.Left)
+
+	// The flow graph narrows the right operand of && to where the left one
+	// is true, and that of || to where it is false
+	right := tc.checkExpression(expr.Right)
+	return tc.binaryType(expr.Operator, expr.Left, expr.Right, left, right)
+}
+
+// binaryType returns the type of leftExpr operator rightExpr, whose operands
+// are of types left and right, reporting operands the operator cannot be
+// applied to
+func (tc *TypeChecker) binaryType(operator string, leftExpr ast.Expression, rightExpr ast.Expression, left Type, right Type) Type {
+	switch operator {
+	case ",":
+		return right
+	case "&&":
+		// The left operand is the result when it is falsy
+		return newUnionType(falsyPart(left), right)
+	case "||":
+		return newUnionType(left, right)
+	case "??":
+		return newUnionType(removeNullish(left), right)
+	case "==", "!=", "===", "!==":
+		if !isComparableTo(left, right) {
+			tc.errorAt(startToken(leftExpr), errNoOverlap, left, right)
+		}
+		return &BasicType{Name: "boolean"}
+	case "<", ">", "<=", ">=":
+		if !isComparable(left, right) {
+			tc.errorAt(startToken(leftExpr), errOperatorTypes, operator, left, right)
+		}
+		return &BasicType{Name: "boolean"}
+	case "in":
+		if !isAssignableTo(left, newUnionType(&BasicType{Name: "string"}, &BasicType{Name: "number"}, &BasicType{Name: "symbol"})) {
+			tc.errorAt(startToken(leftExpr), errInLeft)
+		}
+		if isPrimitive(right) {
+			tc.errorAt(startToken(rightExpr), errInRight)
+		}
+		return &BasicType{Name: "boolean"}
+	case "instanceof":
+		if isPrimitive(left) {
+			tc.errorAt(startToken(leftExpr), errInstanceofLeft)
+		}
+		if _, ok := right.(*FunctionType); !ok && !isBasic(right, "any") {
+			tc.errorAt(startToken(rightExpr), errInstanceofRight)
+		}
+		return &BasicType{Name: "boolean"}
+	case "+":
+		switch {
+		case isStringLike(left) || isStringLike(right):
+			return &BasicType{Name: "string"}
+		case isBasic(left, "any") || isBasic(right, "any"):
+			return &BasicType{Name: "any"}
+		case isNumeric(left) && isNumeric(right):
+			return &BasicType{Name: "number"}
+		}
+		tc.errorAt(startToken(leftExpr), errPlusOperands, left, right)
+		return &BasicType{Name: "any"}
+	default: // - * / % ** << >> >>> & | ^
+		if !isNumeric(left) {
+			tc.errorAt(startToken(leftExpr), errArithmeticLeft)
+		}
+		if !isNumeric(right) {
+			tc.errorAt(startToken(rightExpr), errArithmeticRight)
+		}
+		return &BasicType{Name: "number"}
+	}
+}
+
+// checkAssignmentExpression checks = and the compound assignments. The value
+// of a compound assignment is the result of its binary operator, which must
+// be assignable back to the target.
+func (tc *TypeChecker) checkAssignmentExpression(expr *ast.AssignmentExpression) Type {
+	if ast.IsPattern(expr.Target) {
+		value := tc.checkExpression(expr.Value)
+		tc.bindPattern(expr.Target, value, 0)
+		return value
+	}
+
+	tc.checkAssignmentTarget(expr.Target, errInvalidAssignmentTarget, errOptionalAssignment)
+
+	// A variable may be assigned anything of its declared type, whatever it
+	// has been narrowed to. = does not read it, so it may be unassigned.
+	ident, isName := ast.SkipParentheses(expr.Target).(*ast.Identifier)
+	var target Type
+	if isName && expr.Operator == "=" && tc.env.Lookup(ident.Value) != nil {
+		target = errorType
+		if sym := tc.resolve(ident); sym.Type != nil {
+			target = sym.Type
+		}
+	} else {
+		target = tc.checkExpression(expr.Target)
+		if sym := tc.symbols[ident]; isName && sym != nil && sym.Type != nil {
+			target = sym.Type
+		}
+	}
+	value := tc.checkExpression(expr.Value)
+
+	if expr.Operator != "=" {
+		value = tc.binaryType(expr.Operator[:len(expr.Operator)-1], expr.Target, expr.Value, target, value)
+	}
+
+	tc.checkAssignableValue(startToken(expr.Target), expr.Value, value, target)
+	if isName && expr.Operator == "=" {
+		tc.assigned[expr] = value
+	}
+
+	return value
+}
+
+// checkAssignmentTarget reports target with invalid when it cannot be
+// assigned to, and with optional when it is an optional chain, which may
+// stop before the property it names
+func (tc *TypeChecker) checkAssignmentTarget(target ast.Expression, invalid message, optional message) {
+	switch {
+	case !isAssignmentTarget(target):
+		tc.errorAt(startToken(target), invalid)
+	case ast.IsOptionalChain(ast.SkipParentheses(target)):
+		tc.errorAt(startToken(target), optional)
+	}
+}
+
+// isAssignmentTarget reports whether expr can be assigned to
+func isAssignmentTarget(expr ast.Expression) bool {
+	switch e := ast.SkipParentheses(expr).(type) {
+	case *ast.Identifier:
+		return true
+	default:
+		return isPropertyAccess(e)
+	}
+}
+
+// isPropertyAccess reports whether expr is object.property
+func isPropertyAccess(expr ast.Expression) bool {
+	call, ok := ast.SkipParentheses(expr).(*ast.MethodCallExpression)
+	return ok && call.Arguments == nil
+}
+
+func isNumeric(t Type) bool {
+	return isAssignableTo(t, newUnionType(&BasicType{Name: "number"}, &BasicType{Name: "bigint"}))
+}
+
+func isStringLike(t Type) bool {
+	return !isBasic(t, "any") && !isBasic(t, "never") && isAssignableTo(t, &BasicType{Name: "string"})
+}
+
+// isComparable reports whether the relational operators accept the operands
+func isComparable(left Type, right Type) bool {
+	return isComparableTo(left, right) || (isNumeric(left) && isNumeric(right)) || (isStringLike(left) && isStringLike(right))
+}
+
+// isPrimitive reports whether every value of t is a primitive
+func isPrimitive(t Type) bool {
+	if u, ok := t.(*UnionType); ok {
+		for _, member := range u.Types {
+			if !isPrimitive(member) {
+				return false
+			}
+		}
+		return true
+	}
+
+	if _, ok := t.(*LiteralType); ok {
+		return true
+	}
+
+	b, ok := t.(*BasicType)
+	if !ok {
+		return false
+	}
+	switch b.Name {
+	case "number", "string", "boolean", "bigint", "symbol", "null", "undefined", "void":
+		return true
+	}
+	return false
+}
+
+// removeNullish drops null and undefined from t
+func removeNullish(t Type) Type {
+	if isBasic(t, "null") || isBasic(t, "undefined") {
+		return &BasicType{Name: "never"}
+	}
+
+	u, ok := t.(*UnionType)
+	if !ok {
+		return t
+	}
+
+	var kept []Type
+	for _, member := range u.Types {
+		if !isBasic(member, "null") && !isBasic(member, "undefined") {
+			kept = append(kept, member)
+		}
+	}
+	if len(kept) == 0 {
+		return &BasicType{Name: "never"}
+	}
+	return newUnionType(kept...)
+}
+
+// removeUndefined drops undefined from t
+func removeUndefined(t Type) Type {
+	if isBasic(t, "undefined") {
+		return &BasicType{Name: "never"}
+	}
+
+	u, ok := t.(*UnionType)
+	if !ok {
+		return t
+	}
+
+	var kept []Type
+	for _, member := range u.Types {
+		if !isBasic(member, "undefined") {
+			kept = append(kept, member)
+		}
+	}
+	if len(kept) == 0 {
+		return &BasicType{Name: "never"}
+	}
+	return newUnionType(kept...)
+}
+
+// falsyPart returns the values of t that are falsy: "" for strings, 0 for
+// numbers, false for booleans, and null and undefined themselves. Objects
+// are never falsy.
+func falsyPart(t Type) Type {
+	var falsy []Type
+	for _, member := range unionMembers(t) {
+		switch m := member.(type) {
+		case *LiteralType:
+			if m.Value == `""` || m.Value == "0" || m.Value == "false" {
+				falsy = append(falsy, m)
+			}
+		case *BasicType:
+			switch m.Name {
+			case "string":
+				falsy = append(falsy, &LiteralType{Value: `""`, Base: m})
+			case "number":
+				falsy = append(falsy, &LiteralType{Value: "0", Base: m})
+			case "boolean":
+				falsy = append(falsy, &LiteralType{Value: "false", Base: m})
+			case "null", "undefined", "void", "any", "unknown", "bigint":
+				falsy = append(falsy, m)
+			}
+		}
+	}
+	if len(falsy) == 0 {
+		return &BasicType{Name: "never"}
+	}
+	return newUnionType(falsy...)
+}
+
+// isNullable reports whether t includes null or undefined
+func isNullable(t Type) bool {
+	if u, ok := t.(*UnionType); ok {
+		for _, member := range u.Types {
+			if isNullable(member) {
+				return true
+			}
+		}
+		return false
+	}
+	return isBasic(t, "null") || isBasic(t, "undefined")
+}
@@ -0,0 +1,303 @@
+package typecheck
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/dmarro89/ts-go-compiler/ast"
+	"github.com/dmarro89/ts-go-compiler/token"
+)
+
+// bindPattern gives the names in a declaration or destructuring assignment
+// their types, where t is the type of the value being destructured. The
+// names are declared with flags; with no flags the pattern assigns names
+// that already exist, and t must be assignable to them.
+func (tc *TypeChecker) bindPattern(target ast.Expression, t Type, flags SymbolFlags) {
+	switch n := target.(type) {
+	case *ast.ObjectPattern:
+		var named []string
+		for _, el := range n.Properties {
+			if el.Rest {
+				tc.bindPattern(el.Name, objectRestType(t, named), flags)
+				continue
+			}
+
+			key := propertyKey(el.PropertyName)
+			if el.PropertyName == nil {
+				key = propertyKey(el.Name)
+			}
+			named = append(named, key)
+
+			tc.bindPattern(el.Name, tc.withDefault(tc.destructuredProperty(el.Token, t, key), el.Default), flags)
+		}
+	case *ast.ArrayPattern:
+		if tuple, ok := t.(*TupleType); ok {
+			tc.bindTuple(n, tuple, flags)
+			return
+		}
+
+		element := tc.iteratedType(n.Token, t)
+		for _, el := range n.Elements {
+			switch {
+			case el == nil:
+			case el.Rest:
+				tc.bindPattern(el.Name, &ArrayType{ElementType: element}, flags)
+			default:
+				tc.bindPattern(el.Name, tc.withDefault(element, el.Default), flags)
+			}
+		}
+	case *ast.Identifier:
+		if flags != 0 {
+			tc.declare(n, flags, t)
+			return
+		}
+		tc.checkAssignable(n.Token, t, tc.checkIdentifier(n))
+	default:
+		tc.checkAssignmentTarget(n, errInvalidAssignmentTarget, errOptionalAssignment)
+		tc.checkAssignable(startToken(n), t, tc.checkExpression(n))
+	}
+}
+
+// bindTuple destructures a tuple, where each position has its own type
+func (tc *TypeChecker) bindTuple(pattern *ast.ArrayPattern, tuple *TupleType, flags SymbolFlags) {
+	for i, el := range pattern.Elements {
+		switch {
+		case el == nil:
+		case el.Rest:
+			rest := &TupleType{Elements: []Type{}}
+			if i < len(tuple.Elements) {
+				rest.Elements = tuple.Elements[i:]
+			}
+			tc.bindPattern(el.Name, rest, flags)
+		case i >= len(tuple.Elements):
+			tc.errorAt(el.Token, errTupleIndex, tuple, len(tuple.Elements), i)
+			tc.bindPattern(el.Name, &BasicType{Name: "undefined"}, flags)
+		default:
+			tc.bindPattern(el.Name, tc.withDefault(tuple.Elements[i], el.Default), flags)
+		}
+	}
+}
+
+// withDefault returns the type of a destructured value with a default,
+// which replaces the value when it is undefined
+func (tc *TypeChecker) withDefault(t Type, def ast.Expression) Type {
+	if def == nil {
+		return t
+	}
+
+	defined := removeUndefined(t)
+	defType := tc.checkExpression(def)
+	if !isBasic(defined, "never") {
+		tc.checkAssignable(startToken(def), defType, defined)
+	}
+	return newUnionType(defined, defType)
+}
+
+// destructuredProperty returns the type of the property key of t, reporting
+// at tok properties t does not have
+func (tc *TypeChecker) destructuredProperty(tok token.Token, t Type, key string) Type {
+	switch o := t.(type) {
+	case *ObjectType:
+		if o.Property(key) == nil {
+			tc.errorAt(tok, errNoProperty, key, t)
+			return &BasicType{Name: "any"}
+		}
+	case *BasicType:
+		switch o.Name {
+		case "number", "boolean", "bigint", "symbol":
+			tc.errorAt(tok, errNoProperty, key, t)
+			return &BasicType{Name: "any"}
+		}
+	}
+	return propertyType(t, key)
+}
+
+// objectRestType is the type of ...rest in an object pattern: the
+// properties of t not destructured by name
+func objectRestType(t Type, named []string) Type {
+	o, ok := t.(*ObjectType)
+	if !ok {
+		if isBasic(t, "any") {
+			return t
+		}
+		return &ObjectType{}
+	}
+
+	rest := &ObjectType{}
+	for _, p := range o.Properties {
+		if !slices.Contains(named, p.Name) {
+			rest.Properties = append(rest.Properties, p)
+		}
+	}
+	return rest
+}
+
+// iteratedType returns the type of the elements of t, reporting at tok types
+// that cannot be iterated
+func (tc *TypeChecker) iteratedType(tok token.Token, t Type) Type {
+	if element, ok := iterableElement(t); ok {
+		return element
+	}
+
+	tc.errorAt(tok, errNotIterable, t)
+	return &BasicType{Name: "any"}
+}
+
+// checkArrayLiteral infers the type of an array from its elements. When
+// the array is written where an array of element is expected the elements
+// are checked against element, keeping the literal types it needs.
+func (tc *TypeChecker) checkArrayLiteral(array *ast.ArrayLiteral, element Type) Type {
+	if len(array.Elements) == 0 {
+		return &ArrayType{ElementType: &BasicType{Name: "never"}}
+	}
+
+	elements := make([]Type, len(array.Elements))
+	for i, el := range array.Elements {
+		switch e := el.(type) {
+		case nil:
+			elements[i] = &BasicType{Name: "undefined"}
+		case *ast.SpreadElement:
+			elements[i] = tc.iteratedType(e.Token, tc.checkExpression(e.Argument))
+		default:
+			elements[i] = tc.checkValue(e, element)
+			if element == nil {
+				elements[i] = widenLiteral(elements[i])
+			}
+		}
+	}
+	return &ArrayType{ElementType: newUnionType(elements...)}
+}
+
+// checkValue checks a value written where a value of type expected is
+// expected, e.g. the initializer of a declaration of that type, which is
+// nil when there is none. A literal keeps its literal type when expected
+// needs it, also as an element of an array, a branch of a conditional or
+// a property of an object. An array literal written where a tuple is
+// expected is a tuple itself.
+func (tc *TypeChecker) checkValue(value ast.Expression, expected Type) Type {
+	if expected == nil {
+		return tc.checkExpression(value)
+	}
+
+	switch e := ast.SkipParentheses(value).(type) {
+	case *ast.ArrayLiteral:
+		switch x := expected.(type) {
+		case *TupleType:
+			return tc.checkTupleValue(e, x)
+		case *ArrayType:
+			return tc.checkArrayLiteral(e, x.ElementType)
+		}
+	case *ast.ObjectLiteral:
+		return tc.checkObjectLiteral(e, expected)
+	case *ast.ConditionalExpression:
+		tc.checkExpression(e.Condition)
+		return newUnionType(tc.checkValue(e.Consequence, expected), tc.checkValue(e.Alternative, expected))
+	}
+
+	t := tc.checkExpression(value)
+	if lit := literalType(value); lit != nil && !tc.isAssignable(t, expected) && tc.isAssignable(lit, expected) {
+		return lit
+	}
+	return t
+}
+
+// checkTupleValue checks an array literal written where tuple is expected,
+// each element against the type of its position
+func (tc *TypeChecker) checkTupleValue(array *ast.ArrayLiteral, expected *TupleType) Type {
+	tuple := &TupleType{Elements: []Type{}}
+	for _, el := range array.Elements {
+		switch e := el.(type) {
+		case nil:
+			tuple.Elements = append(tuple.Elements, &BasicType{Name: "undefined"})
+		case *ast.SpreadElement:
+			t := tc.checkExpression(e.Argument)
+			spread, ok := t.(*TupleType)
+			if !ok {
+				// The length is unknown once an array is spread in
+				return tc.checkArrayLiteral(array, nil)
+			}
+			tuple.Elements = append(tuple.Elements, spread.Elements...)
+		default:
+			var element Type
+			if i := len(tuple.Elements); i < len(expected.Elements) {
+				element = expected.Elements[i]
+			}
+			tuple.Elements = append(tuple.Elements, tc.checkValue(e, element))
+		}
+	}
+	return tuple
+}
+
+// checkObjectLiteral infers the type of an object from its properties. When
+// the object is written where a value of type expected is expected its
+// properties are checked against those of expected.
+func (tc *TypeChecker) checkObjectLiteral(object *ast.ObjectLiteral, expected Type) Type {
+	result := &ObjectType{}
+	spreadsAny := false
+	set := func(p *Property) {
+		for i, existing := range result.Properties {
+			if existing.Name == p.Name {
+				result.Properties[i] = p
+				return
+			}
+		}
+		result.Properties = append(result.Properties, p)
+	}
+
+	for _, prop := range object.Properties {
+		if spread, ok := prop.Value.(*ast.SpreadElement); ok {
+			switch s := tc.checkExpression(spread.Argument).(type) {
+			case *ObjectType:
+				// Later properties override earlier ones
+				for _, p := range s.Properties {
+					set(p)
+				}
+			case *BasicType:
+				switch s.Name {
+				case "any":
+					spreadsAny = true
+				case "null", "undefined", "object":
+				default:
+					tc.errorAt(spread.Token, errSpreadType)
+				}
+			}
+			continue
+		}
+
+		if assign, ok := prop.Value.(*ast.AssignmentExpression); ok && prop.Shorthand {
+			tc.errorAt(prop.Token, errShorthandInitializer)
+			set(&Property{Name: propertyKey(prop.Key), Type: tc.checkExpression(assign.Value)})
+			continue
+		}
+
+		key := propertyKey(prop.Key)
+		var property Type
+		if o, ok := expected.(*ObjectType); ok && o.Property(key) != nil {
+			property = o.Property(key).Type
+		}
+		t := tc.checkValue(prop.Value, property)
+		if property == nil {
+			t = widenLiteral(t)
+		}
+		set(&Property{Name: key, Type: t})
+	}
+
+	if spreadsAny {
+		return &BasicType{Name: "any"}
+	}
+	return result
+}
+
+// propertyKey returns the name of a property written as an identifier,
+// string or number
+func propertyKey(key ast.Expression) string {
+	switch k := key.(type) {
+	case *ast.Identifier:
+		return k.Value
+	case *ast.StringLiteral:
+		return k.Value
+	case *ast.IntegerLiteral:
+		return fmt.Sprint(k.Value)
+	}
+	return ""
+}
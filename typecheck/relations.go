@@ -0,0 +1,435 @@
+package typecheck
+
+import (
+	"fmt"
+
+	"github.com/dmarro89/ts-go-compiler/ast"
+	"github.com/dmarro89/ts-go-compiler/diagnostics"
+	"github.com/dmarro89/ts-go-compiler/token"
+)
+
+// Relation is a relation between types, after which the checks of the
+// checker are named
+type Relation int
+
+const (
+	// Identity relates types that are the same
+	Identity Relation = iota
+	// Subtype relates a type to the types it is a subtype of, where any is
+	// only a subtype of any and unknown
+	Subtype
+	// Assignable relates the type of a value to the types of the locations
+	// it may be assigned to
+	Assignable
+	// Comparable relates types whose values may be compared with ===, or
+	// matched by a switch case: a union is comparable to a type when any of
+	// its members is
+	Comparable
+)
+
+// relater compares types under a relation
+type relater struct {
+	relation Relation
+
+	// strictFunctionTypes compares the parameters of functions
+	// contravariantly, instead of bivariantly
+	strictFunctionTypes bool
+
+	// strictNullChecks relates null and undefined only to the types that
+	// include them, instead of to every type but never
+	strictNullChecks bool
+}
+
+// IsTypeRelatedTo reports whether source is related to target by relation,
+// under the options of the checker
+func (tc *TypeChecker) IsTypeRelatedTo(source Type, target Type, relation Relation) bool {
+	return tc.relater(relation).isRelated(source, target)
+}
+
+func (tc *TypeChecker) relater(relation Relation) *relater {
+	return &relater{
+		relation:            relation,
+		strictFunctionTypes: tc.options.StrictFunctionTypes || tc.options.Strict,
+		strictNullChecks:    tc.strictNullChecks(),
+	}
+}
+
+// strictNullChecks reports whether null and undefined are kept out of the
+// other types
+func (tc *TypeChecker) strictNullChecks() bool {
+	return tc.options.StrictNullChecks || tc.options.Strict
+}
+
+// isAssignableTo reports whether a value of type source can be assigned to
+// a location of type target, with the default options apart from null and
+// undefined, which are kept apart so the operands of operators are told
+// from them
+func isAssignableTo(source Type, target Type) bool {
+	return (&relater{relation: Assignable, strictNullChecks: true}).isRelated(source, target)
+}
+
+// isComparableTo reports whether values of types source and target may be
+// compared, with the default options
+func isComparableTo(source Type, target Type) bool {
+	r := &relater{relation: Comparable}
+	return r.isRelated(source, target) || r.isRelated(target, source)
+}
+
+// isAssignable reports whether a value of type source can be assigned to a
+// location of type target, under the options of the checker
+func (tc *TypeChecker) isAssignable(source Type, target Type) bool {
+	return tc.relater(Assignable).isRelated(source, target)
+}
+
+// isAssignableValue reports whether the value of expr, of type t, can be
+// assigned to target. A literal value also has its literal type, so "a"
+// can be assigned to "a" | "b".
+func (tc *TypeChecker) isAssignableValue(expr ast.Expression, t Type, target Type) bool {
+	if tc.isAssignable(t, target) {
+		return true
+	}
+	lit := literalType(expr)
+	return lit != nil && tc.isAssignable(lit, target)
+}
+
+// checkAssignable reports, at tok, a value of type source assigned to a
+// location of type target it is not assignable to
+func (tc *TypeChecker) checkAssignable(tok token.Token, source Type, target Type) {
+	if !tc.isAssignable(source, target) {
+		tc.addRelationError(tok, source, target, errNotAssignable, source, target)
+	}
+}
+
+// checkAssignableValue reports, at tok, the value of expr, of type t,
+// assigned to a location of type target it is not assignable to
+func (tc *TypeChecker) checkAssignableValue(tok token.Token, expr ast.Expression, t Type, target Type) {
+	if !tc.isAssignableValue(expr, t, target) {
+		tc.addRelationError(tok, t, target, errNotAssignable, t, target)
+	}
+}
+
+// addRelationError reports m at tok for source not being assignable to
+// target, followed by the reasons it is not, each indented under the one
+// before, e.g. the property whose types differ
+func (tc *TypeChecker) addRelationError(tok token.Token, source Type, target Type, m message, args ...any) {
+	text := fmt.Sprintf(m.text, args...)
+	indent := "\n  "
+	for _, reason := range tc.relater(Assignable).elaborate(source, target) {
+		text += indent + reason
+		indent += "  "
+	}
+	tc.diagnose(tok, diagnostics.Error, m.code, text)
+}
+
+// isRelated reports whether source is related to target
+func (r *relater) isRelated(source Type, target Type) bool {
+	if r.relation == Identity {
+		return isIdentical(source, target)
+	}
+
+	switch {
+	case isBasic(source, "never"):
+		return true
+	case isBasic(target, "any"), isBasic(target, "unknown"):
+		return true
+	case isBasic(source, "any"):
+		return r.relation != Subtype && !isBasic(target, "never")
+	case isBasic(source, "undefined") && isBasic(target, "void"):
+		return true
+	case r.relation == Comparable && (isBasic(source, "null") || isBasic(source, "undefined")):
+		// Any value may be checked against null and undefined
+		return true
+	case !r.strictNullChecks && (isBasic(source, "null") || isBasic(source, "undefined")):
+		return !isBasic(target, "never")
+	}
+
+	if u, ok := source.(*UnionType); ok {
+		if r.relation == Comparable {
+			for _, member := range u.Types {
+				if r.isRelated(member, target) {
+					return true
+				}
+			}
+			return false
+		}
+		for _, member := range u.Types {
+			if !r.isRelated(member, target) {
+				return false
+			}
+		}
+		return true
+	}
+
+	if u, ok := target.(*UnionType); ok {
+		for _, member := range u.Types {
+			if r.isRelated(source, member) {
+				return true
+			}
+		}
+		// boolean is the union of true and false
+		return isBasic(source, "boolean") &&
+			r.isRelated(&LiteralType{Value: "true", Base: &BasicType{Name: "boolean"}}, target) &&
+			r.isRelated(&LiteralType{Value: "false", Base: &BasicType{Name: "boolean"}}, target)
+	}
+
+	switch t := target.(type) {
+	case *ArrayType:
+		switch s := source.(type) {
+		case *ArrayType:
+			return r.isRelated(s.ElementType, t.ElementType)
+		case *TupleType:
+			for _, el := range s.Elements {
+				if !r.isRelated(el, t.ElementType) {
+					return false
+				}
+			}
+			return true
+		}
+		return false
+	case *GenericType:
+		if s, ok := source.(*GenericType); ok && s.Name == t.Name {
+			for i, arg := range s.TypeArguments {
+				if !r.isRelated(arg, t.TypeArguments[i]) {
+					return false
+				}
+			}
+			return true
+		}
+		// Anything iterable implements the iteration protocol
+		if t.Name == "Iterable" {
+			element, ok := iterableElement(source)
+			return ok && r.isRelated(element, t.TypeArguments[0])
+		}
+		return false
+	case *TupleType:
+		s, ok := source.(*TupleType)
+		if !ok || len(s.Elements) != len(t.Elements) {
+			return false
+		}
+		for i, el := range s.Elements {
+			if !r.isRelated(el, t.Elements[i]) {
+				return false
+			}
+		}
+		return true
+	case *ObjectType:
+		return r.isObjectRelated(source, t)
+	case *FunctionType:
+		s, ok := source.(*FunctionType)
+		return ok && r.isSignatureRelated(s, t)
+	case *BasicType:
+		if t.Name == "object" {
+			return !isPrimitive(source) && !isBasic(source, "unknown") && !isBasic(source, "void")
+		}
+	}
+
+	if source.String() == target.String() {
+		return true
+	}
+	if l, ok := source.(*LiteralType); ok {
+		return r.isRelated(l.Base, target)
+	}
+	return false
+}
+
+// isObjectRelated reports whether source has the properties of the object
+// type target. Every value but null and undefined has those of {}.
+func (r *relater) isObjectRelated(source Type, target *ObjectType) bool {
+	if len(target.Properties) == 0 {
+		return !isBasic(source, "null") && !isBasic(source, "undefined") && !isBasic(source, "void") && !isBasic(source, "unknown")
+	}
+	s, ok := source.(*ObjectType)
+	if !ok {
+		return false
+	}
+	for _, p := range target.Properties {
+		sp := s.Property(p.Name)
+		if sp == nil {
+			if !p.Optional {
+				return false
+			}
+			continue
+		}
+		if sp.Optional && !p.Optional {
+			return false
+		}
+		if !r.isRelated(sp.Type, p.Type) {
+			return false
+		}
+	}
+	return true
+}
+
+// isSignatureRelated reports whether a function of type source may be used
+// as one of type target. It may take fewer parameters, whose types must
+// accept the arguments target is called with; without strictFunctionTypes
+// it is enough that they be related either way. Whatever it returns is
+// ignored when target returns void.
+func (r *relater) isSignatureRelated(source *FunctionType, target *FunctionType) bool {
+	if requiredParameters(source) > parameterCount(target) {
+		return false
+	}
+	for i := range min(len(source.Parameters), len(target.Parameters)) {
+		if !r.isParameterRelated(parameterType(source, i), parameterType(target, i)) {
+			return false
+		}
+	}
+	return isBasic(target.ReturnType, "void") || r.isRelated(source.ReturnType, target.ReturnType)
+}
+
+// isParameterRelated reports whether a parameter of type source accepts the
+// arguments given to a parameter of type target
+func (r *relater) isParameterRelated(source Type, target Type) bool {
+	if r.isRelated(target, source) {
+		return true
+	}
+	return !r.strictFunctionTypes && r.isRelated(source, target)
+}
+
+// requiredParameters is the number of arguments a function must be called
+// with
+func requiredParameters(fn *FunctionType) int {
+	required := 0
+	for i, p := range fn.Parameters {
+		if !p.Optional && !p.Rest {
+			required = i + 1
+		}
+	}
+	return required
+}
+
+// parameterCount is the number of arguments a function may be called with,
+// unlimited with a rest parameter
+func parameterCount(fn *FunctionType) int {
+	for _, p := range fn.Parameters {
+		if p.Rest {
+			return int(^uint(0) >> 1)
+		}
+	}
+	return len(fn.Parameters)
+}
+
+// parameterType is the type of the argument at index i of a call to fn: the
+// element type of a rest parameter
+func parameterType(fn *FunctionType, i int) Type {
+	p := fn.Parameters[i]
+	if p.Rest {
+		if element, ok := iterableElement(p.Type); ok {
+			return element
+		}
+	}
+	return p.Type
+}
+
+// isIdentical reports whether source and target are the same type. The
+// members of unions may be in any order.
+func isIdentical(source Type, target Type) bool {
+	switch s := source.(type) {
+	case *UnionType:
+		t, ok := target.(*UnionType)
+		if !ok || len(s.Types) != len(t.Types) {
+			return false
+		}
+		for _, member := range s.Types {
+			found := false
+			for _, other := range t.Types {
+				if isIdentical(member, other) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return false
+			}
+		}
+		return true
+	case *ObjectType:
+		t, ok := target.(*ObjectType)
+		if !ok || len(s.Properties) != len(t.Properties) {
+			return false
+		}
+		for _, p := range s.Properties {
+			tp := t.Property(p.Name)
+			if tp == nil || tp.Optional != p.Optional || !isIdentical(p.Type, tp.Type) {
+				return false
+			}
+		}
+		return true
+	}
+	return source.String() == target.String()
+}
+
+// elaborate returns the reasons source is not assignable to target, from
+// the outermost to the innermost: the property, element, parameter or
+// return value whose types are not assignable
+func (r *relater) elaborate(source Type, target Type) []string {
+	switch t := target.(type) {
+	case *ObjectType:
+		s, ok := source.(*ObjectType)
+		if !ok {
+			return nil
+		}
+		for _, p := range t.Properties {
+			sp := s.Property(p.Name)
+			switch {
+			case sp == nil && !p.Optional:
+				return []string{fmt.Sprintf(errMissingProperty.text, p.Name, s, t)}
+			case sp != nil && sp.Optional && !p.Optional:
+				return []string{fmt.Sprintf(errOptionalProperty.text, p.Name, s, t)}
+			case sp != nil && !r.isRelated(sp.Type, p.Type):
+				return append([]string{fmt.Sprintf(errPropertyTypes.text, p.Name)}, r.because(sp.Type, p.Type)...)
+			}
+		}
+	case *ArrayType:
+		if s, ok := source.(*ArrayType); ok {
+			return r.because(s.ElementType, t.ElementType)
+		}
+	case *TupleType:
+		s, ok := source.(*TupleType)
+		switch {
+		case !ok:
+			return nil
+		case len(s.Elements) > len(t.Elements):
+			return []string{fmt.Sprintf(errTupleTooLong.text, len(s.Elements), len(t.Elements))}
+		case len(s.Elements) < len(t.Elements):
+			return []string{fmt.Sprintf(errTupleTooShort.text, len(s.Elements), len(t.Elements))}
+		}
+		for i, el := range s.Elements {
+			if !r.isRelated(el, t.Elements[i]) {
+				return append([]string{fmt.Sprintf(errTupleElement.text, i, i)}, r.because(el, t.Elements[i])...)
+			}
+		}
+	case *GenericType:
+		if s, ok := source.(*GenericType); ok && s.Name == t.Name {
+			for i, arg := range s.TypeArguments {
+				if !r.isRelated(arg, t.TypeArguments[i]) {
+					return r.because(arg, t.TypeArguments[i])
+				}
+			}
+		}
+	case *FunctionType:
+		s, ok := source.(*FunctionType)
+		if !ok {
+			return nil
+		}
+		if required := requiredParameters(s); required > parameterCount(t) {
+			return []string{fmt.Sprintf(errTooFewTargetArguments.text, required, len(t.Parameters))}
+		}
+		for i := range min(len(s.Parameters), len(t.Parameters)) {
+			sp, tp := parameterType(s, i), parameterType(t, i)
+			if !r.isParameterRelated(sp, tp) {
+				return append([]string{fmt.Sprintf(errParameterTypes.text, s.Parameters[i].Name, t.Parameters[i].Name)}, r.because(tp, sp)...)
+			}
+		}
+		if !isBasic(t.ReturnType, "void") && !r.isRelated(s.ReturnType, t.ReturnType) {
+			return r.because(s.ReturnType, t.ReturnType)
+		}
+	}
+	return nil
+}
+
+// because returns the reasons for a part of a type that is not assignable:
+// the part itself, then its own reasons
+func (r *relater) because(source Type, target Type) []string {
+	return append([]string{fmt.Sprintf(errNotAssignable.text, source, target)}, r.elaborate(source, target)...)
+}
@@ -0,0 +1,143 @@
+package typecheck
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/dmarro89/ts-go-compiler/lexer"
+	"github.com/dmarro89/ts-go-compiler/parser"
+)
+
+func TestTypeRelations(t *testing.T) {
+	number := &BasicType{Name: "number"}
+	str := &BasicType{Name: "string"}
+	anyType := &BasicType{Name: "any"}
+	unknown := &BasicType{Name: "unknown"}
+	never := &BasicType{Name: "never"}
+	one := &LiteralType{Value: "1", Base: number}
+	object := func(props ...*Property) *ObjectType { return &ObjectType{Properties: props} }
+	fn := func(ret Type, params ...Type) *FunctionType {
+		ft := &FunctionType{ReturnType: ret}
+		for i, p := range params {
+			ft.Parameters = append(ft.Parameters, &Parameter{Name: string(rune('a' + i)), Type: p})
+		}
+		return ft
+	}
+	numberOrString := &UnionType{Types: []Type{number, str}}
+
+	tests := []struct {
+		source   Type
+		target   Type
+		relation Relation
+		strict   bool
+		expected bool
+	}{
+		{number, number, Identity, false, true},
+		{numberOrString, &UnionType{Types: []Type{str, number}}, Identity, false, true},
+		{one, number, Identity, false, false},
+		{one, number, Assignable, false, true},
+		{anyType, number, Assignable, false, true},
+		{anyType, number, Subtype, false, false},
+		{anyType, never, Assignable, false, false},
+		{never, number, Subtype, false, true},
+		{number, unknown, Assignable, false, true},
+		{unknown, number, Assignable, false, false},
+		{numberOrString, number, Assignable, false, false},
+		{numberOrString, number, Comparable, false, true},
+		{&BasicType{Name: "undefined"}, &BasicType{Name: "void"}, Assignable, false, true},
+		{object(&Property{Name: "a", Type: number}, &Property{Name: "b", Type: str}), object(&Property{Name: "a", Type: number}), Assignable, false, true},
+		{object(), object(&Property{Name: "a", Type: number, Optional: true}), Assignable, false, true},
+		{object(), object(&Property{Name: "a", Type: number}), Assignable, false, false},
+		{object(&Property{Name: "a", Type: number, Optional: true}), object(&Property{Name: "a", Type: number}), Assignable, false, false},
+		{str, object(), Assignable, false, true},
+		{str, &BasicType{Name: "object"}, Assignable, false, false},
+		{fn(number), &BasicType{Name: "object"}, Assignable, false, true},
+		{fn(number, number), fn(&BasicType{Name: "void"}, number, str), Assignable, false, true},
+		{fn(number, number, str), fn(number, number), Assignable, false, false},
+		{fn(number, one), fn(number, number), Assignable, false, true},
+		{fn(number, one), fn(number, number), Assignable, true, false},
+		{fn(number, number), fn(number, one), Assignable, true, true},
+		{fn(str), fn(number), Assignable, false, false},
+		{&BasicType{Name: "boolean"}, &UnionType{Types: []Type{
+			&LiteralType{Value: "true", Base: &BasicType{Name: "boolean"}},
+			&LiteralType{Value: "false", Base: &BasicType{Name: "boolean"}},
+		}}, Assignable, false, true},
+	}
+
+	for _, tt := range tests {
+		tc := NewWithOptions(Options{StrictFunctionTypes: tt.strict})
+		if actual := tc.IsTypeRelatedTo(tt.source, tt.target, tt.relation); actual != tt.expected {
+			t.Errorf("%s to %s (relation %d, strict %t): expected %t, got %t", tt.source, tt.target, tt.relation, tt.strict, tt.expected, actual)
+		}
+	}
+}
+
+func TestRelationErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{
+			"let o = {a: 1};\no = {b: 1};",
+			"Type '{ b: number; }' is not assignable to type '{ a: number; }'.\n" +
+				"  Property 'a' is missing in type '{ b: number; }' but required in type '{ a: number; }'.",
+		},
+		{
+			"let o = {a: {b: 1}};\no = {a: {b: \"s\"}};",
+			"Type '{ a: { b: string; }; }' is not assignable to type '{ a: { b: number; }; }'.\n" +
+				"  Types of property 'a' are incompatible.\n" +
+				"    Type '{ b: string; }' is not assignable to type '{ b: number; }'.\n" +
+				"      Types of property 'b' are incompatible.\n" +
+				"        Type 'string' is not assignable to type 'number'.",
+		},
+		{
+			"let t = [1, \"a\"];\nlet u = [1, 2];\nu = t;",
+			"Type '(number | string)[]' is not assignable to type 'number[]'.\n" +
+				"  Type 'number | string' is not assignable to type 'number'.",
+		},
+		{
+			"let f = function(a) { return 1; };\nf = function(a) { return \"s\"; };",
+			"Type '(a: any) => string' is not assignable to type '(a: any) => number'.\n" +
+				"  Type 'string' is not assignable to type 'number'.",
+		},
+		{
+			"let f = function(a) { return 1; };\nf = function(a, b, c) { return 1; };",
+			"Type '(a: any, b: any, c: any) => number' is not assignable to type '(a: any) => number'.\n" +
+				"  Target signature provides too few arguments. Expected 3 or more, but got 1.",
+		},
+		{
+			"let g = function(o) { return {a: 1}; };\ng = function(o) { return {a: \"s\"}; };",
+			"Type '(o: any) => { a: string; }' is not assignable to type '(o: any) => { a: number; }'.\n" +
+				"  Type '{ a: string; }' is not assignable to type '{ a: number; }'.\n" +
+				"    Types of property 'a' are incompatible.\n" +
+				"      Type 'string' is not assignable to type 'number'.",
+		},
+		{
+			"let s: {a?: number} = {};\nlet t: {a: number} = s;",
+			"Type '{ a?: number; }' is not assignable to type '{ a: number; }'.\n" +
+				"  Property 'a' is optional in type '{ a?: number; }' but required in type '{ a: number; }'.",
+		},
+		{"let t: (\"a\" | \"b\")[] = [\"a\"];", ""},
+		{"let t: [1, 2] = [1, 2];", ""},
+		{"let c = 2;\nlet k: \"a\" | \"b\" = c > 1 ? \"a\" : \"b\";", ""},
+		{"let o: { k: \"a\" | \"b\"; } = { k: \"a\" };", ""},
+		{
+			"let t: (\"a\" | \"b\")[] = [\"c\"];",
+			"Type 'string[]' is not assignable to type '(\"a\" | \"b\")[]'.\n" +
+				"  Type 'string' is not assignable to type '\"a\" | \"b\"'.",
+		},
+	}
+
+	for _, tt := range tests {
+		p := parser.New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		if len(p.Errors()) > 0 {
+			t.Fatalf("%q: parser errors: %v", tt.input, p.Errors())
+		}
+
+		errors := New().Check(program)
+		if strings.Join(errors, "\n") != tt.expected {
+			t.Errorf("%q:\nexpected %q\ngot      %q", tt.input, tt.expected, errors)
+		}
+	}
+}
@@ -0,0 +1,368 @@
+package typecheck
+
+import (
+	"slices"
+
+	"github.com/dmarro89/ts-go-compiler/ast"
+)
+
+// SymbolFlags tell what declares a symbol and what it means
+type SymbolFlags int
+
+const (
+	// FunctionScopedVariable is declared by var, or is a parameter; a var
+	// belongs to the scope of the function it is in
+	FunctionScopedVariable SymbolFlags = 1 << iota
+	// BlockScopedVariable is declared by let or const, and cannot be used
+	// before its declaration
+	BlockScopedVariable
+	// Const is set along BlockScopedVariable for const declarations
+	Const
+	// ParameterVariable is set along FunctionScopedVariable for the
+	// parameters of functions and catch clauses
+	ParameterVariable
+	// Function is declared by a function declaration
+	Function
+	// TypeAlias is declared by a @typedef tag
+	TypeAlias
+
+	// Value is the meaning of the symbols that name values
+	Value = FunctionScopedVariable | BlockScopedVariable | Function
+)
+
+// Symbol is a declared name. The declarations of a symbol, and every
+// identifier that refers to it, are bound to it by the checker.
+type Symbol struct {
+	Name  string
+	Flags SymbolFlags
+
+	// Declarations are the names that declare the symbol, in the order they
+	// were checked; a @typedef or a library declaration has none
+	Declarations []*ast.Identifier
+
+	// Type is the type of the value, or the type a type alias names. It is
+	// nil until the declaration of a let or const has been checked.
+	Type Type
+	Doc  *ast.JSDoc // the documentation of the declaration, if any
+
+	scope *TypeEnvironment
+
+	// body is the declaration of a function until its body is checked,
+	// which infers its return type
+	body *ast.FunctionLiteral
+
+	// redeclared is set once the symbol has been reported as declared
+	// more than once
+	redeclared bool
+
+	// definite is set for a variable declared with a definite assignment
+	// assertion, x!: T, which is taken to be assigned wherever it is used
+	definite bool
+}
+
+// ScopeKind tells what makes a scope
+type ScopeKind int
+
+const (
+	// GlobalScope holds the declarations of the library
+	GlobalScope ScopeKind = iota
+	// FileScope holds the top-level declarations of the program
+	FileScope
+	// FunctionScope holds the parameters of a function and the
+	// declarations of its body
+	FunctionScope
+	// BlockScope holds the let, const and function declarations of a block,
+	// a switch or a loop
+	BlockScope
+	// CatchScope holds the variable of a catch clause and the declarations
+	// of its body
+	CatchScope
+)
+
+// TypeEnvironment is a scope: it maps the names declared in it to their
+// symbols, and the variables narrowed in it to their narrowed types. The
+// names of values and of types are separate.
+type TypeEnvironment struct {
+	Kind ScopeKind
+
+	values   map[string]*Symbol
+	types    map[string]*Symbol
+	narrowed map[*Symbol]Type
+	outer    *TypeEnvironment
+
+	// fn is the function whose body the scope is in, nil at the top level
+	fn *functionContext
+}
+
+// NewTypeEnvironment creates a new global scope
+func NewTypeEnvironment() *TypeEnvironment {
+	return &TypeEnvironment{
+		Kind:     GlobalScope,
+		values:   make(map[string]*Symbol),
+		types:    make(map[string]*Symbol),
+		narrowed: make(map[*Symbol]Type),
+	}
+}
+
+// NewEnclosedTypeEnvironment creates a scope of the given kind nested in
+// outer, whose declarations shadow those of outer
+func NewEnclosedTypeEnvironment(outer *TypeEnvironment, kind ScopeKind) *TypeEnvironment {
+	env := NewTypeEnvironment()
+	env.Kind = kind
+	env.outer = outer
+	return env
+}
+
+// Outer returns the scope env is nested in, nil for the global scope
+func (env *TypeEnvironment) Outer() *TypeEnvironment {
+	return env.outer
+}
+
+// Lookup returns the symbol of the value named name in the scope or the
+// scopes it is nested in, or nil when there is none
+func (env *TypeEnvironment) Lookup(name string) *Symbol {
+	for e := env; e != nil; e = e.outer {
+		if sym, ok := e.values[name]; ok {
+			return sym
+		}
+	}
+	return nil
+}
+
+// LookupType returns the symbol of the type named name, or nil when there
+// is none
+func (env *TypeEnvironment) LookupType(name string) *Symbol {
+	for e := env; e != nil; e = e.outer {
+		if sym, ok := e.types[name]; ok {
+			return sym
+		}
+	}
+	return nil
+}
+
+// Get returns the type of the value named name, as narrowed in the scope.
+// It reports false for a let or const whose declaration has not been
+// checked yet.
+func (env *TypeEnvironment) Get(name string) (Type, bool) {
+	sym := env.Lookup(name)
+	if sym == nil || sym.Type == nil {
+		return nil, false
+	}
+	return env.typeOf(sym), true
+}
+
+// Set declares a value of type t in the scope, as the library declares its
+// globals
+func (env *TypeEnvironment) Set(name string, t Type) Type {
+	env.values[name] = &Symbol{Name: name, Flags: FunctionScopedVariable, Type: t, scope: env}
+	return t
+}
+
+// GetType retrieves a type alias from the environment
+func (env *TypeEnvironment) GetType(name string) (Type, bool) {
+	if sym := env.LookupType(name); sym != nil {
+		return sym.Type, true
+	}
+	return nil, false
+}
+
+// SetType adds a type alias to the environment
+func (env *TypeEnvironment) SetType(name string, t Type) Type {
+	env.types[name] = &Symbol{Name: name, Flags: TypeAlias, Type: t, scope: env}
+	return t
+}
+
+// Narrow gives the variable named name the type t in the scope and the
+// scopes nested in it, as a check of its value does
+func (env *TypeEnvironment) Narrow(name string, t Type) {
+	if sym := env.Lookup(name); sym != nil {
+		env.narrowed[sym] = t
+	}
+}
+
+// typeOf returns the type of sym in the scope: the type it was narrowed to
+// by the scope or a scope between it and the declaration, or its declared
+// type
+func (env *TypeEnvironment) typeOf(sym *Symbol) Type {
+	for e := env; e != nil && e != sym.scope.outer; e = e.outer {
+		if t, ok := e.narrowed[sym]; ok {
+			return t
+		}
+	}
+	return sym.Type
+}
+
+// functionScope returns the scope var declarations in env belong to
+func (env *TypeEnvironment) functionScope() *TypeEnvironment {
+	for env.Kind != FunctionScope && env.Kind != FileScope && env.outer != nil {
+		env = env.outer
+	}
+	return env
+}
+
+// inScope runs check with a new scope of the given kind nested in the
+// current one
+func (tc *TypeChecker) inScope(kind ScopeKind, check func()) {
+	outer := tc.env
+	tc.env = NewEnclosedTypeEnvironment(outer, kind)
+	tc.env.fn = tc.fn
+	defer func() { tc.env = outer }()
+
+	check()
+}
+
+// SymbolOf returns the symbol ident declares or refers to, or nil when it
+// names no declaration or has not been checked
+func (tc *TypeChecker) SymbolOf(ident *ast.Identifier) *Symbol {
+	return tc.symbols[ident]
+}
+
+// declarationFlags returns the flags of the names a let, const or var
+// declares; kind is its keyword. It is 0 for "", a loop that assigns
+// existing variables.
+func declarationFlags(kind string) SymbolFlags {
+	switch kind {
+	case "var":
+		return FunctionScopedVariable
+	case "let":
+		return BlockScopedVariable
+	case "const":
+		return BlockScopedVariable | Const
+	}
+	return 0
+}
+
+// declare declares the name ident with the given flags and type. A var is
+// declared in the scope of its function, anything else in the current
+// scope. A let or const hoisted to the start of its block is given its type.
+func (tc *TypeChecker) declare(ident *ast.Identifier, flags SymbolFlags, t Type) *Symbol {
+	scope := tc.env
+	if flags&(FunctionScopedVariable|ParameterVariable) == FunctionScopedVariable {
+		scope = scope.functionScope()
+	}
+
+	sym := tc.declareIn(scope, ident, flags)
+	sym.Type = t
+	return sym
+}
+
+// declareIn adds the declaration ident to the symbol of its name in scope,
+// creating it, and reports the declarations that conflict
+func (tc *TypeChecker) declareIn(scope *TypeEnvironment, ident *ast.Identifier, flags SymbolFlags) *Symbol {
+	sym, ok := scope.values[ident.Value]
+	switch {
+	case !ok:
+		sym = &Symbol{Name: ident.Value, Flags: flags, scope: scope}
+		scope.values[ident.Value] = sym
+	case slices.Contains(sym.Declarations, ident):
+		return sym
+	default:
+		tc.checkRedeclaration(sym, ident, flags)
+		sym.Flags |= flags
+	}
+	sym.Declarations = append(sym.Declarations, ident)
+	tc.symbols[ident] = sym
+	return sym
+}
+
+// checkRedeclaration reports declaring sym again by ident, at both
+// declarations. Only vars and parameters may be declared more than once;
+// a function may not share its name with a variable.
+func (tc *TypeChecker) checkRedeclaration(sym *Symbol, ident *ast.Identifier, flags SymbolFlags) {
+	both := sym.Flags | flags
+	var m message
+	args := []any{sym.Name}
+	switch {
+	case both&BlockScopedVariable != 0:
+		m = errRedeclareBlockScoped
+	case sym.Flags&flags&Function != 0:
+		m, args = errDuplicateFunction, nil
+	case both&Function != 0, sym.Flags&flags&ParameterVariable != 0:
+		m = errDuplicateIdentifier
+	default:
+		return
+	}
+
+	if !sym.redeclared && len(sym.Declarations) > 0 {
+		sym.redeclared = true
+		tc.errorAt(sym.Declarations[0].Token, m, args...)
+	}
+	tc.errorAt(ident.Token, m, args...)
+}
+
+// hoistDeclarations declares the let, const and function declarations of
+// a block before its statements are checked. Functions can be called
+// before their declaration; a let or const used before its declaration is
+// an error.
+func (tc *TypeChecker) hoistDeclarations(statements []ast.Statement) {
+	outer := tc.at
+	defer func() { tc.at = outer }()
+
+	for _, stmt := range statements {
+		// Errors in the JSDoc types of a signature are reported at the
+		// declaration
+		tc.at = statementToken(stmt)
+
+		switch s := stmt.(type) {
+		case *ast.LetStatement:
+			flags := declarationFlags(s.Token.Literal)
+			if flags&BlockScopedVariable == 0 {
+				continue
+			}
+			for _, name := range boundNames(s.Target()) {
+				tc.declareIn(tc.env, name, flags)
+			}
+		case *ast.ExpressionStatement:
+			fn, ok := s.Expression.(*ast.FunctionLiteral)
+			if !ok || fn.Name == nil {
+				continue
+			}
+			sym := tc.declare(fn.Name, Function, tc.functionType(fn))
+			if !tc.bodies[fn] {
+				sym.body = fn
+			}
+			if fn.JSDoc != nil {
+				sym.Doc = fn.JSDoc
+			}
+		}
+	}
+}
+
+// boundNames returns the names a declaration of target declares
+func boundNames(target ast.Expression) []*ast.Identifier {
+	switch p := target.(type) {
+	case *ast.Identifier:
+		return []*ast.Identifier{p}
+	case *ast.ObjectPattern:
+		var names []*ast.Identifier
+		for _, el := range p.Properties {
+			names = append(names, boundNames(el.Name)...)
+		}
+		return names
+	case *ast.ArrayPattern:
+		var names []*ast.Identifier
+		for _, el := range p.Elements {
+			if el != nil {
+				names = append(names, boundNames(el.Name)...)
+			}
+		}
+		return names
+	}
+	return nil
+}
+
+// resolve returns the symbol ident refers to, binding it, or nil when the
+// name is not declared. A let or const used before its declaration in the
+// same function is reported; a function may use it before, as long as it
+// is called after.
+func (tc *TypeChecker) resolve(ident *ast.Identifier) *Symbol {
+	sym := tc.env.Lookup(ident.Value)
+	if sym == nil {
+		return nil
+	}
+	tc.symbols[ident] = sym
+	if sym.Type == nil && sym.scope.fn == tc.fn {
+		tc.errorAt(ident.Token, errUsedBeforeDeclaration, ident.Value)
+	}
+	return sym
+}
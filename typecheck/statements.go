@@ -0,0 +1,221 @@
+package typecheck
+
+import (
+	"github.com/dmarro89/ts-go-compiler/ast"
+	"github.com/dmarro89/ts-go-compiler/token"
+)
+
+// checkIfStatement checks an if statement. The flow graph narrows the
+// variables the condition tests in each branch, and after the statement
+// when one branch cannot complete.
+func (tc *TypeChecker) checkIfStatement(s *ast.IfStatement) Type {
+	tc.checkExpression(s.Condition)
+	tc.checkStatement(s.Consequence)
+	if s.Alternative != nil {
+		tc.checkStatement(s.Alternative)
+	}
+	return &BasicType{Name: "void"}
+}
+
+// checkSwitchStatement checks a switch statement, whose cases must be
+// comparable to the discriminant. When the discriminant is a variable, the
+// flow graph narrows it in each clause to the cases that reach the clause,
+// and in the default clause to what no case matches; after every member of
+// a union is handled that is never.
+func (tc *TypeChecker) checkSwitchStatement(s *ast.SwitchStatement) Type {
+	t := tc.checkExpression(s.Discriminant)
+
+	var cases []Type
+	hasDefault := false
+	for _, c := range s.Cases {
+		if c.Test == nil {
+			hasDefault = true
+			continue
+		}
+		test := tc.checkExpression(c.Test)
+		if lit := literalType(c.Test); lit != nil {
+			test = lit
+			cases = append(cases, lit)
+		}
+		if !isComparableTo(test, t) {
+			tc.errorAt(startToken(c.Test), errNotComparable, test, t)
+		}
+	}
+	if !hasDefault && isBasic(removeCases(t, cases), "never") {
+		tc.exhaustive[s] = true
+	}
+
+	// The clauses share a scope, where the declarations of all of them are
+	// hoisted
+	tc.inScope(BlockScope, func() {
+		for _, c := range s.Cases {
+			tc.hoistDeclarations(c.Consequent)
+		}
+		for _, c := range s.Cases {
+			for _, stmt := range c.Consequent {
+				tc.checkStatement(stmt)
+			}
+		}
+	})
+
+	return &BasicType{Name: "void"}
+}
+
+// checkTryStatement checks a try statement. The catch variable is unknown
+// in strict mode, since anything can be thrown, and any otherwise.
+func (tc *TypeChecker) checkTryStatement(s *ast.TryStatement) Type {
+	tc.inScope(BlockScope, func() { tc.checkBlockStatement(s.Block) })
+
+	if h := s.Handler; h != nil {
+		tc.inScope(CatchScope, func() {
+			if h.Param != nil {
+				var t Type = &BasicType{Name: "any"}
+				if tc.options.Strict {
+					t = &BasicType{Name: "unknown"}
+				}
+				tc.bindPattern(h.Param, t, FunctionScopedVariable|ParameterVariable)
+			}
+			tc.checkBlockStatement(h.Body)
+		})
+	}
+
+	if s.Finalizer != nil {
+		tc.inScope(BlockScope, func() { tc.checkBlockStatement(s.Finalizer) })
+	}
+
+	return &BasicType{Name: "void"}
+}
+
+// checkForOfStatement checks for (x of xs), where x has the type of the
+// elements of xs. A for await loop also takes the values of async iterables
+// and awaits each element.
+func (tc *TypeChecker) checkForOfStatement(s *ast.ForOfStatement) Type {
+	right := tc.checkExpression(s.Right)
+
+	var element Type
+	if s.Await {
+		element = tc.asyncIteratedType(startToken(s.Right), right)
+	} else {
+		element = tc.iteratedType(startToken(s.Right), right)
+	}
+
+	tc.inScope(BlockScope, func() {
+		tc.bindPattern(s.Target, element, declarationFlags(s.Kind))
+		tc.checkStatement(s.Body)
+	})
+
+	return &BasicType{Name: "void"}
+}
+
+// asyncIteratedType returns the type of the values for await takes from t,
+// reporting at tok types that cannot be iterated
+func (tc *TypeChecker) asyncIteratedType(tok token.Token, t Type) Type {
+	if g, ok := t.(*GenericType); ok && (g.Name == "AsyncIterable" || g.Name == "AsyncIterableIterator" || g.Name == "AsyncGenerator") {
+		return awaitedType(g.TypeArguments[0])
+	}
+	if element, ok := iterableElement(t); ok {
+		return awaitedType(element)
+	}
+
+	tc.errorAt(tok, errNotAsyncIterable, t)
+	return &BasicType{Name: "any"}
+}
+
+// checkForInStatement checks for (k in o), where k is a string
+func (tc *TypeChecker) checkForInStatement(s *ast.ForInStatement) Type {
+	right := tc.checkExpression(s.Right)
+	if isPrimitive(right) && !isBasic(right, "null") && !isBasic(right, "undefined") {
+		tc.errorAt(startToken(s.Right), errForInRight, right)
+	}
+
+	tc.inScope(BlockScope, func() {
+		key := &BasicType{Name: "string"}
+		switch {
+		case ast.IsPattern(s.Target):
+			tc.errorAt(startToken(s.Target), errForInPattern)
+		case s.Kind != "":
+			tc.bindPattern(s.Target, key, declarationFlags(s.Kind))
+		case !tc.isAssignable(key, tc.checkExpression(s.Target)):
+			tc.errorAt(startToken(s.Target), errForInLeft)
+		}
+		tc.checkStatement(s.Body)
+	})
+
+	return &BasicType{Name: "void"}
+}
+
+// reportUnknown reports the use of a property of a value of type unknown,
+// which must be narrowed first
+func (tc *TypeChecker) reportUnknown(object ast.Expression) {
+	if ident, ok := ast.SkipParentheses(object).(*ast.Identifier); ok {
+		tc.errorAt(ident.Token, errUnknownName, ident.Value)
+		return
+	}
+	tc.errorAt(startToken(object), errUnknownObject)
+}
+
+// narrowToCase returns the members of t that equal the case value of type
+// test. A literal case narrows a wider member such as string to itself.
+func narrowToCase(t Type, test Type) Type {
+	if _, ok := test.(*LiteralType); !ok {
+		return t
+	}
+
+	var kept []Type
+	for _, member := range unionMembers(t) {
+		switch {
+		case member.String() == test.String():
+			kept = append(kept, member)
+		case isAssignableTo(test, member):
+			kept = append(kept, test)
+		}
+	}
+	if len(kept) == 0 {
+		return &BasicType{Name: "never"}
+	}
+	return newUnionType(kept...)
+}
+
+// removeCases returns the members of t no case matches
+func removeCases(t Type, cases []Type) Type {
+	var kept []Type
+	for _, member := range unionMembers(t) {
+		matched := false
+		if _, ok := member.(*LiteralType); ok {
+			for _, c := range cases {
+				if c.String() == member.String() {
+					matched = true
+				}
+			}
+		}
+		if !matched {
+			kept = append(kept, member)
+		}
+	}
+	if len(kept) == 0 {
+		return &BasicType{Name: "never"}
+	}
+	return newUnionType(kept...)
+}
+
+// unionMembers returns the members of a union, or t itself
+func unionMembers(t Type) []Type {
+	if u, ok := t.(*UnionType); ok {
+		return u.Types
+	}
+	return []Type{t}
+}
+
+// literalType returns the literal type of a string, number or boolean
+// literal, or nil for other expressions
+func literalType(expr ast.Expression) Type {
+	switch e := ast.SkipParentheses(expr).(type) {
+	case *ast.StringLiteral:
+		return &LiteralType{Value: e.String(), Base: &BasicType{Name: "string"}}
+	case *ast.IntegerLiteral:
+		return &LiteralType{Value: e.String(), Base: &BasicType{Name: "number"}}
+	case *ast.Boolean:
+		return &LiteralType{Value: e.String(), Base: &BasicType{Name: "boolean"}}
+	}
+	return nil
+}
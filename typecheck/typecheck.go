@@ -4,6 +4,8 @@ import (
 	"fmt"
 
 	"github.com/dmarro89/ts-go-compiler/ast"
+	"github.com/dmarro89/ts-go-compiler/diagnostics"
+	"github.com/dmarro89/ts-go-compiler/token"
 )
 
 // TypeScript type
@@ -20,41 +22,142 @@ func (t *BasicType) String() string {
 	return t.Name
 }
 
+// Options controls how the program is checked
+type Options struct {
+	// JavaScript checks the program as a .js file, where types come from
+	// JSDoc @type, @param and @returns tags
+	JavaScript bool
+
+	// Strict enables the stricter checks of TypeScript's strict mode, such
+	// as typing catch clause variables as unknown instead of any
+	Strict bool
+
+	// NoImplicitThis reports this in functions that do not declare its type,
+	// where it would be any. Strict enables it.
+	NoImplicitThis bool
+
+	// NoImplicitReturns reports the code paths of a function that end
+	// without returning a value when others return one
+	NoImplicitReturns bool
+
+	// StrictFunctionTypes compares the parameters of function types
+	// contravariantly, so a function may only be used where its parameters
+	// accept every argument. Strict enables it.
+	StrictFunctionTypes bool
+
+	// StrictNullChecks keeps null and undefined out of the other types:
+	// they may only be assigned where a type includes them, and a value
+	// that may be null or undefined must be narrowed before its properties
+	// are used or it is called. Without it they may be assigned anywhere.
+	// Strict enables it.
+	StrictNullChecks bool
+}
+
 // TypeChecker performs type checking on the AST
 type TypeChecker struct {
-	errors []string
-	env    *TypeEnvironment
-}
+	diagnostics []diagnostics.Diagnostic
+	env         *TypeEnvironment
+	options     Options
+
+	// fn is the function whose body is being checked, nil at the top level
+	fn *functionContext
+
+	// at is the start of the statement being checked, or its declared
+	// name, where errors about the statement as a whole are reported
+	at token.Token
 
-// TypeEnvironment stores variable types
-type TypeEnvironment struct {
-	store map[string]Type
-	outer *TypeEnvironment
+	// inJSDoc is set while resolving a type written in a JSDoc comment,
+	// whose tokens are positioned in the comment, so its errors are
+	// reported at the statement instead
+	inJSDoc bool
+
+	// undeclared holds the names used without a declaration, which are
+	// reported at their first use only
+	undeclared map[string]bool
+
+	// symbols binds the identifiers checked to the symbols they declare or
+	// refer to
+	symbols map[*ast.Identifier]*Symbol
+
+	// functions holds the types of the functions met, whose return types
+	// are inferred once their bodies are checked; bodies holds the
+	// functions whose bodies have been checked
+	functions map[*ast.FunctionLiteral]*FunctionType
+	bodies    map[*ast.FunctionLiteral]bool
+
+	// flow is the control flow graph of the program. assigned holds the
+	// types of the values assigned to variables by assignments and
+	// declarations, which they are narrowed to, and asserted the uses of
+	// variables asserted not to be null.
+	flow     *FlowGraph
+	assigned map[ast.Node]Type
+	asserted map[*ast.Identifier]bool
+
+	// exhaustive holds the switch statements without a default clause
+	// whose cases match every value of the discriminant, which cannot be
+	// left by matching no case
+	exhaustive map[*ast.SwitchStatement]bool
 }
 
 // New creates a new TypeChecker
 func New() *TypeChecker {
-	return &TypeChecker{
-		errors: []string{},
-		env:    NewTypeEnvironment(),
-	}
+	return NewWithOptions(Options{})
 }
 
-// NewTypeEnvironment creates a new type environment
-func NewTypeEnvironment() *TypeEnvironment {
-	return &TypeEnvironment{
-		store: make(map[string]Type),
+// NewWithOptions creates a new TypeChecker with the given options
+func NewWithOptions(options Options) *TypeChecker {
+	globals := NewTypeEnvironment()
+	globals.Set("console.log", &FunctionType{
+		Parameters: []*Parameter{{Name: "data", Type: &ArrayType{ElementType: &BasicType{Name: "any"}}, Rest: true}},
+		ReturnType: &BasicType{Name: "void"},
+	})
+	globals.Set("undefined", &BasicType{Name: "undefined"})
+
+	return &TypeChecker{
+		undeclared: map[string]bool{},
+		symbols:    map[*ast.Identifier]*Symbol{},
+		functions:  map[*ast.FunctionLiteral]*FunctionType{},
+		bodies:     map[*ast.FunctionLiteral]bool{},
+		assigned:   map[ast.Node]Type{},
+		asserted:   map[*ast.Identifier]bool{},
+		exhaustive: map[*ast.SwitchStatement]bool{},
+		env:        NewEnclosedTypeEnvironment(globals, FileScope),
+		options:    options,
 	}
 }
 
 func (tc *TypeChecker) Check(program *ast.Program) []string {
+	tc.flow = BuildFlowGraph(program)
+	tc.declareTypedefs(program.Statements)
+	tc.hoistDeclarations(program.Statements)
+
 	for _, stmt := range program.Statements {
 		tc.checkStatement(stmt)
 	}
-	return tc.errors
+
+	for _, stmt := range tc.flow.Unreachable() {
+		tok := statementToken(stmt)
+		if let, ok := stmt.(*ast.LetStatement); ok {
+			tok = let.Token
+		}
+		tc.addWarning(tok, warnUnreachable)
+	}
+	for _, s := range tc.flow.UnusedLabels() {
+		tc.addWarning(s.Label.Token, warnUnusedLabel)
+	}
+	return tc.Errors()
+}
+
+// FlowGraph returns the control flow graph of the program last checked
+func (tc *TypeChecker) FlowGraph() *FlowGraph {
+	return tc.flow
 }
 
 func (tc *TypeChecker) checkStatement(stmt ast.Statement) Type {
+	outer := tc.at
+	tc.at = statementToken(stmt)
+	defer func() { tc.at = outer }()
+
 	switch s := stmt.(type) {
 	case *ast.LetStatement:
 		return tc.checkLetStatement(s)
@@ -63,13 +166,34 @@ func (tc *TypeChecker) checkStatement(stmt ast.Statement) Type {
 	case *ast.ExpressionStatement:
 		return tc.checkExpression(s.Expression)
 	case *ast.BlockStatement:
-		return tc.checkBlockStatement(s)
+		var t Type
+		tc.inScope(BlockScope, func() { t = tc.checkBlockStatement(s) })
+		return t
+	case *ast.IfStatement:
+		return tc.checkIfStatement(s)
+	case *ast.SwitchStatement:
+		return tc.checkSwitchStatement(s)
+	case *ast.ThrowStatement:
+		tc.checkExpression(s.Argument)
+		return &BasicType{Name: "void"}
+	case *ast.TryStatement:
+		return tc.checkTryStatement(s)
+	case *ast.ForOfStatement:
+		return tc.checkForOfStatement(s)
+	case *ast.ForInStatement:
+		return tc.checkForInStatement(s)
+	case *ast.LabeledStatement:
+		return tc.checkStatement(s.Body)
 	default:
 		return &BasicType{Name: "void"}
 	}
 }
 
+// checkBlockStatement checks the statements of a block in the current
+// scope, where its declarations are hoisted
 func (tc *TypeChecker) checkBlockStatement(block *ast.BlockStatement) Type {
+	tc.hoistDeclarations(block.Statements)
+
 	var lastType Type
 	for _, stmt := range block.Statements {
 		lastType = tc.checkStatement(stmt)
@@ -78,22 +202,80 @@ func (tc *TypeChecker) checkBlockStatement(block *ast.BlockStatement) Type {
 }
 
 func (tc *TypeChecker) checkLetStatement(stmt *ast.LetStatement) Type {
+	flags := declarationFlags(stmt.Token.Literal)
+	declared := tc.jsdocType(stmt.JSDoc)
+	if stmt.Type != nil {
+		declared = tc.resolveTypeNode(stmt.Type)
+	}
+	defer func() {
+		if stmt.JSDoc != nil && stmt.Name != nil {
+			tc.symbols[stmt.Name].Doc = stmt.JSDoc
+		}
+		if stmt.Definite {
+			tc.symbols[stmt.Name].definite = true
+		}
+	}()
+
 	if stmt.Value == nil {
 		// Default to any type if no value is assigned
-		tc.env.Set(stmt.Name.Value, &BasicType{Name: "any"})
-		return &BasicType{Name: "any"}
+		if declared == nil {
+			declared = &BasicType{Name: "any"}
+		}
+		tc.bindPattern(stmt.Target(), declared, flags)
+		return declared
 	}
 
-	valueType := tc.checkExpression(stmt.Value)
-	tc.env.Set(stmt.Name.Value, valueType)
-	return valueType
+	valueType := tc.checkValue(stmt.Value, declared)
+	if declared == nil {
+		if !tc.strictNullChecks() && flags&Const == 0 && isNullishLiteral(stmt.Value) {
+			// Without strict null checks a variable set to null or
+			// undefined may be set to anything later
+			valueType = &BasicType{Name: "any"}
+		}
+		if flags&Const == 0 {
+			valueType = widenLiteral(valueType)
+		} else if lit, ok := literalType(stmt.Value).(*LiteralType); ok && stmt.Name != nil {
+			// A const keeps the literal type of its value, which cannot
+			// change
+			lit.Widening = true
+			valueType = lit
+		}
+		tc.bindPattern(stmt.Target(), valueType, flags)
+		return valueType
+	}
+
+	tc.checkAssignableValue(tc.at, stmt.Value, valueType, declared)
+	if stmt.Name != nil {
+		tc.assigned[stmt] = valueType
+	}
+	tc.bindPattern(stmt.Target(), declared, flags)
+	return declared
 }
 
+// checkReturnStatement checks a returned value against the return type of
+// the function, or collects its type when the return type is inferred
 func (tc *TypeChecker) checkReturnStatement(stmt *ast.ReturnStatement) Type {
 	if stmt.ReturnValue == nil {
+		if tc.fn != nil {
+			tc.fn.bareReturns = append(tc.fn.bareReturns, stmt.Token)
+		}
 		return &BasicType{Name: "void"}
 	}
-	return tc.checkExpression(stmt.ReturnValue)
+	t := tc.checkExpression(stmt.ReturnValue)
+	if tc.fn == nil {
+		return t
+	}
+
+	tc.fn.hasReturnValue = true
+	switch {
+	case tc.fn.inferred && tc.fn.async:
+		tc.fn.returns = append(tc.fn.returns, widenLiteral(awaitedType(t)))
+	case tc.fn.inferred:
+		tc.fn.returns = append(tc.fn.returns, widenLiteral(t))
+	default:
+		tc.checkReturnValue(stmt.ReturnValue, t)
+	}
+	return t
 }
 
 func (tc *TypeChecker) checkExpression(expr ast.Expression) Type {
@@ -102,39 +284,256 @@ func (tc *TypeChecker) checkExpression(expr ast.Expression) Type {
 		return &BasicType{Name: "number"}
 	case *ast.StringLiteral:
 		return &BasicType{Name: "string"}
+	case *ast.RegExpLiteral:
+		return &BasicType{Name: "RegExp"}
 	case *ast.Identifier:
 		return tc.checkIdentifier(e)
 	case *ast.FunctionLiteral:
-		return &BasicType{Name: "function"}
+		return tc.checkFunction(e)
+	case *ast.ThisExpression:
+		return tc.checkThisExpression(e)
+	case *ast.SuperExpression:
+		return tc.checkSuperProperty(e)
+	case *ast.NewExpression:
+		return tc.checkNewExpression(e)
+	case *ast.MetaProperty:
+		return &BasicType{Name: "any"}
+	case *ast.AwaitExpression:
+		return awaitedType(tc.checkExpression(e.Argument))
+	case *ast.YieldExpression:
+		return tc.checkYieldExpression(e)
+	case *ast.CallExpression:
+		return tc.checkCallExpression(e)
+	case *ast.MethodCallExpression:
+		return tc.checkMethodCallExpression(e)
+	case *ast.IndexExpression:
+		return tc.checkIndexExpression(e)
+	case *ast.Boolean:
+		return &BasicType{Name: "boolean"}
+	case *ast.NullLiteral:
+		return &BasicType{Name: "null"}
+	case *ast.ParenthesizedExpression:
+		return tc.checkExpression(e.Expression)
+	case *ast.PrefixExpression:
+		return tc.checkPrefixExpression(e)
+	case *ast.PostfixExpression:
+		return tc.checkIncrement(e.Left)
+	case *ast.NonNullExpression:
+		if ident, ok := ast.SkipParentheses(e.Expression).(*ast.Identifier); ok {
+			tc.asserted[ident] = true
+		}
+		return removeNullish(tc.checkExpression(e.Expression))
+	case *ast.InfixExpression:
+		return tc.checkInfixExpression(e)
+	case *ast.AssignmentExpression:
+		return tc.checkAssignmentExpression(e)
+	case *ast.ArrayLiteral:
+		return tc.checkArrayLiteral(e, nil)
+	case *ast.ObjectLiteral:
+		return tc.checkObjectLiteral(e, nil)
+	case *ast.ConditionalExpression:
+		tc.checkExpression(e.Condition)
+		return newUnionType(tc.checkExpression(e.Consequence), tc.checkExpression(e.Alternative))
 	default:
-		tc.addError(fmt.Sprintf("unknown expression type: %T", expr))
-		return &BasicType{Name: "unknown"}
+		tc.errorAt(startToken(expr), errUnknownExpression, expr)
+		return errorType
 	}
 }
 
-func (tc *TypeChecker) checkIdentifier(ident *ast.Identifier) Type {
-	if val, ok := tc.env.Get(ident.Value); ok {
-		return val
+// functionType builds the type of a function from the annotations of its
+// parameters and return type. Without annotations every parameter is any,
+// and the return value is any until the body is checked; async functions
+// return a promise of the value.
+func (tc *TypeChecker) functionType(fn *ast.FunctionLiteral) *FunctionType {
+	if ft, ok := tc.functions[fn]; ok {
+		return ft
+	}
+	ft := &FunctionType{ReturnType: &BasicType{Name: "any"}}
+	tc.functions[fn] = ft
+
+	// The @template type parameters of a function are in scope in its
+	// signature
+	tc.inScope(FunctionScope, func() {
+		tc.declareTemplates(fn.JSDoc)
+		tc.resolveSignature(fn, ft)
+	})
+	if fn.Async && !fn.Generator {
+		ft.ReturnType = promiseType(ft.ReturnType)
+	}
+	ft.Constructor = tc.isConstructor(fn.JSDoc)
+
+	return ft
+}
+
+// resolveSignature sets the parameter and return types of ft from the
+// annotations of fn
+func (tc *TypeChecker) resolveSignature(fn *ast.FunctionLiteral, ft *FunctionType) {
+	for i, param := range fn.Parameters {
+		// A destructured parameter has no name; TypeScript shows it as __0
+		name := fmt.Sprintf("__%d", i)
+		if ident, ok := param.Name.(*ast.Identifier); ok {
+			name = ident.Value
+		}
+
+		p := &Parameter{Name: name, Type: &BasicType{Name: "any"}, Optional: param.Default != nil, Rest: param.Rest}
+		if param.Type != nil {
+			p.Type = tc.resolveTypeNode(param.Type)
+		} else if t, optional := tc.jsdocParamType(fn.JSDoc, name); t != nil {
+			p.Type, p.Optional = t, optional || p.Optional
+		} else if tc.options.JavaScript && !param.Rest {
+			// Untyped parameters of JavaScript functions may be left out
+			p.Optional = true
+		}
+		if p.Rest {
+			p.Type = restParameterType(p.Type)
+		}
+		ft.Parameters = append(ft.Parameters, p)
+	}
+
+	if fn.ReturnType != nil {
+		ft.ReturnType = tc.resolveTypeNode(fn.ReturnType)
+	} else if t := tc.jsdocReturnType(fn.JSDoc); t != nil {
+		ft.ReturnType = t
+	} else if fn.Generator {
+		ft.ReturnType = generatorType(fn.Async)
+	}
+}
+
+func (tc *TypeChecker) checkCallExpression(call *ast.CallExpression) Type {
+	if _, ok := call.Function.(*ast.SuperExpression); ok {
+		tc.checkArguments(call.Arguments)
+		tc.errorAt(startToken(call.Function), errSuperCall)
+		return &BasicType{Name: "void"}
+	}
+
+	calleeType := tc.checkExpression(call.Function)
+	args := tc.checkArguments(call.Arguments)
+
+	calleeType, nullable := chainOperand(calleeType, call.Optional, ast.IsOptionalChain(call.Function))
+	calleeType = tc.nonNullCallee(call.Function, calleeType)
+
+	var result Type = &BasicType{Name: "any"}
+	if fn, ok := calleeType.(*FunctionType); ok {
+		if fn.Constructor {
+			tc.errorAt(startToken(call.Function), errNotCallable, call.Function)
+		}
+		tc.checkCall(startToken(call.Function), fn, args)
+		result = fn.ReturnType
+	} else {
+		tc.checkCallable(call.Function, calleeType)
 	}
-	tc.addError(fmt.Sprintf("undefined variable: %s", ident.Value))
-	return &BasicType{Name: "unknown"}
+	return chainResult(result, nullable)
 }
 
-func (tc *TypeChecker) addError(msg string) {
-	tc.errors = append(tc.errors, msg)
+// checkMethodCallExpression checks object.property and object.method(...)
+func (tc *TypeChecker) checkMethodCallExpression(call *ast.MethodCallExpression) Type {
+	objectType := tc.checkExpression(call.Object)
+	args := tc.checkArguments(call.Arguments)
+
+	objectType, nullable := chainOperand(objectType, call.Optional, ast.IsOptionalChain(call.Object))
+	if isBasic(objectType, "unknown") {
+		tc.reportUnknown(call.Object)
+	}
+	objectType = tc.nonNullObject(call.Object, objectType)
+
+	result := propertyType(objectType, call.Method.Value)
+	if call.Arguments != nil {
+		result = tc.nonNullCallee(call.Method, result)
+		if fn, ok := result.(*FunctionType); ok {
+			tc.checkCall(call.Method.Token, fn, args)
+			result = fn.ReturnType
+		} else {
+			tc.checkCallable(call.Method, result)
+			result = &BasicType{Name: "any"}
+		}
+	}
+	return chainResult(result, nullable)
+}
+
+// checkIndexExpression checks element access, e.g. a[0]
+func (tc *TypeChecker) checkIndexExpression(expr *ast.IndexExpression) Type {
+	leftType := tc.checkExpression(expr.Left)
+	tc.checkExpression(expr.Index)
+
+	leftType, nullable := chainOperand(leftType, expr.Optional, ast.IsOptionalChain(expr.Left))
+	leftType = tc.nonNullObject(expr.Left, leftType)
+
+	var result Type = &BasicType{Name: "any"}
+	switch {
+	case isStringLike(leftType):
+		result = &BasicType{Name: "string"}
+	default:
+		switch a := leftType.(type) {
+		case *ArrayType:
+			result = a.ElementType
+		case *TupleType:
+			result = tc.tupleElement(a, expr.Index)
+		}
+	}
+	return chainResult(result, nullable)
 }
 
-// Get retrieves a type from the environment
-func (env *TypeEnvironment) Get(name string) (Type, bool) {
-	obj, ok := env.store[name]
-	if !ok && env.outer != nil {
-		obj, ok = env.outer.Get(name)
+// chainOperand returns the type a link of an optional chain operates on.
+// After a ?. the operand is known not to be null or undefined; nullable
+// reports whether the chain may short-circuit to undefined instead.
+func chainOperand(t Type, optional bool, inChain bool) (operand Type, nullable bool) {
+	if !optional && !inChain {
+		return t, false
 	}
-	return obj, ok
+	return removeNullish(t), isNullable(t)
 }
 
-// Set adds a type to the environment
-func (env *TypeEnvironment) Set(name string, val Type) Type {
-	env.store[name] = val
-	return val
+// chainResult adds undefined to the type of a chain that may short-circuit
+func chainResult(t Type, nullable bool) Type {
+	if !nullable {
+		return t
+	}
+	return newUnionType(t, &BasicType{Name: "undefined"})
+}
+
+// propertyType returns the type of the named property of t, or any when
+// it is not known
+func propertyType(t Type, name string) Type {
+	switch o := t.(type) {
+	case *ObjectType:
+		if p := o.Property(name); p != nil {
+			if p.Optional {
+				return newUnionType(p.Type, &BasicType{Name: "undefined"})
+			}
+			return p.Type
+		}
+	case *ArrayType, *TupleType:
+		if name == "length" {
+			return &BasicType{Name: "number"}
+		}
+	case *LiteralType:
+		return propertyType(o.Base, name)
+	case *GenericType:
+		return genericProperty(o, name)
+	case *BasicType:
+		if o.Name == "string" && name == "length" {
+			return &BasicType{Name: "number"}
+		}
+	}
+	return &BasicType{Name: "any"}
+}
+
+func (tc *TypeChecker) checkIdentifier(ident *ast.Identifier) Type {
+	if sym := tc.resolve(ident); sym != nil {
+		if sym.Doc != nil && sym.Doc.Deprecated() != nil {
+			tc.addWarning(ident.Token, warnDeprecated, ident.Value)
+		}
+		if sym.body != nil {
+			tc.checkDeclaredFunction(sym)
+		}
+		if sym.Type == nil {
+			return errorType
+		}
+		return tc.flowType(ident, sym)
+	}
+	if !tc.undeclared[ident.Value] {
+		tc.undeclared[ident.Value] = true
+		tc.errorAt(ident.Token, errCannotFindName, ident.Value)
+	}
+	return errorType
 }
@@ -1,8 +1,10 @@
 package typecheck
 
 import (
+	"strings"
 	"testing"
 
+	"github.com/dmarro89/ts-go-compiler/ast"
 	"github.com/dmarro89/ts-go-compiler/lexer"
 	"github.com/dmarro89/ts-go-compiler/parser"
 )
@@ -62,6 +64,40 @@ func TestBasicTypeChecking(t *testing.T) {
 	}
 }
 
+func TestConstLiteralTypes(t *testing.T) {
+	input := `
+	const c = "x";
+	let d: "x" = c;
+	let e = c;
+	e = "y";
+	let o = {k: c};
+	const n = 1;
+	switch (n) {
+	case 2:
+		break;
+	}
+	`
+
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) > 0 {
+		t.Fatalf("parser errors: %v", p.Errors())
+	}
+
+	tc := New()
+	errors := tc.Check(program)
+	expected := []string{"Type '2' is not comparable to type '1'."}
+	if strings.Join(errors, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected errors %q, got %q", expected, errors)
+	}
+
+	for name, want := range map[string]string{"c": `"x"`, "d": `"x"`, "e": "string", "o": "{ k: string; }", "n": "1"} {
+		if got, _ := tc.env.Get(name); got == nil || got.String() != want {
+			t.Errorf("expected %s to be of type %s, got %v", name, want, got)
+		}
+	}
+}
+
 func TestUndefinedVariable(t *testing.T) {
 	input := `
 	let x = y;
@@ -86,7 +122,7 @@ func TestUndefinedVariable(t *testing.T) {
 		t.Fatalf("expected type errors but got none")
 	}
 
-	expectedError := "undefined variable: y"
+	expectedError := "Cannot find name 'y'."
 	for _, err := range errors {
 		if err == expectedError {
 			return // Test passed
@@ -95,3 +131,1097 @@ func TestUndefinedVariable(t *testing.T) {
 
 	t.Errorf("expected error message %q not found in errors: %v", expectedError, errors)
 }
+
+func TestRegExpLiteralType(t *testing.T) {
+	l := lexer.New(`let re = /ab+c/i;`)
+	p := parser.New(l)
+	program := p.ParseProgram()
+
+	tc := New()
+	if errors := tc.Check(program); len(errors) > 0 {
+		t.Fatalf("unexpected type errors: %v", errors)
+	}
+
+	re, ok := tc.env.Get("re")
+	if !ok {
+		t.Fatalf("variable re not found in environment")
+	}
+	if re.String() != "RegExp" {
+		t.Errorf("expected re to be of type RegExp, got %s", re.String())
+	}
+}
+
+func TestOperatorTypes(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let n = 1; let x = n + 2;`, "number"},
+		{`let n = 1; let x = "a" + n;`, "string"},
+		{`let n = 1; let x = n ** 2 % 3 << 1;`, "number"},
+		{`let n = 1; let x = n < 2;`, "boolean"},
+		{`let n = 1; let x = n === 2 && n;`, "false | number"},
+		{`let s = "a"; let x = s && 1;`, `"" | number`},
+		{`let o = {a: 1}; let x = o && 1;`, "number"},
+		{`let n = 1; let x = n || "none";`, "number | string"},
+		{`let n = 1; let x = n ?? "none";`, "number | string"},
+		{`let n = 1; let x = n ? "yes" : 0;`, "string | number"},
+		{`let n = 1; let x = typeof n;`, "string"},
+		{`let n = 1; let x = void n;`, "undefined"},
+		{`let n = 1; let x = !n;`, "boolean"},
+		{`let n = 1; let x = n++;`, "number"},
+		{`let n = 1; let x = (n, "a");`, "string"},
+		{`let s = "a"; let x = s += 1;`, "string"},
+		{`let x = true;`, "boolean"},
+		{`let b = false; let x = b && "yes";`, "false | string"},
+		{`let b = true; let x = b === false;`, "boolean"},
+		{`let b = true; let x = b < false;`, "boolean"},
+		{`let n = 1; let x = n == null;`, "boolean"},
+		{`function f(a) { return a + 1; } let x = f(1);`, "any"},
+		{`function f(a) { return "a"; } let x = f(1);`, "string"},
+	}
+
+	for _, tt := range tests {
+		p := parser.New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		if len(p.Errors()) > 0 {
+			t.Fatalf("%s: parser errors: %v", tt.input, p.Errors())
+		}
+
+		tc := New()
+		if errors := tc.Check(program); len(errors) > 0 {
+			t.Errorf("%s: unexpected type errors: %v", tt.input, errors)
+			continue
+		}
+
+		x, _ := tc.env.Get("x")
+		if x.String() != tt.expected {
+			t.Errorf("%s: expected x to be %s, got %s", tt.input, tt.expected, x)
+		}
+	}
+}
+
+func TestOperatorErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let f = function() {}; let x = f + 1;`, "Operator '+' cannot be applied to types '() => void' and 'number'."},
+		{`let s = "a"; let x = s - 1;`, "The left-hand side of an arithmetic operation must be of type 'any', 'number', 'bigint' or an enum type."},
+		{`let s = "a"; let x = 1 * s;`, "The right-hand side of an arithmetic operation must be of type 'any', 'number', 'bigint' or an enum type."},
+		{`let s = "a"; let x = s < 1;`, "Operator '<' cannot be applied to types 'string' and 'number'."},
+		{`let n = 1; let x = n in n;`, "The right-hand side of an 'in' expression must not be a primitive."},
+		{`let n = 1; let x = n instanceof n;`, "The left-hand side of an 'instanceof' expression must be of type 'any', an object type or a type parameter."},
+		{`let n = 1; n = "a";`, "Type 'string' is not assignable to type 'number'."},
+		{`let n = 1; n + 1 = 2;`, "The left-hand side of an assignment expression must be a variable or a property access."},
+		{`let s = "a"; s++;`, "An arithmetic operand must be of type 'any', 'number', 'bigint' or an enum type."},
+		{`let n = 1; ++(n + 1);`, "The operand of an increment or decrement operator must be a variable or a property access."},
+		{`let o = {a: 1}; o?.a = 2;`, "The left-hand side of an assignment expression may not be an optional property access."},
+		{`let o = {a: 1}; o?.a += 2;`, "The left-hand side of an assignment expression may not be an optional property access."},
+		{`let o = {a: 1}; o?.a++;`, "The operand of an increment or decrement operator may not be an optional property access."},
+		{`let o = {a: 1}; --o?.a;`, "The operand of an increment or decrement operator may not be an optional property access."},
+		{`let n = 1; delete n;`, "The operand of a 'delete' operator must be a property reference."},
+		{`let x = true + 1;`, "Operator '+' cannot be applied to types 'boolean' and 'number'."},
+		{`let b = true; let x = b * 2;`, "The left-hand side of an arithmetic operation must be of type 'any', 'number', 'bigint' or an enum type."},
+		{`let b = true; b = 1;`, "Type 'number' is not assignable to type 'boolean'."},
+		{`let n = 0; let r: string = n && "s";`, "Type '0 | string' is not assignable to type 'string'."},
+		{`let s = ""; let r: number = s && 1;`, "Type '\"\" | number' is not assignable to type 'number'."},
+		{`let n = 1; let x = n === "a";`, "This comparison appears to be unintentional because the types 'number' and 'string' have no overlap."},
+		{`let n = 1; n();`, "This expression is not callable. Type 'Number' has no call signatures."},
+		{`let x = "a"(1);`, "This expression is not callable. Type 'String' has no call signatures."},
+		{`let o = {a: [1]}; o.a();`, "This expression is not callable. Type 'number[]' has no call signatures."},
+		{`function f(a): string { return a; } let x = f("a", 2);`, "Expected 1 arguments, but got 2."},
+	}
+
+	for _, tt := range tests {
+		p := parser.New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		if len(p.Errors()) > 0 {
+			t.Fatalf("%s: parser errors: %v", tt.input, p.Errors())
+		}
+
+		errors := New().Check(program)
+		if len(errors) == 0 || errors[0] != tt.expected {
+			t.Errorf("%s: expected error %q, got %v", tt.input, tt.expected, errors)
+		}
+	}
+}
+
+func TestOptionalChainTypes(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`/** @type {string | undefined} */ let s = undefined; let x = s?.length;`, "number | undefined"},
+		{`/** @type {string} */ let s = ""; let x = s?.length;`, "number"},
+		{`/** @type {?function(): string} */ let f = null; let x = f?.();`, "string | undefined"},
+		{`/** @type {number[] | null} */ let a = null; let x = a?.[0];`, "number | undefined"},
+		{`/** @type {string | undefined} */ let s = undefined; let x = s?.length.toFixed;`, "any"},
+		{`/** @type {number | null} */ let n = null; let x = n ?? "none";`, "number | string"},
+		{`let x = null ?? 1;`, "number"},
+		{`let x = undefined ?? "a";`, "string"},
+	}
+
+	for _, tt := range tests {
+		p := parser.New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		if len(p.Errors()) > 0 {
+			t.Fatalf("%s: parser errors: %v", tt.input, p.Errors())
+		}
+
+		tc := NewWithOptions(Options{JavaScript: true})
+		if errors := tc.Check(program); len(errors) > 0 {
+			t.Errorf("%s: unexpected type errors: %v", tt.input, errors)
+			continue
+		}
+
+		x, _ := tc.env.Get("x")
+		if x.String() != tt.expected {
+			t.Errorf("%s: expected x to be %s, got %s", tt.input, tt.expected, x)
+		}
+	}
+}
+
+func TestDestructuringTypes(t *testing.T) {
+	input := `
+	/** @typedef {Object} Options
+	 * @property {string} name
+	 * @property {number[]} [sizes]
+	 * @property {boolean} debug
+	 */
+	/** @type {Options} */
+	let options = { name: "a", debug: "b" === "c" };
+	const { name, sizes: [first, ...others] = [], ...flags } = options;
+	let [s, , t = "x"] = "ab";
+	let { length } = "abc";
+	`
+
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) > 0 {
+		t.Fatalf("parser errors: %v", p.Errors())
+	}
+
+	tc := NewWithOptions(Options{JavaScript: true})
+	if errors := tc.Check(program); len(errors) > 0 {
+		t.Fatalf("unexpected type errors: %v", errors)
+	}
+
+	expected := map[string]string{
+		"name":   "string",
+		"first":  "number",
+		"others": "number[]",
+		"flags":  "{ debug: boolean; }",
+		"s":      "string",
+		"t":      "string",
+		"length": "number",
+	}
+	for name, typ := range expected {
+		actual, ok := tc.env.Get(name)
+		if !ok {
+			t.Errorf("%s not declared", name)
+			continue
+		}
+		if actual.String() != typ {
+			t.Errorf("expected %s to be %s, got %s", name, typ, actual)
+		}
+	}
+}
+
+func TestDestructuringErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let o = { a: 1 }; let { b } = o;`, "Property 'b' does not exist on type '{ a: number; }'."},
+		{`let n = 1; let [a] = n;`, "Type 'number' must have a '[Symbol.iterator]()' method that returns an iterator."},
+		{`let o = { a: 1 }; let { a = "x" } = o;`, "Type 'string' is not assignable to type 'number'."},
+		{`let a = 1; let b = "b"; [a, b] = [b, a];`, "Type 'string | number' is not assignable to type 'number'."},
+		{`let o = { a = 1 };`, "Did you mean to use a ':'? An '=' can only follow a property name when the containing object literal is part of a destructuring pattern."},
+	}
+
+	for _, tt := range tests {
+		p := parser.New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		if len(p.Errors()) > 0 {
+			t.Fatalf("%s: parser errors: %v", tt.input, p.Errors())
+		}
+
+		errors := New().Check(program)
+		if len(errors) == 0 || errors[0] != tt.expected {
+			t.Errorf("%s: expected error %q, got %v", tt.input, tt.expected, errors)
+		}
+	}
+}
+
+func TestSpreadTypes(t *testing.T) {
+	input := `
+	/** @type {[number, string]} */
+	let pair = [1, "a"];
+	let [n, ...tail] = pair;
+	let s = pair[1];
+	/** @type {[number, number, string]} */
+	let triple = [0, ...pair];
+	/**
+	 * @param {number} a
+	 * @param {...string} rest
+	 * @returns {string}
+	 */
+	function f(a, ...rest) { return rest[0]; }
+	let r = f(...pair);
+	let o = { ...{ a: 1, b: "b" }, b: 2 };
+	`
+
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) > 0 {
+		t.Fatalf("parser errors: %v", p.Errors())
+	}
+
+	tc := NewWithOptions(Options{JavaScript: true})
+	if errors := tc.Check(program); len(errors) > 0 {
+		t.Fatalf("unexpected type errors: %v", errors)
+	}
+
+	expected := map[string]string{
+		"n":      "number",
+		"tail":   "[string]",
+		"triple": "[number, number, string]",
+		"s":      "string",
+		"f":      "(a: number, ...rest: string[]) => string",
+		"r":      "string",
+		"o":      "{ a: number; b: number; }",
+	}
+	for name, typ := range expected {
+		actual, ok := tc.env.Get(name)
+		if !ok {
+			t.Errorf("%s not declared", name)
+			continue
+		}
+		if actual.String() != typ {
+			t.Errorf("expected %s to be %s, got %s", name, typ, actual)
+		}
+	}
+}
+
+func TestSpreadErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let f = function(a, b) {}; f(1);`, "Expected 2 arguments, but got 1."},
+		{`let f = function(a, b = 1) {}; f(1, 2, 3);`, "Expected 1-2 arguments, but got 3."},
+		{`let f = function(a, ...b) {}; f();`, "Expected at least 1 arguments, but got 0."},
+		{`let f = function(a) {}; let xs = [1]; f(...xs);`, "A spread argument must either have a tuple type or be passed to a rest parameter."},
+		{`let o = { ...1 };`, "Spread types may only be created from object types."},
+		{`/** @type {[number]} */ let t = [1]; let [a, b] = t;`, "Tuple type '[number]' of length '1' has no element at index '1'."},
+		{`/** @type {[number]} */ let t = [1]; let x = t[2];`, "Tuple type '[number]' of length '1' has no element at index '2'."},
+		{`/** @param {number} n */ function f(n) {} f("a");`, "Argument of type 'string' is not assignable to parameter of type 'number'."},
+	}
+
+	for _, tt := range tests {
+		p := parser.New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		if len(p.Errors()) > 0 {
+			t.Fatalf("%s: parser errors: %v", tt.input, p.Errors())
+		}
+
+		errors := NewWithOptions(Options{JavaScript: strings.Contains(tt.input, "/**")}).Check(program)
+		if len(errors) == 0 || errors[0] != tt.expected {
+			t.Errorf("%s: expected error %q, got %v", tt.input, tt.expected, errors)
+		}
+	}
+}
+
+func TestSwitchNarrowing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{`
+		/** @param {"a" | "b" | "c"} x */
+		function f(x) {
+			switch (x) {
+			case "a":
+				break;
+			case "b":
+			case "c":
+				/** @type {"b" | "c"} */
+				let y = x;
+				break;
+			default:
+				/** @type {never} */
+				let unreachable = x;
+			}
+		}`, nil},
+		{`
+		/** @param {"a" | "b" | "c"} x */
+		function f(x) {
+			switch (x) {
+			case "a":
+			case "b":
+				break;
+			default:
+				/** @type {never} */
+				let unreachable = x;
+			}
+		}`, []string{`Type '"c"' is not assignable to type 'never'.`}},
+		{`
+		/** @param {never} x */
+		function assertNever(x) {}
+		/** @param {1 | 2} n */
+		function f(n) {
+			switch (n) {
+			case 1:
+				break;
+			default:
+				assertNever(n);
+			}
+		}`, []string{`Argument of type '2' is not assignable to parameter of type 'never'.`}},
+		{`
+		/** @param {"a" | "b"} x */
+		function f(x) {
+			switch (x) {
+			case "z":
+				break;
+			}
+		}`, []string{`Type '"z"' is not comparable to type '"a" | "b"'.`}},
+		{`
+		/** @type {"a" | "b"} */
+		let x = "a";
+		switch (x) {
+		case "b":
+			break;
+		}`, []string{`Type '"b"' is not comparable to type '"a"'.`}},
+	}
+
+	for _, tt := range tests {
+		p := parser.New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		if len(p.Errors()) > 0 {
+			t.Fatalf("parser errors: %v", p.Errors())
+		}
+
+		errors := NewWithOptions(Options{JavaScript: true}).Check(program)
+		if strings.Join(errors, "\n") != strings.Join(tt.expected, "\n") {
+			t.Errorf("%s\nexpected errors %q, got %q", tt.input, tt.expected, errors)
+		}
+	}
+}
+
+func TestCatchVariable(t *testing.T) {
+	input := `
+	try {
+		throw "oops";
+	} catch (e) {
+		let message = e.message;
+	}`
+
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) > 0 {
+		t.Fatalf("parser errors: %v", p.Errors())
+	}
+
+	if errors := New().Check(program); len(errors) > 0 {
+		t.Errorf("expected no errors without strict, got %v", errors)
+	}
+
+	errors := NewWithOptions(Options{Strict: true}).Check(program)
+	expected := "'e' is of type 'unknown'."
+	if len(errors) != 1 || errors[0] != expected {
+		t.Errorf("expected error %q in strict mode, got %v", expected, errors)
+	}
+}
+
+func TestLoopVariableTypes(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`/** @type {number[]} */ let xs = [1]; let x = "s"; for (const n of xs) { x = n; }`, "number"},
+		{`for (const c of "abc") { let x = c; }`, ""},
+		{`/** @returns {Map<string, number>} */ function f() { throw "stub"; } for (const [k, v] of f()) { let x = v; }`, ""},
+		{`/** @returns {Set<string>} */ function f() { throw "stub"; } for (const v of f().values()) { let x = v; }`, ""},
+		{`/** @returns {Iterable<boolean>} */ function f() { throw "stub"; } for (const b of f()) { let x = b; }`, ""},
+		{`/** @returns {AsyncIterable<number>} */ function f() { throw "stub"; } for await (const n of f()) { let x = n; }`, ""},
+		{`/** @type {Promise<string>[]} */ let ps = []; for await (const s of ps) { let x = s; }`, ""},
+	}
+
+	for _, tt := range tests {
+		p := parser.New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		if len(p.Errors()) > 0 {
+			t.Fatalf("%s: parser errors: %v", tt.input, p.Errors())
+		}
+
+		errors := NewWithOptions(Options{JavaScript: true}).Check(program)
+		if tt.expected != "" {
+			// The loop variable is assigned to x, a string
+			expected := "Type '" + tt.expected + "' is not assignable to type 'string'."
+			if len(errors) != 1 || errors[0] != expected {
+				t.Errorf("%s: expected error %q, got %v", tt.input, expected, errors)
+			}
+		} else if len(errors) > 0 {
+			t.Errorf("%s: unexpected errors %v", tt.input, errors)
+		}
+	}
+
+	// The element types of the loops above, read from the loop scope
+	input := `
+	/** @returns {Map<string, number>} */
+	function m() { throw "stub"; }
+	/** @returns {AsyncIterable<Promise<boolean>>} */
+	function stream() { throw "stub"; }
+	let keys = [];
+	let flags = [];
+	for (const [k, v] of m()) { keys = [k]; }
+	for await (const f of stream()) { flags = [f]; }
+	`
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) > 0 {
+		t.Fatalf("parser errors: %v", p.Errors())
+	}
+	errors := NewWithOptions(Options{JavaScript: true}).Check(program)
+	expected := []string{
+		"Type 'string[]' is not assignable to type 'never[]'.\n  Type 'string' is not assignable to type 'never'.",
+		"Type 'boolean[]' is not assignable to type 'never[]'.\n  Type 'boolean' is not assignable to type 'never'.",
+	}
+	if strings.Join(errors, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected errors %q, got %q", expected, errors)
+	}
+}
+
+func TestLoopErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let n = 1; for (const x of n) {}`, "Type 'number' must have a '[Symbol.iterator]()' method that returns an iterator."},
+		{`let n = 1; for await (const x of n) {}`, "Type 'number' must have a '[Symbol.asyncIterator]()' method that returns an async iterator."},
+		{`let n = 1; for (const k in n) {}`, "The right-hand side of a 'for...in' statement must be of type 'any', an object type or a type parameter, but here has type 'number'."},
+		{`let o = { a: 1 }; for (const [k] in o) {}`, "The left-hand side of a 'for...in' statement cannot be a destructuring pattern."},
+		{`let o = { a: 1 }; let n = 1; for (n in o) {}`, "The left-hand side of a 'for...in' statement must be of type 'string' or 'any'."},
+		{`/** @type {Map<string>} */ let m = 1;`, "Generic type 'Map<K, V>' requires 2 type argument(s)."},
+	}
+
+	for _, tt := range tests {
+		p := parser.New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		if len(p.Errors()) > 0 {
+			t.Fatalf("%s: parser errors: %v", tt.input, p.Errors())
+		}
+
+		errors := NewWithOptions(Options{JavaScript: true}).Check(program)
+		if len(errors) == 0 || errors[0] != tt.expected {
+			t.Errorf("%s: expected error %q, got %v", tt.input, tt.expected, errors)
+		}
+	}
+}
+
+func TestAsyncFunctions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`/** @returns {Promise<number>} */ async function f() { return 1; } let x = "s"; x = await f();`, "Type 'number' is not assignable to type 'string'."},
+		{`let x = "s"; x = await 1;`, "Type 'number' is not assignable to type 'string'."},
+		{`/** @returns {Promise<string>} */ async function f() { return 1; }`, "Type 'number' is not assignable to type 'string'."},
+		{`/** @returns {Promise<number>} */ async function f(p) { return p; }`, ""},
+		{`/** @returns {number} */ async function f() { return 1; }`, "The return type of an async function or method must be the global Promise<T> type. Did you mean to write 'Promise<number>'?"},
+		{`/** @param {Promise<string>} p */ async function f(p) { let n = 1; n = await p; }`, "Type 'string' is not assignable to type 'number'."},
+		{`/** @type {Awaited<Promise<Promise<number>>>} */ let n = "s";`, "Type 'string' is not assignable to type 'number'."},
+		{`/** @type {Awaited<number, string>} */ let n = 1;`, "Generic type 'Awaited<T>' requires 1 type argument(s)."},
+		{`let o = { async m() { return 1; } }; let s = "s"; s = await o.m();`, "Type 'number' is not assignable to type 'string'."},
+	}
+
+	for _, tt := range tests {
+		p := parser.New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		if len(p.Errors()) > 0 {
+			t.Fatalf("%s: parser errors: %v", tt.input, p.Errors())
+		}
+
+		errors := NewWithOptions(Options{JavaScript: true}).Check(program)
+		if tt.expected == "" {
+			if len(errors) > 0 {
+				t.Errorf("%s: unexpected errors %v", tt.input, errors)
+			}
+		} else if len(errors) == 0 || errors[0] != tt.expected {
+			t.Errorf("%s: expected error %q, got %v", tt.input, tt.expected, errors)
+		}
+	}
+}
+
+func TestGeneratorFunctions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"/** @returns {Generator<number, string, boolean>} */\nfunction* g() { yield \"s\"; }", "Type 'string' is not assignable to type 'number'."},
+		{"/** @returns {Generator<number, string, boolean>} */\nfunction* g() { const b = yield 1; let n = 1; n = b; }", "Type 'boolean' is not assignable to type 'number'."},
+		{"/** @returns {Generator<number, string, boolean>} */\nfunction* g() { yield 1; return 2; }", "Type 'number' is not assignable to type 'string'."},
+		{"/** @returns {Generator<number>} */\nfunction* g() { yield* [\"a\"]; }", "Type 'string' is not assignable to type 'number'."},
+		{"/** @returns {Generator<number>} */\nfunction* g() { yield* 1; }", "Type 'number' must have a '[Symbol.iterator]()' method that returns an iterator."},
+		{"/** @returns {Generator<number, string>} */\nfunction* inner() {}\n/** @returns {Generator<number>} */\nfunction* g() { let n = 1; n = yield* inner(); }", "Type 'string' is not assignable to type 'number'."},
+		{"/** @returns {Generator<string>} */\nfunction* g() {}\nlet n = 1;\nfor (const s of g()) { n = s; }", "Type 'string' is not assignable to type 'number'."},
+		{"/** @returns {Promise<string>} */\nfunction h() { throw \"stub\"; }\n/** @returns {AsyncGenerator<number>} */\nasync function* g() { yield h(); }", "Type 'string' is not assignable to type 'number'."},
+		{"/** @returns {AsyncGenerator<string>} */\nasync function* g() {}\nlet n = 1;\nfor await (const s of g()) { n = s; }", "Type 'string' is not assignable to type 'number'."},
+		{"/** @returns {IterableIterator<number>} */\nfunction* g() { yield 1; }", ""},
+		{"function* g() { yield 1; yield \"s\"; }", ""},
+		{"function* g() { yield 1; }\nfor (const x of g()) { let s: string = x; }", "Type 'number' is not assignable to type 'string'."},
+		{"function* g() { yield* [\"a\"]; }\nlet n = 1;\nfor (const s of g()) { n = s; }", "Type 'string' is not assignable to type 'number'."},
+		{"async function* g() { yield 1; }\nlet s = \"\";\nfor await (const n of g()) { s = n; }", "Type 'number' is not assignable to type 'string'."},
+		{"/** @returns {Generator<number, string, boolean>} */\nfunction* g() { return \"s\"; }\nlet n = 1;\nn = g().next(true).value;", "Type 'number | string' is not assignable to type 'number'."},
+		{"/** @returns {Generator<number, string, boolean>} */\nfunction* g() { return \"s\"; }\ng().next(1);", "Argument of type 'number' is not assignable to parameter of type 'boolean'."},
+		{"/** @returns {Generator<number, string, boolean>} */\nfunction* g() { return \"s\"; }\ng().return(1);", "Argument of type 'number' is not assignable to parameter of type 'string'."},
+		{"/** @returns {Generator<number, string, boolean>} */\nfunction* g() { return \"s\"; }\n/** @type {IteratorResult<number, string>} */\nlet r = g().throw(\"e\");\nif (!r.done) { r.value; }", ""},
+		{"/** @returns {AsyncGenerator<number>} */\nasync function* g() {}\n/** @type {Promise<IteratorResult<number>>} */\nlet p = g().next();\nlet s = \"\";\ns = g().next();", "Type 'Promise<IteratorResult<number, any>>' is not assignable to type 'string'."},
+		{"/** @returns {void} */\nfunction* g() {}", "A generator cannot have a 'void' type annotation."},
+		{"/** @type {Iterator<number, string, boolean, number>} */\nlet it = 1;", "Generic type 'Iterator<T, TReturn, TNext>' requires between 1 and 3 type arguments."},
+	}
+
+	for _, tt := range tests {
+		p := parser.New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		if len(p.Errors()) > 0 {
+			t.Fatalf("%s: parser errors: %v", tt.input, p.Errors())
+		}
+
+		errors := NewWithOptions(Options{JavaScript: true}).Check(program)
+		if tt.expected == "" {
+			if len(errors) > 0 {
+				t.Errorf("%s: unexpected errors %v", tt.input, errors)
+			}
+		} else if len(errors) == 0 || errors[0] != tt.expected {
+			t.Errorf("%s: expected error %q, got %v", tt.input, tt.expected, errors)
+		}
+	}
+}
+
+func TestNewThisAndSuper(t *testing.T) {
+	tests := []struct {
+		input    string
+		options  Options
+		expected string
+	}{
+		{"/** @param {number} n */\nfunction F(n) {}\nnew F(\"s\");", Options{JavaScript: true}, "Argument of type 'string' is not assignable to parameter of type 'number'."},
+		{"let n = 1;\nnew n();", Options{}, "This expression is not constructable. Type 'number' has no construct signatures."},
+		{"/** @class */\nfunction Point() {}\nPoint();", Options{JavaScript: true}, "Value of type 'typeof Point' is not callable. Did you mean to include 'new'?"},
+		{"/** @constructor */\nfunction Point() { this.x = 1; }\nconst p = new Point();", Options{JavaScript: true, NoImplicitThis: true}, ""},
+		{"/** @type {Map<string, number>} */\nlet m = new Map();\nconst s = new Set();", Options{JavaScript: true}, ""},
+		{"function f() { return this; }", Options{}, ""},
+		{"function f() { return this; }", Options{NoImplicitThis: true}, "'this' implicitly has type 'any' because it does not have a type annotation."},
+		{"function f() { function g() { this.x = 1; } }", Options{Strict: true}, "'this' implicitly has type 'any' because it does not have a type annotation."},
+		{"/** @this {number} */\nfunction f() { let n = 1; n = this; }", Options{JavaScript: true, NoImplicitThis: true}, ""},
+		{"/** @this {number} */\nasync function f() { let s = \"\"; s = this; }", Options{JavaScript: true, NoImplicitThis: true}, "Type 'number' is not assignable to type 'string'."},
+		{"function f(this: number) { let n = 1; n = this; }", Options{NoImplicitThis: true}, ""},
+		{"function f(this: number) { let s = \"\"; s = this; }", Options{}, "Type 'number' is not assignable to type 'string'."},
+		{"function f(a: number, b: string) {}\nf(1, 2);", Options{}, "Argument of type 'number' is not assignable to parameter of type 'string'."},
+		{"function f() { super(); }", Options{}, "Super calls are not permitted outside constructors or in nested functions inside constructors."},
+		{"function f() { return super.x; }", Options{}, "'super' can only be referenced in members of derived classes or object literal expressions."},
+	}
+
+	for _, tt := range tests {
+		p := parser.New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		if len(p.Errors()) > 0 {
+			t.Fatalf("%s: parser errors: %v", tt.input, p.Errors())
+		}
+
+		errors := NewWithOptions(tt.options).Check(program)
+		if tt.expected == "" {
+			if len(errors) > 0 {
+				t.Errorf("%s: unexpected errors %v", tt.input, errors)
+			}
+		} else if len(errors) == 0 || errors[0] != tt.expected {
+			t.Errorf("%s: expected error %q, got %v", tt.input, tt.expected, errors)
+		}
+	}
+}
+
+func TestDiagnostics(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let x = 1;\nx = \"s\";", "2:1 - error TS2322: Type 'string' is not assignable to type 'number'."},
+		{"let x = 1;\nlet y = x + zz;", "2:13 - error TS2304: Cannot find name 'zz'."},
+		{"async function f() {\n  let s = \"a\";\n  s++;\n}", "3:3 - error TS2356: An arithmetic operand must be of type 'any', 'number', 'bigint' or an enum type."},
+		{"let a = 1;\nfor (const k in a) {}", "2:17 - error TS2407: The right-hand side of a 'for...in' statement must be of type 'any', an object type or a type parameter, but here has type 'number'."},
+		{"let s = \"a\";\nlet n = s - 1;", "2:9 - error TS2362: The left-hand side of an arithmetic operation must be of type 'any', 'number', 'bigint' or an enum type."},
+		{"function f(a: number) {}\nf(1, \"x\");", "2:6 - error TS2554: Expected 1 arguments, but got 2."},
+		{"function f(a: number) {}\nf(\"x\");", "2:3 - error TS2345: Argument of type 'string' is not assignable to parameter of type 'number'."},
+	}
+
+	for _, tt := range tests {
+		p := parser.New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		if len(p.Errors()) > 0 {
+			t.Fatalf("%q: parser errors: %v", tt.input, p.Errors())
+		}
+
+		tc := New()
+		tc.Check(program)
+		diags := tc.Diagnostics()
+		if len(diags) == 0 || diags[0].String() != tt.expected {
+			t.Errorf("%q: expected %q, got %v", tt.input, tt.expected, diags)
+		}
+	}
+}
+
+func TestUndeclaredNameDoesNotCascade(t *testing.T) {
+	input := "let b = y;\nlet c = b + 1;\nb = \"s\";\nlet d = y * 2;\nlet e = -y.length;\nf(1);\nf(2);"
+	expected := []string{"Cannot find name 'y'.", "Cannot find name 'f'."}
+
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) > 0 {
+		t.Fatalf("parser errors: %v", p.Errors())
+	}
+
+	errors := New().Check(program)
+	if strings.Join(errors, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected errors %v, got %v", expected, errors)
+	}
+}
+
+func TestScopes(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"{ let x = 1; }\nlet y = x;", []string{"Cannot find name 'x'."}},
+		{"{ var x = 1; }\nlet y = x;", nil},
+		{"let x = 1;\n{ let x = \"s\"; x = \"t\"; }\nx = 2;", nil},
+		{"let x = 1;\n{ let x = \"s\"; }\nx = \"t\";", []string{"Type 'string' is not assignable to type 'number'."}},
+		{"try {} catch (e) { let x = e; }\nlet y = e;", []string{"Cannot find name 'e'."}},
+		{"for (const v of [1, 2]) {}\nlet y = v;", []string{"Cannot find name 'v'."}},
+		{"let x = y;\nlet y = 1;", []string{"Block-scoped variable 'y' used before its declaration."}},
+		{"async function f() { return y; }\nlet y = 1;", nil},
+		{"let x = f();\nfunction f() { return 1; }", nil},
+		{"let x = 1;\nlet x = 2;", []string{"Cannot redeclare block-scoped variable 'x'.", "Cannot redeclare block-scoped variable 'x'."}},
+		{"var x = 1;\nlet x = 2;", []string{"Cannot redeclare block-scoped variable 'x'.", "Cannot redeclare block-scoped variable 'x'."}},
+		{"var x = 1;\nvar x = 2;", nil},
+		{"let x = 1;\n{ let x = 2; }", nil},
+		{"function f() {}\nfunction f() {}", []string{"Duplicate function implementation.", "Duplicate function implementation."}},
+		{"var f = 1;\nfunction f() {}", []string{"Duplicate identifier 'f'.", "Duplicate identifier 'f'."}},
+		{"async function f(a, a) {}", []string{"Duplicate identifier 'a'.", "Duplicate identifier 'a'."}},
+	}
+
+	for _, tt := range tests {
+		p := parser.New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		if len(p.Errors()) > 0 {
+			t.Fatalf("%q: parser errors: %v", tt.input, p.Errors())
+		}
+
+		errors := New().Check(program)
+		if strings.Join(errors, "\n") != strings.Join(tt.expected, "\n") {
+			t.Errorf("%q: expected errors %v, got %v", tt.input, tt.expected, errors)
+		}
+	}
+}
+
+func TestSymbolOf(t *testing.T) {
+	input := "let x = 1;\n{ let x = \"s\"; let y = x; }\nlet z = x;"
+
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) > 0 {
+		t.Fatalf("parser errors: %v", p.Errors())
+	}
+
+	tc := New()
+	if errors := tc.Check(program); len(errors) > 0 {
+		t.Fatalf("expected no errors, got %v", errors)
+	}
+
+	outer := program.Statements[0].(*ast.LetStatement)
+	block := program.Statements[1].(*ast.BlockStatement)
+	inner := block.Statements[0].(*ast.LetStatement)
+	innerRef := block.Statements[1].(*ast.LetStatement).Value.(*ast.Identifier)
+	outerRef := program.Statements[2].(*ast.LetStatement).Value.(*ast.Identifier)
+
+	if sym := tc.SymbolOf(outer.Name); sym == nil || tc.SymbolOf(outerRef) != sym {
+		t.Errorf("expected the outer reference to be bound to the outer declaration")
+	}
+	if sym := tc.SymbolOf(inner.Name); sym == nil || tc.SymbolOf(innerRef) != sym || sym.Flags&BlockScopedVariable == 0 {
+		t.Errorf("expected the inner reference to be bound to the inner declaration")
+	}
+	if tc.SymbolOf(outer.Name) == tc.SymbolOf(inner.Name) {
+		t.Errorf("expected the shadowing declaration to have its own symbol")
+	}
+}
+
+func TestFunctionBodies(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"function f() { let s = \"a\"; s++; }", []string{"An arithmetic operand must be of type 'any', 'number', 'bigint' or an enum type."}},
+		{"let f = function(a) { let x = y; };", []string{"Cannot find name 'y'."}},
+		{"function f(a) { let x = a; }\nlet y = x;", []string{"Cannot find name 'x'."}},
+		{"function f(): number { return \"s\"; }", []string{"Type 'string' is not assignable to type 'number'."}},
+		{"function f(): number { return 1; }\nlet x = f();\nx = \"s\";", []string{"Type 'string' is not assignable to type 'number'."}},
+	}
+
+	for _, tt := range tests {
+		p := parser.New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		if len(p.Errors()) > 0 {
+			t.Fatalf("%q: parser errors: %v", tt.input, p.Errors())
+		}
+
+		errors := New().Check(program)
+		if strings.Join(errors, "\n") != strings.Join(tt.expected, "\n") {
+			t.Errorf("%q: expected errors %v, got %v", tt.input, tt.expected, errors)
+		}
+	}
+}
+
+func TestInferredReturnTypes(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"function f() {}", "() => void"},
+		{"function f() { return; }", "() => void"},
+		{"function f(a) { return 1; }", "(a: any) => number"},
+		{"function f() { switch (1) { case 1: return \"a\"; default: return 2; } }", "() => string | number"},
+		{"function f() { return g(); }\nfunction g() { return \"s\"; }", "() => string"},
+		{"function f() { return f(); }", "() => any"},
+		{"async function f() { return 1; }", "() => Promise<number>"},
+		{"async function f() {}", "() => Promise<void>"},
+		{"function* f() { yield 1; yield \"s\"; return true; }", "() => Generator<number | string, boolean, unknown>"},
+		{"function* f() {}", "() => Generator<never, void, unknown>"},
+		{"async function* f() { yield 1; }", "() => AsyncGenerator<number, void, unknown>"},
+		{"function f(): string | number { return 1; }", "() => string | number"},
+	}
+
+	for _, tt := range tests {
+		p := parser.New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		if len(p.Errors()) > 0 {
+			t.Fatalf("%q: parser errors: %v", tt.input, p.Errors())
+		}
+
+		tc := New()
+		if errors := tc.Check(program); len(errors) > 0 {
+			t.Fatalf("%q: unexpected errors %v", tt.input, errors)
+		}
+		fn := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.FunctionLiteral)
+		if actual := tc.SymbolOf(fn.Name).Type.String(); actual != tt.expected {
+			t.Errorf("%q: expected type %q, got %q", tt.input, tt.expected, actual)
+		}
+	}
+}
+
+func TestReturnPaths(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+		options  Options
+	}{
+		{"function f(): number {}", []string{"1:10 - error TS2355: A function whose declared type is neither 'undefined', 'void', nor 'any' must return a value."}, Options{}},
+		{"function f(): number { throw \"x\"; }", nil, Options{}},
+		{"function f(): void {}", nil, Options{}},
+		{"function f(): any {}", nil, Options{}},
+		{"async function f(): Promise<number> {}", []string{"1:16 - error TS2355: A function whose declared type is neither 'undefined', 'void', nor 'any' must return a value."}, Options{}},
+		{"function f(): number { switch (1) { case 1: return 1; } }", nil, Options{}},
+		{"function f() { switch (1) { case 1: return 1; } }", nil, Options{}},
+		{"function f() { switch (1) { case 1: return 1; } }", []string{"1:10 - error TS7030: Not all code paths return a value."}, Options{NoImplicitReturns: true}},
+		{"function f() { switch (1) { case 1: return 1; default: return 2; } }", nil, Options{NoImplicitReturns: true}},
+		{"function f() { switch (1) { case 1: break; default: return 2; } }", []string{"1:10 - error TS7030: Not all code paths return a value."}, Options{NoImplicitReturns: true}},
+		{"function f() { try { return 1; } catch (e) { throw e; } }", nil, Options{NoImplicitReturns: true}},
+		{"let f = function() { switch (1) { case 1: return; } return 1; };", []string{"1:43 - error TS7030: Not all code paths return a value."}, Options{NoImplicitReturns: true}},
+		{"function f() { switch (1) { case 1: return; } }", nil, Options{NoImplicitReturns: true}},
+		{"function f(a): number { if (a) { return 1; } }", []string{"1:16 - error TS2366: Function lacks ending return statement and return type does not include 'undefined'."}, Options{StrictNullChecks: true}},
+		{"function f(a): number { if (a) { return 1; } }", []string{"1:16 - error TS2366: Function lacks ending return statement and return type does not include 'undefined'."}, Options{Strict: true}},
+		{"function f(a): number { if (a) { return 1; } }", nil, Options{}},
+		{"function f(a): number { if (a) { return 1; } return 2; }", nil, Options{StrictNullChecks: true}},
+		{"function f(a): number | undefined { if (a) { return 1; } }", nil, Options{StrictNullChecks: true}},
+		{"function f(a: \"x\" | \"y\"): number { switch (a) { case \"x\": return 1; case \"y\": return 2; } }", nil, Options{StrictNullChecks: true}},
+		{"function f(a: \"x\" | \"y\") { switch (a) { case \"x\": return 1; case \"y\": return 2; } }", nil, Options{NoImplicitReturns: true}},
+		{"function f(a: \"x\" | \"y\"): number { switch (a) { case \"x\": return 1; } }", []string{"1:27 - error TS2366: Function lacks ending return statement and return type does not include 'undefined'."}, Options{StrictNullChecks: true}},
+	}
+
+	for _, tt := range tests {
+		p := parser.New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		if len(p.Errors()) > 0 {
+			t.Fatalf("%q: parser errors: %v", tt.input, p.Errors())
+		}
+
+		tc := NewWithOptions(tt.options)
+		tc.Check(program)
+		var actual []string
+		for _, d := range tc.Diagnostics() {
+			actual = append(actual, d.String())
+		}
+		if strings.Join(actual, "\n") != strings.Join(tt.expected, "\n") {
+			t.Errorf("%q: expected %v, got %v", tt.input, tt.expected, actual)
+		}
+	}
+}
+
+func TestStrictNullChecks(t *testing.T) {
+	tests := []struct {
+		input            string
+		expected         []string
+		strictNullChecks bool
+	}{
+		{"function f(): number | undefined { return 1; }\nf().toFixed();", []string{"2:1 - error TS2532: Object is possibly 'undefined'."}, true},
+		{"function f(): number | undefined { return 1; }\nf().toFixed();", nil, false},
+		{"let a: number | undefined = 1;\na.toFixed();", nil, true},
+		{"let o: string | undefined = \"a\";\no.length;", nil, true},
+		{"let a: string | null = null;\nlet n = a.length;", []string{"2:9 - error TS18047: 'a' is possibly 'null'."}, true},
+		{"let a: string | null | undefined = f();\na[0];\nfunction f(): string | null | undefined { return null; }", []string{"2:1 - error TS18049: 'a' is possibly 'null' or 'undefined'."}, true},
+		{"let o = { a: f() };\nfunction f(): string | undefined { return; }\no.a.length;", []string{"3:1 - error TS18048: 'o.a' is possibly 'undefined'."}, true},
+		{"function f(): (string | null)[] { return []; }\nf()[0]();", []string{"2:1 - error TS2721: Cannot invoke an object which is possibly 'null'."}, true},
+		{"function g(): number { return 1; }\nfunction h(a) { if (a) { return g; } return null; }\nlet n: string = h(1)();", []string{"3:17 - error TS2721: Cannot invoke an object which is possibly 'null'.", "3:5 - error TS2322: Type 'number' is not assignable to type 'string'."}, true},
+		{"let a: number | null = null;\nif (a) { a.toFixed(); }", nil, true},
+		{"let a: number | null = null;\nif (a != null) { a.toFixed(); } else { a.toFixed(); }", []string{"2:40 - error TS18047: 'a' is possibly 'null'."}, true},
+		{"let a: number | null = null;\nif (!a) { throw \"none\"; }\na.toFixed();", nil, true},
+		{"let a: number | undefined = 1;\nif (a === null) { throw \"none\"; }\na.toFixed();", nil, true},
+		{"let a: number | undefined = f();\nif (a === null) { throw \"none\"; }\na.toFixed();\nfunction f(): number | undefined { return 1; }", []string{"3:1 - error TS18048: 'a' is possibly 'undefined'."}, true},
+		{"let n: \"a\" | \"b\" = \"a\";\nlet m: \"a\" = n;", nil, true},
+		{"let a: number | null = null;\nlet b = a && a.toFixed();\nlet c = a ? a.toFixed() : 0;", nil, true},
+		{"let a: number | null = null;\nlet b = a!.toFixed();", nil, true},
+		{"let a: number | null = 1;\nif (a) { a = null; }", nil, true},
+		{"let n: number = null;", []string{"1:5 - error TS2322: Type 'null' is not assignable to type 'number'."}, true},
+		{"let n: number = null;", nil, false},
+		{"let n: never = undefined;", []string{"1:5 - error TS2322: Type 'undefined' is not assignable to type 'never'."}, false},
+		{"let x = null;\nx = 1;", []string{"2:1 - error TS2322: Type 'number' is not assignable to type 'null'."}, true},
+		{"let x = null;\nx = 1;", nil, false},
+		{"let s: {a?: number} = {};\nlet t: {a: number} = s;", []string{"2:5 - error TS2322: Type '{ a?: number; }' is not assignable to type '{ a: number; }'.\n  Property 'a' is optional in type '{ a?: number; }' but required in type '{ a: number; }'."}, true},
+	}
+
+	for _, tt := range tests {
+		p := parser.New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		if len(p.Errors()) > 0 {
+			t.Fatalf("%q: parser errors: %v", tt.input, p.Errors())
+		}
+
+		tc := NewWithOptions(Options{StrictNullChecks: tt.strictNullChecks})
+		tc.Check(program)
+		var actual []string
+		for _, d := range tc.Diagnostics() {
+			actual = append(actual, d.String())
+		}
+		if strings.Join(actual, "\n") != strings.Join(tt.expected, "\n") {
+			t.Errorf("%q: expected %v, got %v", tt.input, tt.expected, actual)
+		}
+	}
+}
+
+func TestNullNarrowing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let a: number | null = 1;\nlet x = a!;", "number"},
+		{"let a: number | null = 1;\nlet x = a ? a : 0;", "number"},
+		{"let a: string | null | undefined = f();\nlet x = a !== null ? a : \"\";\nfunction f(): string | null | undefined { return null; }", "string | undefined"},
+		{"let a: string | null | undefined = f();\nlet x = a === undefined ? a : \"\";\nfunction f(): string | null | undefined { return null; }", "undefined | string"},
+		{"let a: string | null | undefined = null;\nlet x = a == null ? \"\" : a;", "string"},
+		{"let a: number | null = 1;\nif (a == null) { throw \"none\"; }\nlet x = a;", "number"},
+		{"let a: number | null = 1;\nif (a) { a = null; }\nlet x = a;", "number | null"},
+		{"let x: number;", "number"},
+	}
+
+	for _, tt := range tests {
+		p := parser.New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		if len(p.Errors()) > 0 {
+			t.Fatalf("%q: parser errors: %v", tt.input, p.Errors())
+		}
+
+		tc := NewWithOptions(Options{StrictNullChecks: true})
+		if errors := tc.Check(program); len(errors) > 0 {
+			t.Errorf("%q: unexpected type errors: %v", tt.input, errors)
+			continue
+		}
+
+		x, _ := tc.env.Get("x")
+		if x.String() != tt.expected {
+			t.Errorf("%q: expected x to be %s, got %s", tt.input, tt.expected, x)
+		}
+	}
+}
+
+func TestFlowNarrowing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let a: string | number = 1;\na = \"s\";\nlet x = a;", "string"},
+		{"let a: number | null = 1;\nlet x = a;", "number"},
+		{"let a: \"a\" | \"b\" = \"a\";\nlet x = a;", "\"a\""},
+		{"let a: number | null = 1;\nif (a == null) { a = 2; }\nlet x = a;", "number"},
+		{"let a: number | null = f();\nfor (const i of [1]) { a = null; }\nlet x = a;\nfunction f(): number | null { return 1; }", "number | null"},
+		{"let a: number | null = f();\nfor (const i of [1]) { if (!a) { continue; } let y: number = a; }\nlet x = a;\nfunction f(): number | null { return 1; }", "number | null"},
+		{"let a: number | null = f();\nlet x = a !== null && a > 0 ? a : 1;\nfunction f(): number | null { return 1; }", "number"},
+		{"let a: number | undefined;\nlet x = a;", "number | undefined"},
+		{"let a: number;\ntry { a = 1; } catch { throw \"e\"; }\nlet x = a;", "number"},
+		{"let a: number | null = 1;\nfunction f() { return a; }\nlet x = f();", "number | null"},
+		{"let a: number | null = f();\nlabel: { if (a === null) { break label; } let y: number = a; }\nlet x = a;\nfunction f(): number | null { return 1; }", "number | null"},
+	}
+
+	for _, tt := range tests {
+		p := parser.New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		if len(p.Errors()) > 0 {
+			t.Fatalf("%q: parser errors: %v", tt.input, p.Errors())
+		}
+
+		tc := NewWithOptions(Options{StrictNullChecks: true})
+		if errors := tc.Check(program); len(errors) > 0 {
+			t.Errorf("%q: unexpected type errors: %v", tt.input, errors)
+			continue
+		}
+
+		x, _ := tc.env.Get("x")
+		if x.String() != tt.expected {
+			t.Errorf("%q: expected x to be %s, got %s", tt.input, tt.expected, x)
+		}
+	}
+}
+
+func TestDefiniteAssignment(t *testing.T) {
+	tests := []struct {
+		input            string
+		expected         []string
+		strictNullChecks bool
+	}{
+		{"let a: number;\na.toFixed();", []string{"2:1 - error TS2454: Variable 'a' is used before being assigned."}, true},
+		{"let a: number;\na.toFixed();", nil, false},
+		{"let a: number;\na = 1;\na.toFixed();", nil, true},
+		{"let a: number;\nif (f()) { a = 1; }\nlet b = a;\nfunction f() { return true; }", []string{"3:9 - error TS2454: Variable 'a' is used before being assigned."}, true},
+		{"let a: number;\nif (f()) { a = 1; } else { a = 2; }\nlet b = a;\nfunction f() { return true; }", nil, true},
+		{"let a: number;\nif (true) { a = 1; }\nlet b = a;", nil, true},
+		{"let a: number;\nswitch (f()) { case 1: a = 1; break; default: throw \"e\"; }\nlet b = a;\nfunction f() { return 1; }", nil, true},
+		{"var a: number;\na += 1;", []string{"2:1 - error TS2454: Variable 'a' is used before being assigned."}, true},
+		{"let a: number;\nfunction f() { return a; }", nil, true},
+		{"let a!: number;\nlet b = a;", nil, true},
+		{"let a: number;\nlet b = a!;", nil, true},
+		{"let a: number | undefined;\nlet b = a;", nil, true},
+		{"let a: any;\nlet b = a;", nil, true},
+		{"for (const x of [1]) { let a: number; if (x) { a = x; } let b = a; }", []string{"1:65 - error TS2454: Variable 'a' is used before being assigned."}, true},
+	}
+
+	for _, tt := range tests {
+		p := parser.New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		if len(p.Errors()) > 0 {
+			t.Fatalf("%q: parser errors: %v", tt.input, p.Errors())
+		}
+
+		tc := NewWithOptions(Options{StrictNullChecks: tt.strictNullChecks})
+		tc.Check(program)
+		var actual []string
+		for _, d := range tc.Diagnostics() {
+			actual = append(actual, d.String())
+		}
+		if strings.Join(actual, "\n") != strings.Join(tt.expected, "\n") {
+			t.Errorf("%q: expected %v, got %v", tt.input, tt.expected, actual)
+		}
+	}
+}
+
+func TestFlowGraph(t *testing.T) {
+	input := `function f(x) {
+	if (x) {
+		return 1;
+	} else {
+		throw x;
+	}
+	g();
+	h();
+	function g() {}
+}
+function k(xs) {
+	outer: for (const x of xs) {
+		inner: for (const y of xs) {
+			continue outer;
+		}
+	}
+	try {
+		return 1;
+	} finally {
+		g();
+	}
+	g();
+}
+let a = 1;
+switch (a) {
+case 1:
+	break;
+default:
+	a = 2;
+}`
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) > 0 {
+		t.Fatalf("parser errors: %v", p.Errors())
+	}
+
+	graph := BuildFlowGraph(program)
+
+	var unreachable []string
+	for _, stmt := range graph.Unreachable() {
+		unreachable = append(unreachable, stmt.String())
+	}
+	if expected := []string{"g()", "g()"}; strings.Join(unreachable, ";") != strings.Join(expected, ";") {
+		t.Errorf("expected unreachable statements %v, got %v", expected, unreachable)
+	}
+
+	var labels []string
+	for _, s := range graph.UnusedLabels() {
+		labels = append(labels, s.Label.Value)
+	}
+	if len(labels) != 1 || labels[0] != "inner" {
+		t.Errorf("expected inner to be the unused label, got %v", labels)
+	}
+
+	f := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.FunctionLiteral)
+	k := program.Statements[1].(*ast.ExpressionStatement).Expression.(*ast.FunctionLiteral)
+	if graph.EndReachable(f) || graph.EndReachable(k) {
+		t.Errorf("expected the ends of f and k to be unreachable")
+	}
+	if g := f.Body.Statements[3].(*ast.ExpressionStatement).Expression.(*ast.FunctionLiteral); !graph.EndReachable(g) {
+		t.Errorf("expected the end of g to be reachable")
+	}
+
+	// After the switch, a is either 1 or assigned in the default clause
+	s := program.Statements[3].(*ast.SwitchStatement)
+	end := graph.FlowAt(s.Cases[1].Consequent[0])
+	if end == nil || end.Kind != FlowSwitchClause || end.Clause != 1 || end.Node != s {
+		t.Errorf("expected the default clause to be entered by the switch, got %#v", end)
+	}
+	if !graph.IsReachable(s) || graph.IsReachable(f.Body.Statements[1]) {
+		t.Errorf("expected the switch to be reachable and g() not to be")
+	}
+}
+
+func TestFlowWarnings(t *testing.T) {
+	input := "function f() {\n  return 1;\n  let x = 2;\n  x++;\n}\nloop: for (const x of [1]) {}"
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) > 0 {
+		t.Fatalf("parser errors: %v", p.Errors())
+	}
+
+	tc := New()
+	tc.Check(program)
+	var actual []string
+	for _, d := range tc.Diagnostics() {
+		actual = append(actual, d.String())
+	}
+	expected := []string{"3:3 - warning TS7027: Unreachable code detected.", "6:1 - warning TS7028: Unused label."}
+	if strings.Join(actual, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected %v, got %v", expected, actual)
+	}
+}
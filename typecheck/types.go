@@ -0,0 +1,499 @@
+package typecheck
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/dmarro89/ts-go-compiler/ast"
+)
+
+// UnionType is a type that can be any one of Types, e.g. string | number
+type UnionType struct {
+	Types []Type
+}
+
+func (t *UnionType) String() string {
+	parts := make([]string, len(t.Types))
+	for i, member := range t.Types {
+		parts[i] = member.String()
+	}
+	return strings.Join(parts, " | ")
+}
+
+// ArrayType is the type of arrays whose elements are ElementType
+type ArrayType struct {
+	ElementType Type
+}
+
+func (t *ArrayType) String() string {
+	if _, ok := t.ElementType.(*UnionType); ok {
+		return "(" + t.ElementType.String() + ")[]"
+	}
+	if _, ok := t.ElementType.(*FunctionType); ok {
+		return "(" + t.ElementType.String() + ")[]"
+	}
+	return t.ElementType.String() + "[]"
+}
+
+// TupleType is the type of arrays with a known length and a type per
+// position, e.g. [number, string]
+type TupleType struct {
+	Elements []Type
+}
+
+func (t *TupleType) String() string {
+	parts := make([]string, len(t.Elements))
+	for i, el := range t.Elements {
+		parts[i] = el.String()
+	}
+	return "[" + strings.Join(parts, ", ") + "]"
+}
+
+// GenericType is an instance of a generic type of the standard library,
+// e.g. Map<string, number> or Iterable<T>
+type GenericType struct {
+	Name          string
+	TypeArguments []Type
+}
+
+func (t *GenericType) String() string {
+	args := make([]string, len(t.TypeArguments))
+	for i, arg := range t.TypeArguments {
+		args[i] = arg.String()
+	}
+	return t.Name + "<" + strings.Join(args, ", ") + ">"
+}
+
+// genericTypes are the type parameters of the generic library types
+var genericTypes = map[string][]string{
+	"Iterable":              {"T"},
+	"IterableIterator":      {"T"},
+	"AsyncIterable":         {"T"},
+	"AsyncIterableIterator": {"T"},
+	"Iterator":              {"T", "TReturn", "TNext"},
+	"AsyncIterator":         {"T", "TReturn", "TNext"},
+	"Generator":             {"T", "TReturn", "TNext"},
+	"AsyncGenerator":        {"T", "TReturn", "TNext"},
+	"IteratorResult":        {"T", "TReturn"},
+	"Map":                   {"K", "V"},
+	"Set":                   {"T"},
+	"Promise":               {"T"},
+}
+
+// genericDefaults are the defaults of the last type parameters of the
+// generic library types that have them, which may be left out
+var genericDefaults = map[string][]string{
+	"Iterator":       {"any", "any"},
+	"AsyncIterator":  {"any", "any"},
+	"Generator":      {"unknown", "any", "any"},
+	"AsyncGenerator": {"unknown", "any", "any"},
+	"IteratorResult": {"any"},
+}
+
+// LiteralType is the type of a single string, number or boolean, e.g. "a"
+// in the union "a" | "b"
+type LiteralType struct {
+	Value string     // the literal as written, e.g. "a", 1 or true
+	Base  *BasicType // string, number or boolean
+
+	// Widening is set on the type of a const declared with a literal,
+	// which a mutable variable, property or element given its value
+	// widens to Base
+	Widening bool
+}
+
+func (t *LiteralType) String() string {
+	return t.Value
+}
+
+// widenLiteral returns t with its widening literal types replaced by their
+// base types
+func widenLiteral(t Type) Type {
+	switch v := t.(type) {
+	case *LiteralType:
+		if v.Widening {
+			return v.Base
+		}
+	case *UnionType:
+		members := make([]Type, len(v.Types))
+		for i, member := range v.Types {
+			members[i] = widenLiteral(member)
+		}
+		return newUnionType(members...)
+	}
+	return t
+}
+
+// Parameter is a parameter of a function type
+type Parameter struct {
+	Name     string
+	Type     Type // an array or tuple type for rest parameters
+	Optional bool
+	Rest     bool // ...name, which takes the remaining arguments
+}
+
+// FunctionType is the type of functions, e.g. (a: number) => string
+type FunctionType struct {
+	Parameters []*Parameter
+	ReturnType Type
+
+	// Constructor marks a function documented as a class with @class or
+	// @constructor, which must be called with new
+	Constructor bool
+}
+
+func (t *FunctionType) String() string {
+	var out bytes.Buffer
+
+	params := make([]string, len(t.Parameters))
+	for i, p := range t.Parameters {
+		name := p.Name
+		if p.Optional {
+			name += "?"
+		}
+		if p.Rest {
+			name = "..." + name
+		}
+		params[i] = name + ": " + p.Type.String()
+	}
+
+	out.WriteString("(")
+	out.WriteString(strings.Join(params, ", "))
+	out.WriteString(") => ")
+	out.WriteString(t.ReturnType.String())
+
+	return out.String()
+}
+
+// Property is a member of an object type
+type Property struct {
+	Name     string
+	Type     Type
+	Optional bool
+}
+
+// ObjectType is the type of objects with the given properties
+type ObjectType struct {
+	Properties []*Property
+}
+
+func (t *ObjectType) String() string {
+	if len(t.Properties) == 0 {
+		return "{}"
+	}
+
+	parts := make([]string, len(t.Properties))
+	for i, p := range t.Properties {
+		name := p.Name
+		if p.Optional {
+			name += "?"
+		}
+		parts[i] = name + ": " + p.Type.String() + ";"
+	}
+	return "{ " + strings.Join(parts, " ") + " }"
+}
+
+// Property returns the property with the given name, if any
+func (t *ObjectType) Property(name string) *Property {
+	for _, p := range t.Properties {
+		if p.Name == name {
+			return p
+		}
+	}
+	return nil
+}
+
+// newUnionType builds the union of types, flattening nested unions and
+// dropping duplicates. A union of one type is that type.
+func newUnionType(types ...Type) Type {
+	var members []Type
+	seen := map[string]bool{}
+
+	var add func(t Type)
+	add = func(t Type) {
+		if u, ok := t.(*UnionType); ok {
+			for _, member := range u.Types {
+				add(member)
+			}
+			return
+		}
+		if !seen[t.String()] {
+			seen[t.String()] = true
+			members = append(members, t)
+		}
+	}
+	for _, t := range types {
+		add(t)
+	}
+
+	// any absorbs every other member, and never adds nothing to a union
+	if seen["any"] {
+		return &BasicType{Name: "any"}
+	}
+	if len(members) > 1 && seen["never"] {
+		kept := members[:0]
+		for _, member := range members {
+			if !isBasic(member, "never") {
+				kept = append(kept, member)
+			}
+		}
+		members = kept
+	}
+
+	if len(members) == 1 {
+		return members[0]
+	}
+	return &UnionType{Types: members}
+}
+
+func isBasic(t Type, name string) bool {
+	b, ok := t.(*BasicType)
+	return ok && b.Name == name
+}
+
+// resolveTypeNode turns a type written in the source into a Type
+func (tc *TypeChecker) resolveTypeNode(node ast.TypeNode) Type {
+	switch n := node.(type) {
+	case *ast.TypeReference:
+		return tc.resolveTypeReference(n)
+	case *ast.ArrayTypeNode:
+		return &ArrayType{ElementType: tc.resolveTypeNode(n.ElementType)}
+	case *ast.TupleTypeNode:
+		tuple := &TupleType{Elements: []Type{}}
+		for _, el := range n.Elements {
+			tuple.Elements = append(tuple.Elements, tc.resolveTypeNode(el))
+		}
+		return tuple
+	case *ast.UnionTypeNode:
+		types := make([]Type, len(n.Types))
+		for i, member := range n.Types {
+			types[i] = tc.resolveTypeNode(member)
+		}
+		return newUnionType(types...)
+	case *ast.LiteralTypeNode:
+		if t := literalType(n.Literal); t != nil {
+			return t
+		}
+		return tc.checkExpression(n.Literal)
+	case *ast.ObjectTypeNode:
+		obj := &ObjectType{}
+		for _, prop := range n.Properties {
+			p := &Property{Name: prop.Name, Type: &BasicType{Name: "any"}, Optional: prop.Optional}
+			if prop.Type != nil {
+				p.Type = tc.resolveTypeNode(prop.Type)
+			}
+			obj.Properties = append(obj.Properties, p)
+		}
+		return obj
+	case *ast.FunctionTypeNode:
+		fn := &FunctionType{ReturnType: &BasicType{Name: "any"}}
+		for i, param := range n.Parameters {
+			fn.Parameters = append(fn.Parameters, &Parameter{
+				Name: fmt.Sprintf("arg%d", i),
+				Type: tc.resolveTypeNode(param),
+			})
+		}
+		if n.ReturnType != nil {
+			fn.ReturnType = tc.resolveTypeNode(n.ReturnType)
+		}
+		return fn
+	default:
+		return &BasicType{Name: "any"}
+	}
+}
+
+func (tc *TypeChecker) resolveTypeReference(ref *ast.TypeReference) Type {
+	switch ref.Name {
+	case "number", "string", "boolean", "any", "unknown", "never", "void",
+		"null", "undefined", "object", "symbol", "bigint", "RegExp":
+		return &BasicType{Name: ref.Name}
+	case "Array":
+		if len(ref.TypeArguments) == 1 {
+			return &ArrayType{ElementType: tc.resolveTypeNode(ref.TypeArguments[0])}
+		}
+		return &ArrayType{ElementType: &BasicType{Name: "any"}}
+	case "Object":
+		return &BasicType{Name: "object"}
+	case "Function":
+		return &FunctionType{
+			Parameters: []*Parameter{{Name: "args", Type: &ArrayType{ElementType: &BasicType{Name: "any"}}, Rest: true}},
+			ReturnType: &BasicType{Name: "any"},
+		}
+	case "Awaited":
+		// Awaited<T> is the type await gives a value of type T
+		if len(ref.TypeArguments) != 1 {
+			tc.errorAt(tc.typeErrorToken(ref), errAwaitedArguments)
+			return &BasicType{Name: "any"}
+		}
+		return awaitedType(tc.resolveTypeNode(ref.TypeArguments[0]))
+	}
+
+	if params, ok := genericTypes[ref.Name]; ok {
+		return tc.resolveGenericType(ref, params)
+	}
+
+	if t, ok := tc.env.GetType(ref.Name); ok {
+		return t
+	}
+
+	tc.errorAt(tc.typeErrorToken(ref), errCannotFindName, ref.Name)
+	return &BasicType{Name: "any"}
+}
+
+// resolveGenericType resolves a reference to a generic library type. JSDoc
+// may leave out all the type arguments, which are then any.
+func (tc *TypeChecker) resolveGenericType(ref *ast.TypeReference, params []string) Type {
+	t := &GenericType{Name: ref.Name}
+	if len(ref.TypeArguments) == 0 {
+		for range params {
+			t.TypeArguments = append(t.TypeArguments, &BasicType{Name: "any"})
+		}
+		return t
+	}
+
+	defaults := genericDefaults[ref.Name]
+	required := len(params) - len(defaults)
+	if len(ref.TypeArguments) < required || len(ref.TypeArguments) > len(params) {
+		if len(defaults) == 0 {
+			tc.errorAt(tc.typeErrorToken(ref), errTypeArguments, ref.Name, strings.Join(params, ", "), len(params))
+		} else {
+			tc.errorAt(tc.typeErrorToken(ref), errTypeArgumentRange, ref.Name, strings.Join(params, ", "), required, len(params))
+		}
+		return &BasicType{Name: "any"}
+	}
+	for _, arg := range ref.TypeArguments {
+		t.TypeArguments = append(t.TypeArguments, tc.resolveTypeNode(arg))
+	}
+	for _, name := range defaults[len(t.TypeArguments)-required:] {
+		t.TypeArguments = append(t.TypeArguments, &BasicType{Name: name})
+	}
+	return t
+}
+
+// iterableElement returns the type of the values a for...of loop or a
+// spread takes from t, and whether t is iterable at all
+func iterableElement(t Type) (Type, bool) {
+	switch v := t.(type) {
+	case *ArrayType:
+		return v.ElementType, true
+	case *TupleType:
+		if len(v.Elements) == 0 {
+			return &BasicType{Name: "never"}, true
+		}
+		return newUnionType(v.Elements...), true
+	case *LiteralType:
+		return iterableElement(v.Base)
+	case *GenericType:
+		switch v.Name {
+		case "Iterable", "IterableIterator", "Generator", "Set":
+			return v.TypeArguments[0], true
+		case "Map":
+			return &TupleType{Elements: v.TypeArguments}, true
+		}
+	case *UnionType:
+		elements := make([]Type, len(v.Types))
+		for i, member := range v.Types {
+			element, ok := iterableElement(member)
+			if !ok {
+				return nil, false
+			}
+			elements[i] = element
+		}
+		return newUnionType(elements...), true
+	case *BasicType:
+		switch v.Name {
+		case "any":
+			return v, true
+		case "string":
+			return &BasicType{Name: "string"}, true
+		}
+	}
+	return nil, false
+}
+
+// promiseType returns Promise<t>, or t itself when it is a promise already
+func promiseType(t Type) Type {
+	if isPromise(t) {
+		return t
+	}
+	return &GenericType{Name: "Promise", TypeArguments: []Type{t}}
+}
+
+func isPromise(t Type) bool {
+	g, ok := t.(*GenericType)
+	return ok && g.Name == "Promise"
+}
+
+// awaitedType is the type of await on a value of type t: the value of a
+// promise, or t itself
+func awaitedType(t Type) Type {
+	if isPromise(t) {
+		return awaitedType(t.(*GenericType).TypeArguments[0])
+	}
+	if u, ok := t.(*UnionType); ok {
+		members := make([]Type, len(u.Types))
+		for i, member := range u.Types {
+			members[i] = awaitedType(member)
+		}
+		return newUnionType(members...)
+	}
+	return t
+}
+
+// genericProperty returns the type of a property of a generic library
+// type. Only the members used to iterate maps and sets, to resume
+// generators and to read their results are known.
+func genericProperty(t *GenericType, name string) Type {
+	iterator := func(element Type) Type {
+		return &FunctionType{ReturnType: &GenericType{Name: "IterableIterator", TypeArguments: []Type{element}}}
+	}
+
+	switch t.Name {
+	case "Generator", "AsyncGenerator":
+		yield, result, next := t.TypeArguments[0], t.TypeArguments[1], t.TypeArguments[2]
+		var resumed Type = &GenericType{Name: "IteratorResult", TypeArguments: []Type{yield, result}}
+		if t.Name == "AsyncGenerator" {
+			resumed = promiseType(resumed)
+		}
+		switch name {
+		case "next":
+			return &FunctionType{Parameters: []*Parameter{{Name: "value", Type: next, Optional: true}}, ReturnType: resumed}
+		case "return":
+			return &FunctionType{Parameters: []*Parameter{{Name: "value", Type: result}}, ReturnType: resumed}
+		case "throw":
+			return &FunctionType{Parameters: []*Parameter{{Name: "e", Type: &BasicType{Name: "any"}}}, ReturnType: resumed}
+		}
+	case "IteratorResult":
+		// The result of a yield leaves done out, that of a return sets it
+		switch name {
+		case "value":
+			return newUnionType(t.TypeArguments[0], t.TypeArguments[1])
+		case "done":
+			return newUnionType(&BasicType{Name: "boolean"}, &BasicType{Name: "undefined"})
+		}
+	case "Map":
+		key, value := t.TypeArguments[0], t.TypeArguments[1]
+		switch name {
+		case "size":
+			return &BasicType{Name: "number"}
+		case "keys":
+			return iterator(key)
+		case "values":
+			return iterator(value)
+		case "entries":
+			return iterator(&TupleType{Elements: []Type{key, value}})
+		}
+	case "Set":
+		value := t.TypeArguments[0]
+		switch name {
+		case "size":
+			return &BasicType{Name: "number"}
+		case "keys", "values":
+			return iterator(value)
+		case "entries":
+			return iterator(&TupleType{Elements: []Type{value, value}})
+		}
+	}
+	return &BasicType{Name: "any"}
+}